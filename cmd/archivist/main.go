@@ -22,18 +22,29 @@ import (
 const (
 	defaultPort    = "8080"
 	defaultRootDir = "/data"
+
+	defaultReadTimeout  = 15 * time.Second
+	defaultWriteTimeout = 15 * time.Second
+	defaultIdleTimeout  = 60 * time.Second
 )
 
 func main() {
 	// Parse command line flags
 	port := flag.String("port", getEnv("ARCHIVIST_PORT", defaultPort), "HTTP server port")
 	rootDir := flag.String("root", getEnv("ARCHIVIST_ROOT", defaultRootDir), "Root data directory")
+	dbFlag := flag.String("db", getEnv("ARCHIVIST_DB", ""), "SQLite database path (default {root}/config/archivist.db)")
 	logLevel := flag.String("log-level", getEnv("ARCHIVIST_LOG_LEVEL", "info"), "Log level (debug, info, warn, error)")
+	readTimeout := flag.Duration("read-timeout", getEnvDuration("ARCHIVIST_READ_TIMEOUT", defaultReadTimeout), "Maximum time to read a request, including its body")
+	writeTimeout := flag.Duration("write-timeout", getEnvDuration("ARCHIVIST_WRITE_TIMEOUT", defaultWriteTimeout), "Maximum time to write a response (WebSocket connections are exempt)")
+	idleTimeout := flag.Duration("idle-timeout", getEnvDuration("ARCHIVIST_IDLE_TIMEOUT", defaultIdleTimeout), "Maximum time to keep an idle keep-alive connection open")
 	flag.Parse()
 
 	// Derive paths from root directory
 	configPath := filepath.Join(*rootDir, "config", "config.json")
-	dbPath := filepath.Join(*rootDir, "config", "archivist.db")
+	dbPath := *dbFlag
+	if dbPath == "" {
+		dbPath = filepath.Join(*rootDir, "config", "archivist.db")
+	}
 	tempDir := filepath.Join(*rootDir, "temp")
 	sourcesDir := filepath.Join(*rootDir, "sources")
 
@@ -47,7 +58,7 @@ func main() {
 	log.Printf("Database: %s", dbPath)
 
 	// Ensure required directories exist
-	if err := ensureDirectories(*rootDir, tempDir, sourcesDir); err != nil {
+	if err := ensureDirectories(*rootDir, tempDir, sourcesDir, filepath.Dir(dbPath)); err != nil {
 		log.Fatalf("Failed to create directories: %v", err)
 	}
 
@@ -84,6 +95,13 @@ func main() {
 	}()
 	log.Println("Database initialized")
 
+	// Runs the last process left running or queued can't be resumed
+	if resolved, err := db.ResolveUnfinishedExecutions(time.Now()); err != nil {
+		log.Printf("Error resolving unfinished executions: %v", err)
+	} else if resolved > 0 {
+		log.Printf("Closed %d execution(s) left unfinished by the last shutdown", resolved)
+	}
+
 	// Initialize backup executor
 	log.Println("Initializing executor...")
 	exec := executor.NewExecutor(configMgr, db)
@@ -91,12 +109,7 @@ func main() {
 
 	// Initialize scheduler
 	log.Println("Initializing scheduler...")
-	sched := scheduler.NewScheduler(exec, configMgr)
-	if err := sched.Start(); err != nil {
-		log.Fatalf("Failed to start scheduler: %v", err)
-	}
-	defer sched.Stop()
-	log.Println("Scheduler started")
+	sched := scheduler.NewScheduler(exec, configMgr, db)
 
 	// Initialize API server
 	log.Println("Initializing API server...")
@@ -105,9 +118,9 @@ func main() {
 	httpServer := &http.Server{
 		Addr:         fmt.Sprintf(":%s", *port),
 		Handler:      server.Router(),
-		ReadTimeout:  15 * time.Second,
-		WriteTimeout: 15 * time.Second,
-		IdleTimeout:  60 * time.Second,
+		ReadTimeout:  *readTimeout,
+		WriteTimeout: *writeTimeout,
+		IdleTimeout:  *idleTimeout,
 	}
 
 	// Start HTTP server in a goroutine
@@ -118,6 +131,31 @@ func main() {
 		}
 	}()
 
+	// The listener is up first, so /system/ready reports not ready until the
+	// scheduler has started rather than refusing connections
+	if err := sched.Start(); err != nil {
+		log.Fatalf("Failed to start scheduler: %v", err)
+	}
+	defer sched.Stop()
+	log.Println("Scheduler started")
+
+	// Reload configuration on SIGHUP
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			log.Println("Received SIGHUP, reloading configuration...")
+			if err := configMgr.Reload(); err != nil {
+				log.Printf("Configuration reload failed, keeping current configuration: %v", err)
+				continue
+			}
+			if err := sched.ReloadSchedules(); err != nil {
+				log.Printf("Error reloading schedules: %v", err)
+			}
+			log.Println("Configuration reloaded")
+		}
+	}()
+
 	// Wait for interrupt signal to gracefully shutdown the server
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
@@ -144,12 +182,28 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+// getEnvDuration gets a duration such as "30s" or "5m" from an environment
+// variable, or returns a default value if it is unset or invalid
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("Ignoring invalid %s %q: %v", key, value, err)
+		return defaultValue
+	}
+	return duration
+}
+
 // ensureDirectories creates required directories if they don't exist
-func ensureDirectories(rootDir, tempDir, sourcesDir string) error {
+func ensureDirectories(rootDir, tempDir, sourcesDir, dbDir string) error {
 	dirs := []string{
 		filepath.Join(rootDir, "config"),
 		tempDir,
 		sourcesDir,
+		dbDir,
 	}
 
 	for _, dir := range dirs {
@@ -4,6 +4,8 @@ import (
 	"context"
 	"log"
 	"net/http"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/gorilla/mux"
@@ -50,29 +52,26 @@ func (s *Server) createBackend(w http.ResponseWriter, r *http.Request) {
 
 	// Build backend from form fields
 	backendData := models.Backend{
-		Name:    r.FormValue("name"),
-		Type:    r.FormValue("type"),
-		Enabled: r.FormValue("enabled") == "true",
-		Config:  make(map[string]interface{}),
-	}
+		Name:      r.FormValue("name"),
+		Type:      r.FormValue("type"),
+		Enabled:   r.FormValue("enabled") == "true",
+		Immutable: r.FormValue("immutable") == "true",
+		Config:    backendConfigFromForm(r),
 
-	// Extract config_ prefixed fields into Config map
-	for key, values := range r.Form {
-		if len(key) > 7 && key[:7] == "config_" {
-			configKey := key[7:] // Remove "config_" prefix
-			if len(values) > 0 && values[0] != "" {
-				backendData.Config[configKey] = values[0]
-			}
-		}
+		MaxConcurrentUploads: parseMaxConcurrentUploads(r),
 	}
 
 	// Validate required fields
 	if backendData.Type == "" {
-		s.error(w, "VALIDATION_ERROR", "Backend type is required", http.StatusBadRequest)
+		s.validationError(w, "type", "Backend type is required")
 		return
 	}
 	if backendData.Name == "" {
-		s.error(w, "VALIDATION_ERROR", "Backend name is required", http.StatusBadRequest)
+		s.validationError(w, "name", "Backend name is required")
+		return
+	}
+	if problems := backend.ValidateConfig(backendData.Type, backendData.Config); len(problems) > 0 {
+		s.errorWithDetails(w, "VALIDATION_ERROR", "Backend configuration is incomplete", problems, http.StatusBadRequest)
 		return
 	}
 
@@ -108,25 +107,23 @@ func (s *Server) updateBackend(w http.ResponseWriter, r *http.Request) {
 
 	// Build backend from form fields
 	backendData := models.Backend{
-		Name:    r.FormValue("name"),
-		Type:    r.FormValue("type"),
-		Enabled: r.FormValue("enabled") == "true",
-		Config:  make(map[string]interface{}),
-	}
+		Name:      r.FormValue("name"),
+		Type:      r.FormValue("type"),
+		Enabled:   r.FormValue("enabled") == "true",
+		Immutable: r.FormValue("immutable") == "true",
+		Config:    backendConfigFromForm(r),
 
-	// Extract config_ prefixed fields into Config map
-	for key, values := range r.Form {
-		if len(key) > 7 && key[:7] == "config_" {
-			configKey := key[7:] // Remove "config_" prefix
-			if len(values) > 0 && values[0] != "" {
-				backendData.Config[configKey] = values[0]
-			}
-		}
+		MaxConcurrentUploads: parseMaxConcurrentUploads(r),
 	}
 
 	// Merge config, preserving original values for masked fields
 	backendData.Config = unmaskSensitiveFields(backendData.Config, existing.Config)
 
+	if problems := backend.ValidateConfig(backendData.Type, backendData.Config); len(problems) > 0 {
+		s.errorWithDetails(w, "VALIDATION_ERROR", "Backend configuration is incomplete", problems, http.StatusBadRequest)
+		return
+	}
+
 	// Update backend
 	if err := s.config.UpdateBackend(id, &backendData); err != nil {
 		s.error(w, "INTERNAL_ERROR", err.Error(), http.StatusInternalServerError)
@@ -167,12 +164,155 @@ func (s *Server) testBackend(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	result, err := s.runBackendTest(backendCfg)
+	if err != nil {
+		s.errorWithDetails(w, "CONNECTION_FAILED", err.Error(), backendErrorDetails(backendCfg, err), http.StatusInternalServerError)
+		return
+	}
+
+	// Update backend test status
+	now := time.Now()
+	backendCfg.LastTest = &now
+	backendCfg.LastTestStatus = "success"
+	if err := s.config.UpdateBackend(id, backendCfg); err != nil {
+		log.Printf("Warning: failed to update backend test status: %v", err)
+	}
+
+	s.success(w, result)
+}
+
+const (
+	// testAllConcurrency limits how many backends are tested at once by test-all
+	testAllConcurrency = 8
+
+	// testAllTimeout bounds the whole test-all request, keeping it under the HTTP write timeout.
+	// Backends that haven't answered by then are reported as timed out.
+	testAllTimeout = 10 * time.Second
+)
+
+// testAllBackends handles POST /api/v1/backends/test-all
+// Tests every enabled backend concurrently and records each outcome as its last test.
+func (s *Server) testAllBackends(w http.ResponseWriter, r *http.Request) {
+	var backends []models.Backend
+	for _, b := range s.config.GetBackends() {
+		if b.Enabled {
+			backends = append(backends, b)
+		}
+	}
+
+	results := make([]map[string]interface{}, len(backends))
+	for i, b := range backends {
+		results[i] = map[string]interface{}{
+			"backend_id":   b.ID,
+			"backend_name": b.Name,
+			"status":       "failed",
+			"message":      "Test timed out",
+			"error_code":   backend.ErrorCodeNetwork,
+		}
+	}
+	finished := make([]bool, len(backends))
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, testAllConcurrency)
+	for i := range backends {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			start := time.Now()
+			result, err := s.runBackendTest(&backends[i])
+			if err != nil {
+				result = map[string]interface{}{
+					"status":     "failed",
+					"message":    err.Error(),
+					"error_code": backend.ClassifyError(nil, err),
+					"latency_ms": time.Since(start).Milliseconds(),
+				}
+			}
+			result["backend_id"] = backends[i].ID
+			result["backend_name"] = backends[i].Name
+
+			mu.Lock()
+			results[i] = result
+			finished[i] = true
+			mu.Unlock()
+		}(i)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(testAllTimeout):
+	}
+
+	// Tests still running keep going in the background but no longer affect the response
+	mu.Lock()
+	results = append([]map[string]interface{}(nil), results...)
+	finished = append([]bool(nil), finished...)
+	mu.Unlock()
+
+	now := time.Now()
+	for i, result := range results {
+		if !finished[i] {
+			continue
+		}
+		backendCfg, err := s.config.GetBackend(backends[i].ID)
+		if err != nil {
+			continue
+		}
+		backendCfg.LastTest = &now
+		backendCfg.LastTestStatus = result["status"].(string)
+		if err := s.config.UpdateBackend(backendCfg.ID, backendCfg); err != nil {
+			log.Printf("Warning: failed to update backend test status: %v", err)
+		}
+	}
+
+	s.success(w, results)
+}
+
+// testUnsavedBackend handles POST /api/v1/backends/test
+// Tests a backend config from the request without persisting anything.
+func (s *Server) testUnsavedBackend(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		s.error(w, "VALIDATION_ERROR", "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	backendCfg := &models.Backend{
+		Name:    r.FormValue("name"),
+		Type:    r.FormValue("type"),
+		Enabled: true,
+		Config:  backendConfigFromForm(r),
+	}
+
+	if backendCfg.Type == "" {
+		s.validationError(w, "type", "Backend type is required")
+		return
+	}
+
+	result, err := s.runBackendTest(backendCfg)
+	if err != nil {
+		s.errorWithDetails(w, "CONNECTION_FAILED", err.Error(), backendErrorDetails(backendCfg, err), http.StatusBadRequest)
+		return
+	}
+
+	s.success(w, result)
+}
+
+// runBackendTest instantiates a backend, tests the connection and reports latency and usage
+func (s *Server) runBackendTest(backendCfg *models.Backend) (map[string]interface{}, error) {
 	// Create backend instance
 	start := time.Now()
 	backendInstance, err := backend.Factory(backendCfg, s.config)
 	if err != nil {
-		s.error(w, "CONNECTION_FAILED", err.Error(), http.StatusInternalServerError)
-		return
+		return nil, err
 	}
 	defer func() {
 		if err := backendInstance.Close(); err != nil {
@@ -185,34 +325,186 @@ func (s *Server) testBackend(w http.ResponseWriter, r *http.Request) {
 	defer cancel()
 
 	if err := backendInstance.Test(); err != nil {
-		s.error(w, "CONNECTION_FAILED", err.Error(), http.StatusInternalServerError)
-		return
+		return nil, err
 	}
 
 	latency := time.Since(start).Milliseconds()
 
-	// Get storage usage
-	usage, _ := backendInstance.GetUsage(ctx)
-
-	// Update backend test status
-	now := time.Now()
-	backendCfg.LastTest = &now
-	backendCfg.LastTestStatus = "success"
-	if err := s.config.UpdateBackend(id, backendCfg); err != nil {
-		log.Printf("Warning: failed to update backend test status: %v", err)
-	}
-
 	result := map[string]interface{}{
 		"status":     "success",
 		"message":    "Connection successful",
 		"latency_ms": latency,
 	}
 
-	if usage != nil {
+	// Get storage usage
+	if usage, _ := backendInstance.GetUsage(ctx); usage != nil {
 		result["storage_usage"] = usage
 	}
 
-	s.success(w, result)
+	return result, nil
+}
+
+// backendErrorDetails identifies the backend a request failed on and classifies
+// the error, with the same keys as the test-all results
+func backendErrorDetails(backendCfg *models.Backend, err error) map[string]interface{} {
+	details := map[string]interface{}{
+		"backend_name": backendCfg.Name,
+		"backend_type": backendCfg.Type,
+		"error_code":   backend.ClassifyError(nil, err),
+	}
+	if backendCfg.ID != "" {
+		details["backend_id"] = backendCfg.ID
+	}
+	return details
+}
+
+// backendForDetails looks up a backend for backendErrorDetails, falling back to
+// just its ID when it isn't configured
+func (s *Server) backendForDetails(id string) *models.Backend {
+	backendCfg, err := s.config.GetBackend(id)
+	if err != nil {
+		return &models.Backend{ID: id}
+	}
+	return backendCfg
+}
+
+// parseMaxConcurrentUploads reads the per-backend transfer limit; anything but a positive number means no limit
+func parseMaxConcurrentUploads(r *http.Request) int {
+	val, err := strconv.Atoi(r.FormValue("max_concurrent_uploads"))
+	if err != nil || val < 0 {
+		return 0
+	}
+	return val
+}
+
+// backendConfigFromForm extracts config_ prefixed form fields into a backend config map
+func backendConfigFromForm(r *http.Request) map[string]interface{} {
+	config := make(map[string]interface{})
+	for key, values := range r.Form {
+		if len(key) > 7 && key[:7] == "config_" {
+			configKey := key[7:] // Remove "config_" prefix
+			if len(values) > 0 && values[0] != "" {
+				config[configKey] = values[0]
+			}
+		}
+	}
+	return config
+}
+
+// migrateBackend handles POST /api/v1/migrate
+// Copies all objects under prefix from one backend to another in the background.
+func (s *Server) migrateBackend(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		s.error(w, "VALIDATION_ERROR", "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	sourceID := r.FormValue("source_backend_id")
+	destinationID := r.FormValue("destination_backend_id")
+	var problems []models.ConfigProblem
+	if sourceID == "" {
+		problems = append(problems, models.ConfigProblem{Field: "source_backend_id", Message: "source backend is required"})
+	}
+	if destinationID == "" {
+		problems = append(problems, models.ConfigProblem{Field: "destination_backend_id", Message: "destination backend is required"})
+	}
+	if len(problems) > 0 {
+		s.errorWithDetails(w, "VALIDATION_ERROR", "source_backend_id and destination_backend_id are required", problems, http.StatusBadRequest)
+		return
+	}
+
+	migrationID, err := s.executor.Migrate(sourceID, destinationID, r.FormValue("prefix"))
+	if err != nil {
+		s.errorWithDetails(w, "MIGRATION_ERROR", err.Error(), map[string]interface{}{
+			"source_backend_id":      sourceID,
+			"destination_backend_id": destinationID,
+			"error_code":             backend.ClassifyError(nil, err),
+		}, http.StatusBadRequest)
+		return
+	}
+
+	s.success(w, map[string]interface{}{
+		"migration_id": migrationID,
+		"status":       "started",
+	})
+}
+
+// cancelMigration handles POST /api/v1/migrations/{id}/cancel
+func (s *Server) cancelMigration(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	if err := s.executor.CancelMigration(id); err != nil {
+		s.error(w, "NOT_FOUND", err.Error(), http.StatusNotFound)
+		return
+	}
+
+	s.success(w, map[string]interface{}{
+		"migration_id": id,
+		"status":       "cancelling",
+	})
+}
+
+// restoreChunked handles POST /api/v1/backends/{id}/restore-chunked
+// Reassembles a chunked archive into the temp directory in the background.
+func (s *Server) restoreChunked(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	if err := r.ParseForm(); err != nil {
+		s.error(w, "VALIDATION_ERROR", "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	restoreID, err := s.executor.RestoreChunked(id, r.FormValue("manifest"), r.FormValue("execution_id"))
+	if err != nil {
+		s.errorWithDetails(w, "RESTORE_ERROR", err.Error(), backendErrorDetails(s.backendForDetails(id), err), http.StatusBadRequest)
+		return
+	}
+
+	s.success(w, map[string]interface{}{
+		"restore_id": restoreID,
+		"status":     "started",
+	})
+}
+
+// restoreArchive handles POST /api/v1/backends/{id}/restore?dry_run=true
+// Downloads an archive into the temp directory in the background, joining split parts.
+// A dry run instead reads the archive through and returns the restore plan.
+func (s *Server) restoreArchive(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	if err := r.ParseForm(); err != nil {
+		s.error(w, "VALIDATION_ERROR", "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if r.FormValue("dry_run") == "true" {
+		// Reading a large archive outlasts the server's write timeout
+		if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+			log.Printf("Error clearing restore plan write deadline: %v", err)
+		}
+
+		plan, err := s.executor.PlanRestore(r.Context(), id, r.FormValue("remote_path"))
+		if err != nil {
+			s.errorWithDetails(w, "RESTORE_ERROR", err.Error(), backendErrorDetails(s.backendForDetails(id), err), http.StatusBadRequest)
+			return
+		}
+		s.success(w, plan)
+		return
+	}
+
+	restoreID, err := s.executor.RestoreArchive(id, r.FormValue("remote_path"))
+	if err != nil {
+		s.errorWithDetails(w, "RESTORE_ERROR", err.Error(), backendErrorDetails(s.backendForDetails(id), err), http.StatusBadRequest)
+		return
+	}
+
+	s.success(w, map[string]interface{}{
+		"restore_id": restoreID,
+		"status":     "started",
+	})
 }
 
 // maskSensitiveFields masks sensitive configuration values
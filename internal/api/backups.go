@@ -0,0 +1,375 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/nsilverman/archivist/internal/backend"
+	"github.com/nsilverman/archivist/internal/executor"
+	"github.com/nsilverman/archivist/internal/models"
+)
+
+// deleteTokenTTL is how long a backup deletion confirmation token stays valid
+const deleteTokenTTL = 5 * time.Minute
+
+// downloadWriteTimeout bounds each write of a backup download to the client,
+// in place of the server's write timeout, which would cut off large downloads
+const downloadWriteTimeout = time.Minute
+
+// deleteToken confirms the deletion of one backup
+type deleteToken struct {
+	target    string // task, backend and path the token was issued for
+	expiresAt time.Time
+}
+
+// listTaskBackups handles GET /api/v1/tasks/{id}/backups
+// Query params: ?backend_id= limits the listing to one of the task's backends;
+// ?filter= keeps backups whose file name matches a glob such as *.zip;
+// ?page= and ?per_page= (default 50) page each backend's listing.
+func (s *Server) listTaskBackups(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	query := r.URL.Query()
+
+	if _, err := s.config.GetTask(id); err != nil {
+		s.error(w, "NOT_FOUND", "Task not found", http.StatusNotFound)
+		return
+	}
+
+	filter := query.Get("filter")
+	if _, err := path.Match(filter, ""); err != nil {
+		s.validationError(w, "filter", "Invalid filter pattern")
+		return
+	}
+
+	// Without page or per_page the whole listing is returned
+	page, perPage := 1, 0
+	if query.Get("page") != "" || query.Get("per_page") != "" {
+		perPage = 50
+		if value := query.Get("per_page"); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil || n <= 0 {
+				s.validationError(w, "per_page", "per_page must be a positive number")
+				return
+			}
+			perPage = n
+		}
+		if value := query.Get("page"); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil || n <= 0 {
+				s.validationError(w, "page", "page must be a positive number")
+				return
+			}
+			page = n
+		}
+	}
+
+	listings, err := s.executor.ListBackups(r.Context(), id, query.Get("backend_id"))
+	if err != nil {
+		s.validationError(w, "backend_id", err.Error())
+		return
+	}
+
+	for i := range listings {
+		backups := filterBackups(listings[i].Backups, filter)
+		listings[i].Total = len(backups)
+		listings[i].Backups = pageBackups(backups, page, perPage)
+	}
+
+	s.success(w, listings)
+}
+
+// filterBackups keeps the backups whose file name matches pattern, or all of them if it is empty
+func filterBackups(backups []models.RemoteBackup, pattern string) []models.RemoteBackup {
+	if pattern == "" {
+		return backups
+	}
+	matched := make([]models.RemoteBackup, 0, len(backups))
+	for _, backup := range backups {
+		if ok, _ := path.Match(pattern, path.Base(backup.Path)); ok {
+			matched = append(matched, backup)
+		}
+	}
+	return matched
+}
+
+// pageBackups returns one page of backups; perPage 0 returns them all
+func pageBackups(backups []models.RemoteBackup, page, perPage int) []models.RemoteBackup {
+	if perPage == 0 {
+		return backups
+	}
+	start := (page - 1) * perPage
+	if start >= len(backups) {
+		return make([]models.RemoteBackup, 0)
+	}
+	return backups[start:min(start+perPage, len(backups))]
+}
+
+// deleteTaskBackup handles DELETE /api/v1/tasks/{id}/backups?backend_id=...&path=...
+// The first request returns a confirmation token instead of deleting; repeating
+// it with &confirm=<token> within five minutes deletes the backup.
+func (s *Server) deleteTaskBackup(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	if _, err := s.config.GetTask(id); err != nil {
+		s.error(w, "NOT_FOUND", "Task not found", http.StatusNotFound)
+		return
+	}
+
+	query := r.URL.Query()
+	backendID := query.Get("backend_id")
+	path := query.Get("path")
+	var problems []models.ConfigProblem
+	if backendID == "" {
+		problems = append(problems, models.ConfigProblem{Field: "backend_id", Message: "backend_id is required"})
+	}
+	if path == "" {
+		problems = append(problems, models.ConfigProblem{Field: "path", Message: "path is required"})
+	}
+	if len(problems) > 0 {
+		s.errorWithDetails(w, "VALIDATION_ERROR", "backend_id and path are required", problems, http.StatusBadRequest)
+		return
+	}
+	if !validBackupPath(path) {
+		s.validationError(w, "path", "path must be relative to the backend and cannot contain '..'")
+		return
+	}
+
+	target := strings.Join([]string{id, backendID, path}, "\x00")
+	confirm := query.Get("confirm")
+	if confirm == "" {
+		token, expiresAt, err := s.issueDeleteToken(target)
+		if err != nil {
+			s.error(w, "INTERNAL_ERROR", err.Error(), http.StatusInternalServerError)
+			return
+		}
+		s.errorWithDetails(w, "CONFIRMATION_REQUIRED", "Repeat the request with confirm set to the token to delete this backup", map[string]interface{}{
+			"confirm_token": token,
+			"expires_at":    expiresAt,
+		}, http.StatusPreconditionRequired)
+		return
+	}
+	if !s.redeemDeleteToken(confirm, target) {
+		s.error(w, "INVALID_CONFIRMATION", "Confirmation token is invalid or expired", http.StatusBadRequest)
+		return
+	}
+
+	deletions, err := s.executor.DeleteBackup(r.Context(), id, backendID, path)
+	if err != nil {
+		details := backendErrorDetails(s.backendForDetails(backendID), err)
+		details["task_id"] = id
+		details["path"] = path
+		switch {
+		case errors.Is(err, backend.ErrImmutableBackend):
+			s.errorWithDetails(w, "IMMUTABLE_BACKEND", err.Error(), details, http.StatusForbidden)
+		case errors.Is(err, executor.ErrBackupNotFound):
+			s.errorWithDetails(w, "NOT_FOUND", err.Error(), details, http.StatusNotFound)
+		default:
+			s.errorWithDetails(w, "DELETE_ERROR", err.Error(), details, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	s.success(w, deletions)
+}
+
+// downloadTaskBackup handles GET /api/v1/tasks/{id}/backups/download?backend_id=...&path=...
+// Streams one of the task's backups from the backend through the server, so
+// backends users can't reach directly can still be downloaded from. A split
+// archive is sent whole, its parts joined in order.
+func (s *Server) downloadTaskBackup(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	if _, err := s.config.GetTask(id); err != nil {
+		s.error(w, "NOT_FOUND", "Task not found", http.StatusNotFound)
+		return
+	}
+
+	query := r.URL.Query()
+	backendID := query.Get("backend_id")
+	remotePath := query.Get("path")
+	var problems []models.ConfigProblem
+	if backendID == "" {
+		problems = append(problems, models.ConfigProblem{Field: "backend_id", Message: "backend_id is required"})
+	}
+	if remotePath == "" {
+		problems = append(problems, models.ConfigProblem{Field: "path", Message: "path is required"})
+	}
+	if len(problems) > 0 {
+		s.errorWithDetails(w, "VALIDATION_ERROR", "backend_id and path are required", problems, http.StatusBadRequest)
+		return
+	}
+	if !validBackupPath(remotePath) {
+		s.validationError(w, "path", "path must be relative to the backend and cannot contain '..'")
+		return
+	}
+
+	reader, size, err := s.executor.OpenBackup(r.Context(), id, backendID, remotePath)
+	if err != nil {
+		details := backendErrorDetails(s.backendForDetails(backendID), err)
+		details["task_id"] = id
+		details["path"] = remotePath
+		if errors.Is(err, executor.ErrBackupNotFound) {
+			s.errorWithDetails(w, "NOT_FOUND", err.Error(), details, http.StatusNotFound)
+		} else {
+			s.errorWithDetails(w, "DOWNLOAD_ERROR", err.Error(), details, http.StatusInternalServerError)
+		}
+		return
+	}
+	defer func() {
+		if err := reader.Close(); err != nil {
+			log.Printf("Error closing backup download: %v", err)
+		}
+	}()
+
+	w.Header().Set("Content-Type", backend.ContentType(remotePath))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": path.Base(remotePath)}))
+	if size > 0 {
+		w.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+	}
+	w.WriteHeader(http.StatusOK)
+
+	// Headers are sent, so a failure from here on can only cut the download short
+	writer := &deadlineWriter{writer: w, controller: http.NewResponseController(w)}
+	if _, err := io.Copy(writer, reader); err != nil {
+		log.Printf("Backup download of %s interrupted: %v", remotePath, err)
+	}
+}
+
+// validBackupPath reports whether a remote path stays within the backend: it
+// must be relative, already clean, and so free of '..' segments
+func validBackupPath(remotePath string) bool {
+	return !path.IsAbs(remotePath) && path.Clean(remotePath) == remotePath &&
+		remotePath != ".." && !strings.HasPrefix(remotePath, "../")
+}
+
+// deadlineWriter gives each write to the client its own deadline
+type deadlineWriter struct {
+	writer     io.Writer
+	controller *http.ResponseController
+}
+
+func (d *deadlineWriter) Write(p []byte) (int, error) {
+	if err := d.controller.SetWriteDeadline(time.Now().Add(downloadWriteTimeout)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return 0, err
+	}
+	return d.writer.Write(p)
+}
+
+// listBackupDeletions handles GET /api/v1/tasks/{id}/backups/deletions
+// Query params: ?limit=50 (default)
+func (s *Server) listBackupDeletions(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	if _, err := s.config.GetTask(id); err != nil {
+		s.error(w, "NOT_FOUND", "Task not found", http.StatusNotFound)
+		return
+	}
+
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = 50
+	}
+
+	deletions, err := s.db.ListBackupDeletions(id, limit)
+	if err != nil {
+		s.error(w, "DATABASE_ERROR", err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	s.success(w, deletions)
+}
+
+// verifyTaskBackups handles POST /api/v1/tasks/{id}/verify
+// Checks the task's backups against their hashes in the background and returns
+// the running report; the finished one is listed by GET .../verifications.
+func (s *Server) verifyTaskBackups(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	if _, err := s.config.GetTask(id); err != nil {
+		s.error(w, "NOT_FOUND", "Task not found", http.StatusNotFound)
+		return
+	}
+
+	verification, err := s.executor.VerifyBackups(id)
+	if err != nil {
+		s.errorWithDetails(w, "VERIFICATION_ERROR", err.Error(), map[string]interface{}{"task_id": id}, http.StatusBadRequest)
+		return
+	}
+
+	s.success(w, verification)
+}
+
+// listBackupVerifications handles GET /api/v1/tasks/{id}/verifications
+// Query params: ?limit=10 (default)
+func (s *Server) listBackupVerifications(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	if _, err := s.config.GetTask(id); err != nil {
+		s.error(w, "NOT_FOUND", "Task not found", http.StatusNotFound)
+		return
+	}
+
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = 10
+	}
+
+	verifications, err := s.db.ListBackupVerifications(id, limit)
+	if err != nil {
+		s.error(w, "DATABASE_ERROR", err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	s.success(w, verifications)
+}
+
+// issueDeleteToken creates a single-use token confirming the deletion of target
+func (s *Server) issueDeleteToken(target string) (string, time.Time, error) {
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		return "", time.Time{}, err
+	}
+	token := hex.EncodeToString(raw)
+	expiresAt := time.Now().Add(deleteTokenTTL)
+
+	s.deleteTokensMu.Lock()
+	defer s.deleteTokensMu.Unlock()
+
+	// Drop expired tokens so abandoned confirmations don't accumulate
+	for key, issued := range s.deleteTokens {
+		if time.Now().After(issued.expiresAt) {
+			delete(s.deleteTokens, key)
+		}
+	}
+	s.deleteTokens[token] = deleteToken{target: target, expiresAt: expiresAt}
+	return token, expiresAt, nil
+}
+
+// redeemDeleteToken consumes token, reporting whether it was issued for target and is still valid
+func (s *Server) redeemDeleteToken(token, target string) bool {
+	s.deleteTokensMu.Lock()
+	defer s.deleteTokensMu.Unlock()
+
+	issued, ok := s.deleteTokens[token]
+	if !ok {
+		return false
+	}
+	delete(s.deleteTokens, token)
+	return issued.target == target && time.Now().Before(issued.expiresAt)
+}
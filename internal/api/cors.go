@@ -0,0 +1,65 @@
+package api
+
+import (
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// corsMaxAge is how long browsers may cache a preflight response, in seconds
+const corsMaxAge = "600"
+
+// withCORS lets the origins listed in the allowed_origins setting call the
+// API from the browser. Requests from the same origin, or without an Origin
+// header, pass through; requests from any other origin are refused, so a page
+// on another site can't trigger runs or change the configuration.
+func (s *Server) withCORS(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" || sameOrigin(r, origin) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Add("Vary", "Origin")
+		if !s.originListed(origin) {
+			s.error(w, "ORIGIN_NOT_ALLOWED", "Origin not allowed: "+origin, http.StatusForbidden)
+			return
+		}
+
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Expose-Headers", requestIDHeader)
+
+		// Answer preflight requests here; they carry no credentials and match no route
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, "+requestIDHeader+", "+idempotencyKeyHeader)
+			w.Header().Set("Access-Control-Max-Age", corsMaxAge)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// checkOrigin is the WebSocket upgrader's origin check, applying the same rules as withCORS
+func (s *Server) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	return origin == "" || sameOrigin(r, origin) || s.originListed(origin)
+}
+
+// originListed reports whether the allowed_origins setting lists origin, or is "*"
+func (s *Server) originListed(origin string) bool {
+	origins := s.config.GetSettings().AllowedOrigins
+	return slices.Contains(origins, "*") || slices.ContainsFunc(origins, func(allowed string) bool {
+		return strings.EqualFold(strings.TrimSuffix(allowed, "/"), origin)
+	})
+}
+
+// sameOrigin reports whether origin names the host the request was sent to
+func sameOrigin(r *http.Request, origin string) bool {
+	u, err := url.Parse(origin)
+	return err == nil && u.Host != "" && strings.EqualFold(u.Host, r.Host)
+}
@@ -1,10 +1,12 @@
 package api
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
 	"github.com/gorilla/mux"
+	"github.com/nsilverman/archivist/internal/executor"
 )
 
 // listExecutions handles GET /api/v1/executions
@@ -50,6 +52,29 @@ func (s *Server) getExecution(w http.ResponseWriter, r *http.Request) {
 	s.success(w, execution)
 }
 
+// getExecutionManifest handles GET /api/v1/executions/{id}/manifest
+func (s *Server) getExecutionManifest(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	if _, err := s.db.GetExecution(id); err != nil {
+		s.error(w, "NOT_FOUND", "Execution not found", http.StatusNotFound)
+		return
+	}
+
+	contents, err := s.db.GetArchiveContents(id)
+	if err != nil {
+		s.error(w, "INTERNAL_ERROR", err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if contents == nil {
+		s.error(w, "NOT_FOUND", "No manifest recorded for this execution", http.StatusNotFound)
+		return
+	}
+
+	s.success(w, contents)
+}
+
 // cancelExecution handles POST /api/v1/executions/{id}/cancel
 func (s *Server) cancelExecution(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
@@ -66,6 +91,77 @@ func (s *Server) cancelExecution(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// listRunningExecutions handles GET /api/v1/executions/running
+func (s *Server) listRunningExecutions(w http.ResponseWriter, r *http.Request) {
+	s.success(w, s.executor.GetRunningExecutions())
+}
+
+// listQueuedExecutions handles GET /api/v1/executions/queue
+func (s *Server) listQueuedExecutions(w http.ResponseWriter, r *http.Request) {
+	s.success(w, s.executor.GetQueuedExecutions())
+}
+
+// dequeueExecution handles DELETE /api/v1/executions/queue/{id}
+func (s *Server) dequeueExecution(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	if err := s.executor.Dequeue(id); err != nil {
+		s.error(w, "NOT_FOUND", err.Error(), http.StatusNotFound)
+		return
+	}
+
+	s.success(w, map[string]interface{}{
+		"id":     id,
+		"status": "cancelled",
+	})
+}
+
+// cancelAllExecutions handles POST /api/v1/executions/cancel-all
+func (s *Server) cancelAllExecutions(w http.ResponseWriter, r *http.Request) {
+	ids := s.executor.CancelAll()
+
+	s.success(w, map[string]interface{}{
+		"cancelled": ids,
+		"count":     len(ids),
+	})
+}
+
+// retryExecution handles POST /api/v1/executions/{id}/retry?failed_only=true
+func (s *Server) retryExecution(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	execution, err := s.db.GetExecution(id)
+	if err != nil {
+		s.error(w, "NOT_FOUND", "Execution not found", http.StatusNotFound)
+		return
+	}
+
+	failedOnly := r.URL.Query().Get("failed_only") == "true"
+
+	executionID, err := s.executor.Retry(id, failedOnly, requestID(r))
+	if err != nil {
+		details := map[string]interface{}{
+			"task_id":      execution.TaskID,
+			"execution_id": id,
+		}
+		if errors.Is(err, executor.ErrTaskRunning) {
+			s.errorWithDetails(w, "ALREADY_RUNNING", err.Error(), details, http.StatusConflict)
+			return
+		}
+		s.errorWithDetails(w, "EXECUTION_ERROR", err.Error(), details, http.StatusBadRequest)
+		return
+	}
+
+	s.success(w, map[string]interface{}{
+		"execution_id": executionID,
+		"retry_of":     id,
+		"status":       "running",
+		"failed_only":  failedOnly,
+	})
+}
+
 // clearHistory handles DELETE /api/v1/executions
 func (s *Server) clearHistory(w http.ResponseWriter, r *http.Request) {
 	if err := s.db.ClearHistory(); err != nil {
@@ -0,0 +1,143 @@
+package api
+
+import (
+	"compress/gzip"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/websocket"
+)
+
+// gzipMinSize is the response size below which compressing isn't worth it
+const gzipMinSize = 1024
+
+// withGzip compresses JSON and HTML responses of at least gzipMinSize bytes
+// for clients that accept gzip. WebSocket upgrades and responses that already
+// have a Content-Encoding or another content type pass through untouched.
+func withGzip(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if websocket.IsWebSocketUpgrade(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Add("Vary", "Accept-Encoding")
+		if !acceptsGzip(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		gw := &gzipResponseWriter{ResponseWriter: w}
+		defer gw.finish()
+		next.ServeHTTP(gw, r)
+	})
+}
+
+// acceptsGzip reports whether the request's Accept-Encoding allows gzip
+func acceptsGzip(r *http.Request) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if !strings.EqualFold(strings.TrimSpace(coding), "gzip") {
+			continue
+		}
+		// gzip;q=0 refuses it
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			q, err := strconv.ParseFloat(value, 64)
+			return err != nil || q > 0
+		}
+		return true
+	}
+	return false
+}
+
+// gzipResponseWriter holds back the start of a response until it knows whether
+// the response is worth compressing: a compressible type and at least gzipMinSize bytes
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	status  int
+	buf     []byte       // body written while undecided
+	gz      *gzip.Writer // set once compressing
+	decided bool         // header sent, plain or compressed
+}
+
+func (w *gzipResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *gzipResponseWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	if !w.decided {
+		if !w.compressible() {
+			w.sendHeader()
+		} else {
+			w.buf = append(w.buf, p...)
+			if len(w.buf) < gzipMinSize {
+				return len(p), nil
+			}
+			w.startGzip()
+			if _, err := w.gz.Write(w.buf); err != nil {
+				return 0, err
+			}
+			w.buf = nil
+			return len(p), nil
+		}
+	}
+
+	if w.gz != nil {
+		return w.gz.Write(p)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (w *gzipResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// compressible reports whether the response's type is worth compressing
+func (w *gzipResponseWriter) compressible() bool {
+	header := w.Header()
+	if header.Get("Content-Encoding") != "" {
+		return false
+	}
+	contentType := header.Get("Content-Type")
+	return strings.HasPrefix(contentType, "application/json") || strings.HasPrefix(contentType, "text/html")
+}
+
+// startGzip sends the header for a compressed response and starts the gzip stream
+func (w *gzipResponseWriter) startGzip() {
+	header := w.Header()
+	header.Set("Content-Encoding", "gzip")
+	header.Del("Content-Length")
+	w.sendHeader()
+	w.gz = gzip.NewWriter(w.ResponseWriter)
+}
+
+// sendHeader sends the response header once the encoding is settled
+func (w *gzipResponseWriter) sendHeader() {
+	w.decided = true
+	w.ResponseWriter.WriteHeader(w.status)
+}
+
+// finish sends a short response uncompressed, or ends the gzip stream
+func (w *gzipResponseWriter) finish() {
+	switch {
+	case w.gz != nil:
+		if err := w.gz.Close(); err != nil {
+			log.Printf("Error finishing gzip response: %v", err)
+		}
+	case !w.decided && w.status != 0:
+		w.sendHeader()
+		if len(w.buf) > 0 {
+			if _, err := w.ResponseWriter.Write(w.buf); err != nil {
+				log.Printf("Error writing response: %v", err)
+			}
+		}
+	}
+}
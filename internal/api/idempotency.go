@@ -0,0 +1,59 @@
+package api
+
+import (
+	"errors"
+	"time"
+)
+
+// idempotencyKeyHeader lets a client retry POST /tasks/{id}/execute without
+// starting a second run
+const idempotencyKeyHeader = "Idempotency-Key"
+
+// idempotencyKeyTTL is how long an idempotency key keeps pointing at its execution
+const idempotencyKeyTTL = 24 * time.Hour
+
+// errIdempotencyKeyReused is returned when a key that started a run of one task is sent for another
+var errIdempotencyKeyReused = errors.New("idempotency key was already used for another task")
+
+// idempotentExecution is the execution an idempotency key started
+type idempotentExecution struct {
+	taskID      string
+	executionID string
+	expiresAt   time.Time
+}
+
+// executeOnce starts a run of taskID through start unless key already started
+// one within idempotencyKeyTTL, in which case that execution's ID is returned
+// with replayed set. Failed starts aren't remembered, so the client can retry
+// with the same key. The lock is held while starting, so concurrent requests
+// with one key start a single run.
+func (s *Server) executeOnce(key, taskID string, start func() (string, error)) (executionID string, replayed bool, err error) {
+	s.idempotencyMu.Lock()
+	defer s.idempotencyMu.Unlock()
+
+	// Drop expired keys so they don't accumulate
+	now := time.Now()
+	for k, execution := range s.idempotencyKeys {
+		if now.After(execution.expiresAt) {
+			delete(s.idempotencyKeys, k)
+		}
+	}
+
+	if execution, ok := s.idempotencyKeys[key]; ok {
+		if execution.taskID != taskID {
+			return "", false, errIdempotencyKeyReused
+		}
+		return execution.executionID, true, nil
+	}
+
+	executionID, err = start()
+	if err != nil {
+		return "", false, err
+	}
+	s.idempotencyKeys[key] = idempotentExecution{
+		taskID:      taskID,
+		executionID: executionID,
+		expiresAt:   now.Add(idempotencyKeyTTL),
+	}
+	return executionID, false, nil
+}
@@ -1,12 +1,22 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
+	"github.com/nsilverman/archivist/internal/archive"
+	"github.com/nsilverman/archivist/internal/backend"
+	"github.com/nsilverman/archivist/internal/config"
 	"github.com/nsilverman/archivist/internal/models"
+	"github.com/nsilverman/archivist/internal/scheduler"
 )
 
 // listSourcesHTML handles GET /api/v1/sources (with Accept: text/html)
@@ -18,20 +28,13 @@ func (s *Server) listSourcesHTML(w http.ResponseWriter, r *http.Request) {
 	// Get optional path parameter for browsing subdirectories
 	subPath := r.URL.Query().Get("path")
 
-	// Validate path doesn't escape sources directory
-	if err := validateSubPath(subPath); err != nil {
+	// Build the target directory path, rejecting paths that escape the sources directory
+	targetDir, err := resolveSourcePath(sourcesDir, subPath)
+	if err != nil {
 		http.Error(w, "Invalid path", http.StatusBadRequest)
 		return
 	}
 
-	// Build the target directory path
-	var targetDir string
-	if subPath != "" {
-		targetDir = filepath.Join(sourcesDir, subPath)
-	} else {
-		targetDir = sourcesDir
-	}
-
 	// Check if target directory exists
 	if _, err := os.Stat(targetDir); os.IsNotExist(err) {
 		s.htmlResponse(w, "file_browser.html", map[string]interface{}{
@@ -123,20 +126,13 @@ func (s *Server) listSources(w http.ResponseWriter, r *http.Request) {
 	// Get optional path parameter for browsing subdirectories
 	subPath := r.URL.Query().Get("path")
 
-	// Validate path doesn't escape sources directory
-	if err := validateSubPath(subPath); err != nil {
-		s.error(w, "VALIDATION_ERROR", "Invalid path", http.StatusBadRequest)
+	// Build the target directory path, rejecting paths that escape the sources directory
+	targetDir, err := resolveSourcePath(sourcesDir, subPath)
+	if err != nil {
+		s.validationError(w, "path", "Invalid path")
 		return
 	}
 
-	// Build the target directory path
-	var targetDir string
-	if subPath != "" {
-		targetDir = filepath.Join(sourcesDir, subPath)
-	} else {
-		targetDir = sourcesDir
-	}
-
 	// Check if target directory exists
 	if _, err := os.Stat(targetDir); os.IsNotExist(err) {
 		s.success(w, map[string]interface{}{
@@ -207,6 +203,55 @@ func (s *Server) listSources(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// sourceStatsTimeout bounds how long a recursive source scan may take
+const sourceStatsTimeout = 30 * time.Second
+
+// getSourceStats handles GET /api/v1/sources/stats
+// Unlike the browser listing, sizes are computed recursively.
+func (s *Server) getSourceStats(w http.ResponseWriter, r *http.Request) {
+	settings := s.config.GetSettings()
+	sourcesDir := s.config.ResolvePath(settings.SourcesDir)
+
+	subPath := r.URL.Query().Get("path")
+
+	// Validate path doesn't escape sources directory
+	targetDir, err := resolveSourcePath(sourcesDir, subPath)
+	if err != nil {
+		s.validationError(w, "path", "Invalid path")
+		return
+	}
+
+	info, err := os.Stat(targetDir)
+	if err != nil {
+		s.error(w, "NOT_FOUND", "Path not found", http.StatusNotFound)
+		return
+	}
+	if !info.IsDir() {
+		s.validationError(w, "path", "Path is not a directory")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), sourceStatsTimeout)
+	defer cancel()
+
+	// A timed out scan still returns the partial totals, flagged as truncated
+	summary, err := s.executor.ScanSource(ctx, targetDir)
+	truncated := errors.Is(err, context.DeadlineExceeded)
+	if err != nil && !truncated {
+		s.error(w, "SCAN_ERROR", err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	s.success(w, map[string]interface{}{
+		"path":        subPath,
+		"total_size":  summary.TotalSize,
+		"total_files": summary.TotalFiles,
+		"total_dirs":  summary.TotalDirs,
+		"file_types":  summary.FileTypes,
+		"truncated":   truncated,
+	})
+}
+
 // getConfig handles GET /api/v1/config
 func (s *Server) getConfig(w http.ResponseWriter, r *http.Request) {
 	config := s.config.Get()
@@ -230,26 +275,181 @@ func (s *Server) updateSettings(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if settings.AutoVacuumSchedule != "" {
+		if err := scheduler.ValidateSchedule(models.Schedule{Type: "cron", CronExpr: settings.AutoVacuumSchedule}); err != nil {
+			s.validationError(w, "auto_vacuum_schedule", fmt.Sprintf("invalid auto_vacuum_schedule: %v", err))
+			return
+		}
+	}
+	if settings.StateBackup != nil && settings.StateBackup.Schedule != "" {
+		if err := scheduler.ValidateSchedule(models.Schedule{Type: "cron", CronExpr: settings.StateBackup.Schedule}); err != nil {
+			s.validationError(w, "state_backup.schedule", fmt.Sprintf("invalid state_backup schedule: %v", err))
+			return
+		}
+	}
+	if settings.StateBackup != nil && settings.StateBackup.KeepLast < 0 {
+		s.validationError(w, "state_backup.keep_last", "keep_last must be 0 (keep every state backup) or a number of backups")
+		return
+	}
+
+	for field, mode := range map[string]string{
+		"archive_file_mode": settings.ArchiveFileMode,
+		"config_file_mode":  settings.ConfigFileMode,
+	} {
+		if _, err := config.ParseFileMode(mode); err != nil {
+			s.validationError(w, field, fmt.Sprintf("invalid %s: %v", field, err))
+			return
+		}
+	}
+
+	if err := archive.ValidateHashAlgorithm(settings.HashAlgorithm); err != nil {
+		s.validationError(w, "hash_algorithm", fmt.Sprintf("invalid hash_algorithm: %v", err))
+		return
+	}
+
 	if err := s.config.UpdateSettings(settings); err != nil {
 		s.error(w, "INTERNAL_ERROR", err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	if err := s.scheduler.ScheduleMaintenance(); err != nil {
+		log.Printf("Error scheduling database maintenance: %v", err)
+	}
+
 	s.success(w, map[string]interface{}{
 		"settings": settings,
 	})
 }
 
-// validateSubPath validates that a subpath doesn't escape the base directory
-func validateSubPath(subPath string) error {
+// validateConfig handles GET/POST /api/v1/config/validate
+// Checks a full configuration body without applying it and reports every problem found.
+func (s *Server) validateConfig(w http.ResponseWriter, r *http.Request) {
+	var cfg models.Config
+	if err := json.NewDecoder(r.Body).Decode(&cfg); err != nil {
+		s.error(w, "VALIDATION_ERROR", "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	problems := s.config.Validate(&cfg)
+	for i, b := range cfg.Backends {
+		if b.Type == "" {
+			continue // already reported as missing
+		}
+		for _, problem := range backend.ValidateConfig(b.Type, b.Config) {
+			problem.Field = fmt.Sprintf("backends[%d].%s", i, problem.Field)
+			problems = append(problems, problem)
+		}
+	}
+	for i, task := range cfg.Tasks {
+		if err := scheduler.ValidateSchedule(task.Schedule); err != nil {
+			problems = append(problems, models.ConfigProblem{
+				Field:   fmt.Sprintf("tasks[%d].schedule", i),
+				Message: fmt.Sprintf("invalid schedule for task %s: %v", task.ID, err),
+			})
+		}
+	}
+	if cfg.Settings.AutoVacuumSchedule != "" {
+		if err := scheduler.ValidateSchedule(models.Schedule{Type: "cron", CronExpr: cfg.Settings.AutoVacuumSchedule}); err != nil {
+			problems = append(problems, models.ConfigProblem{
+				Field:   "settings.auto_vacuum_schedule",
+				Message: fmt.Sprintf("invalid auto-vacuum schedule: %v", err),
+			})
+		}
+	}
+	if cfg.Settings.StateBackup != nil && cfg.Settings.StateBackup.Schedule != "" {
+		if err := scheduler.ValidateSchedule(models.Schedule{Type: "cron", CronExpr: cfg.Settings.StateBackup.Schedule}); err != nil {
+			problems = append(problems, models.ConfigProblem{
+				Field:   "settings.state_backup.schedule",
+				Message: fmt.Sprintf("invalid state backup schedule: %v", err),
+			})
+		}
+	}
+	if problems == nil {
+		problems = []models.ConfigProblem{}
+	}
+
+	s.success(w, map[string]interface{}{
+		"valid":    len(problems) == 0,
+		"problems": problems,
+	})
+}
+
+// reloadConfig handles POST /api/v1/config/reload
+func (s *Server) reloadConfig(w http.ResponseWriter, r *http.Request) {
+	if err := s.config.Reload(); err != nil {
+		s.error(w, "VALIDATION_ERROR", err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := s.scheduler.ReloadSchedules(); err != nil {
+		s.error(w, "SCHEDULE_ERROR", err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	s.success(w, map[string]interface{}{
+		"message": "Configuration reloaded successfully",
+	})
+}
+
+// errPathEscapesSources is returned when a requested path resolves outside the sources directory
+var errPathEscapesSources = errors.New("path escapes sources directory")
+
+// resolveSourcePath joins subPath onto the sources directory and verifies the
+// result stays inside it. Symlinks directly inside the sources directory are
+// trusted since that is how sources are added; symlinks nested deeper must
+// resolve inside the top-level source they belong to.
+func resolveSourcePath(sourcesDir, subPath string) (string, error) {
+	root := filepath.Clean(sourcesDir)
 	if subPath == "" {
-		return nil
+		return root, nil
+	}
+	if filepath.IsAbs(subPath) {
+		return "", errPathEscapesSources
+	}
+
+	target := filepath.Join(root, subPath)
+	if !isWithinDir(root, target) {
+		return "", errPathEscapesSources
+	}
+	if target == root {
+		return target, nil
+	}
+
+	// Resolve symlinks; paths that don't exist yet are left for the caller to handle
+	rel, err := filepath.Rel(root, target)
+	if err != nil {
+		return "", errPathEscapesSources
 	}
-	// filepath.IsLocal ensures the path is relative and doesn't escape (Go 1.20+)
-	if !filepath.IsLocal(subPath) {
-		return filepath.ErrBadPattern
+	topLevel := filepath.Join(root, strings.SplitN(rel, string(filepath.Separator), 2)[0])
+
+	resolvedTop, err := filepath.EvalSymlinks(topLevel)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return target, nil
+		}
+		return "", err
+	}
+	resolvedTarget, err := filepath.EvalSymlinks(target)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return target, nil
+		}
+		return "", err
+	}
+	if !isWithinDir(resolvedTop, resolvedTarget) {
+		return "", errPathEscapesSources
+	}
+
+	return target, nil
+}
+
+// isWithinDir reports whether path is dir or located beneath it
+func isWithinDir(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
 	}
-	return nil
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
 }
 
 // calculateDirSize calculates the total size of files in a directory (non-recursive)
@@ -0,0 +1,54 @@
+package api
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// requestIDHeader carries the ID that ties an API call to its log lines and to
+// any execution it starts
+const requestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds IDs taken from clients, which end up in logs and the database
+const maxRequestIDLength = 128
+
+type requestIDKey struct{}
+
+// withRequestID assigns each request an ID, taken from the X-Request-ID header
+// when the client sent a usable one and generated otherwise. The ID is echoed
+// in the response and kept in the request context for handlers and logs.
+func withRequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestIDHeader)
+		if !validRequestID(id) {
+			id = uuid.New().String()
+		}
+		w.Header().Set(requestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id)))
+	})
+}
+
+// requestID returns the ID withRequestID assigned to r
+func requestID(r *http.Request) string {
+	id, _ := r.Context().Value(requestIDKey{}).(string)
+	return id
+}
+
+// validRequestID reports whether a client-supplied ID is short and made only of
+// characters that are safe to log: letters, digits and - _ . :
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -1,16 +1,22 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"html/template"
 	"log"
 	"net/http"
+	"os"
 	"path/filepath"
 	"runtime"
 	"sync"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/gorilla/websocket"
+	"github.com/nsilverman/archivist/internal/backend"
 	"github.com/nsilverman/archivist/internal/config"
 	"github.com/nsilverman/archivist/internal/executor"
 	"github.com/nsilverman/archivist/internal/models"
@@ -21,13 +27,25 @@ import (
 // Server represents the HTTP API server
 type Server struct {
 	config    *config.Manager
-	db        *storage.Database
+	db        storage.Store
 	executor  *executor.Executor
 	scheduler *scheduler.Scheduler
 	templates map[string]*template.Template
-	wsClients map[*websocket.Conn]bool
+	wsClients map[*wsClient]bool
 	wsMu      sync.RWMutex
 	upgrader  websocket.Upgrader
+
+	storageMu    sync.Mutex
+	storageCache *models.StorageOverview // last aggregate storage usage, see systemStorage
+
+	deleteTokensMu sync.Mutex
+	deleteTokens   map[string]deleteToken // pending backup deletion confirmations, see deleteTaskBackup
+
+	sourceHealthMu sync.Mutex
+	sourceHealth   map[string]sourceHealth // recent source accessibility checks, see sourceAccessible
+
+	idempotencyMu   sync.Mutex
+	idempotencyKeys map[string]idempotentExecution // executions started with an Idempotency-Key, see executeOnce
 }
 
 // Response represents a standard API response
@@ -45,20 +63,20 @@ type ErrorInfo struct {
 }
 
 // NewServer creates a new API server
-func NewServer(cfg *config.Manager, db *storage.Database, exec *executor.Executor, sched *scheduler.Scheduler) *Server {
+func NewServer(cfg *config.Manager, db storage.Store, exec *executor.Executor, sched *scheduler.Scheduler) *Server {
 	s := &Server{
-		config:    cfg,
-		db:        db,
-		executor:  exec,
-		scheduler: sched,
-		templates: make(map[string]*template.Template),
-		wsClients: make(map[*websocket.Conn]bool),
-		upgrader: websocket.Upgrader{
-			CheckOrigin: func(r *http.Request) bool {
-				return true // Allow all origins for now
-			},
-		},
+		config:       cfg,
+		db:           db,
+		executor:     exec,
+		scheduler:    sched,
+		templates:    make(map[string]*template.Template),
+		wsClients:    make(map[*wsClient]bool),
+		deleteTokens: make(map[string]deleteToken),
+		sourceHealth: make(map[string]sourceHealth),
+
+		idempotencyKeys: make(map[string]idempotentExecution),
 	}
+	s.upgrader.CheckOrigin = s.checkOrigin
 
 	// Initialize templates
 	if err := s.initTemplates(); err != nil {
@@ -103,22 +121,27 @@ func (s *Server) initTemplates() error {
 func (s *Server) Router() *mux.Router {
 	r := mux.NewRouter()
 
-	// Logging middleware
+	// Request ID and logging middleware
+	r.Use(withRequestID)
 	r.Use(func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			log.Printf("%s %s", r.Method, r.URL.Path)
+			log.Printf("[%s] %s %s", requestID(r), r.Method, r.URL.Path)
 			next.ServeHTTP(w, r)
 		})
 	})
 
-	// API routes
+	// API routes, callable from the origins in the allowed_origins setting and
+	// compressed for clients that accept gzip
 	api := r.PathPrefix("/api/v1").Subrouter()
+	api.Use(s.withCORS)
+	api.Use(withGzip)
 
 	// HTML routes MUST come before parameterized routes to avoid conflicts
 	// Tasks HTML
 	api.HandleFunc("/tasks/html", s.listTasksHTML).Methods("GET")
 	api.HandleFunc("/tasks/form/create", s.createTaskFormHTML).Methods("GET")
 	api.HandleFunc("/tasks/form/edit/{id}", s.editTaskFormHTML).Methods("GET")
+	api.HandleFunc("/tasks/{id}/dry-run/html", s.dryRunTaskHTML).Methods("POST")
 
 	// Backends HTML
 	api.HandleFunc("/backends/html", s.listBackendsHTML).Methods("GET")
@@ -137,8 +160,19 @@ func (s *Server) Router() *mux.Router {
 	// Tasks (JSON API)
 	api.HandleFunc("/tasks", s.listTasks).Methods("GET")
 	api.HandleFunc("/tasks", s.createTask).Methods("POST")
-	api.HandleFunc("/tasks/{id}/dry-run", s.dryRunTaskHTML).Methods("POST")
+	api.HandleFunc("/tasks/preview-name", s.previewUnsavedTaskName).Methods("POST")
+	api.HandleFunc("/tasks/{id}/dry-run", s.dryRunTask).Methods("GET", "POST")
+	api.HandleFunc("/tasks/{id}/retention-preview", s.retentionPreview).Methods("GET")
+	api.HandleFunc("/tasks/{id}/trends", s.taskTrends).Methods("GET")
+	api.HandleFunc("/tasks/{id}/backups", s.listTaskBackups).Methods("GET")
+	api.HandleFunc("/tasks/{id}/backups", s.deleteTaskBackup).Methods("DELETE")
+	api.HandleFunc("/tasks/{id}/backups/deletions", s.listBackupDeletions).Methods("GET")
+	api.HandleFunc("/tasks/{id}/backups/download", s.downloadTaskBackup).Methods("GET")
+	api.HandleFunc("/tasks/{id}/verify", s.verifyTaskBackups).Methods("POST")
+	api.HandleFunc("/tasks/{id}/verifications", s.listBackupVerifications).Methods("GET")
+	api.HandleFunc("/tasks/{id}/preview-name", s.previewTaskName).Methods("GET")
 	api.HandleFunc("/tasks/{id}/execute", s.executeTask).Methods("POST")
+	api.HandleFunc("/tasks/{id}/clone", s.cloneTask).Methods("POST")
 	api.HandleFunc("/tasks/{id}/enable", s.enableTask).Methods("POST")
 	api.HandleFunc("/tasks/{id}/disable", s.disableTask).Methods("POST")
 	api.HandleFunc("/tasks/{id}", s.getTask).Methods("GET")
@@ -148,31 +182,62 @@ func (s *Server) Router() *mux.Router {
 	// Backends (JSON API)
 	api.HandleFunc("/backends", s.listBackends).Methods("GET")
 	api.HandleFunc("/backends", s.createBackend).Methods("POST")
+	api.HandleFunc("/backends/test", s.testUnsavedBackend).Methods("POST")
+	api.HandleFunc("/backends/test-all", s.testAllBackends).Methods("POST")
 	api.HandleFunc("/backends/{id}/test", s.testBackend).Methods("POST")
+	api.HandleFunc("/backends/{id}/restore", s.restoreArchive).Methods("POST")
+	api.HandleFunc("/backends/{id}/restore-chunked", s.restoreChunked).Methods("POST")
 	api.HandleFunc("/backends/{id}", s.getBackend).Methods("GET")
 	api.HandleFunc("/backends/{id}", s.updateBackend).Methods("PUT")
 	api.HandleFunc("/backends/{id}", s.deleteBackend).Methods("DELETE")
+	api.HandleFunc("/migrate", s.migrateBackend).Methods("POST")
+	api.HandleFunc("/migrations/{id}/cancel", s.cancelMigration).Methods("POST")
 
 	// Executions (JSON API)
 	api.HandleFunc("/executions", s.listExecutions).Methods("GET")
 	api.HandleFunc("/executions", s.clearHistory).Methods("DELETE")
+	api.HandleFunc("/executions/running", s.listRunningExecutions).Methods("GET")
+	api.HandleFunc("/executions/queue", s.listQueuedExecutions).Methods("GET")
+	api.HandleFunc("/executions/queue/{id}", s.dequeueExecution).Methods("DELETE")
+	api.HandleFunc("/executions/cancel-all", s.cancelAllExecutions).Methods("POST")
 	api.HandleFunc("/executions/{id}/cancel", s.cancelExecution).Methods("POST")
+	api.HandleFunc("/executions/{id}/retry", s.retryExecution).Methods("POST")
+	api.HandleFunc("/executions/{id}/manifest", s.getExecutionManifest).Methods("GET")
 	api.HandleFunc("/executions/{id}", s.getExecution).Methods("GET")
 
+	// Statistics
+	api.HandleFunc("/stats/tasks", s.taskStatsSummary).Methods("GET")
+
 	// Sources
+	api.HandleFunc("/sources/stats", s.getSourceStats).Methods("GET")
 	api.HandleFunc("/sources", s.listSources).Methods("GET")
 
 	// Configuration
 	api.HandleFunc("/config", s.getConfig).Methods("GET")
 	api.HandleFunc("/config/settings", s.updateSettings).Methods("PUT")
+	api.HandleFunc("/config/validate", s.validateConfig).Methods("GET", "POST")
+	api.HandleFunc("/config/reload", s.reloadConfig).Methods("POST")
 
 	// System
-	api.HandleFunc("/system/health", s.healthCheck).Methods("GET")
+	api.HandleFunc("/system/live", s.livenessCheck).Methods("GET")
+	api.HandleFunc("/system/ready", s.readinessCheck).Methods("GET")
+	api.HandleFunc("/system/health", s.readinessCheck).Methods("GET") // Alias of /system/ready for backward compatibility
 	api.HandleFunc("/system/stats", s.systemStats).Methods("GET")
+	api.HandleFunc("/system/storage", s.systemStorage).Methods("GET")
+	api.HandleFunc("/system/maintenance/vacuum", s.vacuumDatabase).Methods("POST")
+	api.HandleFunc("/system/maintenance/backup", s.backupState).Methods("POST")
 
 	// WebSocket
 	api.HandleFunc("/ws/progress", s.handleWebSocket)
 
+	// CORS preflight requests, answered by withCORS for allowed origins. A
+	// matcher rather than Methods keeps 404s for unknown paths from turning into 405s.
+	api.PathPrefix("/").MatcherFunc(func(r *http.Request, _ *mux.RouteMatch) bool {
+		return r.Method == http.MethodOptions
+	}).HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
 	// Serve static files
 	fs := http.FileServer(http.Dir("./web/static"))
 	r.PathPrefix("/css/").Handler(fs)
@@ -186,54 +251,6 @@ func (s *Server) Router() *mux.Router {
 	return r
 }
 
-// BroadcastProgress implements executor.ProgressBroadcaster.
-// Uses an exclusive lock so concurrent task goroutines cannot write to the
-// same WebSocket connection simultaneously (gorilla/websocket requires
-// serialised writers).
-func (s *Server) BroadcastProgress(event models.ProgressEvent) {
-	s.wsMu.Lock()
-	defer s.wsMu.Unlock()
-
-	var failed []*websocket.Conn
-	for client := range s.wsClients {
-		if err := client.WriteJSON(event); err != nil {
-			failed = append(failed, client)
-		}
-	}
-	for _, client := range failed {
-		delete(s.wsClients, client)
-		client.Close()
-	}
-}
-
-// handleWebSocket handles WebSocket connections
-func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
-	conn, err := s.upgrader.Upgrade(w, r, nil)
-	if err != nil {
-		return
-	}
-
-	s.wsMu.Lock()
-	s.wsClients[conn] = true
-	s.wsMu.Unlock()
-
-	defer func() {
-		s.wsMu.Lock()
-		delete(s.wsClients, conn)
-		s.wsMu.Unlock()
-		if err := conn.Close(); err != nil {
-			log.Printf("Error closing WebSocket connection: %v", err)
-		}
-	}()
-
-	// Keep connection alive and handle client messages if needed
-	for {
-		if _, _, err := conn.ReadMessage(); err != nil {
-			break
-		}
-	}
-}
-
 // Helper functions
 func (s *Server) success(w http.ResponseWriter, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
@@ -243,6 +260,27 @@ func (s *Server) success(w http.ResponseWriter, data interface{}) {
 }
 
 func (s *Server) error(w http.ResponseWriter, code string, message string, status int) {
+	s.errorWithDetails(w, code, message, nil, status)
+}
+
+// validationError responds with a VALIDATION_ERROR whose details name the offending field,
+// in the same form as the problems reported by config validation
+func (s *Server) validationError(w http.ResponseWriter, field string, message string) {
+	s.errorWithDetails(w, "VALIDATION_ERROR", message, []models.ConfigProblem{{Field: field, Message: message}}, http.StatusBadRequest)
+}
+
+// taskConfigError responds to a failed task change: validation errors name the
+// offending field, anything else (such as failing to save) is an internal error
+func (s *Server) taskConfigError(w http.ResponseWriter, err error) {
+	var validationErr *config.ValidationError
+	if errors.As(err, &validationErr) {
+		s.validationError(w, validationErr.Field, validationErr.Message)
+		return
+	}
+	s.error(w, "INTERNAL_ERROR", err.Error(), http.StatusInternalServerError)
+}
+
+func (s *Server) errorWithDetails(w http.ResponseWriter, code string, message string, details interface{}, status int) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
 	if err := json.NewEncoder(w).Encode(Response{
@@ -250,20 +288,153 @@ func (s *Server) error(w http.ResponseWriter, code string, message string, statu
 		Error: &ErrorInfo{
 			Code:    code,
 			Message: message,
+			Details: details,
 		},
 	}); err != nil {
 		log.Printf("Error encoding error response: %v", err)
 	}
 }
 
-// Health check
-func (s *Server) healthCheck(w http.ResponseWriter, r *http.Request) {
+// ComponentStatus represents the health of a single subsystem
+type ComponentStatus struct {
+	Status string `json:"status"` // ok, failed
+	Error  string `json:"error,omitempty"`
+}
+
+// livenessCheck reports that the process is up and serving requests.
+// It deliberately checks nothing else so orchestrators only restart a wedged process.
+func (s *Server) livenessCheck(w http.ResponseWriter, r *http.Request) {
 	s.success(w, map[string]interface{}{
-		"status":  "healthy",
+		"status":  "alive",
 		"version": "1.0.0-dev",
 	})
 }
 
+// readinessCheck reports whether the service can accept traffic.
+// Fails until configuration is loaded, the database is reachable and the scheduler has started.
+func (s *Server) readinessCheck(w http.ResponseWriter, r *http.Request) {
+	components := map[string]ComponentStatus{
+		"config":    s.checkConfig(),
+		"database":  s.checkDatabase(r.Context()),
+		"scheduler": s.checkScheduler(),
+		"temp_dir":  s.checkTempDir(),
+	}
+
+	for _, component := range components {
+		if component.Status != "ok" {
+			s.errorWithDetails(w, "UNHEALTHY", "One or more components are unhealthy", map[string]interface{}{
+				"status":     "unhealthy",
+				"version":    "1.0.0-dev",
+				"components": components,
+			}, http.StatusServiceUnavailable)
+			return
+		}
+	}
+
+	s.success(w, map[string]interface{}{
+		"status":     "healthy",
+		"version":    "1.0.0-dev",
+		"components": components,
+	})
+}
+
+// checkConfig verifies the configuration has been loaded
+func (s *Server) checkConfig() ComponentStatus {
+	if !s.config.IsLoaded() {
+		return ComponentStatus{Status: "failed", Error: "configuration not loaded"}
+	}
+	return ComponentStatus{Status: "ok"}
+}
+
+// checkDatabase pings the database with a short timeout
+func (s *Server) checkDatabase(ctx context.Context) ComponentStatus {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	if err := s.db.Ping(ctx); err != nil {
+		return ComponentStatus{Status: "failed", Error: err.Error()}
+	}
+	return ComponentStatus{Status: "ok"}
+}
+
+// checkScheduler verifies the scheduler has been started
+func (s *Server) checkScheduler() ComponentStatus {
+	if !s.scheduler.IsRunning() {
+		return ComponentStatus{Status: "failed", Error: "scheduler is not running (starting up or stopped)"}
+	}
+	return ComponentStatus{Status: "ok"}
+}
+
+// checkTempDir verifies the temp directory is writable
+func (s *Server) checkTempDir() ComponentStatus {
+	if !s.config.IsLoaded() {
+		return ComponentStatus{Status: "failed", Error: "configuration not loaded"}
+	}
+	tempDir := s.config.ResolvePath(s.config.GetSettings().TempDir)
+
+	file, err := os.CreateTemp(tempDir, ".archivist_health_*")
+	if err != nil {
+		return ComponentStatus{Status: "failed", Error: fmt.Sprintf("temp directory is not writable: %v", err)}
+	}
+	if err := file.Close(); err != nil {
+		log.Printf("Error closing health check file: %v", err)
+	}
+	if err := os.Remove(file.Name()); err != nil {
+		log.Printf("Warning: failed to remove health check file: %v", err)
+	}
+	return ComponentStatus{Status: "ok"}
+}
+
+// vacuumDatabase handles POST /api/v1/system/maintenance/vacuum
+// Compacts the database file and reports how much space was reclaimed.
+func (s *Server) vacuumDatabase(w http.ResponseWriter, r *http.Request) {
+	result, err := s.db.Vacuum(r.Context())
+	if err != nil {
+		s.error(w, "DATABASE_ERROR", err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	s.success(w, result)
+}
+
+// backupState handles POST /api/v1/system/maintenance/backup
+// Uploads the configuration and a database snapshot to backend_id, or to the
+// state_backup setting's backend when none is given.
+func (s *Server) backupState(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		s.error(w, "VALIDATION_ERROR", "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	backendID := r.FormValue("backend_id")
+	if backendID == "" {
+		if stateBackup := s.config.GetSettings().StateBackup; stateBackup != nil {
+			backendID = stateBackup.BackendID
+		}
+	}
+	if backendID == "" {
+		s.validationError(w, "backend_id", "backend_id is required when the state_backup setting is not configured")
+		return
+	}
+	if _, err := s.config.GetBackend(backendID); err != nil {
+		s.error(w, "NOT_FOUND", "Backend not found", http.StatusNotFound)
+		return
+	}
+
+	// Snapshotting and uploading a large database outlasts the server's write timeout
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+		log.Printf("Error clearing state backup write deadline: %v", err)
+	}
+
+	result, err := s.executor.BackupState(r.Context(), backendID)
+	if err != nil {
+		s.errorWithDetails(w, "BACKUP_ERROR", err.Error(), backendErrorDetails(s.backendForDetails(backendID), err), http.StatusInternalServerError)
+		return
+	}
+
+	s.success(w, result)
+}
+
 // System stats
 func (s *Server) systemStats(w http.ResponseWriter, r *http.Request) {
 	var m runtime.MemStats
@@ -314,3 +485,104 @@ func (s *Server) systemStats(w http.ResponseWriter, r *http.Request) {
 
 	s.success(w, stats)
 }
+
+// Storage usage is expensive to compute on some backends, which list every object
+const (
+	storageCacheTTL     = 5 * time.Minute
+	storageUsageTimeout = 30 * time.Second
+)
+
+// systemStorage handles GET /api/v1/system/storage
+// Sums storage usage across enabled backends. Results are cached for a few
+// minutes; pass refresh=true to query the backends again. The backends are
+// queried without holding storageMu, so a slow backend doesn't hold up
+// requests that the cache can answer.
+func (s *Server) systemStorage(w http.ResponseWriter, r *http.Request) {
+	s.storageMu.Lock()
+	overview := s.storageCache
+	s.storageMu.Unlock()
+
+	if overview == nil || r.URL.Query().Get("refresh") == "true" || time.Since(overview.CheckedAt) > storageCacheTTL {
+		overview = s.collectStorageUsage()
+
+		// Keep whichever of concurrent refreshes checked last
+		s.storageMu.Lock()
+		if s.storageCache == nil || overview.CheckedAt.After(s.storageCache.CheckedAt) {
+			s.storageCache = overview
+		}
+		s.storageMu.Unlock()
+	}
+
+	s.success(w, overview)
+}
+
+// collectStorageUsage queries every enabled backend concurrently
+func (s *Server) collectStorageUsage() *models.StorageOverview {
+	var backends []models.Backend
+	for _, backendCfg := range s.config.GetBackends() {
+		if backendCfg.Enabled {
+			backends = append(backends, backendCfg)
+		}
+	}
+
+	usages := make([]models.BackendStorageUsage, len(backends))
+	var wg sync.WaitGroup
+	for i := range backends {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			usages[i] = s.backendStorageUsage(&backends[i])
+		}(i)
+	}
+	wg.Wait()
+
+	overview := &models.StorageOverview{
+		CapacityKnown: true,
+		Backends:      usages,
+		CheckedAt:     time.Now(),
+	}
+	for _, usage := range usages {
+		overview.Used += usage.Used
+		if usage.Total < 0 {
+			overview.CapacityKnown = false
+			continue
+		}
+		overview.Total += usage.Total
+	}
+	if !overview.CapacityKnown && overview.Total == 0 {
+		overview.Total = -1
+	}
+	return overview
+}
+
+// backendStorageUsage reads one backend's usage, recording any error in the result
+func (s *Server) backendStorageUsage(backendCfg *models.Backend) models.BackendStorageUsage {
+	usage := models.BackendStorageUsage{
+		BackendID:   backendCfg.ID,
+		BackendName: backendCfg.Name,
+		Total:       -1,
+	}
+
+	backendInstance, err := backend.Factory(backendCfg, s.config)
+	if err != nil {
+		usage.Error = err.Error()
+		return usage
+	}
+	defer func() {
+		if err := backendInstance.Close(); err != nil {
+			log.Printf("Error closing backend instance: %v", err)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), storageUsageTimeout)
+	defer cancel()
+
+	result, err := backendInstance.GetUsage(ctx)
+	if err != nil {
+		usage.Error = err.Error()
+		return usage
+	}
+	usage.Used = result.Used
+	usage.Total = result.Total
+	return usage
+}
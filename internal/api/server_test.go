@@ -0,0 +1,1228 @@
+package api
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/nsilverman/archivist/internal/config"
+	"github.com/nsilverman/archivist/internal/executor"
+	"github.com/nsilverman/archivist/internal/models"
+	"github.com/nsilverman/archivist/internal/scheduler"
+	"github.com/nsilverman/archivist/internal/storage"
+)
+
+// newTestServer returns a server over a fresh config and database in a
+// temporary directory, with a local backend "local". Templates aren't
+// loaded, so only the JSON API can be exercised.
+func newTestServer(t *testing.T) *Server {
+	t.Helper()
+	dir := t.TempDir()
+	cfg, err := config.NewManager(filepath.Join(dir, "config", "config.json"), dir)
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+	if err := cfg.CreateDefaultWithPaths(filepath.Join(dir, "temp"), filepath.Join(dir, "sources")); err != nil {
+		t.Fatalf("CreateDefaultWithPaths: %v", err)
+	}
+	// main creates the temp directory on startup
+	if err := os.MkdirAll(filepath.Join(dir, "temp"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.AddBackend(&models.Backend{
+		ID:      "local",
+		Name:    "local",
+		Type:    "local",
+		Enabled: true,
+		Config:  map[string]interface{}{"path": filepath.Join(dir, "store")},
+	}); err != nil {
+		t.Fatalf("AddBackend: %v", err)
+	}
+
+	db, err := storage.NewDatabase(filepath.Join(dir, "config", "archivist.db"))
+	if err != nil {
+		t.Fatalf("NewDatabase: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := db.Close(); err != nil {
+			t.Errorf("closing database: %v", err)
+		}
+	})
+
+	exec := executor.NewExecutor(cfg, db)
+	s := &Server{
+		config:       cfg,
+		db:           db,
+		executor:     exec,
+		scheduler:    scheduler.NewScheduler(exec, cfg, db),
+		wsClients:    make(map[*wsClient]bool),
+		deleteTokens: make(map[string]deleteToken),
+		sourceHealth: make(map[string]sourceHealth),
+
+		idempotencyKeys: make(map[string]idempotentExecution),
+	}
+	s.upgrader.CheckOrigin = s.checkOrigin
+	exec.SetProgressBroadcaster(s)
+	return s
+}
+
+// serve sends a request through the router and decodes the response
+func serve(t *testing.T, s *Server, r *http.Request) (int, Response) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	s.Router().ServeHTTP(rec, r)
+	var resp Response
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decoding %s %s response %q: %v", r.Method, r.URL.Path, rec.Body.String(), err)
+	}
+	return rec.Code, resp
+}
+
+func TestReadiness(t *testing.T) {
+	s := newTestServer(t)
+
+	tests := []struct {
+		name          string
+		before        func()
+		wantStatus    int
+		wantScheduler ComponentStatus
+	}{
+		{
+			name:          "starting up",
+			before:        func() {},
+			wantStatus:    http.StatusServiceUnavailable,
+			wantScheduler: ComponentStatus{Status: "failed", Error: "scheduler is not running (starting up or stopped)"},
+		},
+		{
+			name: "started",
+			before: func() {
+				if err := s.scheduler.Start(); err != nil {
+					t.Fatalf("Start: %v", err)
+				}
+			},
+			wantStatus:    http.StatusOK,
+			wantScheduler: ComponentStatus{Status: "ok"},
+		},
+		{
+			name:          "stopped",
+			before:        s.scheduler.Stop,
+			wantStatus:    http.StatusServiceUnavailable,
+			wantScheduler: ComponentStatus{Status: "failed", Error: "scheduler is not running (starting up or stopped)"},
+		},
+	}
+
+	// The cases run in order, each from where the last left off
+	for _, tt := range tests {
+		tt.before()
+		for _, path := range []string{"/api/v1/system/ready", "/api/v1/system/health"} {
+			status, resp := serve(t, s, httptest.NewRequest(http.MethodGet, path, nil))
+			if status != tt.wantStatus {
+				t.Errorf("%s: %s returned %d, want %d", tt.name, path, status, tt.wantStatus)
+			}
+
+			data := resp.Data
+			if resp.Error != nil {
+				data = resp.Error.Details
+			}
+			var body struct {
+				Components map[string]ComponentStatus `json:"components"`
+			}
+			raw, _ := json.Marshal(data)
+			if err := json.Unmarshal(raw, &body); err != nil {
+				t.Fatalf("%s: decoding components: %v", tt.name, err)
+			}
+			if got := body.Components["scheduler"]; got != tt.wantScheduler {
+				t.Errorf("%s: scheduler component = %+v, want %+v", tt.name, got, tt.wantScheduler)
+			}
+			for _, component := range []string{"config", "database", "temp_dir"} {
+				if got := body.Components[component]; got.Status != "ok" {
+					t.Errorf("%s: %s component = %+v, want ok", tt.name, component, got)
+				}
+			}
+		}
+	}
+
+	// Liveness doesn't depend on the scheduler
+	if status, _ := serve(t, s, httptest.NewRequest(http.MethodGet, "/api/v1/system/live", nil)); status != http.StatusOK {
+		t.Errorf("liveness returned %d while stopped, want 200", status)
+	}
+}
+
+func TestCreateTaskValidation(t *testing.T) {
+	tests := []struct {
+		name       string
+		source     string // dir, file, fifo or missing
+		form       url.Values
+		wantStatus int
+		wantField  string
+	}{
+		{"valid", "dir", url.Values{}, http.StatusOK, ""},
+		{"missing name", "dir", url.Values{"name": {""}}, http.StatusBadRequest, "name"},
+		{"missing backend", "dir", url.Values{"backend_ids": nil}, http.StatusBadRequest, "backend_ids"},
+		{"missing source path", "missing", url.Values{}, http.StatusBadRequest, "source_path"},
+		{"missing source path forced", "missing", url.Values{"force": {"true"}}, http.StatusOK, ""},
+		{"source neither a directory nor a file", "fifo", url.Values{}, http.StatusBadRequest, "source_path"},
+		{"source neither a directory nor a file forced", "fifo", url.Values{"force": {"true"}}, http.StatusOK, ""},
+		{"single file source", "file", url.Values{}, http.StatusOK, ""},
+		{"unknown backend", "dir", url.Values{"backend_ids": {"missing"}}, http.StatusBadRequest, "backend_ids"},
+		{"keep_last below -1", "dir", url.Values{"keep_last": {"-2"}}, http.StatusBadRequest, "retention_policy"},
+		{"chunked with keep_last", "dir", url.Values{"backup_mode": {"chunked"}, "keep_last": {"3"}}, http.StatusBadRequest, "keep_last"},
+		{"chunked keeping everything", "dir", url.Values{"backup_mode": {"chunked"}, "keep_last": {"-1"}}, http.StatusOK, ""},
+		{"depends on a missing task", "dir", url.Values{"depends_on": {"missing"}}, http.StatusBadRequest, "depends_on"},
+		{"zstd compression", "dir", url.Values{"compression": {"zstd"}}, http.StatusOK, ""},
+		{"unknown compression", "dir", url.Values{"compression": {"bzip2"}}, http.StatusBadRequest, "compression"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t)
+			source := t.TempDir()
+			switch tt.source {
+			case "file":
+				source = filepath.Join(source, "notes.txt")
+				if err := os.WriteFile(source, []byte("notes"), 0644); err != nil {
+					t.Fatal(err)
+				}
+			case "fifo":
+				source = filepath.Join(source, "pipe")
+				if err := syscall.Mkfifo(source, 0644); err != nil {
+					t.Fatal(err)
+				}
+			case "missing":
+				source = filepath.Join(source, "not-mounted-yet")
+			}
+			form := url.Values{
+				"name":          {"documents"},
+				"source_path":   {source},
+				"backend_ids":   {"local"},
+				"schedule_type": {"manual"},
+			}
+			for key, values := range tt.form {
+				form[key] = values
+			}
+
+			r := httptest.NewRequest(http.MethodPost, "/api/v1/tasks", strings.NewReader(form.Encode()))
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			status, resp := serve(t, s, r)
+			if status != tt.wantStatus {
+				t.Fatalf("status %d, want %d: %+v", status, tt.wantStatus, resp.Error)
+			}
+			if tt.wantField == "" {
+				if len(s.config.GetTasks()) != 1 {
+					t.Error("task was not added")
+				}
+				return
+			}
+
+			if resp.Error.Code != "VALIDATION_ERROR" {
+				t.Errorf("error code %s, want VALIDATION_ERROR", resp.Error.Code)
+			}
+			raw, _ := json.Marshal(resp.Error.Details)
+			var problems []models.ConfigProblem
+			if err := json.Unmarshal(raw, &problems); err != nil {
+				t.Fatalf("decoding problems: %v", err)
+			}
+			if len(problems) != 1 || problems[0].Field != tt.wantField {
+				t.Errorf("problems = %+v, want one for %s", problems, tt.wantField)
+			}
+			if len(s.config.GetTasks()) != 0 {
+				t.Error("invalid task was added")
+			}
+		})
+	}
+}
+
+func TestCreateTaskRejectsBackingUpItsOwnOutput(t *testing.T) {
+	tests := []struct {
+		name string
+		form func(source, store string) url.Values
+	}{
+		{"local backend inside the source", func(source, store string) url.Values {
+			return url.Values{"source_path": {filepath.Dir(store)}}
+		}},
+		{"local backend is the source", func(source, store string) url.Values {
+			return url.Values{"source_path": {store}}
+		}},
+		{"temp directory inside the source", func(source, store string) url.Values {
+			return url.Values{"temp_dir": {filepath.Join(source, "staging")}}
+		}},
+		{"local copy inside the source", func(source, store string) url.Values {
+			return url.Values{"local_copy_dir": {filepath.Join(source, "copies")}}
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t)
+			local, err := s.config.GetBackend("local")
+			if err != nil {
+				t.Fatal(err)
+			}
+			store := local.Config["path"].(string)
+			if err := os.MkdirAll(store, 0755); err != nil {
+				t.Fatal(err)
+			}
+			source := t.TempDir()
+
+			form := url.Values{
+				"name":          {"documents"},
+				"source_path":   {source},
+				"backend_ids":   {"local"},
+				"schedule_type": {"manual"},
+			}
+			for key, values := range tt.form(source, store) {
+				form[key] = values
+			}
+			r := httptest.NewRequest(http.MethodPost, "/api/v1/tasks", strings.NewReader(form.Encode()))
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			status, resp := serve(t, s, r)
+			if status != http.StatusBadRequest || resp.Error == nil || resp.Error.Code != "VALIDATION_ERROR" {
+				t.Fatalf("status %d, error %+v; want a 400 VALIDATION_ERROR", status, resp.Error)
+			}
+			if len(s.config.GetTasks()) != 0 {
+				t.Error("task backing up its own output was added")
+			}
+		})
+	}
+}
+
+func TestCancelUnknownMigration(t *testing.T) {
+	s := newTestServer(t)
+	status, resp := serve(t, s, httptest.NewRequest(http.MethodPost, "/api/v1/migrations/missing/cancel", nil))
+	if status != http.StatusNotFound {
+		t.Errorf("status %d, want 404", status)
+	}
+	if resp.Error == nil || resp.Error.Code != "NOT_FOUND" {
+		t.Errorf("error = %+v, want NOT_FOUND", resp.Error)
+	}
+}
+
+// decodeData re-decodes a response's data into v
+func decodeData(t *testing.T, resp Response, v interface{}) {
+	t.Helper()
+	raw, err := json.Marshal(resp.Data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		t.Fatalf("decoding data: %v", err)
+	}
+}
+
+func TestDryRunJSON(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		query  string
+		check  func(t *testing.T, result models.DryRunResult)
+	}{
+		{"archive", "tar.gz", "", func(t *testing.T, result models.DryRunResult) {
+			if result.Mode != "archive" || result.ArchiveDetails == nil || result.SyncDetails != nil {
+				t.Fatalf("mode %s with archive details %v and sync details %v, want archive details only",
+					result.Mode, result.ArchiveDetails, result.SyncDetails)
+			}
+			if result.ArchiveDetails.Format != "tar.gz" || result.ArchiveDetails.ArchiveName == "" || result.ArchiveDetails.EstimatedArchiveSize <= 0 {
+				t.Errorf("archive details = %+v", result.ArchiveDetails)
+			}
+			if len(result.BackendPlans) != 2 {
+				t.Errorf("%d backend plans, want one for each of the task's backends", len(result.BackendPlans))
+			}
+		}},
+		{"sync", "sync", "", func(t *testing.T, result models.DryRunResult) {
+			if result.Mode != "sync" || result.SyncDetails == nil || result.ArchiveDetails != nil {
+				t.Fatalf("mode %s with archive details %v and sync details %v, want sync details only",
+					result.Mode, result.ArchiveDetails, result.SyncDetails)
+			}
+			if result.SyncDetails.UploadCount != 2 || result.SyncDetails.BytesToUpload != 15 || result.SyncDetails.DeleteCount != 0 {
+				t.Errorf("sync details = %+v, want 2 uploads of 15 bytes", result.SyncDetails)
+			}
+		}},
+		{"selected backends", "tar.gz", "?backend_ids=local", func(t *testing.T, result models.DryRunResult) {
+			if len(result.BackendPlans) != 1 {
+				t.Errorf("%d backend plans, want only the selected one", len(result.BackendPlans))
+			}
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t)
+			if err := s.config.AddBackend(&models.Backend{
+				ID:      "other",
+				Name:    "other",
+				Type:    "local",
+				Enabled: true,
+				Config:  map[string]interface{}{"path": t.TempDir()},
+			}); err != nil {
+				t.Fatalf("AddBackend: %v", err)
+			}
+			source := t.TempDir()
+			if err := os.MkdirAll(filepath.Join(source, "nested"), 0755); err != nil {
+				t.Fatal(err)
+			}
+			for name, data := range map[string]string{"a.txt": "alpha", "nested/b.txt": "bravo-beta"} {
+				if err := os.WriteFile(filepath.Join(source, filepath.FromSlash(name)), []byte(data), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := s.config.AddTask(&models.Task{
+				ID:             "task-1",
+				Name:           "documents",
+				SourcePath:     source,
+				BackendIDs:     []string{"local", "other"},
+				Schedule:       models.Schedule{Type: "manual"},
+				ArchiveOptions: models.ArchiveOptions{Format: tt.format, UseTimestamp: true},
+				Enabled:        true,
+			}); err != nil {
+				t.Fatalf("AddTask: %v", err)
+			}
+
+			status, resp := serve(t, s, httptest.NewRequest(http.MethodGet, "/api/v1/tasks/task-1/dry-run"+tt.query, nil))
+			if status != http.StatusOK {
+				t.Fatalf("status %d: %+v", status, resp.Error)
+			}
+			var result models.DryRunResult
+			decodeData(t, resp, &result)
+
+			if result.TaskID != "task-1" || result.SourcePath != source {
+				t.Errorf("dry run of task %s at %s", result.TaskID, result.SourcePath)
+			}
+			if summary := result.FilesSummary; summary.TotalFiles != 2 || summary.TotalSize != 15 || summary.LargestFile == "" {
+				t.Errorf("files summary = %+v, want 2 files of 15 bytes", summary)
+			}
+			if len(result.BackendPlans) == 0 {
+				t.Fatal("no backend plans")
+			}
+			for _, plan := range result.BackendPlans {
+				if !plan.Available || plan.BackendType != "local" || plan.RemotePath == "" {
+					t.Errorf("backend plan = %+v", plan)
+				}
+			}
+			tt.check(t, result)
+		})
+	}
+
+	s := newTestServer(t)
+	if status, _ := serve(t, s, httptest.NewRequest(http.MethodGet, "/api/v1/tasks/missing/dry-run", nil)); status != http.StatusNotFound {
+		t.Errorf("dry run of a missing task returned %d, want 404", status)
+	}
+}
+
+func TestReloadConfig(t *testing.T) {
+	s := newTestServer(t)
+	if err := s.scheduler.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer s.scheduler.Stop()
+	configPath := s.config.ResolvePath(filepath.Join("config", "config.json"))
+
+	// Edit the file behind the running server's back, as a user would
+	c := s.config.Get()
+	c.Tasks = append(c.Tasks, models.Task{
+		ID:             "nightly",
+		Name:           "nightly",
+		SourcePath:     t.TempDir(),
+		BackendIDs:     []string{"local"},
+		Schedule:       models.Schedule{Type: "simple", SimpleType: "daily"},
+		ArchiveOptions: models.ArchiveOptions{Format: "tar.gz", UseTimestamp: true},
+		Enabled:        true,
+	})
+	data, err := json.Marshal(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(configPath, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.scheduler.GetNextRun("nightly"); err == nil {
+		t.Fatal("task scheduled before the reload")
+	}
+
+	status, resp := serve(t, s, httptest.NewRequest(http.MethodPost, "/api/v1/config/reload", nil))
+	if status != http.StatusOK {
+		t.Fatalf("reload returned %d: %+v", status, resp.Error)
+	}
+	if _, err := s.config.GetTask("nightly"); err != nil {
+		t.Errorf("reloaded config is missing the new task: %v", err)
+	}
+	if _, err := s.scheduler.GetNextRun("nightly"); err != nil {
+		t.Errorf("new task not scheduled after the reload: %v", err)
+	}
+
+	// A broken file is refused and the running configuration kept
+	if err := os.WriteFile(configPath, []byte(`{"version": `), 0600); err != nil {
+		t.Fatal(err)
+	}
+	status, _ = serve(t, s, httptest.NewRequest(http.MethodPost, "/api/v1/config/reload", nil))
+	if status != http.StatusBadRequest {
+		t.Errorf("reloading a broken file returned %d, want 400", status)
+	}
+	if _, err := s.config.GetTask("nightly"); err != nil {
+		t.Errorf("failed reload dropped the running configuration: %v", err)
+	}
+	if _, err := s.scheduler.GetNextRun("nightly"); err != nil {
+		t.Errorf("failed reload unscheduled the task: %v", err)
+	}
+}
+
+func TestResolveSourcePath(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	for _, dir := range []string{"docs/nested/deep", "photos"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	links := map[string]string{
+		"linked":      outside,                               // sources are added as top-level links
+		"docs/escape": outside,                               // a nested link leaving its source
+		"docs/alias":  filepath.Join(root, "docs", "nested"), // a nested link within its source
+		"docs/photos": filepath.Join(root, "photos"),         // a nested link into another source
+	}
+	for link, target := range links {
+		if err := os.Symlink(target, filepath.Join(root, link)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		path    string
+		wantErr bool
+	}{
+		{"", false},
+		{"docs", false},
+		{"docs/nested/deep", false},
+		{"docs/./nested/../nested", false},
+		{"docs/missing", false},
+		{"linked", false},
+		{"docs/alias/deep", false},
+		{"..", true},
+		{"../" + filepath.Base(outside), true},
+		{"docs/../../etc", true},
+		{"/etc", true},
+		{root, true},
+		{"docs/escape", true},
+		{"docs/photos", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, err := resolveSourcePath(root, tt.path)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("resolved to %s, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolveSourcePath: %v", err)
+			}
+			if want := filepath.Join(root, tt.path); got != want {
+				t.Errorf("resolved to %s, want %s", got, want)
+			}
+		})
+	}
+
+	// The browsing endpoints refuse traversal outright
+	s := newTestServer(t)
+	if err := os.MkdirAll(s.config.ResolvePath(s.config.GetSettings().SourcesDir), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, target := range []string{"/api/v1/sources", "/api/v1/sources/stats"} {
+		if status, _ := serve(t, s, httptest.NewRequest(http.MethodGet, target+"?path=../", nil)); status != http.StatusBadRequest {
+			t.Errorf("%s with ../ returned %d, want 400", target, status)
+		}
+	}
+}
+
+// dialWS opens a progress stream on srv and reads off the snapshot sent on connect
+func dialWS(t *testing.T, srv *httptest.Server, header http.Header) (*websocket.Conn, models.ProgressEvent) {
+	t.Helper()
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/api/v1/ws/progress"
+	conn, _, err := websocket.DefaultDialer.Dial(url, header)
+	if err != nil {
+		t.Fatalf("dialing %s: %v", url, err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn, readWS(t, conn)
+}
+
+// readWS reads the next event, failing the test if none arrives promptly
+func readWS(t *testing.T, conn *websocket.Conn) models.ProgressEvent {
+	t.Helper()
+	if err := conn.SetReadDeadline(time.Now().Add(5 * time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	var event models.ProgressEvent
+	if err := conn.ReadJSON(&event); err != nil {
+		t.Fatalf("reading event: %v", err)
+	}
+	return event
+}
+
+// wsClientCount returns the number of connected progress clients
+func wsClientCount(s *Server) int {
+	s.wsMu.RLock()
+	defer s.wsMu.RUnlock()
+	return len(s.wsClients)
+}
+
+func TestWebSocketSlowClient(t *testing.T) {
+	s := newTestServer(t)
+	srv := httptest.NewServer(s.Router())
+	defer srv.Close()
+
+	// The slow client connects and then never reads
+	_, _ = dialWS(t, srv, nil)
+	fast, _ := dialWS(t, srv, nil)
+
+	// Enough data to fill the slow client's queue and socket buffers many times over
+	payload := strings.Repeat("x", 64*1024)
+	const events = 600
+	for i := 0; i < events; i++ {
+		start := time.Now()
+		s.BroadcastProgress(models.ProgressEvent{
+			Type: "execution_log",
+			Data: map[string]interface{}{"seq": float64(i), "line": payload},
+		})
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Fatalf("broadcast %d blocked for %s", i, elapsed)
+		}
+
+		event := readWS(t, fast)
+		data, _ := event.Data.(map[string]interface{})
+		if data["seq"] != float64(i) {
+			t.Fatalf("fast client got event %v, want %d", data["seq"], i)
+		}
+	}
+
+	// The slow client fell behind on events that can't be dropped, so it was cut off
+	if n := wsClientCount(s); n != 1 {
+		t.Errorf("%d clients connected, want only the fast one", n)
+	}
+}
+
+func TestWebSocketSubscription(t *testing.T) {
+	s := newTestServer(t)
+	srv := httptest.NewServer(s.Router())
+	defer srv.Close()
+
+	// subscribe sends a filter and waits for the server to apply it
+	subscribe := func(conn *websocket.Conn, subscription wsSubscription) {
+		t.Helper()
+		if err := conn.WriteJSON(wsMessage{Subscribe: &subscription}); err != nil {
+			t.Fatal(err)
+		}
+		for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+			s.wsMu.RLock()
+			applied := false
+			for client := range s.wsClients {
+				client.mu.Lock()
+				applied = applied || (client.executions != nil && client.subscription == subscription)
+				client.mu.Unlock()
+			}
+			s.wsMu.RUnlock()
+			if applied {
+				return
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("subscription %+v was not applied", subscription)
+			}
+		}
+	}
+
+	events := []models.ProgressEvent{
+		{Type: "execution_started", Data: map[string]interface{}{"execution_id": "exec-b", "task_id": "task-b"}},
+		{Type: "execution_started", Data: map[string]interface{}{"execution_id": "exec-a", "task_id": "task-a"}},
+		{Type: "archive_progress", Data: models.ArchiveProgress{ExecutionID: "exec-b"}},
+		{Type: "archive_progress", Data: models.ArchiveProgress{ExecutionID: "exec-a"}},
+		{Type: "upload_progress", Data: models.UploadProgress{ExecutionID: "exec-c"}},
+		{Type: "backup_deleted", Data: map[string]interface{}{"backend_id": "local"}},
+		{Type: "execution_completed", Data: map[string]interface{}{"execution_id": "exec-a", "task_id": "task-a"}},
+	}
+
+	// describe renders an event the same whether sent or received
+	describe := func(event models.ProgressEvent) string {
+		raw, _ := json.Marshal(event.Data)
+		var data interface{}
+		_ = json.Unmarshal(raw, &data)
+		raw, _ = json.Marshal(data)
+		return event.Type + " " + string(raw)
+	}
+
+	tests := []struct {
+		name         string
+		subscription wsSubscription
+		want         []int // indexes into events
+	}{
+		{"everything by default", wsSubscription{}, []int{0, 1, 2, 3, 4, 5, 6}},
+		{"one task", wsSubscription{TaskID: "task-a"}, []int{1, 3, 6}},
+		{"one execution", wsSubscription{ExecutionID: "exec-b"}, []int{0, 2}},
+		{"unknown task", wsSubscription{TaskID: "task-z"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, _ := dialWS(t, srv, nil)
+			subscribe(conn, tt.subscription)
+			for _, event := range events {
+				s.BroadcastProgress(event)
+			}
+
+			// Events are delivered in order, so once the filter is lifted a
+			// marker shows everything the subscription let through has arrived
+			subscribe(conn, wsSubscription{})
+			s.BroadcastProgress(models.ProgressEvent{Type: "marker"})
+
+			var got []string
+			for {
+				event := readWS(t, conn)
+				if event.Type == "marker" {
+					break
+				}
+				got = append(got, describe(event))
+			}
+			var want []string
+			for _, i := range tt.want {
+				want = append(want, describe(events[i]))
+			}
+			if strings.Join(got, "\n") != strings.Join(want, "\n") {
+				t.Errorf("received:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+			}
+		})
+	}
+}
+
+func TestWebSocketSnapshot(t *testing.T) {
+	s := newTestServer(t)
+	srv := httptest.NewServer(s.Router())
+	defer srv.Close()
+
+	// An upload to a slow backend keeps the run in flight
+	if err := s.config.AddBackend(&models.Backend{
+		ID:      "slow",
+		Name:    "slow",
+		Type:    "memory",
+		Enabled: true,
+		Config:  map[string]interface{}{"name": t.Name(), "latency": 30},
+	}); err != nil {
+		t.Fatalf("AddBackend: %v", err)
+	}
+	source := t.TempDir()
+	if err := os.WriteFile(filepath.Join(source, "a.txt"), []byte("alpha"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.config.AddTask(&models.Task{
+		ID:             "task-1",
+		Name:           "documents",
+		SourcePath:     source,
+		BackendIDs:     []string{"slow"},
+		Schedule:       models.Schedule{Type: "manual"},
+		ArchiveOptions: models.ArchiveOptions{Format: "tar.gz", UseTimestamp: true},
+		Enabled:        true,
+	}); err != nil {
+		t.Fatalf("AddTask: %v", err)
+	}
+
+	finished := make(chan struct{})
+	s.executor.OnFinished(func(models.Execution) { close(finished) })
+	executionID, err := s.executor.Execute("task-1")
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	defer func() {
+		if err := s.executor.Cancel(executionID); err != nil {
+			t.Errorf("Cancel: %v", err)
+		}
+		select {
+		case <-finished:
+		case <-time.After(10 * time.Second):
+			t.Error("cancelled execution did not finish")
+		}
+	}()
+
+	// Wait for the run to report progress
+	var latest *models.ProgressEvent
+	for deadline := time.Now().Add(10 * time.Second); latest == nil; time.Sleep(10 * time.Millisecond) {
+		if running := s.executor.GetRunningExecutions(); len(running) == 1 {
+			latest = running[0].Progress
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("run reported no progress")
+		}
+	}
+
+	_, snapshot := dialWS(t, srv, nil)
+	if snapshot.Type != "snapshot" {
+		t.Fatalf("first event is %s, want snapshot", snapshot.Type)
+	}
+	raw, _ := json.Marshal(snapshot.Data)
+	var data struct {
+		Running []models.RunningExecution `json:"running"`
+	}
+	if err := json.Unmarshal(raw, &data); err != nil {
+		t.Fatalf("decoding snapshot: %v", err)
+	}
+	if len(data.Running) != 1 {
+		t.Fatalf("snapshot lists %d running executions, want 1", len(data.Running))
+	}
+	running := data.Running[0]
+	if running.ExecutionID != executionID || running.TaskID != "task-1" || running.TaskName != "documents" {
+		t.Errorf("snapshot execution = %+v, want %s of task-1", running, executionID)
+	}
+	if running.Progress == nil || running.Progress.Type != latest.Type {
+		t.Errorf("snapshot progress = %+v, want the latest %s event", running.Progress, latest.Type)
+	}
+}
+
+func TestWebSocketOutlivesServerTimeouts(t *testing.T) {
+	s := newTestServer(t)
+	const timeout = 100 * time.Millisecond
+	srv := httptest.NewUnstartedServer(s.Router())
+	srv.Config.ReadTimeout = timeout
+	srv.Config.WriteTimeout = timeout
+	srv.Start()
+	defer srv.Close()
+
+	conn, _ := dialWS(t, srv, nil)
+	time.Sleep(3 * timeout)
+
+	// Both directions still work well past the server's timeouts
+	s.BroadcastProgress(models.ProgressEvent{Type: "execution_started", Data: map[string]interface{}{"task_id": "task-1"}})
+	if event := readWS(t, conn); event.Type != "execution_started" {
+		t.Errorf("received %s, want execution_started", event.Type)
+	}
+	if err := conn.WriteJSON(wsMessage{Subscribe: &wsSubscription{TaskID: "task-2"}}); err != nil {
+		t.Fatalf("sending a subscription: %v", err)
+	}
+	time.Sleep(3 * timeout)
+	s.BroadcastProgress(models.ProgressEvent{Type: "execution_started", Data: map[string]interface{}{"task_id": "task-2"}})
+	if event := readWS(t, conn); event.Type != "execution_started" {
+		t.Errorf("received %s, want execution_started", event.Type)
+	}
+	if n := wsClientCount(s); n != 1 {
+		t.Errorf("%d clients connected, want 1", n)
+	}
+}
+
+func TestCORS(t *testing.T) {
+	s := newTestServer(t)
+	settings := s.config.GetSettings()
+	settings.AllowedOrigins = []string{"https://ui.example.com/"}
+	if err := s.config.UpdateSettings(settings); err != nil {
+		t.Fatalf("UpdateSettings: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		method      string
+		origin      string
+		preflight   bool
+		wantStatus  int
+		wantAllowed string
+	}{
+		{name: "no origin", method: "GET", wantStatus: http.StatusOK},
+		{name: "same origin", method: "GET", origin: "http://example.com", wantStatus: http.StatusOK},
+		{name: "listed origin", method: "GET", origin: "https://ui.example.com", wantStatus: http.StatusOK, wantAllowed: "https://ui.example.com"},
+		{name: "listed origin preflight", method: "OPTIONS", origin: "https://ui.example.com", preflight: true, wantStatus: http.StatusNoContent, wantAllowed: "https://ui.example.com"},
+		{name: "unlisted origin", method: "GET", origin: "https://evil.example.com", wantStatus: http.StatusForbidden},
+		{name: "unlisted origin preflight", method: "OPTIONS", origin: "https://evil.example.com", preflight: true, wantStatus: http.StatusForbidden},
+		{name: "unlisted origin write", method: "POST", origin: "https://evil.example.com", wantStatus: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, "/api/v1/tasks", nil)
+			if tt.origin != "" {
+				r.Header.Set("Origin", tt.origin)
+			}
+			if tt.preflight {
+				r.Header.Set("Access-Control-Request-Method", "POST")
+			}
+			rec := httptest.NewRecorder()
+			s.Router().ServeHTTP(rec, r)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if got := rec.Header().Get("Access-Control-Allow-Origin"); got != tt.wantAllowed {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, tt.wantAllowed)
+			}
+			if tt.preflight && tt.wantStatus == http.StatusNoContent && rec.Header().Get("Access-Control-Allow-Methods") == "" {
+				t.Error("preflight response has no Access-Control-Allow-Methods")
+			}
+			if tt.wantStatus == http.StatusForbidden {
+				var resp Response
+				if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+					t.Fatalf("decoding response %q: %v", rec.Body.String(), err)
+				}
+				if resp.Error == nil || resp.Error.Code != "ORIGIN_NOT_ALLOWED" {
+					t.Errorf("error = %+v, want ORIGIN_NOT_ALLOWED", resp.Error)
+				}
+			}
+		})
+	}
+
+	t.Run("websocket upgrade", func(t *testing.T) {
+		srv := httptest.NewServer(s.Router())
+		defer srv.Close()
+
+		for _, origin := range []string{"", srv.URL, "https://ui.example.com"} {
+			header := http.Header{}
+			if origin != "" {
+				header.Set("Origin", origin)
+			}
+			if _, snapshot := dialWS(t, srv, header); snapshot.Type != "snapshot" {
+				t.Errorf("origin %q: first event = %s, want snapshot", origin, snapshot.Type)
+			}
+		}
+
+		url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/api/v1/ws/progress"
+		conn, resp, err := websocket.DefaultDialer.Dial(url, http.Header{"Origin": {"https://evil.example.com"}})
+		if err == nil {
+			_ = conn.Close()
+			t.Fatal("upgrade from an unlisted origin succeeded")
+		}
+		if resp == nil || resp.StatusCode != http.StatusForbidden {
+			t.Errorf("upgrade from an unlisted origin: response %v, want 403", resp)
+		}
+	})
+}
+
+func TestGzip(t *testing.T) {
+	large := `{"data":"` + strings.Repeat("archivist ", gzipMinSize/10+10) + `"}`
+	small := `{"data":"ok"}`
+
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		contentType    string
+		body           string
+		wantGzip       bool
+	}{
+		{name: "requested", acceptEncoding: "gzip, deflate", contentType: "application/json", body: large, wantGzip: true},
+		{name: "requested with a quality", acceptEncoding: "br;q=1.0, gzip;q=0.5", contentType: "text/html; charset=utf-8", body: large, wantGzip: true},
+		{name: "not requested", contentType: "application/json", body: large},
+		{name: "other encoding", acceptEncoding: "br", contentType: "application/json", body: large},
+		{name: "refused", acceptEncoding: "gzip;q=0", contentType: "application/json", body: large},
+		{name: "small response", acceptEncoding: "gzip", contentType: "application/json", body: small},
+		{name: "incompressible type", acceptEncoding: "gzip", contentType: "application/octet-stream", body: large},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := withGzip(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", tt.contentType)
+				w.WriteHeader(http.StatusCreated)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			r := httptest.NewRequest("GET", "/api/v1/tasks", nil)
+			if tt.acceptEncoding != "" {
+				r.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, r)
+
+			if rec.Code != http.StatusCreated {
+				t.Errorf("status = %d, want %d", rec.Code, http.StatusCreated)
+			}
+			if vary := rec.Header().Get("Vary"); vary != "Accept-Encoding" {
+				t.Errorf("Vary = %q, want Accept-Encoding", vary)
+			}
+			body := rec.Body.String()
+			if gotGzip := rec.Header().Get("Content-Encoding") == "gzip"; gotGzip != tt.wantGzip {
+				t.Fatalf("compressed = %v, want %v", gotGzip, tt.wantGzip)
+			}
+			if tt.wantGzip {
+				zr, err := gzip.NewReader(rec.Body)
+				if err != nil {
+					t.Fatalf("gzip.NewReader: %v", err)
+				}
+				data, err := io.ReadAll(zr)
+				if err != nil {
+					t.Fatalf("decompressing: %v", err)
+				}
+				body = string(data)
+			}
+			if body != tt.body {
+				t.Errorf("body = %.40q..., want %.40q...", body, tt.body)
+			}
+		})
+	}
+
+	t.Run("websocket upgrade", func(t *testing.T) {
+		s := newTestServer(t)
+		srv := httptest.NewServer(s.Router())
+		defer srv.Close()
+
+		if _, snapshot := dialWS(t, srv, http.Header{"Accept-Encoding": {"gzip"}}); snapshot.Type != "snapshot" {
+			t.Errorf("first event = %s, want snapshot", snapshot.Type)
+		}
+	})
+}
+
+func TestExecuteIdempotencyKey(t *testing.T) {
+	s := newTestServer(t)
+	source := t.TempDir()
+	if err := os.WriteFile(filepath.Join(source, "a.txt"), []byte("alpha"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"task-1", "task-2"} {
+		if err := s.config.AddTask(&models.Task{
+			ID:             id,
+			Name:           id,
+			SourcePath:     source,
+			BackendIDs:     []string{"local"},
+			Schedule:       models.Schedule{Type: "manual"},
+			ArchiveOptions: models.ArchiveOptions{Format: "tar.gz", UseTimestamp: true},
+			Enabled:        true,
+		}); err != nil {
+			t.Fatalf("AddTask: %v", err)
+		}
+	}
+	finished := make(chan string, 10)
+	s.executor.OnFinished(func(execution models.Execution) { finished <- execution.ID })
+
+	type result struct {
+		ExecutionID string `json:"execution_id"`
+		Status      string `json:"status"`
+		Replayed    bool   `json:"replayed"`
+	}
+	execute := func(taskID, key string) (int, Response, result) {
+		r := httptest.NewRequest(http.MethodPost, "/api/v1/tasks/"+taskID+"/execute", nil)
+		if key != "" {
+			r.Header.Set(idempotencyKeyHeader, key)
+		}
+		status, resp := serve(t, s, r)
+		var data result
+		if status == http.StatusOK {
+			decodeData(t, resp, &data)
+		}
+		return status, resp, data
+	}
+	waitFinished := func(executionID string) {
+		t.Helper()
+		select {
+		case id := <-finished:
+			if id != executionID {
+				t.Fatalf("execution %s finished, want %s", id, executionID)
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatalf("execution %s didn't finish", executionID)
+		}
+	}
+
+	// Concurrent retries with one key start a single run
+	const requests = 5
+	results := make(chan result, requests)
+	for range requests {
+		go func() {
+			status, resp, data := execute("task-1", "retry-1")
+			if status != http.StatusOK {
+				t.Errorf("status %d: %+v", status, resp.Error)
+			}
+			results <- data
+		}()
+	}
+	var executionID string
+	replays := 0
+	for range requests {
+		data := <-results
+		if executionID == "" {
+			executionID = data.ExecutionID
+		}
+		if data.ExecutionID != executionID {
+			t.Fatalf("requests with one key got executions %s and %s", executionID, data.ExecutionID)
+		}
+		if data.Replayed {
+			replays++
+		}
+	}
+	if replays != requests-1 {
+		t.Errorf("%d of %d requests were replays, want %d", replays, requests, requests-1)
+	}
+	waitFinished(executionID)
+
+	// A later retry reports the same execution as it is now
+	status, resp, data := execute("task-1", "retry-1")
+	if status != http.StatusOK {
+		t.Fatalf("status %d: %+v", status, resp.Error)
+	}
+	if data.ExecutionID != executionID || !data.Replayed || data.Status != "success" {
+		t.Errorf("retry = %+v, want a replay of %s with status success", data, executionID)
+	}
+	executions, err := s.db.ListExecutions("task-1", "", 10, 0)
+	if err != nil {
+		t.Fatalf("ListExecutions: %v", err)
+	}
+	if len(executions) != 1 {
+		t.Errorf("task-1 has %d executions, want 1", len(executions))
+	}
+
+	// The key can't start a run of another task
+	status, resp, _ = execute("task-2", "retry-1")
+	if status != http.StatusUnprocessableEntity || resp.Error == nil || resp.Error.Code != "IDEMPOTENCY_KEY_REUSED" {
+		t.Errorf("key reused for another task: status %d, error %+v", status, resp.Error)
+	}
+
+	// A new key starts a new run
+	status, resp, data = execute("task-1", "retry-2")
+	if status != http.StatusOK {
+		t.Fatalf("status %d: %+v", status, resp.Error)
+	}
+	if data.ExecutionID == executionID || data.Replayed {
+		t.Errorf("new key = %+v, want a new execution", data)
+	}
+	waitFinished(data.ExecutionID)
+
+	status, resp, _ = execute("task-1", "not a valid key")
+	if status != http.StatusBadRequest || resp.Error == nil || resp.Error.Code != "VALIDATION_ERROR" {
+		t.Errorf("invalid key: status %d, error %+v", status, resp.Error)
+	}
+}
+
+func TestDeleteTaskBackup(t *testing.T) {
+	s := newTestServer(t)
+	if err := s.config.AddTask(&models.Task{
+		ID:              "task-1",
+		Name:            "documents",
+		SourcePath:      t.TempDir(),
+		BackendIDs:      []string{"local"},
+		Schedule:        models.Schedule{Type: "manual"},
+		ArchiveOptions:  models.ArchiveOptions{Format: "tar.gz", UseTimestamp: true},
+		RetentionPolicy: models.RetentionPolicy{KeepLast: 2},
+		Enabled:         true,
+	}); err != nil {
+		t.Fatalf("AddTask: %v", err)
+	}
+	backendCfg, err := s.config.GetBackend("local")
+	if err != nil {
+		t.Fatal(err)
+	}
+	storeDir := backendCfg.Config["path"].(string)
+	if err := os.MkdirAll(filepath.Join(storeDir, "documents"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	oldest := "documents/documents_20250127_120000.tar.gz"
+	base := time.Date(2025, 1, 27, 12, 0, 0, 0, time.UTC)
+	for i, name := range []string{oldest, "documents/documents_20250128_120000.tar.gz", "documents/documents_20250129_120000.tar.gz"} {
+		path := filepath.Join(storeDir, filepath.FromSlash(name))
+		if err := os.WriteFile(path, []byte("archive"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		modTime := base.Add(time.Duration(i) * 24 * time.Hour)
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Outside the task's backups, where a path escaping the backend would point
+	if err := os.WriteFile(filepath.Join(filepath.Dir(storeDir), "outside.tar.gz"), []byte("keep"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	retentionToDelete := func() []string {
+		t.Helper()
+		status, resp := serve(t, s, httptest.NewRequest(http.MethodGet, "/api/v1/tasks/task-1/retention-preview", nil))
+		if status != http.StatusOK {
+			t.Fatalf("retention preview: status %d: %+v", status, resp.Error)
+		}
+		var preview models.RetentionPreview
+		decodeData(t, resp, &preview)
+		if len(preview.Backends) != 1 {
+			t.Fatalf("retention preview for %d backends, want 1", len(preview.Backends))
+		}
+		paths := make([]string, 0)
+		for _, candidate := range preview.Backends[0].ToDelete {
+			paths = append(paths, candidate.Path)
+		}
+		return paths
+	}
+	deleteBackup := func(path, confirm string) (int, Response) {
+		target := "/api/v1/tasks/task-1/backups?" + url.Values{"backend_id": {"local"}, "path": {path}, "confirm": {confirm}}.Encode()
+		return serve(t, s, httptest.NewRequest(http.MethodDelete, target, nil))
+	}
+
+	if got := retentionToDelete(); len(got) != 1 || got[0] != oldest {
+		t.Fatalf("retention would delete %v, want [%s]", got, oldest)
+	}
+
+	// Paths leaving the backend are refused before a token is issued
+	for _, path := range []string{"../outside.tar.gz", "documents/../../outside.tar.gz", "/outside.tar.gz"} {
+		status, resp := deleteBackup(path, "")
+		if status != http.StatusBadRequest || resp.Error == nil || resp.Error.Code != "VALIDATION_ERROR" {
+			t.Errorf("deleting %s: status %d, error %+v; want a validation error", path, status, resp.Error)
+		}
+	}
+
+	status, resp := deleteBackup(oldest, "")
+	if status != http.StatusPreconditionRequired {
+		t.Fatalf("unconfirmed delete: status %d, want %d: %+v", status, http.StatusPreconditionRequired, resp.Error)
+	}
+	details, _ := resp.Error.Details.(map[string]interface{})
+	token, _ := details["confirm_token"].(string)
+	if token == "" {
+		t.Fatalf("no confirmation token in %+v", resp.Error)
+	}
+	if _, err := os.Stat(filepath.Join(storeDir, filepath.FromSlash(oldest))); err != nil {
+		t.Fatalf("backup deleted before confirmation: %v", err)
+	}
+
+	status, resp = deleteBackup(oldest, token)
+	if status != http.StatusOK {
+		t.Fatalf("confirmed delete: status %d: %+v", status, resp.Error)
+	}
+	var deleted []models.BackupDeletion
+	decodeData(t, resp, &deleted)
+	if len(deleted) != 1 || deleted[0].RemotePath != oldest {
+		t.Errorf("deleted %+v, want %s", deleted, oldest)
+	}
+	if _, err := os.Stat(filepath.Join(storeDir, filepath.FromSlash(oldest))); !os.IsNotExist(err) {
+		t.Errorf("backup still on the backend: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(storeDir), "outside.tar.gz")); err != nil {
+		t.Errorf("file outside the backend removed: %v", err)
+	}
+
+	// The token is spent
+	if status, _ := deleteBackup(oldest, token); status != http.StatusBadRequest {
+		t.Errorf("reusing the token: status %d, want %d", status, http.StatusBadRequest)
+	}
+
+	// History records the deletion, the listing drops the backup and retention has nothing left to prune
+	status, resp = serve(t, s, httptest.NewRequest(http.MethodGet, "/api/v1/tasks/task-1/backups/deletions", nil))
+	if status != http.StatusOK {
+		t.Fatalf("deletions: status %d: %+v", status, resp.Error)
+	}
+	var history []models.BackupDeletion
+	decodeData(t, resp, &history)
+	if len(history) != 1 || history[0].RemotePath != oldest || history[0].BackendID != "local" {
+		t.Errorf("deletion history %+v, want the deletion of %s from local", history, oldest)
+	}
+
+	status, resp = serve(t, s, httptest.NewRequest(http.MethodGet, "/api/v1/tasks/task-1/backups", nil))
+	if status != http.StatusOK {
+		t.Fatalf("backups: status %d: %+v", status, resp.Error)
+	}
+	var listings []models.BackendBackups
+	decodeData(t, resp, &listings)
+	if len(listings) != 1 || listings[0].Total != 2 {
+		t.Fatalf("listing %+v, want 2 backups on local", listings)
+	}
+	for _, backup := range listings[0].Backups {
+		if backup.Path == oldest {
+			t.Errorf("deleted backup %s still listed", oldest)
+		}
+	}
+
+	if got := retentionToDelete(); len(got) != 0 {
+		t.Errorf("retention would delete %v after the manual deletion, want nothing", got)
+	}
+}
@@ -0,0 +1,39 @@
+package api
+
+import (
+	"os"
+	"time"
+)
+
+// sourceHealthTTL is how long a source's accessibility is cached, so listing
+// tasks doesn't stat every source on every request
+const sourceHealthTTL = 30 * time.Second
+
+// sourceHealth is a cached accessibility check of a task source
+type sourceHealth struct {
+	accessible bool
+	checkedAt  time.Time
+}
+
+// sourceAccessible reports whether a task's source can be read: its resolved
+// path, following symlinks, must exist. A symlink left dangling by an unmounted
+// volume is reported here instead of only failing the next run.
+func (s *Server) sourceAccessible(sourcePath string) bool {
+	path := s.config.ResolvePath(sourcePath)
+
+	s.sourceHealthMu.Lock()
+	cached, ok := s.sourceHealth[path]
+	s.sourceHealthMu.Unlock()
+	if ok && time.Since(cached.checkedAt) < sourceHealthTTL {
+		return cached.accessible
+	}
+
+	// Stat outside the lock, since a hung mount can block it
+	_, err := os.Stat(path)
+	cached = sourceHealth{accessible: err == nil, checkedAt: time.Now()}
+
+	s.sourceHealthMu.Lock()
+	defer s.sourceHealthMu.Unlock()
+	s.sourceHealth[path] = cached
+	return cached.accessible
+}
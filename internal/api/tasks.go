@@ -1,12 +1,17 @@
 package api
 
 import (
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
+	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/nsilverman/archivist/internal/archive"
 	"github.com/nsilverman/archivist/internal/models"
 )
 
@@ -18,25 +23,30 @@ func (s *Server) listTasks(w http.ResponseWriter, r *http.Request) {
 	var enrichedTasks []map[string]interface{}
 	for _, task := range tasks {
 		taskMap := map[string]interface{}{
-			"id":               task.ID,
-			"name":             task.Name,
-			"description":      task.Description,
-			"source_path":      task.SourcePath,
-			"backend_ids":      task.BackendIDs,
-			"schedule":         task.Schedule,
-			"archive_options":  task.ArchiveOptions,
-			"retention_policy": task.RetentionPolicy,
-			"enabled":          task.Enabled,
-			"created_at":       task.CreatedAt,
-			"updated_at":       task.UpdatedAt,
-			"last_run":         task.LastRun,
-			"next_run":         task.NextRun,
+			"id":                task.ID,
+			"name":              task.Name,
+			"description":       task.Description,
+			"source_path":       task.SourcePath,
+			"source_accessible": s.sourceAccessible(task.SourcePath),
+			"backend_ids":       task.BackendIDs,
+			"sync_backend_ids":  task.SyncBackendIDs,
+			"schedule":          task.Schedule,
+			"archive_options":   task.ArchiveOptions,
+			"retention_policy":  task.RetentionPolicy,
+			"max_age_hours":     task.MaxAgeHours,
+			"enabled":           task.Enabled,
+			"created_at":        task.CreatedAt,
+			"updated_at":        task.UpdatedAt,
+			"last_run":          task.LastRun,
+			"next_run":          task.NextRun,
 		}
 
 		// Add stats
 		stats, err := s.db.GetTaskStats(task.ID)
 		if err == nil {
+			stats.Stale = isTaskStale(&task, stats.LastSuccessfulRun, time.Now())
 			taskMap["stats"] = stats
+			taskMap["stale"] = stats.Stale
 		}
 
 		enrichedTasks = append(enrichedTasks, taskMap)
@@ -45,6 +55,36 @@ func (s *Server) listTasks(w http.ResponseWriter, r *http.Request) {
 	s.success(w, enrichedTasks)
 }
 
+// taskStatsSummary handles GET /api/v1/stats/tasks
+// Returns the statistics of every task from one query, for dashboards that would
+// otherwise request each task's stats separately.
+func (s *Server) taskStatsSummary(w http.ResponseWriter, r *http.Request) {
+	allStats, err := s.db.GetAllTaskStats()
+	if err != nil {
+		s.error(w, "DATABASE_ERROR", err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	now := time.Now()
+	summaries := make([]map[string]interface{}, 0)
+	for _, task := range s.config.GetTasks() {
+		stats := allStats[task.ID]
+		if stats == nil {
+			stats = &models.TaskStats{}
+		}
+		stats.Stale = isTaskStale(&task, stats.LastSuccessfulRun, now)
+
+		summaries = append(summaries, map[string]interface{}{
+			"task_id":   task.ID,
+			"task_name": task.Name,
+			"enabled":   task.Enabled,
+			"stats":     stats,
+		})
+	}
+
+	s.success(w, summaries)
+}
+
 // getTask handles GET /api/v1/tasks/{id}
 func (s *Server) getTask(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
@@ -75,55 +115,160 @@ func (s *Server) createTask(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	// Map backup mode to format
-	backupMode := r.FormValue("backup_mode")
-	format := "tar.gz" // default
-	if backupMode == "sync" {
-		format = "sync"
+	// Parse max_age_hours
+	maxAgeHours := 0
+	if maxAgeStr := r.FormValue("max_age_hours"); maxAgeStr != "" {
+		if val, err := strconv.Atoi(maxAgeStr); err == nil && val > 0 {
+			maxAgeHours = val
+		}
+	}
+
+	// Parse max_execution_history
+	maxHistory := 0
+	if maxHistoryStr := r.FormValue("max_execution_history"); maxHistoryStr != "" {
+		if val, err := strconv.Atoi(maxHistoryStr); err == nil && val > 0 {
+			maxHistory = val
+		}
+	}
+
+	// Parse min_source_percent
+	minSourcePercent := 0
+	if minSourceStr := r.FormValue("min_source_percent"); minSourceStr != "" {
+		if val, err := strconv.Atoi(minSourceStr); err == nil && val > 0 && val <= 100 {
+			minSourcePercent = val
+		}
+	}
+
+	format := formatForBackupMode(r.FormValue("backup_mode"))
+
+	compression := r.FormValue("compression")
+	if compression == "" {
+		compression = "gzip"
+	}
+	if err := archive.ValidateCompression(compression); err != nil {
+		s.validationError(w, "compression", err.Error())
+		return
+	}
+	if format == archive.FormatChunked && keepLast > 0 {
+		s.validationError(w, "keep_last", "keep_last is not supported for chunked archives; they are kept until deleted")
+		return
+	}
+
+	// Parse max_file_bytes
+	var maxFileBytes int64
+	if maxFileBytesStr := r.FormValue("max_file_bytes"); maxFileBytesStr != "" {
+		if val, err := strconv.ParseInt(maxFileBytesStr, 10, 64); err == nil && val > 0 {
+			maxFileBytes = val
+		}
+	}
+
+	// Parse split_size_bytes
+	var splitSizeBytes int64
+	if splitSizeStr := r.FormValue("split_size_bytes"); splitSizeStr != "" {
+		if val, err := strconv.ParseInt(splitSizeStr, 10, 64); err == nil && val > 0 {
+			splitSizeBytes = val
+		}
+	}
+
+	// Parse file_retries and max_failures
+	fileRetries := 0
+	if fileRetriesStr := r.FormValue("file_retries"); fileRetriesStr != "" {
+		if val, err := strconv.Atoi(fileRetriesStr); err == nil && val > 0 {
+			fileRetries = val
+		}
+	}
+	maxFailures := 0
+	if maxFailuresStr := r.FormValue("max_failures"); maxFailuresStr != "" {
+		if val, err := strconv.Atoi(maxFailuresStr); err == nil && val > 0 {
+			maxFailures = val
+		}
+	}
+	maxDeletePercent := 0
+	if maxDeletePercentStr := r.FormValue("max_delete_percent"); maxDeletePercentStr != "" {
+		if val, err := strconv.Atoi(maxDeletePercentStr); err == nil && val > 0 && val < 100 {
+			maxDeletePercent = val
+		}
 	}
 
 	// Map form to Task model
 	task := models.Task{
-		Name:        r.FormValue("name"),
-		Description: r.FormValue("description"),
-		SourcePath:  r.FormValue("source_path"),
-		BackendIDs:  r.Form["backend_ids"],
+		Name:           r.FormValue("name"),
+		Description:    r.FormValue("description"),
+		SourcePath:     r.FormValue("source_path"),
+		TempDir:        strings.TrimSpace(r.FormValue("temp_dir")),
+		BackendIDs:     r.Form["backend_ids"],
+		SyncBackendIDs: parseList(r, "sync_backend_ids"),
 		Schedule: models.Schedule{
 			Type:       r.FormValue("schedule_type"),
 			SimpleType: r.FormValue("simple_type"),
 			CronExpr:   r.FormValue("cron_expr"),
 		},
 		ArchiveOptions: models.ArchiveOptions{
-			Format:       format,
-			Compression:  "gzip",
-			UseTimestamp: r.FormValue("use_timestamp") == "true",
+			Format:          format,
+			Compression:     compression,
+			NamePattern:     r.FormValue("name_pattern"),
+			UseTimestamp:    r.FormValue("use_timestamp") == "true",
+			KeepLatest:      r.FormValue("keep_latest") == "true",
+			SplitSizeBytes:  splitSizeBytes,
+			LocalCopyDir:    strings.TrimSpace(r.FormValue("local_copy_dir")),
+			Stream:          r.FormValue("stream") == "true",
+			StoreExtensions: parseList(r, "store_extensions"),
+			Redaction:       parseRedaction(r),
 			SyncOptions: models.SyncOptions{
-				DeleteRemote: r.FormValue("delete_remote") == "true",
+				DeleteRemote:  r.FormValue("delete_remote") == "true",
+				CompressFiles: r.FormValue("compress_files") == "true",
+				MaxFileBytes:  maxFileBytes,
+				FileRetries:   fileRetries,
+				MaxFailures:   maxFailures,
+				Layout:        r.FormValue("layout"),
+				RemotePrefix:  strings.Trim(r.FormValue("remote_prefix"), "/"),
+
+				DeleteAfterSuccessfulUpload: r.FormValue("delete_after_successful_upload") == "true",
+				MaxDeletePercent:            maxDeletePercent,
 			},
 		},
 		RetentionPolicy: models.RetentionPolicy{
-			KeepLast: keepLast,
+			KeepLast:           keepLast,
+			RequireFullSuccess: r.FormValue("require_full_success") == "true",
 		},
-		Enabled: r.FormValue("enabled") == "true",
+		MaxAgeHours:         maxAgeHours,
+		MaxExecutionHistory: maxHistory,
+		RunOnStartup:        r.FormValue("run_on_startup") == "true",
+		RunMissedOnStartup:  r.FormValue("run_missed_on_startup") == "true",
+		AllowEmptySource:    r.FormValue("allow_empty_source") == "true",
+		MinSourcePercent:    minSourcePercent,
+		DependsOn:           parseList(r, "depends_on"),
+		Notifications:       parseTaskNotifications(r),
+		Heartbeat:           parseHeartbeat(r),
+		FailureRetry:        parseFailureRetry(r),
+		Enabled:             r.FormValue("enabled") == "true",
 	}
 
 	// Validate required fields
 	if task.Name == "" {
-		s.error(w, "VALIDATION_ERROR", "Task name is required", http.StatusBadRequest)
+		s.validationError(w, "name", "Task name is required")
 		return
 	}
 	if task.SourcePath == "" {
-		s.error(w, "VALIDATION_ERROR", "Source path is required", http.StatusBadRequest)
+		s.validationError(w, "source_path", "Source path is required")
 		return
 	}
 	if len(task.BackendIDs) == 0 {
-		s.error(w, "VALIDATION_ERROR", "At least one backend is required", http.StatusBadRequest)
+		s.validationError(w, "backend_ids", "At least one backend is required")
 		return
 	}
 
+	// Validate source path unless forced (e.g. a volume that will be mounted later)
+	if r.FormValue("force") != "true" {
+		if err := s.validateSourcePath(task.SourcePath); err != nil {
+			s.validationError(w, "source_path", err.Error())
+			return
+		}
+	}
+
 	// Add task
 	if err := s.config.AddTask(&task); err != nil {
-		s.error(w, "INTERNAL_ERROR", err.Error(), http.StatusInternalServerError)
+		s.taskConfigError(w, err)
 		return
 	}
 
@@ -156,41 +301,146 @@ func (s *Server) updateTask(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	// Map backup mode to format
-	backupMode := r.FormValue("backup_mode")
-	format := "tar.gz" // default
-	if backupMode == "sync" {
-		format = "sync"
+	// Parse max_age_hours
+	maxAgeHours := 0
+	if maxAgeStr := r.FormValue("max_age_hours"); maxAgeStr != "" {
+		if val, err := strconv.Atoi(maxAgeStr); err == nil && val > 0 {
+			maxAgeHours = val
+		}
+	}
+
+	// Parse max_execution_history
+	maxHistory := 0
+	if maxHistoryStr := r.FormValue("max_execution_history"); maxHistoryStr != "" {
+		if val, err := strconv.Atoi(maxHistoryStr); err == nil && val > 0 {
+			maxHistory = val
+		}
+	}
+
+	// Parse min_source_percent
+	minSourcePercent := 0
+	if minSourceStr := r.FormValue("min_source_percent"); minSourceStr != "" {
+		if val, err := strconv.Atoi(minSourceStr); err == nil && val > 0 && val <= 100 {
+			minSourcePercent = val
+		}
+	}
+
+	format := formatForBackupMode(r.FormValue("backup_mode"))
+
+	compression := r.FormValue("compression")
+	if compression == "" {
+		compression = "gzip"
+	}
+	if err := archive.ValidateCompression(compression); err != nil {
+		s.validationError(w, "compression", err.Error())
+		return
+	}
+	if format == archive.FormatChunked && keepLast > 0 {
+		s.validationError(w, "keep_last", "keep_last is not supported for chunked archives; they are kept until deleted")
+		return
+	}
+
+	// Parse max_file_bytes
+	var maxFileBytes int64
+	if maxFileBytesStr := r.FormValue("max_file_bytes"); maxFileBytesStr != "" {
+		if val, err := strconv.ParseInt(maxFileBytesStr, 10, 64); err == nil && val > 0 {
+			maxFileBytes = val
+		}
+	}
+
+	// Parse split_size_bytes
+	var splitSizeBytes int64
+	if splitSizeStr := r.FormValue("split_size_bytes"); splitSizeStr != "" {
+		if val, err := strconv.ParseInt(splitSizeStr, 10, 64); err == nil && val > 0 {
+			splitSizeBytes = val
+		}
+	}
+
+	// Parse file_retries and max_failures
+	fileRetries := 0
+	if fileRetriesStr := r.FormValue("file_retries"); fileRetriesStr != "" {
+		if val, err := strconv.Atoi(fileRetriesStr); err == nil && val > 0 {
+			fileRetries = val
+		}
+	}
+	maxFailures := 0
+	if maxFailuresStr := r.FormValue("max_failures"); maxFailuresStr != "" {
+		if val, err := strconv.Atoi(maxFailuresStr); err == nil && val > 0 {
+			maxFailures = val
+		}
+	}
+	maxDeletePercent := 0
+	if maxDeletePercentStr := r.FormValue("max_delete_percent"); maxDeletePercentStr != "" {
+		if val, err := strconv.Atoi(maxDeletePercentStr); err == nil && val > 0 && val < 100 {
+			maxDeletePercent = val
+		}
 	}
 
 	// Map form to Task model
 	task := models.Task{
-		Name:        r.FormValue("name"),
-		Description: r.FormValue("description"),
-		SourcePath:  r.FormValue("source_path"),
-		BackendIDs:  r.Form["backend_ids"],
+		Name:           r.FormValue("name"),
+		Description:    r.FormValue("description"),
+		SourcePath:     r.FormValue("source_path"),
+		TempDir:        strings.TrimSpace(r.FormValue("temp_dir")),
+		BackendIDs:     r.Form["backend_ids"],
+		SyncBackendIDs: parseList(r, "sync_backend_ids"),
 		Schedule: models.Schedule{
 			Type:       r.FormValue("schedule_type"),
 			SimpleType: r.FormValue("simple_type"),
 			CronExpr:   r.FormValue("cron_expr"),
 		},
 		ArchiveOptions: models.ArchiveOptions{
-			Format:       format,
-			Compression:  "gzip",
-			UseTimestamp: r.FormValue("use_timestamp") == "true",
+			Format:          format,
+			Compression:     compression,
+			NamePattern:     r.FormValue("name_pattern"),
+			UseTimestamp:    r.FormValue("use_timestamp") == "true",
+			KeepLatest:      r.FormValue("keep_latest") == "true",
+			SplitSizeBytes:  splitSizeBytes,
+			LocalCopyDir:    strings.TrimSpace(r.FormValue("local_copy_dir")),
+			Stream:          r.FormValue("stream") == "true",
+			StoreExtensions: parseList(r, "store_extensions"),
+			Redaction:       parseRedaction(r),
 			SyncOptions: models.SyncOptions{
-				DeleteRemote: r.FormValue("delete_remote") == "true",
+				DeleteRemote:  r.FormValue("delete_remote") == "true",
+				CompressFiles: r.FormValue("compress_files") == "true",
+				MaxFileBytes:  maxFileBytes,
+				FileRetries:   fileRetries,
+				MaxFailures:   maxFailures,
+				Layout:        r.FormValue("layout"),
+				RemotePrefix:  strings.Trim(r.FormValue("remote_prefix"), "/"),
+
+				DeleteAfterSuccessfulUpload: r.FormValue("delete_after_successful_upload") == "true",
+				MaxDeletePercent:            maxDeletePercent,
 			},
 		},
 		RetentionPolicy: models.RetentionPolicy{
-			KeepLast: keepLast,
+			KeepLast:           keepLast,
+			RequireFullSuccess: r.FormValue("require_full_success") == "true",
 		},
-		Enabled: r.FormValue("enabled") == "true",
+		MaxAgeHours:         maxAgeHours,
+		MaxExecutionHistory: maxHistory,
+		RunOnStartup:        r.FormValue("run_on_startup") == "true",
+		RunMissedOnStartup:  r.FormValue("run_missed_on_startup") == "true",
+		AllowEmptySource:    r.FormValue("allow_empty_source") == "true",
+		MinSourcePercent:    minSourcePercent,
+		DependsOn:           parseList(r, "depends_on"),
+		Notifications:       parseTaskNotifications(r),
+		Heartbeat:           parseHeartbeat(r),
+		FailureRetry:        parseFailureRetry(r),
+		Enabled:             r.FormValue("enabled") == "true",
+	}
+
+	// Validate source path unless forced (e.g. a volume that will be mounted later)
+	if r.FormValue("force") != "true" {
+		if err := s.validateSourcePath(task.SourcePath); err != nil {
+			s.validationError(w, "source_path", err.Error())
+			return
+		}
 	}
 
 	// Update task
 	if err := s.config.UpdateTask(id, &task); err != nil {
-		s.error(w, "INTERNAL_ERROR", err.Error(), http.StatusInternalServerError)
+		s.taskConfigError(w, err)
 		return
 	}
 
@@ -209,7 +459,7 @@ func (s *Server) deleteTask(w http.ResponseWriter, r *http.Request) {
 
 	// Check if task is running
 	if s.executor.IsRunning(id) {
-		s.error(w, "TASK_RUNNING", "Cannot delete a running task", http.StatusConflict)
+		s.errorWithDetails(w, "TASK_RUNNING", "Cannot delete a running task", map[string]interface{}{"task_id": id}, http.StatusConflict)
 		return
 	}
 
@@ -225,7 +475,12 @@ func (s *Server) deleteTask(w http.ResponseWriter, r *http.Request) {
 	s.success(w, map[string]string{"message": "Task deleted successfully"})
 }
 
+// maxLabelLength bounds the label a run can be started with
+const maxLabelLength = 200
+
 // executeTask handles POST /api/v1/tasks/{id}/execute?dry_run=true&backend_ids=id1,id2
+// With an Idempotency-Key header, repeating the request within 24 hours returns
+// the execution the key started instead of starting another run.
 func (s *Server) executeTask(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["id"]
@@ -234,36 +489,404 @@ func (s *Server) executeTask(w http.ResponseWriter, r *http.Request) {
 	dryRun := r.URL.Query().Get("dry_run") == "true"
 
 	if dryRun {
-		// Parse optional backend_ids parameter
-		var backendIDs []string
-		if backendIDsParam := r.URL.Query().Get("backend_ids"); backendIDsParam != "" {
-			backendIDs = strings.Split(backendIDsParam, ",")
-			// Trim spaces from each ID
-			for i, id := range backendIDs {
-				backendIDs[i] = strings.TrimSpace(id)
-			}
+		s.dryRunTask(w, r)
+		return
+	}
+
+	label := strings.TrimSpace(r.FormValue("label"))
+	if len(label) > maxLabelLength {
+		s.validationError(w, "label", fmt.Sprintf("label must be at most %d characters", maxLabelLength))
+		return
+	}
+
+	start := func() (string, error) {
+		return s.executor.ExecuteRequest(id, requestID(r), label)
+	}
+
+	// Without an Idempotency-Key every request starts a run
+	var executionID string
+	var replayed bool
+	var err error
+	switch key := r.Header.Get(idempotencyKeyHeader); {
+	case key == "":
+		executionID, err = start()
+	case !validRequestID(key):
+		s.validationError(w, "Idempotency-Key", fmt.Sprintf("Idempotency-Key must be at most %d letters, digits and - _ . :", maxRequestIDLength))
+		return
+	default:
+		executionID, replayed, err = s.executeOnce(key, id, start)
+	}
+	if errors.Is(err, errIdempotencyKeyReused) {
+		s.errorWithDetails(w, "IDEMPOTENCY_KEY_REUSED", err.Error(), map[string]interface{}{"task_id": id}, http.StatusUnprocessableEntity)
+		return
+	}
+	if err != nil {
+		s.errorWithDetails(w, "EXECUTION_ERROR", err.Error(), map[string]interface{}{"task_id": id}, http.StatusInternalServerError)
+		return
+	}
+
+	data := map[string]interface{}{
+		"execution_id": executionID,
+		"status":       "running",
+	}
+	// A repeated key reports the execution it started, as it is now
+	if replayed {
+		data["replayed"] = true
+		if execution, err := s.db.GetExecution(executionID); err == nil {
+			data["status"] = execution.Status
 		}
+	}
+	s.success(w, data)
+}
+
+// dryRunTask handles GET/POST /api/v1/tasks/{id}/dry-run?backend_ids=id1,id2
+func (s *Server) dryRunTask(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	if err := r.ParseForm(); err != nil {
+		s.error(w, "VALIDATION_ERROR", "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := s.config.GetTask(id); err != nil {
+		s.error(w, "NOT_FOUND", "Task not found", http.StatusNotFound)
+		return
+	}
+
+	result, err := s.executor.ExecuteDryRun(id, parseBackendIDs(r))
+	if err != nil {
+		s.errorWithDetails(w, "DRY_RUN_ERROR", err.Error(), map[string]interface{}{"task_id": id}, http.StatusInternalServerError)
+		return
+	}
+
+	s.success(w, result)
+}
+
+// isTaskStale reports whether a task has gone longer than its MaxAgeHours without a successful run.
+// A task that has never succeeded is measured from its creation time.
+func isTaskStale(task *models.Task, lastSuccess *time.Time, now time.Time) bool {
+	if task.MaxAgeHours <= 0 {
+		return false
+	}
+
+	reference := task.CreatedAt
+	if lastSuccess != nil {
+		reference = *lastSuccess
+	}
+
+	return now.Sub(reference) > time.Duration(task.MaxAgeHours)*time.Hour
+}
+
+// validateSourcePath checks that a task source path resolves to a readable directory
+// or file. Symlinks are followed, so a symlink to a directory is accepted.
+func (s *Server) validateSourcePath(sourcePath string) error {
+	resolved := s.config.ResolvePath(sourcePath)
+
+	info, err := os.Stat(resolved)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("source path does not exist: %s", resolved)
+		}
+		return fmt.Errorf("source path not accessible: %s: %v", resolved, err)
+	}
+
+	if !info.IsDir() && !info.Mode().IsRegular() {
+		return fmt.Errorf("source path is not a directory or regular file: %s", resolved)
+	}
 
-		// Execute dry run
-		result, err := s.executor.ExecuteDryRun(id, backendIDs)
+	source, err := os.Open(resolved)
+	if err != nil {
+		return fmt.Errorf("source path is not readable: %s: %v", resolved, err)
+	}
+	if err := source.Close(); err != nil {
+		log.Printf("Error closing source path: %v", err)
+	}
+
+	return nil
+}
+
+// retentionPreview handles GET /api/v1/tasks/{id}/retention-preview
+func (s *Server) retentionPreview(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	if _, err := s.config.GetTask(id); err != nil {
+		s.error(w, "NOT_FOUND", "Task not found", http.StatusNotFound)
+		return
+	}
+
+	preview, err := s.executor.PreviewRetention(r.Context(), id)
+	if err != nil {
+		s.errorWithDetails(w, "RETENTION_ERROR", err.Error(), map[string]interface{}{"task_id": id}, http.StatusInternalServerError)
+		return
+	}
+
+	s.success(w, preview)
+}
+
+// taskTrends handles GET /api/v1/tasks/{id}/trends
+// Query params: ?window=30d (default) and ?bucket=1d; both accept d and w suffixes or Go durations.
+// The bucket defaults to one hour for windows up to two days and one day otherwise.
+func (s *Server) taskTrends(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	if _, err := s.config.GetTask(id); err != nil {
+		s.error(w, "NOT_FOUND", "Task not found", http.StatusNotFound)
+		return
+	}
+
+	window := 30 * 24 * time.Hour
+	if windowStr := r.URL.Query().Get("window"); windowStr != "" {
+		parsed, err := parseWindow(windowStr)
 		if err != nil {
-			s.error(w, "DRY_RUN_ERROR", err.Error(), http.StatusInternalServerError)
+			s.validationError(w, "window", fmt.Sprintf("Invalid window: %v", err))
 			return
 		}
-		s.success(w, result)
-	} else {
-		// Normal execution
-		executionID, err := s.executor.Execute(id)
+		window = parsed
+	}
+
+	bucket := 24 * time.Hour
+	if window <= 48*time.Hour {
+		bucket = time.Hour
+	}
+	if bucketStr := r.URL.Query().Get("bucket"); bucketStr != "" {
+		parsed, err := parseWindow(bucketStr)
 		if err != nil {
-			s.error(w, "EXECUTION_ERROR", err.Error(), http.StatusInternalServerError)
+			s.validationError(w, "bucket", fmt.Sprintf("Invalid bucket: %v", err))
 			return
 		}
+		bucket = parsed
+	}
 
-		s.success(w, map[string]interface{}{
-			"execution_id": executionID,
-			"status":       "running",
-		})
+	end := time.Now()
+	trends, err := s.db.GetTaskTrends(id, end.Add(-window), end, bucket)
+	if err != nil {
+		s.error(w, "VALIDATION_ERROR", err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.success(w, trends)
+}
+
+// parseWindow parses a positive duration, accepting day (30d) and week (4w)
+// suffixes in addition to Go duration syntax (12h)
+func parseWindow(value string) (time.Duration, error) {
+	var d time.Duration
+	var err error
+	switch {
+	case strings.HasSuffix(value, "d"), strings.HasSuffix(value, "w"):
+		unit := 24 * time.Hour
+		if strings.HasSuffix(value, "w") {
+			unit *= 7
+		}
+		var n int
+		n, err = strconv.Atoi(value[:len(value)-1])
+		d = time.Duration(n) * unit
+	default:
+		d, err = time.ParseDuration(value)
+	}
+	if err != nil {
+		return 0, err
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("must be positive")
+	}
+	return d, nil
+}
+
+// parseBackendIDs extracts backend IDs from the query string or form body.
+// Accepts both repeated values (backend_ids=a&backend_ids=b) and a
+// comma-separated list (backend_ids=a,b). Form must already be parsed.
+func parseBackendIDs(r *http.Request) []string {
+	return parseList(r, "backend_ids")
+}
+
+// parseList reads a form field given as repeated values, a comma-separated
+// list or both. Form must already be parsed.
+func parseList(r *http.Request, key string) []string {
+	var items []string
+	for _, value := range r.Form[key] {
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+	}
+	return items
+}
+
+// parseHeartbeat reads the heartbeat URLs from the task form. Returns nil when
+// none are set, so the task uses the global heartbeat.
+func parseHeartbeat(r *http.Request) *models.Heartbeat {
+	heartbeat := &models.Heartbeat{
+		StartURL:   strings.TrimSpace(r.FormValue("heartbeat_start_url")),
+		SuccessURL: strings.TrimSpace(r.FormValue("heartbeat_success_url")),
+		FailureURL: strings.TrimSpace(r.FormValue("heartbeat_failure_url")),
+	}
+	if *heartbeat == (models.Heartbeat{}) {
+		return nil
+	}
+	return heartbeat
+}
+
+// parseFailureRetry reads the re-run settings for failed scheduled runs from
+// the task form. Returns nil when no attempts are set.
+func parseFailureRetry(r *http.Request) *models.FailureRetry {
+	attempts, err := strconv.Atoi(r.FormValue("failure_retry_attempts"))
+	if err != nil || attempts <= 0 {
+		return nil
+	}
+	delayMinutes, err := strconv.Atoi(r.FormValue("failure_retry_delay_minutes"))
+	if err != nil || delayMinutes <= 0 {
+		delayMinutes = 10
+	}
+	return &models.FailureRetry{Attempts: attempts, DelayMinutes: delayMinutes}
+}
+
+// parseRedaction reads the task's sensitive file patterns from the task form.
+// Returns nil when none are set, so only the settings' redaction applies.
+func parseRedaction(r *http.Request) *models.Redaction {
+	patterns := parseList(r, "redaction_patterns")
+	if len(patterns) == 0 {
+		return nil
+	}
+	return &models.Redaction{Patterns: patterns, Mode: r.FormValue("redaction_mode")}
+}
+
+// parseTaskNotifications reads the notification overrides from the task form.
+// Returns nil when none are set, so the task follows the global channels.
+func parseTaskNotifications(r *http.Request) *models.TaskNotifications {
+	notifications := &models.TaskNotifications{
+		Silent:   r.FormValue("notify_silent") == "true",
+		Channels: parseList(r, "notify_channels"),
+		Muted:    parseList(r, "mute_channels"),
+		Events:   parseList(r, "notify_events"),
+	}
+	if !notifications.Silent && len(notifications.Channels) == 0 && len(notifications.Muted) == 0 && len(notifications.Events) == 0 {
+		return nil
 	}
+	return notifications
+}
+
+// formatForBackupMode maps the backup mode chosen in the task form to an archive format
+func formatForBackupMode(backupMode string) string {
+	switch backupMode {
+	case "sync":
+		return "sync"
+	case archive.FormatChunked:
+		return archive.FormatChunked
+	case archive.FormatZip:
+		return archive.FormatZip
+	default:
+		return "tar.gz"
+	}
+}
+
+// previewTaskName handles GET /api/v1/tasks/{id}/preview-name
+// The name_pattern and use_timestamp query parameters override the saved options.
+func (s *Server) previewTaskName(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	task, err := s.config.GetTask(id)
+	if err != nil {
+		s.error(w, "NOT_FOUND", "Task not found", http.StatusNotFound)
+		return
+	}
+
+	query := r.URL.Query()
+	if query.Has("name_pattern") {
+		task.ArchiveOptions.NamePattern = query.Get("name_pattern")
+	}
+	if query.Has("use_timestamp") {
+		task.ArchiveOptions.UseTimestamp = query.Get("use_timestamp") == "true"
+	}
+
+	s.previewArchiveName(w, task)
+}
+
+// previewUnsavedTaskName handles POST /api/v1/tasks/preview-name
+// Previews the archive name for the task form fields without saving anything.
+func (s *Server) previewUnsavedTaskName(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		s.error(w, "VALIDATION_ERROR", "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	task := &models.Task{
+		Name: r.FormValue("name"),
+		ArchiveOptions: models.ArchiveOptions{
+			Format:       formatForBackupMode(r.FormValue("backup_mode")),
+			NamePattern:  r.FormValue("name_pattern"),
+			UseTimestamp: r.FormValue("use_timestamp") == "true",
+			KeepLatest:   r.FormValue("keep_latest") == "true",
+		},
+	}
+	if task.Name == "" {
+		task.Name = "task"
+	}
+
+	s.previewArchiveName(w, task)
+}
+
+// previewArchiveName responds with the archive name a task would produce if it ran now
+func (s *Server) previewArchiveName(w http.ResponseWriter, task *models.Task) {
+	if task.ArchiveOptions.Format == "sync" {
+		s.error(w, "VALIDATION_ERROR", "Sync tasks upload files individually and don't create archives", http.StatusBadRequest)
+		return
+	}
+
+	builder := archive.NewBuilder("", "", task.ArchiveOptions, nil)
+	name, err := builder.GenerateFilename(task.Name)
+	if err != nil {
+		s.error(w, "VALIDATION_ERROR", err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result := map[string]interface{}{
+		"archive_name": name,
+	}
+	if task.ArchiveOptions.Format == archive.FormatChunked {
+		result["manifest_name"] = name + archive.ManifestSuffix
+	}
+	if task.ArchiveOptions.KeepLatest && task.ArchiveOptions.UseTimestamp {
+		result["latest_name"] = archive.LatestFilename(task.Name, name)
+	}
+
+	s.success(w, result)
+}
+
+// cloneTask handles POST /api/v1/tasks/{id}/clone
+// The copy gets a new ID and starts disabled so it can be adjusted before it runs.
+// An optional name form field overrides the default "<name> (copy)".
+func (s *Server) cloneTask(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	task, err := s.config.GetTask(id)
+	if err != nil {
+		s.error(w, "NOT_FOUND", "Task not found", http.StatusNotFound)
+		return
+	}
+
+	task.ID = ""
+	if name := r.FormValue("name"); name != "" {
+		task.Name = name
+	} else {
+		task.Name += " (copy)"
+	}
+	task.Enabled = false
+	task.LastRun = nil
+	task.NextRun = nil
+
+	if err := s.config.AddTask(task); err != nil {
+		s.taskConfigError(w, err)
+		return
+	}
+
+	s.success(w, task)
 }
 
 // enableTask handles POST /api/v1/tasks/{id}/enable
@@ -279,7 +902,7 @@ func (s *Server) enableTask(w http.ResponseWriter, r *http.Request) {
 
 	task.Enabled = true
 	if err := s.config.UpdateTask(id, task); err != nil {
-		s.error(w, "INTERNAL_ERROR", err.Error(), http.StatusInternalServerError)
+		s.taskConfigError(w, err)
 		return
 	}
 
@@ -309,7 +932,7 @@ func (s *Server) disableTask(w http.ResponseWriter, r *http.Request) {
 
 	task.Enabled = false
 	if err := s.config.UpdateTask(id, task); err != nil {
-		s.error(w, "INTERNAL_ERROR", err.Error(), http.StatusInternalServerError)
+		s.taskConfigError(w, err)
 		return
 	}
 
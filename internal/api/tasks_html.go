@@ -3,6 +3,7 @@ package api
 import (
 	"log"
 	"net/http"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/nsilverman/archivist/internal/models"
@@ -14,8 +15,9 @@ func (s *Server) listTasksHTML(w http.ResponseWriter, r *http.Request) {
 
 	// Enrich with stats
 	type TaskWithStats struct {
-		Task  interface{}
-		Stats *models.TaskStats
+		Task             interface{}
+		Stats            *models.TaskStats
+		SourceAccessible bool
 	}
 
 	var enrichedTasks []TaskWithStats
@@ -26,12 +28,14 @@ func (s *Server) listTasksHTML(w http.ResponseWriter, r *http.Request) {
 			// If there's an error getting stats, create an empty stats object
 			stats = &models.TaskStats{}
 		} else {
+			stats.Stale = isTaskStale(&task, stats.LastSuccessfulRun, time.Now())
 			log.Printf("Stats for task %s: Total=%d, Success=%d, Failure=%d",
 				task.ID, stats.TotalExecutions, stats.SuccessCount, stats.FailureCount)
 		}
 		enrichedTasks = append(enrichedTasks, TaskWithStats{
-			Task:  task,
-			Stats: stats,
+			Task:             task,
+			Stats:            stats,
+			SourceAccessible: s.sourceAccessible(task.SourcePath),
 		})
 	}
 
@@ -43,7 +47,9 @@ func (s *Server) createTaskFormHTML(w http.ResponseWriter, r *http.Request) {
 	backends := s.config.GetBackends()
 
 	data := map[string]interface{}{
-		"Backends": backends,
+		"Backends":             backends,
+		"NotificationChannels": notificationChannelOptions(s.config.GetSettings().NotificationChannels, nil),
+		"Tasks":                s.config.GetTasks(),
 	}
 
 	s.htmlResponse(w, "task_form_create.html", data)
@@ -62,21 +68,37 @@ func (s *Server) editTaskFormHTML(w http.ResponseWriter, r *http.Request) {
 
 	backends := s.config.GetBackends()
 
+	notifyEvents := make(map[string]bool)
+	if task.Notifications != nil {
+		for _, event := range task.Notifications.Events {
+			notifyEvents[event] = true
+		}
+	}
+
 	data := map[string]interface{}{
-		"Task":     task,
-		"Backends": backends,
+		"Task":                 task,
+		"Backends":             backends,
+		"NotificationChannels": notificationChannelOptions(s.config.GetSettings().NotificationChannels, task.Notifications),
+		"NotifyEvents":         notifyEvents,
+		"Tasks":                otherTasks(s.config.GetTasks(), task.ID),
 	}
 
 	s.htmlResponse(w, "task_form_edit.html", data)
 }
 
-// dryRunTaskHTML handles POST /api/v1/tasks/{id}/dry-run
+// dryRunTaskHTML handles POST /api/v1/tasks/{id}/dry-run/html
+// Accepts the same optional backend_ids as the JSON dry-run endpoint.
 func (s *Server) dryRunTaskHTML(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["id"]
 
-	// Execute dry run using the executor (uses nil for backendIDs to use all task backends)
-	result, err := s.executor.ExecuteDryRun(id, nil)
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Execute dry run using the executor (no backend_ids means all task backends)
+	result, err := s.executor.ExecuteDryRun(id, parseBackendIDs(r))
 	if err != nil {
 		http.Error(w, "Dry run failed: "+err.Error(), http.StatusInternalServerError)
 		return
@@ -84,3 +106,40 @@ func (s *Server) dryRunTaskHTML(w http.ResponseWriter, r *http.Request) {
 
 	s.htmlResponse(w, "task_dry_run.html", result)
 }
+
+// otherTasks returns tasks without the one with the given ID, which can't depend on itself
+func otherTasks(tasks []models.Task, id string) []models.Task {
+	others := make([]models.Task, 0, len(tasks))
+	for _, task := range tasks {
+		if task.ID != id {
+			others = append(others, task)
+		}
+	}
+	return others
+}
+
+// notificationChannelOption is a notification channel as shown in the task form
+type notificationChannelOption struct {
+	Name     string
+	TaskOnly bool
+	Added    bool // listed in the task's channels
+	Muted    bool // listed in the task's muted channels
+}
+
+// notificationChannelOptions lists the global channels with the task's overrides applied
+func notificationChannelOptions(channels []models.NotificationChannel, overrides *models.TaskNotifications) []notificationChannelOption {
+	options := make([]notificationChannelOption, 0, len(channels))
+	for _, channel := range channels {
+		option := notificationChannelOption{Name: channel.Name, TaskOnly: channel.TaskOnly}
+		if overrides != nil {
+			for _, name := range overrides.Channels {
+				option.Added = option.Added || name == channel.Name
+			}
+			for _, name := range overrides.Muted {
+				option.Muted = option.Muted || name == channel.Name
+			}
+		}
+		options = append(options, option)
+	}
+	return options
+}
@@ -0,0 +1,230 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/nsilverman/archivist/internal/models"
+)
+
+const (
+	// wsSendBuffer is how many events may queue for a client before new
+	// events are dropped or the client is disconnected
+	wsSendBuffer = 256
+
+	// wsWriteTimeout bounds a single write to a client
+	wsWriteTimeout = 10 * time.Second
+)
+
+// wsSubscription limits the events a client receives to one task or execution
+type wsSubscription struct {
+	TaskID      string `json:"task_id,omitempty"`
+	ExecutionID string `json:"execution_id,omitempty"`
+}
+
+// wsMessage is a message sent by a client. {"subscribe": {"task_id": "..."}}
+// filters events; {"subscribe": {}} restores the unfiltered default.
+type wsMessage struct {
+	Subscribe *wsSubscription `json:"subscribe"`
+}
+
+// wsClient is a WebSocket connection with its own outgoing queue. A dedicated
+// writer goroutine drains the queue, so a slow client never blocks broadcasts.
+type wsClient struct {
+	conn      *websocket.Conn
+	send      chan models.ProgressEvent
+	done      chan struct{}
+	closeOnce sync.Once
+
+	mu           sync.Mutex
+	subscription wsSubscription
+	executions   map[string]bool // executions of the subscribed task seen so far
+}
+
+func newWSClient(conn *websocket.Conn) *wsClient {
+	return &wsClient{
+		conn: conn,
+		send: make(chan models.ProgressEvent, wsSendBuffer),
+		done: make(chan struct{}),
+	}
+}
+
+// subscribe replaces the client's filter. running seeds the executions already
+// in progress so a task subscription also matches their progress events.
+func (c *wsClient) subscribe(subscription wsSubscription, running []models.RunningExecution) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.subscription = subscription
+	c.executions = make(map[string]bool)
+	for _, execution := range running {
+		if execution.TaskID == subscription.TaskID {
+			c.executions[execution.ExecutionID] = true
+		}
+	}
+}
+
+// wants reports whether an event for the given execution and task passes the
+// client's filter. Progress events carry only an execution ID, so executions
+// of a subscribed task are remembered from the events that name both.
+func (c *wsClient) wants(executionID, taskID string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch {
+	case c.subscription.ExecutionID != "":
+		return executionID == c.subscription.ExecutionID
+	case c.subscription.TaskID != "":
+		if taskID == c.subscription.TaskID {
+			if executionID != "" {
+				c.executions[executionID] = true
+			}
+			return true
+		}
+		return executionID != "" && c.executions[executionID]
+	default:
+		return true
+	}
+}
+
+// close stops the writer and closes the connection; safe to call more than once
+func (c *wsClient) close() {
+	c.closeOnce.Do(func() {
+		close(c.done)
+		if err := c.conn.Close(); err != nil {
+			log.Printf("Error closing WebSocket connection: %v", err)
+		}
+	})
+}
+
+// writeLoop sends queued events until the client is closed or a write fails.
+// It is the only writer on the connection, as gorilla/websocket requires.
+func (c *wsClient) writeLoop(onError func()) {
+	for {
+		select {
+		case event := <-c.send:
+			if err := c.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout)); err != nil {
+				onError()
+				return
+			}
+			if err := c.conn.WriteJSON(event); err != nil {
+				onError()
+				return
+			}
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// BroadcastProgress implements executor.ProgressBroadcaster.
+// Events are queued per client without blocking. When a client's queue is full,
+// progress events are dropped for it since a later one supersedes them; any other
+// event means the client has fallen too far behind, so it is disconnected and
+// can reconnect.
+func (s *Server) BroadcastProgress(event models.ProgressEvent) {
+	droppable := strings.HasSuffix(event.Type, "_progress")
+	executionID, taskID := eventScope(event)
+	var overflowed []*wsClient
+
+	s.wsMu.RLock()
+	for client := range s.wsClients {
+		if !client.wants(executionID, taskID) {
+			continue
+		}
+		select {
+		case client.send <- event:
+		default:
+			if !droppable {
+				overflowed = append(overflowed, client)
+			}
+		}
+	}
+	s.wsMu.RUnlock()
+
+	for _, client := range overflowed {
+		log.Printf("Disconnecting slow WebSocket client %s", client.conn.RemoteAddr())
+		s.removeWSClient(client)
+	}
+}
+
+// eventScope returns the execution and task an event belongs to, if any
+func eventScope(event models.ProgressEvent) (executionID, taskID string) {
+	switch data := event.Data.(type) {
+	case models.ArchiveProgress:
+		return data.ExecutionID, ""
+	case models.UploadProgress:
+		return data.ExecutionID, ""
+	case map[string]interface{}:
+		executionID, _ = data["execution_id"].(string)
+		taskID, _ = data["task_id"].(string)
+		return executionID, taskID
+	default:
+		return "", ""
+	}
+}
+
+// removeWSClient unregisters and closes a client
+func (s *Server) removeWSClient(client *wsClient) {
+	s.wsMu.Lock()
+	delete(s.wsClients, client)
+	s.wsMu.Unlock()
+	client.close()
+}
+
+// handleWebSocket handles WebSocket connections
+func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	// The server's read and write timeouts are meant for ordinary requests and
+	// would cut off a progress stream. The upgrader clears them on hijack too,
+	// but don't rely on it; each write gets its own deadline in writeLoop.
+	controller := http.NewResponseController(w)
+	if err := controller.SetReadDeadline(time.Time{}); err != nil {
+		log.Printf("Error clearing WebSocket read deadline: %v", err)
+	}
+	if err := controller.SetWriteDeadline(time.Time{}); err != nil {
+		log.Printf("Error clearing WebSocket write deadline: %v", err)
+	}
+
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+
+	client := newWSClient(conn)
+
+	// Queue a snapshot of running executions first so the client can render
+	// in-flight runs at once. Holding the lock while registering means no
+	// broadcast can slip in between the snapshot and the client's first event.
+	s.wsMu.Lock()
+	client.send <- models.ProgressEvent{
+		Type: "snapshot",
+		Data: map[string]interface{}{
+			"running": s.executor.GetRunningExecutions(),
+		},
+	}
+	s.wsClients[client] = true
+	s.wsMu.Unlock()
+
+	defer s.removeWSClient(client)
+
+	go client.writeLoop(func() { s.removeWSClient(client) })
+
+	// Keep connection alive and handle subscription messages
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			break
+		}
+
+		var msg wsMessage
+		if err := json.Unmarshal(data, &msg); err != nil || msg.Subscribe == nil {
+			continue
+		}
+		client.subscribe(*msg.Subscribe, s.executor.GetRunningExecutions())
+	}
+}
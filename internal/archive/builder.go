@@ -3,29 +3,53 @@ package archive
 import (
 	"archive/tar"
 	"compress/gzip"
-	"crypto/sha256"
+	"context"
 	"fmt"
 	"io"
 	"log"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/klauspost/compress/zstd"
+	"github.com/nsilverman/archivist/internal/ignore"
 	"github.com/nsilverman/archivist/internal/models"
 )
 
-// ProgressCallback is called during archive creation to report progress
+// ProgressCallback is called after each file is archived with the bytes written
+// so far. total is 0 when the source wasn't sized, see SinglePassFiles.
 type ProgressCallback func(current, total int64, currentFile string)
 
 // Builder creates compressed archives from source directories
 type Builder struct {
-	SourcePath string
-	OutputPath string
-	Options    models.ArchiveOptions
-	Progress   ProgressCallback
+	SourcePath    string
+	OutputPath    string
+	Options       models.ArchiveOptions
+	Progress      ProgressCallback
+	FileMode      os.FileMode // permissions for the created archive; 0 keeps the umask default
+	HashAlgorithm string      // algorithm for the archive hash; empty uses SHA256
+	SourceFiles   int64       // files in the source if already counted; 0 if unknown
+	ScanWorkers   int         // directories read at once when sizing the source; 0 or 1 is serial
+	Redactor      *Redactor   // sensitive files to leave out or store empty; nil redacts nothing
+	MinFreeSpace  int64       // bytes to keep free in the output directory while building; 0 doesn't check
+
+	contents  models.ArchiveContents
+	split     *splitWriter
+	codec     string
+	totalSize int64 // from prepare, valid once codec is set
 }
 
+// MaxContentsFiles caps how many file entries are kept in an archive's contents listing
+const MaxContentsFiles = 50000
+
+// SinglePassFiles is the source file count above which the builder skips
+// walking the source to size it and archives in a single pass. Progress is
+// then reported without a byte total; callers that counted the files can
+// report it by file instead.
+const SinglePassFiles = 100000
+
 // NewBuilder creates a new archive builder
 func NewBuilder(sourcePath, outputDir string, options models.ArchiveOptions, progress ProgressCallback) *Builder {
 	return &Builder{
@@ -38,6 +62,12 @@ func NewBuilder(sourcePath, outputDir string, options models.ArchiveOptions, pro
 
 // Build creates the archive and returns the path and hash
 func (b *Builder) Build(taskName string) (archivePath string, hash string, size int64, err error) {
+	// The codec is picked first, since it decides the extension
+	totalSize, err := b.prepare()
+	if err != nil {
+		return "", "", 0, err
+	}
+
 	// Generate filename from pattern
 	filename, err := b.GenerateFilename(taskName)
 	if err != nil {
@@ -51,36 +81,239 @@ func (b *Builder) Build(taskName string) (archivePath string, hash string, size
 		return "", "", 0, fmt.Errorf("failed to create output directory: %w", err)
 	}
 
-	// Calculate total size for progress reporting
-	totalSize, fileCount, err := b.calculateSize(b.SourcePath)
-	if err != nil {
-		return "", "", 0, fmt.Errorf("failed to calculate source size: %w", err)
-	}
-
 	// Create archive based on format
 	switch b.Options.Format {
-	case "tar.gz", "tar":
-		hash, size, err = b.createTarGz(archivePath, totalSize, fileCount)
+	case "tar.gz", "tar", FormatChunked:
+		hash, size, err = b.createTarGz(archivePath, totalSize)
+	case FormatZip:
+		hash, size, err = b.createZip(archivePath, totalSize)
 	default:
 		return "", "", 0, fmt.Errorf("unsupported archive format: %s", b.Options.Format)
 	}
 
 	if err != nil {
+		b.removeOutput(archivePath)
 		return "", "", 0, err
 	}
 
 	return archivePath, hash, size, nil
 }
 
+// removeOutput deletes what a failed Build wrote, whether a file or parts
+func (b *Builder) removeOutput(archivePath string) {
+	paths := []string{archivePath}
+	if b.split != nil {
+		paths = b.split.parts
+	}
+	for _, path := range paths {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			log.Printf("Error removing incomplete archive: %v", err)
+		}
+	}
+}
+
+// Stream writes the archive to w instead of a file and returns its hash and size.
+// The caller names the archive with GenerateFilename, after Prepare so the name
+// follows the codec. Chunked and split archives need a file and can't be streamed.
+func (b *Builder) Stream(w io.Writer) (hash string, size int64, err error) {
+	if b.Options.Format == FormatChunked || b.Options.SplitSizeBytes > 0 {
+		return "", 0, fmt.Errorf("chunked and split archives can't be streamed")
+	}
+
+	totalSize, err := b.prepare()
+	if err != nil {
+		return "", 0, err
+	}
+
+	switch b.Options.Format {
+	case "tar.gz", "tar":
+		return b.writeTar(w, totalSize)
+	case FormatZip:
+		return b.writeZip(w, totalSize)
+	default:
+		return "", 0, fmt.Errorf("unsupported archive format: %s", b.Options.Format)
+	}
+}
+
+// Prepare sizes the source and picks the codec ahead of Build or Stream, which
+// otherwise do it themselves, so GenerateFilename can name the archive after the codec
+func (b *Builder) Prepare() error {
+	_, err := b.prepare()
+	return err
+}
+
+// prepare sizes the source for progress reporting and picks the codec, once.
+// Sources of more than SinglePassFiles files aren't sized, and totalSize is 0.
+func (b *Builder) prepare() (totalSize int64, err error) {
+	if b.codec != "" {
+		return b.totalSize, nil
+	}
+	totalSize, err = b.sizeAndPickCodec()
+	if err != nil {
+		return 0, err
+	}
+	b.totalSize = totalSize
+	return totalSize, nil
+}
+
+// sizeAndPickCodec does the work of prepare
+func (b *Builder) sizeAndPickCodec() (totalSize int64, err error) {
+	if b.SourceFiles > SinglePassFiles {
+		log.Printf("Source has %d files, archiving in a single pass without sizing it first", b.SourceFiles)
+		b.codec, err = b.codecFor(0, int(b.SourceFiles), -1)
+		return 0, err
+	}
+
+	totalSize, fileCount, incompressibleSize, err := b.calculateSize(b.SourcePath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to calculate source size: %w", err)
+	}
+
+	b.codec, err = b.codecFor(totalSize, fileCount, incompressibleSize)
+	if err != nil {
+		return 0, err
+	}
+	return totalSize, nil
+}
+
+// codecFor picks the codec for the format and compression options.
+// incompressibleSize is -1 when the source wasn't sized.
+func (b *Builder) codecFor(totalSize int64, fileCount int, incompressibleSize int64) (string, error) {
+	if err := ValidateCompression(b.Options.Compression); err != nil {
+		return "", err
+	}
+
+	// Chunked archives are never compressed, since compression defeats deduplication
+	switch {
+	case b.Options.Format == FormatChunked:
+		return CodecNone, nil
+	// Zip compresses each entry on its own, so "auto" needs no sample
+	case b.Options.Format == FormatZip:
+		if b.Options.Compression == "none" {
+			return CodecNone, nil
+		}
+		return CodecDeflate, nil
+	case b.Options.Compression == "gzip" || b.Options.Compression == "":
+		return CodecGzip, nil
+	case b.Options.Compression == "zstd":
+		return CodecZstd, nil
+	case b.Options.Compression == "auto":
+		return b.autoCodec(totalSize, fileCount, incompressibleSize)
+	default: // "none"
+		return CodecNone, nil
+	}
+}
+
+// autoCodec resolves compression "auto". Sources that are mostly
+// already-compressed formats are stored as is; otherwise a sample of the
+// content decides between no compression, zstd and best-compression zstd.
+// Without a size breakdown the sample alone decides.
+func (b *Builder) autoCodec(totalSize int64, fileCount int, incompressibleSize int64) (string, error) {
+	if incompressibleSize >= 0 && !ShouldCompress(incompressibleSize, totalSize) {
+		log.Printf("Source is mostly already-compressed data, storing archive without compression")
+		return CodecNone, nil
+	}
+
+	ratio, sampled, err := SampleRatio(b.SourcePath, fileCount)
+	if err != nil {
+		return "", fmt.Errorf("failed to sample source: %w", err)
+	}
+	if sampled == 0 {
+		return CodecZstd, nil
+	}
+
+	codec := ChooseCodec(ratio)
+	log.Printf("Sampled %d bytes of source (compression ratio %.2f), using codec %s", sampled, ratio, codec)
+	return codec, nil
+}
+
+// Codec returns the codec the last Build or Stream wrote the archive with
+func (b *Builder) Codec() string {
+	return b.codec
+}
+
+// Contents returns the files written by the last Build
+func (b *Builder) Contents() models.ArchiveContents {
+	return b.contents
+}
+
+// Parts returns the part files written by the last Build, in order, or nil
+// if the archive wasn't split. The archive path Build returns is then the
+// name the parts share and doesn't exist itself.
+func (b *Builder) Parts() []string {
+	if b.split == nil {
+		return nil
+	}
+	return b.split.parts
+}
+
+// LatestFilename returns the name of the alias kept pointing at a task's newest
+// archive. It has the same extension as archiveName, the archive it points at.
+func LatestFilename(taskName, archiveName string) string {
+	return sanitizeFilename(taskName) + "_latest" + ArchiveExtension(archiveName)
+}
+
+// ArchiveExtension returns the archive extension of a name: .tar.gz, .tar.zst,
+// .tar or .zip, or "" for none of them
+func ArchiveExtension(name string) string {
+	for _, extension := range []string{".tar.gz", ".tar.zst", ".tar", ".zip"} {
+		if strings.HasSuffix(name, extension) {
+			return extension
+		}
+	}
+	return ""
+}
+
+// archiveExtension returns the extension the default name patterns give an
+// archive format written with codec
+func archiveExtension(format, codec string) string {
+	switch {
+	case format == FormatChunked:
+		return ".tar"
+	case format == FormatZip:
+		return ".zip"
+	case codec == CodecNone:
+		return ".tar"
+	case codec == CodecZstd || codec == CodecZstdBest:
+		return ".tar.zst"
+	default:
+		return ".tar.gz"
+	}
+}
+
+// plannedCodec returns the codec the archive is named after: the one picked
+// by prepare, or before that the one the options ask for. "auto" is named as
+// zstd until the source has been sampled.
+func (b *Builder) plannedCodec() string {
+	if b.codec != "" {
+		return b.codec
+	}
+	switch b.Options.Compression {
+	case "none":
+		return CodecNone
+	case "zstd", "auto":
+		return CodecZstd
+	default:
+		return CodecGzip
+	}
+}
+
+// TaskPrefix returns the prefix the default name patterns give a task's archives
+func TaskPrefix(taskName string) string {
+	return sanitizeFilename(taskName) + "_"
+}
+
 // GenerateFilename creates the archive filename from the pattern
 func (b *Builder) GenerateFilename(taskName string) (string, error) {
+	extension := archiveExtension(b.Options.Format, b.plannedCodec())
+
 	pattern := b.Options.NamePattern
 	if pattern == "" {
 		// Default pattern
 		if b.Options.UseTimestamp {
-			pattern = "{task}_{timestamp}.tar.gz"
+			pattern = "{task}_{timestamp}" + extension
 		} else {
-			pattern = "{task}_latest.tar.gz"
+			pattern = "{task}_latest" + extension
 		}
 	}
 
@@ -104,57 +337,71 @@ func (b *Builder) GenerateFilename(taskName string) (string, error) {
 		}
 	}
 
-	// Ensure proper extension
-	if !strings.HasSuffix(filename, ".tar.gz") && !strings.HasSuffix(filename, ".tar") {
-		filename += ".tar.gz"
+	// Ensure proper extension. A tar pattern's extension follows the codec,
+	// so an uncompressed archive isn't named .tar.gz or the other way round.
+	switch current := ArchiveExtension(filename); current {
+	case "":
+		filename += extension
+	case ".tar.gz", ".tar.zst", ".tar":
+		if extension != ".zip" {
+			filename = strings.TrimSuffix(filename, current) + extension
+		}
 	}
 
 	return filename, nil
 }
 
-// createTarGz creates a tar.gz archive
-func (b *Builder) createTarGz(outputPath string, totalSize int64, fileCount int) (hash string, size int64, err error) {
-	// Create output file
-	outFile, err := os.Create(outputPath)
+// createTarGz creates a tar.gz archive, split into parts if SplitSizeBytes is set
+func (b *Builder) createTarGz(outputPath string, totalSize int64) (hash string, size int64, err error) {
+	if err := ValidateHashAlgorithm(b.HashAlgorithm); err != nil {
+		return "", 0, err
+	}
+
+	out, err := b.openOutput(outputPath)
 	if err != nil {
-		return "", 0, fmt.Errorf("failed to create archive file: %w", err)
+		return "", 0, err
 	}
 	defer func() {
-		if err := outFile.Close(); err != nil {
+		if err := out.Close(); err != nil {
 			log.Printf("Error closing output file: %v", err)
 		}
 	}()
 
-	// Create hash writer
-	hasher := sha256.New()
-	multiWriter := io.MultiWriter(outFile, hasher)
+	return b.writeTar(out, totalSize)
+}
 
-	// Create gzip writer if compression is enabled
+// writeTar writes the source as a tar stream, compressed with the prepared codec, to out
+func (b *Builder) writeTar(out io.Writer, totalSize int64) (hash string, size int64, err error) {
+	hasher, algorithm, err := NewHasher(b.HashAlgorithm)
+	if err != nil {
+		return "", 0, err
+	}
+
+	// Hash and count what reaches the output
+	counter := &countingWriter{}
+	multiWriter := io.MultiWriter(out, hasher, counter)
+
+	// Create the compressor if compression is enabled
 	var archiveWriter = multiWriter
-	if b.Options.Compression == "gzip" || b.Options.Compression == "" {
-		gzipWriter := gzip.NewWriter(multiWriter)
-		defer func() {
-			if err := gzipWriter.Close(); err != nil {
-				log.Printf("Error closing gzip writer: %v", err)
-			}
-		}()
-		archiveWriter = gzipWriter
+	compressor, err := newCompressor(multiWriter, b.codec)
+	if err != nil {
+		return "", 0, err
+	}
+	if compressor != nil {
+		archiveWriter = compressor
 	}
 
 	// Create tar writer
 	tarWriter := tar.NewWriter(archiveWriter)
-	defer func() {
-		if err := tarWriter.Close(); err != nil {
-			log.Printf("Error closing tar writer: %v", err)
-		}
-	}()
 
 	// Track progress
 	var bytesProcessed int64
 	filesProcessed := 0
+	b.contents = models.ArchiveContents{Files: make([]models.ArchiveFile, 0)}
 
 	// Walk the source directory
-	err = filepath.Walk(b.SourcePath, func(path string, info os.FileInfo, err error) error {
+	root := SourceRoot(b.SourcePath)
+	err = ignore.Walk(b.SourcePath, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
@@ -166,12 +413,32 @@ func (b *Builder) createTarGz(outputPath string, totalSize int64, fileCount int)
 		}
 
 		// Set the name to be relative to the source path
-		relPath, err := filepath.Rel(b.SourcePath, path)
+		relPath, err := filepath.Rel(root, path)
 		if err != nil {
 			return err
 		}
 		header.Name = relPath
 
+		// Sensitive files are left out, or stored empty
+		switch b.redact(relPath, info) {
+		case "skipped":
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		case "zeroed":
+			header.Size = 0
+			if err := tarWriter.WriteHeader(header); err != nil {
+				return fmt.Errorf("failed to write tar header: %w", err)
+			}
+			filesProcessed++
+			b.recordFile(filepath.ToSlash(relPath), 0)
+			if b.Progress != nil {
+				b.Progress(bytesProcessed, totalSize, relPath)
+			}
+			return nil
+		}
+
 		// Write header
 		if err := tarWriter.WriteHeader(header); err != nil {
 			return fmt.Errorf("failed to write tar header: %w", err)
@@ -196,6 +463,7 @@ func (b *Builder) createTarGz(outputPath string, totalSize int64, fileCount int)
 
 			bytesProcessed += written
 			filesProcessed++
+			b.recordFile(filepath.ToSlash(relPath), written)
 
 			// Report progress
 			if b.Progress != nil {
@@ -207,37 +475,130 @@ func (b *Builder) createTarGz(outputPath string, totalSize int64, fileCount int)
 	})
 
 	if err != nil {
+		// Stops the zstd encoder's goroutines
+		if compressor != nil {
+			_ = compressor.Close()
+		}
 		return "", 0, fmt.Errorf("failed to create archive: %w", err)
 	}
 
-	// Get file size
-	stat, err := outFile.Stat()
-	if err != nil {
-		return "", 0, fmt.Errorf("failed to stat archive: %w", err)
+	// Flush the tar and compressor trailers so the hash and size cover the whole archive
+	if err := tarWriter.Close(); err != nil {
+		return "", 0, fmt.Errorf("failed to finish archive: %w", err)
+	}
+	if compressor != nil {
+		if err := compressor.Close(); err != nil {
+			return "", 0, fmt.Errorf("failed to finish archive: %w", err)
+		}
 	}
 
 	// Calculate hash
 	hashBytes := hasher.Sum(nil)
-	hashString := fmt.Sprintf("sha256:%x", hashBytes)
+	hashString := fmt.Sprintf("%s:%x", algorithm, hashBytes)
 
-	return hashString, stat.Size(), nil
+	return hashString, counter.n, nil
 }
 
-// calculateSize calculates the total size of files in a directory
-func (b *Builder) calculateSize(path string) (totalSize int64, fileCount int, err error) {
-	err = filepath.Walk(path, func(_ string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
+// newCompressor returns the writer compressing a tar stream into w with codec,
+// or nil for an uncompressed archive
+func newCompressor(w io.Writer, codec string) (io.WriteCloser, error) {
+	switch codec {
+	case CodecGzip:
+		return gzip.NewWriterLevel(w, gzip.DefaultCompression)
+	case CodecZstd:
+		return zstd.NewWriter(w, zstd.WithEncoderLevel(zstd.SpeedDefault))
+	case CodecZstdBest:
+		return zstd.NewWriter(w, zstd.WithEncoderLevel(zstd.SpeedBestCompression))
+	default:
+		return nil, nil
+	}
+}
+
+// openOutput opens the archive's output, failing writes once free space runs
+// below MinFreeSpace
+func (b *Builder) openOutput(outputPath string) (io.WriteCloser, error) {
+	out, err := b.createOutput(outputPath)
+	if err != nil || b.MinFreeSpace <= 0 {
+		return out, err
+	}
+	return &spaceWatcher{WriteCloser: out, dir: filepath.Dir(outputPath), minFree: b.MinFreeSpace}, nil
+}
+
+// createOutput creates the archive file, or the writer of its parts if it is split
+func (b *Builder) createOutput(outputPath string) (io.WriteCloser, error) {
+	b.split = nil
+	if b.Options.SplitSizeBytes > 0 && b.Options.Format != FormatChunked {
+		b.split = newSplitWriter(outputPath, b.Options.SplitSizeBytes, b.FileMode)
+		return b.split, nil
+	}
+
+	outFile, err := os.Create(outputPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create archive file: %w", err)
+	}
+	if b.FileMode != 0 {
+		if err := outFile.Chmod(b.FileMode); err != nil {
+			if closeErr := outFile.Close(); closeErr != nil {
+				log.Printf("Error closing output file: %v", closeErr)
+			}
+			return nil, fmt.Errorf("failed to set archive file mode: %w", err)
 		}
-		if !info.IsDir() {
-			totalSize += info.Size()
-			fileCount++
+	}
+	return outFile, nil
+}
+
+// recordFile adds a file to the contents listing, keeping totals once the listing is full
+func (b *Builder) recordFile(name string, size int64) {
+	b.contents.FileCount++
+	b.contents.TotalSize += size
+	if len(b.contents.Files) < MaxContentsFiles {
+		b.contents.Files = append(b.contents.Files, models.ArchiveFile{Path: name, Size: size})
+	} else {
+		b.contents.Truncated = true
+	}
+}
+
+// countingWriter counts the bytes written through it
+type countingWriter struct {
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
+
+// calculateSize calculates the total size of files in a directory, along with
+// how many of those bytes are in already-compressed formats
+func (b *Builder) calculateSize(path string) (totalSize int64, fileCount int, incompressibleSize int64, err error) {
+	var mu sync.Mutex
+	err = ignore.WalkParallel(context.Background(), path, b.ScanWorkers, func(filePath string, info os.FileInfo) error {
+		if info.IsDir() {
+			return nil
+		}
+		incompressible := IsIncompressible(filePath)
+
+		mu.Lock()
+		defer mu.Unlock()
+		totalSize += info.Size()
+		fileCount++
+		if incompressible {
+			incompressibleSize += info.Size()
 		}
 		return nil
 	})
 	return
 }
 
+// SourceRoot returns the directory that paths within a source are made relative to:
+// the source itself, or for a single-file source its parent, so the file keeps its name
+func SourceRoot(sourcePath string) string {
+	if info, err := os.Stat(sourcePath); err == nil && !info.IsDir() {
+		return filepath.Dir(sourcePath)
+	}
+	return sourcePath
+}
+
 // sanitizeFilename removes characters that aren't safe for filenames
 func sanitizeFilename(name string) string {
 	// Replace spaces with hyphens
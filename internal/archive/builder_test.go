@@ -0,0 +1,305 @@
+package archive
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/nsilverman/archivist/internal/models"
+	"lukechampine.com/blake3"
+)
+
+func TestGenerateFilename(t *testing.T) {
+	tests := []struct {
+		name    string
+		options models.ArchiveOptions
+		want    string // regexp
+	}{
+		{"gzip with timestamp", models.ArchiveOptions{Format: "tar.gz", UseTimestamp: true}, `^my-task_\d{8}_\d{6}\.tar\.gz$`},
+		{"gzip without timestamp", models.ArchiveOptions{Format: "tar.gz"}, `^my-task_latest\.tar\.gz$`},
+		{"no compression", models.ArchiveOptions{Format: "tar.gz", Compression: "none", UseTimestamp: true}, `^my-task_\d{8}_\d{6}\.tar$`},
+		{"zstd", models.ArchiveOptions{Format: "tar.gz", Compression: "zstd", UseTimestamp: true}, `^my-task_\d{8}_\d{6}\.tar\.zst$`},
+		{"zstd with a .tar.gz pattern", models.ArchiveOptions{Format: "tar.gz", Compression: "zstd", NamePattern: "{task}.tar.gz"}, `^my-task\.tar\.zst$`},
+		{"gzip with a .tar.zst pattern", models.ArchiveOptions{Format: "tar.gz", NamePattern: "{task}.tar.zst"}, `^my-task\.tar\.gz$`},
+		{"auto before sampling", models.ArchiveOptions{Format: "tar.gz", Compression: "auto", UseTimestamp: true}, `^my-task_\d{8}_\d{6}\.tar\.zst$`},
+		{"no compression with a .tar.gz pattern", models.ArchiveOptions{Format: "tar.gz", Compression: "none", NamePattern: "{task}.tar.gz"}, `^my-task\.tar$`},
+		{"gzip with a .tar pattern", models.ArchiveOptions{Format: "tar.gz", NamePattern: "{task}.tar"}, `^my-task\.tar\.gz$`},
+		{"pattern without extension", models.ArchiveOptions{Format: "tar.gz", NamePattern: "backup-{task}"}, `^backup-my-task\.tar\.gz$`},
+		{"zip", models.ArchiveOptions{Format: "zip", UseTimestamp: true}, `^my-task_\d{8}_\d{6}\.zip$`},
+		{"chunked", models.ArchiveOptions{Format: "chunked", UseTimestamp: true}, `^my-task_\d{8}_\d{6}\.tar$`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewBuilder(t.TempDir(), t.TempDir(), tt.options, nil)
+			got, err := b.GenerateFilename("my task")
+			if err != nil {
+				t.Fatalf("GenerateFilename: %v", err)
+			}
+			if !regexp.MustCompile(tt.want).MatchString(got) {
+				t.Errorf("GenerateFilename = %q, want a match for %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLatestFilename(t *testing.T) {
+	tests := []struct {
+		archiveName, want string
+	}{
+		{"docs_20250127_143022.tar.gz", "docs_latest.tar.gz"},
+		{"docs_20250127_143022.tar", "docs_latest.tar"},
+		{"docs_20250127_143022.tar.zst", "docs_latest.tar.zst"},
+		{"docs_20250127_143022.zip", "docs_latest.zip"},
+		{"docs_20250127_143022", "docs_latest"},
+	}
+	for _, tt := range tests {
+		if got := LatestFilename("docs", tt.archiveName); got != tt.want {
+			t.Errorf("LatestFilename(docs, %s) = %s, want %s", tt.archiveName, got, tt.want)
+		}
+	}
+}
+
+func TestArchiveExtension(t *testing.T) {
+	tests := map[string]string{
+		"a.tar.gz":   ".tar.gz",
+		"a.tar":      ".tar",
+		"a.tar.zst":  ".tar.zst",
+		"a.zip":      ".zip",
+		"a.txt.gz":   "",
+		"a.tar.gz.1": "",
+		"a":          "",
+	}
+	for name, want := range tests {
+		if got := ArchiveExtension(name); got != want {
+			t.Errorf("ArchiveExtension(%s) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestBuildRoundTrip(t *testing.T) {
+	source := t.TempDir()
+	files := map[string]string{
+		"a.txt":          "alpha",
+		"nested/b.txt":   "bravo",
+		"nested/c/d.txt": strings.Repeat("delta", 1000),
+	}
+	for name, data := range files {
+		path := filepath.Join(source, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name          string
+		compression   string
+		wantExtension string
+	}{
+		{"gzip", "gzip", ".tar.gz"},
+		{"zstd", "zstd", ".tar.zst"},
+		{"none", "none", ".tar"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewBuilder(source, t.TempDir(), models.ArchiveOptions{Format: "tar.gz", Compression: tt.compression, UseTimestamp: true}, nil)
+			archivePath, hash, size, err := b.Build("docs")
+			if err != nil {
+				t.Fatalf("Build: %v", err)
+			}
+			if ArchiveExtension(archivePath) != tt.wantExtension {
+				t.Errorf("archive %s, want extension %s", filepath.Base(archivePath), tt.wantExtension)
+			}
+			if hash == "" || size == 0 {
+				t.Errorf("Build returned hash %q and size %d", hash, size)
+			}
+
+			f, err := os.Open(archivePath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() { _ = f.Close() }()
+			var got []string
+			err = InspectTar(f, func(entry Entry) error {
+				if entry.Type == "file" {
+					got = append(got, entry.Name)
+				}
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("InspectTar: %v", err)
+			}
+			if len(got) != len(files) {
+				t.Fatalf("archive holds %v, want %d files", got, len(files))
+			}
+			sort.Strings(got)
+			for _, name := range got {
+				if _, ok := files[strings.TrimPrefix(name, filepath.Base(source)+"/")]; !ok {
+					t.Errorf("unexpected entry %s", name)
+				}
+			}
+		})
+	}
+}
+
+// stubFreeSpace makes free space checks call fn for the rest of the test
+func stubFreeSpace(t *testing.T, fn func(dir string) (int64, error)) {
+	t.Helper()
+	saved := freeSpace
+	freeSpace = fn
+	t.Cleanup(func() { freeSpace = saved })
+}
+
+func TestBuildStopsOnLowSpace(t *testing.T) {
+	source := t.TempDir()
+	if err := os.WriteFile(filepath.Join(source, "a.txt"), []byte(strings.Repeat("alpha", 1000)), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	const minFree = 256 << 20
+	tests := []struct {
+		name         string
+		format       string
+		minFreeSpace int64
+		free         int64
+		freeErr      error
+		wantErr      bool
+		wantChecks   bool
+	}{
+		{name: "plenty of space", format: "tar.gz", minFreeSpace: minFree, free: 1 << 40, wantChecks: true},
+		{name: "low space", format: "tar.gz", minFreeSpace: minFree, free: minFree - 1, wantErr: true, wantChecks: true},
+		{name: "low space zip", format: "zip", minFreeSpace: minFree, free: 1 << 20, wantErr: true, wantChecks: true},
+		{name: "free space unknown", format: "tar.gz", minFreeSpace: minFree, freeErr: errors.New("statfs not supported"), wantChecks: true},
+		{name: "check turned off", format: "tar.gz", free: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output := t.TempDir()
+			var checked []string
+			stubFreeSpace(t, func(dir string) (int64, error) {
+				checked = append(checked, dir)
+				return tt.free, tt.freeErr
+			})
+
+			b := NewBuilder(source, output, models.ArchiveOptions{Format: tt.format, UseTimestamp: true}, nil)
+			b.MinFreeSpace = tt.minFreeSpace
+			archivePath, _, _, err := b.Build("docs")
+
+			if (len(checked) > 0) != tt.wantChecks {
+				t.Errorf("free space checked in %v, want checks %v", checked, tt.wantChecks)
+			}
+			for _, dir := range checked {
+				if dir != output {
+					t.Errorf("free space checked in %s, want the output directory %s", dir, output)
+				}
+			}
+			if !tt.wantErr {
+				if err != nil {
+					t.Fatalf("Build: %v", err)
+				}
+				if _, err := os.Stat(archivePath); err != nil {
+					t.Errorf("archive missing: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrInsufficientSpace) {
+				t.Fatalf("Build error = %v, want ErrInsufficientSpace", err)
+			}
+			// The incomplete archive is removed
+			if entries, err := os.ReadDir(output); err != nil || len(entries) != 0 {
+				t.Errorf("output directory holds %v (%v), want nothing", entries, err)
+			}
+		})
+	}
+}
+
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
+
+func TestSpaceWatcherChecksPeriodically(t *testing.T) {
+	// Space runs out after the first check
+	free := []int64{1 << 40, 0}
+	checks := 0
+	stubFreeSpace(t, func(string) (int64, error) {
+		available := free[min(checks, len(free)-1)]
+		checks++
+		return available, nil
+	})
+
+	w := &spaceWatcher{WriteCloser: nopWriteCloser{io.Discard}, dir: t.TempDir(), minFree: 1 << 20}
+	chunk := make([]byte, 1<<20)
+	var written int64
+	var err error
+	for written < 2*spaceCheckInterval {
+		var n int
+		n, err = w.Write(chunk)
+		written += int64(n)
+		if err != nil {
+			break
+		}
+	}
+	if !errors.Is(err, ErrInsufficientSpace) {
+		t.Fatalf("Write error = %v, want ErrInsufficientSpace", err)
+	}
+	if written != spaceCheckInterval || checks != 2 {
+		t.Errorf("failed after %d bytes and %d checks, want %d bytes and 2 checks", written, checks, spaceCheckInterval)
+	}
+}
+
+func TestBuildHashAlgorithms(t *testing.T) {
+	source := t.TempDir()
+	if err := os.WriteFile(filepath.Join(source, "a.txt"), []byte(strings.Repeat("alpha", 1000)), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		algorithm  string
+		wantPrefix string
+		sum        func([]byte) []byte
+	}{
+		{"", "sha256:", func(data []byte) []byte { sum := sha256.Sum256(data); return sum[:] }},
+		{HashSHA256, "sha256:", func(data []byte) []byte { sum := sha256.Sum256(data); return sum[:] }},
+		{HashSHA512, "sha512:", func(data []byte) []byte { sum := sha512.Sum512(data); return sum[:] }},
+		{HashBLAKE3, "blake3:", func(data []byte) []byte { sum := blake3.Sum256(data); return sum[:] }},
+	}
+	for _, tt := range tests {
+		t.Run("algorithm "+tt.algorithm, func(t *testing.T) {
+			b := NewBuilder(source, t.TempDir(), models.ArchiveOptions{Format: "tar.gz", UseTimestamp: true}, nil)
+			b.HashAlgorithm = tt.algorithm
+			archivePath, hash, _, err := b.Build("docs")
+			if err != nil {
+				t.Fatalf("Build: %v", err)
+			}
+
+			digest, ok := strings.CutPrefix(hash, tt.wantPrefix)
+			if !ok {
+				t.Fatalf("hash %q, want prefix %s", hash, tt.wantPrefix)
+			}
+			data, err := os.ReadFile(archivePath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := hex.EncodeToString(tt.sum(data)); digest != want {
+				t.Errorf("hash %s, want %s%s computed from the archive", hash, tt.wantPrefix, want)
+			}
+		})
+	}
+
+	b := NewBuilder(source, t.TempDir(), models.ArchiveOptions{Format: "tar.gz"}, nil)
+	b.HashAlgorithm = "md5"
+	if _, _, _, err := b.Build("docs"); err == nil {
+		t.Error("Build with an unsupported hash algorithm succeeded")
+	}
+}
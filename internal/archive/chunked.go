@@ -0,0 +1,258 @@
+package archive
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+
+	"github.com/nsilverman/archivist/internal/models"
+)
+
+// Chunked archives are stored as an uncompressed tar split into
+// content-defined chunks. Chunk boundaries depend only on nearby bytes, so an
+// edit to one file changes the chunks around it and the rest are reused.
+const (
+	FormatChunked = "chunked"
+
+	// ChunkPrefix is the remote directory holding chunks, shared by all tasks on a backend
+	ChunkPrefix = "chunks/"
+
+	// ManifestSuffix is appended to the archive name for the manifest listing its chunks
+	ManifestSuffix = ".manifest.json"
+
+	manifestVersion = 1
+
+	minChunkSize = 256 * 1024
+	maxChunkSize = 4 * 1024 * 1024
+
+	// A boundary is cut when the top 20 bits of the rolling hash are zero,
+	// giving chunks of about 1 MiB on average above the minimum size
+	chunkMask = uint64(1<<20-1) << 44
+)
+
+// gearTable holds the per-byte values for the rolling hash. It is derived
+// from a fixed seed so chunk boundaries are stable across runs and releases.
+var gearTable = func() [256]uint64 {
+	var table [256]uint64
+	state := uint64(0x61726368697669) // "archivi"
+	for i := range table {
+		// splitmix64
+		state += 0x9e3779b97f4a7c15
+		z := state
+		z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+		z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+		table[i] = z ^ (z >> 31)
+	}
+	return table
+}()
+
+// Chunk is one piece of a chunked archive
+type Chunk = models.ArchiveChunk
+
+// Manifest lists the chunks that make up an archive, in order
+type Manifest struct {
+	Version     int     `json:"version"`
+	ArchiveName string  `json:"archive_name"`
+	ArchiveHash string  `json:"archive_hash"` // "sha256:<hex>" of the reassembled archive
+	Size        int64   `json:"size"`
+	Chunks      []Chunk `json:"chunks"`
+}
+
+// ChunkPath returns the remote path of a chunk. Chunks are spread over
+// subdirectories by the first two hex digits of their hash.
+func ChunkPath(hash string) string {
+	return path.Join(ChunkPrefix, hash[:2], hash)
+}
+
+// ChunkFile splits a file into content-defined chunks and builds its manifest
+func ChunkFile(filePath string) (*Manifest, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open archive: %w", err)
+	}
+	defer func() {
+		if err := file.Close(); err != nil {
+			log.Printf("Error closing archive: %v", err)
+		}
+	}()
+
+	manifest := &Manifest{
+		Version:     manifestVersion,
+		ArchiveName: path.Base(filePath),
+		Chunks:      make([]Chunk, 0),
+	}
+
+	archiveHasher := sha256.New()
+	chunkHasher := sha256.New()
+	reader := bufio.NewReaderSize(file, 1024*1024)
+
+	var offset, size int64
+	var rolling uint64
+
+	cut := func() {
+		manifest.Chunks = append(manifest.Chunks, Chunk{
+			Hash:   hex.EncodeToString(chunkHasher.Sum(nil)),
+			Offset: offset,
+			Size:   size,
+		})
+		offset += size
+		size = 0
+		rolling = 0
+		chunkHasher.Reset()
+	}
+
+	buf := make([]byte, 64*1024)
+	for {
+		n, readErr := reader.Read(buf)
+		data := buf[:n]
+		if _, err := archiveHasher.Write(data); err != nil {
+			return nil, err
+		}
+
+		start := 0
+		for i, c := range data {
+			rolling = (rolling << 1) + gearTable[c]
+			size++
+			if (size >= minChunkSize && rolling&chunkMask == 0) || size >= maxChunkSize {
+				if _, err := chunkHasher.Write(data[start : i+1]); err != nil {
+					return nil, err
+				}
+				start = i + 1
+				cut()
+			}
+		}
+		if _, err := chunkHasher.Write(data[start:]); err != nil {
+			return nil, err
+		}
+
+		if errors.Is(readErr, io.EOF) {
+			break
+		}
+		if readErr != nil {
+			return nil, fmt.Errorf("failed to read archive: %w", readErr)
+		}
+	}
+	if size > 0 {
+		cut()
+	}
+
+	manifest.Size = offset
+	manifest.ArchiveHash = fmt.Sprintf("sha256:%x", archiveHasher.Sum(nil))
+	return manifest, nil
+}
+
+// WriteChunk copies one chunk of the archive at archivePath to destPath
+func WriteChunk(archivePath string, chunk Chunk, destPath string) error {
+	src, err := os.Open(archivePath)
+	if err != nil {
+		return fmt.Errorf("failed to open archive: %w", err)
+	}
+	defer func() {
+		if err := src.Close(); err != nil {
+			log.Printf("Error closing archive: %v", err)
+		}
+	}()
+
+	dst, err := os.Create(destPath)
+	if err != nil {
+		return fmt.Errorf("failed to create chunk file: %w", err)
+	}
+
+	_, err = io.Copy(dst, io.NewSectionReader(src, chunk.Offset, chunk.Size))
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write chunk: %w", err)
+	}
+	return nil
+}
+
+// ReadManifest parses a manifest file
+func ReadManifest(manifestPath string) (*Manifest, error) {
+	data, err := os.ReadFile(manifestPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+	if manifest.Version != manifestVersion {
+		return nil, fmt.Errorf("unsupported manifest version: %d", manifest.Version)
+	}
+	return &manifest, nil
+}
+
+// WriteManifest saves a manifest as JSON
+func WriteManifest(manifest *Manifest, manifestPath string) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	if err := os.WriteFile(manifestPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return nil
+}
+
+// ErrManifestMismatch is returned when a chunk or a reassembled archive doesn't
+// match the hashes in its manifest
+var ErrManifestMismatch = errors.New("content does not match manifest")
+
+// Reassemble rebuilds an archive from its chunks, writing it to w. Every chunk
+// and the finished archive are checked against the hashes in the manifest.
+func Reassemble(ctx context.Context, manifest *Manifest, open func(ctx context.Context, chunk Chunk) (io.ReadCloser, error), w io.Writer) error {
+	archiveHasher := sha256.New()
+	out := io.MultiWriter(w, archiveHasher)
+
+	for i, chunk := range manifest.Chunks {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if err := copyChunk(ctx, chunk, open, out); err != nil {
+			return fmt.Errorf("chunk %d (%s): %w", i, chunk.Hash, err)
+		}
+	}
+
+	if got := fmt.Sprintf("sha256:%x", archiveHasher.Sum(nil)); got != manifest.ArchiveHash {
+		return fmt.Errorf("reassembled archive %w: expected %s, got %s", ErrManifestMismatch, manifest.ArchiveHash, got)
+	}
+	return nil
+}
+
+// copyChunk writes a single verified chunk to out
+func copyChunk(ctx context.Context, chunk Chunk, open func(ctx context.Context, chunk Chunk) (io.ReadCloser, error), out io.Writer) error {
+	reader, err := open(ctx, chunk)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := reader.Close(); err != nil {
+			log.Printf("Error closing chunk: %v", err)
+		}
+	}()
+
+	// Verify before writing so a corrupt chunk never reaches the output
+	data, err := io.ReadAll(io.LimitReader(reader, maxChunkSize+1))
+	if err != nil {
+		return fmt.Errorf("failed to read chunk: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	if int64(len(data)) != chunk.Size || hex.EncodeToString(sum[:]) != chunk.Hash {
+		return fmt.Errorf("chunk %w", ErrManifestMismatch)
+	}
+
+	_, err = out.Write(data)
+	return err
+}
@@ -0,0 +1,150 @@
+package archive
+
+import (
+	"compress/flate"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/nsilverman/archivist/internal/ignore"
+)
+
+// Codecs an archive can be written with. With compression "auto" the codec is
+// chosen per run from a sample of the source.
+const (
+	CodecNone     = "none"
+	CodecGzip     = "gzip"      // default level, the default codec
+	CodecZstd     = "zstd"      // default level: fast, general purpose
+	CodecZstdBest = "zstd-best" // best compression, for highly compressible sources
+	CodecDeflate  = "deflate"   // zip entries deflated one by one, with already-compressed files stored
+)
+
+// ValidateCompression checks a task's compression option: gzip (the default
+// when empty), zstd, none or auto. "auto" chooses between none, zstd and
+// best-compression zstd.
+func ValidateCompression(compression string) error {
+	switch compression {
+	case "", "gzip", "zstd", "none", "auto":
+		return nil
+	default:
+		return fmt.Errorf("unsupported compression: %s (use gzip, zstd, none or auto)", compression)
+	}
+}
+
+// Sampling limits for "auto" compression
+const (
+	sampleBytesPerFile = 64 * 1024
+	sampleMaxFiles     = 64
+	// HighlyCompressibleRatio is the sampled compressed/original ratio at or
+	// below which the extra CPU time of best-compression zstd pays off
+	HighlyCompressibleRatio = 0.35
+)
+
+// IncompressibleThreshold is the share of source bytes in already-compressed
+// formats at or above which compression is not worth the CPU time
+const IncompressibleThreshold = 0.9
+
+// incompressibleExtensions lists formats whose contents are already compressed
+var incompressibleExtensions = map[string]bool{
+	// Images
+	".jpg": true, ".jpeg": true, ".png": true, ".gif": true, ".webp": true, ".heic": true, ".avif": true,
+	// Video
+	".mp4": true, ".m4v": true, ".mkv": true, ".mov": true, ".avi": true, ".webm": true,
+	// Audio
+	".mp3": true, ".m4a": true, ".aac": true, ".ogg": true, ".opus": true, ".flac": true,
+	// Archives
+	".zip": true, ".gz": true, ".tgz": true, ".bz2": true, ".xz": true, ".zst": true, ".7z": true, ".rar": true,
+	// Zip-based documents and packages
+	".docx": true, ".xlsx": true, ".pptx": true, ".odt": true, ".jar": true, ".apk": true,
+}
+
+// IsIncompressible reports whether a file's extension indicates already-compressed content
+func IsIncompressible(path string) bool {
+	return incompressibleExtensions[strings.ToLower(filepath.Ext(path))]
+}
+
+// ShouldCompress decides whether compression is worthwhile given how many of the
+// source bytes are already compressed
+func ShouldCompress(incompressibleSize, totalSize int64) bool {
+	if totalSize == 0 {
+		return true
+	}
+	return float64(incompressibleSize)/float64(totalSize) < IncompressibleThreshold
+}
+
+// SampleRatio estimates how well a source compresses by deflating the start of
+// up to sampleMaxFiles files spread evenly across it. It returns the
+// compressed/original ratio and the number of bytes sampled.
+func SampleRatio(sourcePath string, fileCount int) (ratio float64, sampled int64, err error) {
+	stride := 1
+	if fileCount > sampleMaxFiles {
+		stride = fileCount / sampleMaxFiles
+	}
+
+	counter := &countingWriter{}
+	deflater, err := flate.NewWriter(counter, flate.BestSpeed)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	index, sampledFiles := 0, 0
+	err = ignore.Walk(sourcePath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() || info.Size() == 0 {
+			return nil
+		}
+		index++
+		if (index-1)%stride != 0 {
+			return nil
+		}
+
+		file, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("failed to open file %s: %w", path, err)
+		}
+		defer func() {
+			if err := file.Close(); err != nil {
+				log.Printf("Error closing file %s: %v", path, err)
+			}
+		}()
+
+		n, err := io.Copy(deflater, io.LimitReader(file, sampleBytesPerFile))
+		sampled += n
+		if err != nil {
+			return fmt.Errorf("failed to sample file %s: %w", path, err)
+		}
+		sampledFiles++
+		if sampledFiles >= sampleMaxFiles {
+			return filepath.SkipAll
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	if err := deflater.Close(); err != nil {
+		return 0, 0, err
+	}
+
+	if sampled == 0 {
+		return 1, 0, nil
+	}
+	return float64(counter.n) / float64(sampled), sampled, nil
+}
+
+// ChooseCodec picks the codec for a sampled compressed/original ratio
+func ChooseCodec(ratio float64) string {
+	switch {
+	case ratio >= IncompressibleThreshold:
+		return CodecNone
+	case ratio <= HighlyCompressibleRatio:
+		return CodecZstdBest
+	default:
+		return CodecZstd
+	}
+}
@@ -0,0 +1,60 @@
+package archive
+
+import "testing"
+
+func TestValidateCompression(t *testing.T) {
+	tests := []struct {
+		compression string
+		valid       bool
+	}{
+		{"", true},
+		{"gzip", true},
+		{"zstd", true},
+		{"none", true},
+		{"auto", true},
+		{"zstd-best", false},
+		{"bzip2", false},
+		{"GZIP", false},
+	}
+	for _, tt := range tests {
+		if err := ValidateCompression(tt.compression); (err == nil) != tt.valid {
+			t.Errorf("ValidateCompression(%q) = %v, want valid %v", tt.compression, err, tt.valid)
+		}
+	}
+}
+
+func TestChooseCodec(t *testing.T) {
+	tests := []struct {
+		ratio float64
+		want  string
+	}{
+		{0.1, CodecZstdBest},
+		{HighlyCompressibleRatio, CodecZstdBest},
+		{0.5, CodecZstd},
+		{IncompressibleThreshold, CodecNone},
+		{1.0, CodecNone},
+	}
+	for _, tt := range tests {
+		if got := ChooseCodec(tt.ratio); got != tt.want {
+			t.Errorf("ChooseCodec(%v) = %s, want %s", tt.ratio, got, tt.want)
+		}
+	}
+}
+
+func TestShouldCompress(t *testing.T) {
+	tests := []struct {
+		incompressible, total int64
+		want                  bool
+	}{
+		{0, 0, true},
+		{0, 100, true},
+		{89, 100, true},
+		{90, 100, false},
+		{100, 100, false},
+	}
+	for _, tt := range tests {
+		if got := ShouldCompress(tt.incompressible, tt.total); got != tt.want {
+			t.Errorf("ShouldCompress(%d, %d) = %v, want %v", tt.incompressible, tt.total, got, tt.want)
+		}
+	}
+}
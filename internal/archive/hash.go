@@ -0,0 +1,38 @@
+package archive
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"fmt"
+	"hash"
+
+	"lukechampine.com/blake3"
+)
+
+// Archive hash algorithms. Hashes are recorded as "<algorithm>:<hex>".
+const (
+	HashSHA256 = "sha256"
+	HashSHA512 = "sha512"
+	HashBLAKE3 = "blake3" // 256-bit digest
+)
+
+// NewHasher returns a hash for the named algorithm along with the name used to
+// prefix its digests. An empty name selects SHA256.
+func NewHasher(algorithm string) (hash.Hash, string, error) {
+	switch algorithm {
+	case "", HashSHA256:
+		return sha256.New(), HashSHA256, nil
+	case HashSHA512:
+		return sha512.New(), HashSHA512, nil
+	case HashBLAKE3:
+		return blake3.New(32, nil), HashBLAKE3, nil
+	default:
+		return nil, "", fmt.Errorf("unsupported hash algorithm %q (use %s, %s or %s)", algorithm, HashSHA256, HashSHA512, HashBLAKE3)
+	}
+}
+
+// ValidateHashAlgorithm checks that algorithm is empty or supported
+func ValidateHashAlgorithm(algorithm string) error {
+	_, _, err := NewHasher(algorithm)
+	return err
+}
@@ -0,0 +1,189 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"path"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Entry is a file, directory or link read from an archive without extracting it
+type Entry struct {
+	Name     string // path inside the archive, as stored
+	Type     string // file, dir, symlink, hardlink or other
+	Size     int64
+	Linkname string // target of a symlink or hardlink
+}
+
+// CheckEntry reports why extracting an entry could write outside the
+// extraction directory: an absolute or parent-relative path, or a link whose
+// target leaves the directory. It returns nil for a safe entry. Backslashes are
+// treated as separators, since extracting on Windows would.
+func CheckEntry(entry Entry) error {
+	if err := checkEntryPath(entry.Name); err != nil {
+		return err
+	}
+	switch entry.Type {
+	case "symlink":
+		// A symlink target is relative to the link's own directory
+		target := strings.ReplaceAll(entry.Linkname, `\`, "/")
+		if path.IsAbs(target) || hasDriveLetter(target) {
+			return fmt.Errorf("symlink to absolute path %s", entry.Linkname)
+		}
+		if escapes(path.Join(path.Dir(strings.ReplaceAll(entry.Name, `\`, "/")), target)) {
+			return fmt.Errorf("symlink to %s leaves the extraction directory", entry.Linkname)
+		}
+	case "hardlink":
+		// A hardlink target is relative to the archive root
+		if err := checkEntryPath(entry.Linkname); err != nil {
+			return fmt.Errorf("hardlink to %s: %w", entry.Linkname, err)
+		}
+	}
+	return nil
+}
+
+// checkEntryPath rejects names that are absolute or climb out with ".."
+func checkEntryPath(name string) error {
+	name = strings.ReplaceAll(name, `\`, "/")
+	if path.IsAbs(name) || hasDriveLetter(name) {
+		return fmt.Errorf("absolute path")
+	}
+	if escapes(path.Clean(name)) {
+		return fmt.Errorf("path leaves the extraction directory")
+	}
+	return nil
+}
+
+// escapes reports whether a cleaned relative path starts outside its root
+func escapes(cleaned string) bool {
+	return cleaned == ".." || strings.HasPrefix(cleaned, "../")
+}
+
+// hasDriveLetter reports whether name starts with a Windows drive, such as C:
+func hasDriveLetter(name string) bool {
+	return len(name) >= 2 && name[1] == ':' &&
+		(name[0] >= 'a' && name[0] <= 'z' || name[0] >= 'A' && name[0] <= 'Z')
+}
+
+// zstdMagic starts every zstd frame
+var zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+
+// InspectTar reads a tar archive, gzipped, zstd-compressed or not, calling fn
+// for each entry without extracting anything. Reading to the end also checks
+// that the archive isn't truncated or corrupt.
+func InspectTar(r io.Reader, fn func(Entry) error) error {
+	buffered := bufio.NewReader(r)
+	var reader io.Reader = buffered
+	magic, _ := buffered.Peek(len(zstdMagic))
+	switch {
+	case len(magic) >= 2 && magic[0] == 0x1f && magic[1] == 0x8b:
+		gz, err := gzip.NewReader(buffered)
+		if err != nil {
+			return fmt.Errorf("failed to read gzip stream: %w", err)
+		}
+		defer func() {
+			if err := gz.Close(); err != nil {
+				log.Printf("Error closing gzip reader: %v", err)
+			}
+		}()
+		reader = gz
+	case bytes.Equal(magic, zstdMagic):
+		zr, err := zstd.NewReader(buffered)
+		if err != nil {
+			return fmt.Errorf("failed to read zstd stream: %w", err)
+		}
+		defer zr.Close()
+		reader = zr
+	}
+
+	tarReader := tar.NewReader(reader)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read tar entry: %w", err)
+		}
+
+		entry := Entry{Name: header.Name, Size: header.Size, Linkname: header.Linkname}
+		switch header.Typeflag {
+		case tar.TypeReg:
+			entry.Type = "file"
+		case tar.TypeDir:
+			entry.Type = "dir"
+		case tar.TypeSymlink:
+			entry.Type = "symlink"
+		case tar.TypeLink:
+			entry.Type = "hardlink"
+		default:
+			entry.Type = "other"
+		}
+		if err := fn(entry); err != nil {
+			return err
+		}
+
+		// Read the data too, so a corrupt or truncated archive is caught
+		if _, err := io.Copy(io.Discard, tarReader); err != nil {
+			return fmt.Errorf("failed to read %s: %w", header.Name, err)
+		}
+	}
+}
+
+// InspectZip reads a zip archive, calling fn for each entry without
+// extracting anything. Every entry is decompressed to check its checksum.
+func InspectZip(r io.ReaderAt, size int64, fn func(Entry) error) error {
+	zipReader, err := zip.NewReader(r, size)
+	if err != nil {
+		return fmt.Errorf("failed to read zip archive: %w", err)
+	}
+
+	for _, file := range zipReader.File {
+		entry := Entry{Name: file.Name, Size: int64(file.UncompressedSize64)}
+		mode := file.Mode()
+		switch {
+		case mode.IsDir():
+			entry.Type = "dir"
+		case mode.IsRegular():
+			entry.Type = "file"
+		case mode&fs.ModeSymlink != 0:
+			entry.Type = "symlink"
+		default:
+			entry.Type = "other"
+		}
+
+		data, err := file.Open()
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", file.Name, err)
+		}
+		// A zip symlink stores its target as the entry's data
+		if entry.Type == "symlink" {
+			target, readErr := io.ReadAll(io.LimitReader(data, 4096))
+			entry.Linkname = string(target)
+			err = readErr
+		}
+		if err == nil {
+			_, err = io.Copy(io.Discard, data)
+		}
+		if closeErr := data.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", file.Name, err)
+		}
+
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
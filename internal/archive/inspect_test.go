@@ -0,0 +1,33 @@
+package archive
+
+import "testing"
+
+func TestCheckEntry(t *testing.T) {
+	tests := []struct {
+		name   string
+		entry  Entry
+		unsafe bool
+	}{
+		{"plain file", Entry{Name: "docs/a.txt", Type: "file"}, false},
+		{"dot segment inside", Entry{Name: "docs/./a.txt", Type: "file"}, false},
+		{"parent that stays inside", Entry{Name: "docs/../a.txt", Type: "file"}, false},
+		{"parent path", Entry{Name: "../a.txt", Type: "file"}, true},
+		{"nested parent path", Entry{Name: "docs/../../a.txt", Type: "file"}, true},
+		{"backslash parent path", Entry{Name: `docs\..\..\a.txt`, Type: "file"}, true},
+		{"absolute path", Entry{Name: "/etc/passwd", Type: "file"}, true},
+		{"drive letter", Entry{Name: `C:\Windows\a.txt`, Type: "file"}, true},
+		{"symlink inside", Entry{Name: "docs/link", Type: "symlink", Linkname: "../a.txt"}, false},
+		{"symlink out", Entry{Name: "docs/link", Type: "symlink", Linkname: "../../a.txt"}, true},
+		{"absolute symlink", Entry{Name: "link", Type: "symlink", Linkname: "/etc/passwd"}, true},
+		{"hardlink inside", Entry{Name: "b", Type: "hardlink", Linkname: "docs/a.txt"}, false},
+		{"hardlink out", Entry{Name: "b", Type: "hardlink", Linkname: "../a.txt"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckEntry(tt.entry)
+			if (err != nil) != tt.unsafe {
+				t.Errorf("CheckEntry(%+v) = %v, want unsafe %v", tt.entry, err, tt.unsafe)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package archive
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/nsilverman/archivist/internal/ignore"
+	"github.com/nsilverman/archivist/internal/models"
+)
+
+// Redaction modes
+const (
+	RedactSkip = "skip" // leave sensitive files out of the archive
+	RedactZero = "zero" // store sensitive files, but empty
+)
+
+// ValidateRedaction checks the mode and patterns of a redaction
+func ValidateRedaction(redaction *models.Redaction) error {
+	if redaction == nil {
+		return nil
+	}
+	switch redaction.Mode {
+	case "", RedactSkip, RedactZero:
+	default:
+		return fmt.Errorf("unsupported redaction mode: %s (use skip or zero)", redaction.Mode)
+	}
+	for _, pattern := range redaction.Patterns {
+		if strings.TrimSpace(pattern) == "" {
+			return fmt.Errorf("redaction patterns cannot be empty")
+		}
+	}
+	return nil
+}
+
+// Redactor decides which source files are too sensitive to archive as they
+// are. It applies after ignore files, so it also catches files that were
+// meant to be archived.
+type Redactor struct {
+	patterns ignore.Patterns
+	mode     string
+}
+
+// NewRedactor combines redactions, such as the settings' and a task's: their
+// patterns add up and the last mode set wins. It returns nil when there are no
+// patterns, and a nil Redactor redacts nothing.
+func NewRedactor(redactions ...*models.Redaction) *Redactor {
+	var patterns []string
+	mode := RedactSkip
+	for _, redaction := range redactions {
+		if redaction == nil {
+			continue
+		}
+		patterns = append(patterns, redaction.Patterns...)
+		if redaction.Mode != "" {
+			mode = redaction.Mode
+		}
+	}
+	if len(patterns) == 0 {
+		return nil
+	}
+	return &Redactor{patterns: ignore.ParsePatterns(patterns), mode: mode}
+}
+
+// redact reports what happens to a source entry: "" archives it as it is;
+// "skipped" leaves it, and for a directory everything in it, out; "zeroed"
+// stores a file empty. Redacted entries are recorded in the contents and logged.
+// In zero mode directories are kept, and only regular files can be zeroed.
+func (b *Builder) redact(relPath string, info os.FileInfo) string {
+	if b.Redactor == nil || relPath == "." || !b.Redactor.patterns.Matches(relPath, info.IsDir()) {
+		return ""
+	}
+	if b.Redactor.mode == RedactZero && info.IsDir() {
+		return ""
+	}
+
+	action := "skipped"
+	if b.Redactor.mode == RedactZero && info.Mode().IsRegular() {
+		action = "zeroed"
+	}
+
+	path := filepath.ToSlash(relPath)
+	if info.IsDir() {
+		path += "/"
+	}
+	size := int64(0)
+	if !info.IsDir() {
+		size = info.Size()
+	}
+	b.contents.Redacted = append(b.contents.Redacted, models.RedactedFile{Path: path, Size: size, Action: action})
+	log.Printf("Redacted %s from archive (%s)", path, action)
+	return action
+}
@@ -0,0 +1,70 @@
+package archive
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"syscall"
+
+	"github.com/nsilverman/archivist/internal/diskspace"
+)
+
+// DefaultMinFreeSpace is the free space a build keeps in the output directory
+// unless configured otherwise
+const DefaultMinFreeSpace = 256 << 20
+
+// spaceCheckInterval is how many bytes are written between free space checks
+const spaceCheckInterval = 64 << 20
+
+// ErrInsufficientSpace is returned when the output directory runs low on
+// space while an archive is being written
+var ErrInsufficientSpace = errors.New("insufficient temp space")
+
+// freeSpace returns the bytes available in dir; replaced in tests
+var freeSpace = func(dir string) (int64, error) {
+	_, available, err := diskspace.Usage(dir)
+	return available, err
+}
+
+// spaceWatcher fails writes once the free space in dir drops below minFree,
+// so a build stops with a clear error instead of filling the disk. Space is
+// checked before the first write and then every spaceCheckInterval bytes.
+type spaceWatcher struct {
+	io.WriteCloser
+	dir     string
+	minFree int64
+
+	unchecked int64 // bytes left before the next check
+	disabled  bool  // the filesystem can't report free space
+}
+
+func (w *spaceWatcher) Write(p []byte) (int, error) {
+	if w.unchecked <= 0 && !w.disabled {
+		if err := w.check(); err != nil {
+			return 0, err
+		}
+		w.unchecked = spaceCheckInterval
+	}
+
+	n, err := w.WriteCloser.Write(p)
+	w.unchecked -= int64(n)
+	if errors.Is(err, syscall.ENOSPC) {
+		err = fmt.Errorf("%w in %s: %w", ErrInsufficientSpace, w.dir, err)
+	}
+	return n, err
+}
+
+// check returns ErrInsufficientSpace when dir has less than minFree bytes available
+func (w *spaceWatcher) check() error {
+	available, err := freeSpace(w.dir)
+	if err != nil {
+		log.Printf("Cannot check free space in %s, continuing without checks: %v", w.dir, err)
+		w.disabled = true
+		return nil
+	}
+	if available < w.minFree {
+		return fmt.Errorf("%w: %d bytes free in %s, below the %d bytes to keep free", ErrInsufficientSpace, available, w.dir, w.minFree)
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+package archive
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+)
+
+// Split archives are written as numbered parts: name.tar.gz.001, name.tar.gz.002, ...
+// Concatenating the parts in order gives back the original archive.
+
+// partPattern matches the numbered suffix of an archive part
+var partPattern = regexp.MustCompile(`^(.+)\.(\d{3,})$`)
+
+// PartName returns the name of the nth (1-based) part of an archive
+func PartName(name string, n int) string {
+	return fmt.Sprintf("%s.%03d", name, n)
+}
+
+// SplitPartName reports whether name is an archive part, returning the archive name it belongs to
+func SplitPartName(name string) (archiveName string, ok bool) {
+	match := partPattern.FindStringSubmatch(name)
+	if match == nil {
+		return "", false
+	}
+	return match[1], true
+}
+
+// splitWriter writes a stream as a sequence of part files of at most partSize bytes
+type splitWriter struct {
+	basePath string
+	partSize int64
+	mode     os.FileMode
+
+	current *os.File
+	written int64 // bytes written to the current part
+	parts   []string
+}
+
+func newSplitWriter(basePath string, partSize int64, mode os.FileMode) *splitWriter {
+	return &splitWriter{basePath: basePath, partSize: partSize, mode: mode}
+}
+
+// Write fills the current part and starts new ones as needed
+func (w *splitWriter) Write(p []byte) (int, error) {
+	total := 0
+	for len(p) > 0 {
+		if w.current == nil || w.written == w.partSize {
+			if err := w.nextPart(); err != nil {
+				return total, err
+			}
+		}
+
+		chunk := p
+		if remaining := w.partSize - w.written; int64(len(chunk)) > remaining {
+			chunk = chunk[:remaining]
+		}
+
+		n, err := w.current.Write(chunk)
+		total += n
+		w.written += int64(n)
+		if err != nil {
+			return total, err
+		}
+		p = p[n:]
+	}
+	return total, nil
+}
+
+// nextPart closes the current part and creates the next one
+func (w *splitWriter) nextPart() error {
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	path := PartName(w.basePath, len(w.parts)+1)
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create archive part: %w", err)
+	}
+	w.parts = append(w.parts, path)
+	w.current = file
+	w.written = 0
+
+	if w.mode != 0 {
+		if err := file.Chmod(w.mode); err != nil {
+			return fmt.Errorf("failed to set archive file mode: %w", err)
+		}
+	}
+	return nil
+}
+
+// Close closes the current part
+func (w *splitWriter) Close() error {
+	if w.current == nil {
+		return nil
+	}
+	err := w.current.Close()
+	w.current = nil
+	return err
+}
+
+// SortParts puts the parts of an archive in the order of their numbered suffix
+func SortParts(parts []string) {
+	// Suffixes grow past three digits after part 999, so shorter names sort first
+	sort.Slice(parts, func(i, j int) bool {
+		if len(parts[i]) != len(parts[j]) {
+			return len(parts[i]) < len(parts[j])
+		}
+		return parts[i] < parts[j]
+	})
+}
+
+// JoinParts concatenates archive parts into destPath. Parts are joined in
+// the order of their numbered suffix regardless of the order given.
+func JoinParts(parts []string, destPath string) error {
+	sorted := append([]string(nil), parts...)
+	SortParts(sorted)
+
+	dst, err := os.Create(destPath)
+	if err != nil {
+		return fmt.Errorf("failed to create archive: %w", err)
+	}
+
+	for _, part := range sorted {
+		if err = appendFile(dst, part); err != nil {
+			break
+		}
+	}
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		if removeErr := os.Remove(destPath); removeErr != nil {
+			log.Printf("Error removing incomplete archive: %v", removeErr)
+		}
+		return err
+	}
+	return nil
+}
+
+// appendFile copies the contents of path to w
+func appendFile(w io.Writer, path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open archive part: %w", err)
+	}
+	defer func() {
+		if err := src.Close(); err != nil {
+			log.Printf("Error closing archive part: %v", err)
+		}
+	}()
+
+	if _, err := io.Copy(w, src); err != nil {
+		return fmt.Errorf("failed to join archive part %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package archive
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSplitPartName(t *testing.T) {
+	tests := []struct {
+		name        string
+		wantArchive string
+		wantOK      bool
+	}{
+		{"docs.tar.gz.001", "docs.tar.gz", true},
+		{"docs.tar.gz.1000", "docs.tar.gz", true},
+		{"dir/docs.tar.002", "dir/docs.tar", true},
+		{"docs.tar.gz.01", "", false},
+		{"docs.tar.gz", "", false},
+	}
+	for _, tt := range tests {
+		archiveName, ok := SplitPartName(tt.name)
+		if archiveName != tt.wantArchive || ok != tt.wantOK {
+			t.Errorf("SplitPartName(%s) = %q, %v, want %q, %v", tt.name, archiveName, ok, tt.wantArchive, tt.wantOK)
+		}
+	}
+}
+
+func TestSortParts(t *testing.T) {
+	parts := []string{"a.tar.1000", "a.tar.002", "a.tar.999", "a.tar.001"}
+	SortParts(parts)
+	want := []string{"a.tar.001", "a.tar.002", "a.tar.999", "a.tar.1000"}
+	if !reflect.DeepEqual(parts, want) {
+		t.Errorf("SortParts = %v, want %v", parts, want)
+	}
+}
+
+func TestJoinPartsOrdersBySuffix(t *testing.T) {
+	dir := t.TempDir()
+	var parts []string
+	for i, data := range []string{"one-", "two-", "three"} {
+		part := filepath.Join(dir, PartName("a.tar", i+1))
+		if err := os.WriteFile(part, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		parts = append([]string{part}, parts...) // reversed on purpose
+	}
+
+	dest := filepath.Join(dir, "a.tar")
+	if err := JoinParts(parts, dest); err != nil {
+		t.Fatalf("JoinParts: %v", err)
+	}
+	got, err := os.ReadFile(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "one-two-three" {
+		t.Errorf("joined archive = %q, want %q", got, "one-two-three")
+	}
+}
+
+func TestChunkFileStableAcrossEdits(t *testing.T) {
+	original := make([]byte, 24*1024*1024)
+	rand.New(rand.NewSource(1)).Read(original)
+
+	// A few bytes inserted near the middle shift everything after them
+	middle := len(original) / 2
+	edited := append(append(append([]byte(nil), original[:middle]...), "inserted"...), original[middle:]...)
+	// and a few bytes overwritten near the end
+	edited[len(edited)-3*1024*1024] ^= 0xff
+
+	dir := t.TempDir()
+	chunkBytes := func(name string, data []byte) (string, *Manifest) {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		manifest, err := ChunkFile(path)
+		if err != nil {
+			t.Fatalf("ChunkFile: %v", err)
+		}
+		return path, manifest
+	}
+	originalPath, before := chunkBytes("original.tar", original)
+	editedPath, after := chunkBytes("edited.tar", edited)
+
+	// The same input always gives the same chunks
+	if _, again := chunkBytes("again.tar", original); !reflect.DeepEqual(before.Chunks, again.Chunks) {
+		t.Error("chunking the same content twice gave different chunks")
+	}
+
+	for _, m := range []struct {
+		path     string
+		manifest *Manifest
+		size     int
+	}{{originalPath, before, len(original)}, {editedPath, after, len(edited)}} {
+		var offset int64
+		for i, chunk := range m.manifest.Chunks {
+			if chunk.Offset != offset {
+				t.Fatalf("chunk %d starts at %d, want %d", i, chunk.Offset, offset)
+			}
+			last := i == len(m.manifest.Chunks)-1
+			if chunk.Size > maxChunkSize || (!last && chunk.Size < minChunkSize) {
+				t.Errorf("chunk %d is %d bytes, outside [%d, %d]", i, chunk.Size, minChunkSize, maxChunkSize)
+			}
+			offset += chunk.Size
+		}
+		if offset != int64(m.size) || m.manifest.Size != int64(m.size) {
+			t.Errorf("chunks cover %d bytes, manifest %d, want %d", offset, m.manifest.Size, m.size)
+		}
+
+		// The chunks reassemble into the archive
+		var out bytes.Buffer
+		err := Reassemble(context.Background(), m.manifest, func(ctx context.Context, chunk Chunk) (io.ReadCloser, error) {
+			chunkPath := filepath.Join(dir, chunk.Hash)
+			if err := WriteChunk(m.path, chunk, chunkPath); err != nil {
+				return nil, err
+			}
+			return os.Open(chunkPath)
+		}, &out)
+		if err != nil {
+			t.Fatalf("Reassemble: %v", err)
+		}
+		if out.Len() != m.size {
+			t.Errorf("reassembled %d bytes, want %d", out.Len(), m.size)
+		}
+	}
+
+	// Only the chunks around the two edits change
+	reused := make(map[string]bool)
+	for _, chunk := range before.Chunks {
+		reused[chunk.Hash] = true
+	}
+	changed := 0
+	for _, chunk := range after.Chunks {
+		if !reused[chunk.Hash] {
+			changed++
+		}
+	}
+	if changed == 0 || changed > 4 {
+		t.Errorf("%d of %d chunks changed after two small edits, want 1 to 4", changed, len(after.Chunks))
+	}
+}
@@ -0,0 +1,175 @@
+package archive
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/nsilverman/archivist/internal/ignore"
+	"github.com/nsilverman/archivist/internal/models"
+)
+
+// FormatZip writes a zip archive. Each entry is compressed on its own, so
+// already-compressed files are stored as they are while the rest are deflated.
+const FormatZip = "zip"
+
+// createZip creates a zip archive, split into parts if SplitSizeBytes is set
+func (b *Builder) createZip(outputPath string, totalSize int64) (hash string, size int64, err error) {
+	if err := ValidateHashAlgorithm(b.HashAlgorithm); err != nil {
+		return "", 0, err
+	}
+
+	out, err := b.openOutput(outputPath)
+	if err != nil {
+		return "", 0, err
+	}
+	defer func() {
+		if err := out.Close(); err != nil {
+			log.Printf("Error closing output file: %v", err)
+		}
+	}()
+
+	return b.writeZip(out, totalSize)
+}
+
+// writeZip writes the source as a zip archive to out. Entries whose extension
+// is stored, see storedExtensions, skip compression.
+func (b *Builder) writeZip(out io.Writer, totalSize int64) (hash string, size int64, err error) {
+	hasher, algorithm, err := NewHasher(b.HashAlgorithm)
+	if err != nil {
+		return "", 0, err
+	}
+
+	// Hash and count what reaches the output
+	counter := &countingWriter{}
+	zipWriter := zip.NewWriter(io.MultiWriter(out, hasher, counter))
+
+	stored := storedExtensions(b.Options.StoreExtensions)
+	var bytesProcessed int64
+	b.contents = models.ArchiveContents{Files: make([]models.ArchiveFile, 0)}
+
+	root := SourceRoot(b.SourcePath)
+	err = ignore.Walk(b.SourcePath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relPath, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		if relPath == "." {
+			return nil
+		}
+
+		// Sensitive files are left out, or stored empty
+		action := b.redact(relPath, info)
+		if action == "skipped" {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		header, err := zip.FileInfoHeader(info)
+		if err != nil {
+			return fmt.Errorf("failed to create zip header: %w", err)
+		}
+		header.Name = filepath.ToSlash(relPath)
+
+		switch {
+		case info.IsDir():
+			header.Name += "/"
+			header.Method = zip.Store
+		case b.codec == CodecNone || stored[strings.ToLower(filepath.Ext(path))]:
+			header.Method = zip.Store
+		default:
+			header.Method = zip.Deflate
+		}
+
+		entry, err := zipWriter.CreateHeader(header)
+		if err != nil {
+			return fmt.Errorf("failed to write zip header: %w", err)
+		}
+		if info.IsDir() {
+			return nil
+		}
+		if action == "zeroed" {
+			b.recordFile(header.Name, 0)
+			if b.Progress != nil {
+				b.Progress(bytesProcessed, totalSize, relPath)
+			}
+			return nil
+		}
+
+		// Symlinks are stored as their target, the usual zip convention
+		if info.Mode()&os.ModeSymlink != 0 {
+			target, err := os.Readlink(path)
+			if err != nil {
+				return fmt.Errorf("failed to read link %s: %w", path, err)
+			}
+			_, err = io.WriteString(entry, target)
+			return err
+		}
+
+		file, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("failed to open file %s: %w", path, err)
+		}
+		defer func() {
+			if err := file.Close(); err != nil {
+				log.Printf("Error closing file %s: %v", path, err)
+			}
+		}()
+
+		written, err := io.Copy(entry, file)
+		if err != nil {
+			return fmt.Errorf("failed to write file %s: %w", path, err)
+		}
+
+		bytesProcessed += written
+		b.recordFile(header.Name, written)
+
+		if b.Progress != nil {
+			b.Progress(bytesProcessed, totalSize, relPath)
+		}
+		return nil
+	})
+
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to create archive: %w", err)
+	}
+
+	// Write the central directory so the hash and size cover the whole archive
+	if err := zipWriter.Close(); err != nil {
+		return "", 0, fmt.Errorf("failed to finish archive: %w", err)
+	}
+
+	return fmt.Sprintf("%s:%x", algorithm, hasher.Sum(nil)), counter.n, nil
+}
+
+// storedExtensions returns the extensions a zip archive stores uncompressed:
+// the configured ones, normalized to a lowercase ".ext", or when none are
+// configured the known already-compressed formats
+func storedExtensions(configured []string) map[string]bool {
+	if len(configured) == 0 {
+		return incompressibleExtensions
+	}
+
+	stored := make(map[string]bool, len(configured))
+	for _, ext := range configured {
+		ext = strings.ToLower(strings.TrimSpace(ext))
+		if ext == "" {
+			continue
+		}
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		stored[ext] = true
+	}
+	return stored
+}
@@ -3,23 +3,35 @@ package backend
 import (
 	"context"
 	"fmt"
-	"log"
-	"os"
+	"io"
 	"strings"
 	"time"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/container"
 	"github.com/nsilverman/archivist/internal/models"
 )
 
+// Bounds for the upload tuning options. Each in-flight block is buffered in
+// memory, so peak usage is roughly block size times concurrency.
+const (
+	azureMinBlockSizeMB   = 1
+	azureMaxBlockSizeMB   = 4000
+	azureMinConcurrency   = 1
+	azureMaxConcurrency   = 64
+	azureBytesPerMegabyte = 1024 * 1024
+)
+
 // AzureBackend stores backups on Azure Blob Storage
 type AzureBackend struct {
 	client      *azblob.Client
 	container   string
 	prefix      string
 	storageTier *blob.AccessTier
+	blockSize   int64 // bytes; zero uses the SDK default
+	concurrency int   // zero uses the SDK default
 }
 
 // Initialize sets up the Azure backend
@@ -46,6 +58,25 @@ func (b *AzureBackend) Initialize(cfg map[string]interface{}, pathResolver PathR
 	}
 	// If not specified, Azure will use the account's default tier
 
+	// Optional upload tuning for large archives
+	blockSizeMB, err := configInt(cfg, "block_size", 0)
+	if err != nil {
+		return err
+	}
+	if blockSizeMB != 0 && (blockSizeMB < azureMinBlockSizeMB || blockSizeMB > azureMaxBlockSizeMB) {
+		return fmt.Errorf("azure 'block_size' must be between %d and %d MB", azureMinBlockSizeMB, azureMaxBlockSizeMB)
+	}
+	b.blockSize = blockSizeMB * azureBytesPerMegabyte
+
+	concurrency, err := configInt(cfg, "concurrency", 0)
+	if err != nil {
+		return err
+	}
+	if concurrency != 0 && (concurrency < azureMinConcurrency || concurrency > azureMaxConcurrency) {
+		return fmt.Errorf("azure 'concurrency' must be between %d and %d", azureMinConcurrency, azureMaxConcurrency)
+	}
+	b.concurrency = int(concurrency)
+
 	// Get account name
 	accountName, ok := cfg["account_name"].(string)
 	if !ok || accountName == "" {
@@ -54,7 +85,6 @@ func (b *AzureBackend) Initialize(cfg map[string]interface{}, pathResolver PathR
 
 	// Create client using account key or SAS token
 	var client *azblob.Client
-	var err error
 
 	if accountKey, ok := cfg["account_key"].(string); ok && accountKey != "" {
 		// Use account key authentication
@@ -100,24 +130,13 @@ func (b *AzureBackend) Test() error {
 
 // Upload uploads a file to Azure Blob Storage
 func (b *AzureBackend) Upload(ctx context.Context, localPath string, remotePath string, progress ProgressCallback) error {
-	// Open local file
-	file, err := os.Open(localPath)
-	if err != nil {
-		return fmt.Errorf("failed to open file: %w", err)
-	}
-	defer func() {
-		if err := file.Close(); err != nil {
-			log.Printf("Error closing file: %v", err)
-		}
-	}()
-
-	// Get file size
-	stat, err := file.Stat()
-	if err != nil {
-		return fmt.Errorf("failed to stat file: %w", err)
-	}
-	fileSize := stat.Size()
+	return uploadFile(localPath, func(file io.Reader, size int64) error {
+		return b.UploadReader(ctx, file, size, remotePath, progress)
+	})
+}
 
+// UploadReader uploads a stream to Azure Blob Storage
+func (b *AzureBackend) UploadReader(ctx context.Context, reader io.Reader, size int64, remotePath string, progress ProgressCallback) error {
 	// Add prefix if configured
 	blobName := remotePath
 	if b.prefix != "" {
@@ -126,19 +145,30 @@ func (b *AzureBackend) Upload(ctx context.Context, localPath string, remotePath
 
 	// Wrap with progress reader
 	progressReader := &progressReader{
-		reader:   file,
-		size:     fileSize,
+		reader:   reader,
+		size:     size,
 		callback: progress,
 	}
 
 	// Configure upload options
-	uploadOptions := &azblob.UploadStreamOptions{}
+	contentType := ContentType(remotePath)
+	uploadOptions := &azblob.UploadStreamOptions{
+		BlockSize:   b.blockSize,
+		Concurrency: b.concurrency,
+		HTTPHeaders: &blob.HTTPHeaders{BlobContentType: &contentType},
+	}
 	if b.storageTier != nil {
 		uploadOptions.AccessTier = b.storageTier
 	}
+	if metadata := uploadMetadata(ctx); metadata != nil {
+		uploadOptions.Metadata = make(map[string]*string, len(metadata))
+		for key, value := range metadata {
+			uploadOptions.Metadata[key] = &value
+		}
+	}
 
 	// Upload to blob
-	_, err = b.client.UploadStream(ctx, b.container, blobName, progressReader, uploadOptions)
+	_, err := b.client.UploadStream(ctx, b.container, blobName, progressReader, uploadOptions)
 	if err != nil {
 		return fmt.Errorf("failed to upload to Azure: %w", err)
 	}
@@ -146,6 +176,45 @@ func (b *AzureBackend) Upload(ctx context.Context, localPath string, remotePath
 	return nil
 }
 
+// Download downloads a blob from Azure Blob Storage, using a byte range to resume a partial download
+func (b *AzureBackend) Download(ctx context.Context, remotePath string, localPath string, progress ProgressCallback) error {
+	return resumableDownload(ctx, localPath, progress, b.opener(ctx, remotePath))
+}
+
+// Open streams a blob from Azure Blob Storage
+func (b *AzureBackend) Open(ctx context.Context, remotePath string) (io.ReadCloser, int64, error) {
+	body, size, _, err := b.opener(ctx, remotePath)(0)
+	return body, size, err
+}
+
+// opener reads a blob from a byte offset
+func (b *AzureBackend) opener(ctx context.Context, remotePath string) rangeOpener {
+	// Add prefix if configured
+	blobName := remotePath
+	if b.prefix != "" {
+		blobName = b.prefix + "/" + remotePath
+	}
+
+	return func(offset int64) (io.ReadCloser, int64, string, error) {
+		resp, err := b.client.DownloadStream(ctx, b.container, blobName, &azblob.DownloadStreamOptions{
+			Range: blob.HTTPRange{Offset: offset},
+		})
+		if err != nil {
+			return nil, 0, "", fmt.Errorf("failed to download from Azure: %w", err)
+		}
+
+		total := offset
+		if resp.ContentLength != nil {
+			total += *resp.ContentLength
+		}
+		var version string
+		if resp.ETag != nil {
+			version = string(*resp.ETag)
+		}
+		return resp.Body, total, version, nil
+	}
+}
+
 // List returns all backups with a given prefix
 func (b *AzureBackend) List(ctx context.Context, prefix string) ([]BackupInfo, error) {
 	// Combine backend prefix with query prefix
@@ -162,7 +231,8 @@ func (b *AzureBackend) List(ctx context.Context, prefix string) ([]BackupInfo, e
 	containerClient := b.client.ServiceClient().NewContainerClient(b.container)
 
 	pager := containerClient.NewListBlobsFlatPager(&container.ListBlobsFlatOptions{
-		Prefix: &fullPrefix,
+		Prefix:  &fullPrefix,
+		Include: container.ListBlobsInclude{Metadata: true},
 	})
 
 	for pager.More() {
@@ -178,11 +248,19 @@ func (b *AzureBackend) List(ctx context.Context, prefix string) ([]BackupInfo, e
 				displayPath = displayPath[len(b.prefix)+1:]
 			}
 
+			metadata := make(map[string]string, len(blob.Metadata))
+			for key, value := range blob.Metadata {
+				if value != nil {
+					metadata[key] = *value
+				}
+			}
+
 			backups = append(backups, BackupInfo{
 				Path:         displayPath,
 				Size:         *blob.Properties.ContentLength,
 				LastModified: blob.Properties.LastModified.Format(time.RFC3339),
 				Hash:         "", // Azure uses different hash format
+				Metadata:     metadata,
 			})
 		}
 	}
@@ -206,6 +284,49 @@ func (b *AzureBackend) Delete(ctx context.Context, remotePath string) error {
 	return nil
 }
 
+// azureCopyPollInterval is how often a pending blob copy is checked
+const azureCopyPollInterval = time.Second
+
+// Copy copies a blob within the container. Azure copies asynchronously, so
+// this waits until the copy has finished.
+func (b *AzureBackend) Copy(ctx context.Context, srcRemotePath string, dstRemotePath string) error {
+	srcName, dstName := srcRemotePath, dstRemotePath
+	if b.prefix != "" {
+		srcName = b.prefix + "/" + srcRemotePath
+		dstName = b.prefix + "/" + dstRemotePath
+	}
+
+	containerClient := b.client.ServiceClient().NewContainerClient(b.container)
+	srcURL := containerClient.NewBlobClient(srcName).URL()
+	dstClient := containerClient.NewBlobClient(dstName)
+
+	resp, err := dstClient.StartCopyFromURL(ctx, srcURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to copy in Azure: %w", err)
+	}
+
+	status := resp.CopyStatus
+	for status != nil && *status == blob.CopyStatusTypePending {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(azureCopyPollInterval):
+		}
+
+		props, err := dstClient.GetProperties(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("failed to check Azure copy status: %w", err)
+		}
+		status = props.CopyStatus
+	}
+
+	if status != nil && *status != blob.CopyStatusTypeSuccess {
+		return fmt.Errorf("azure copy finished with status %s", *status)
+	}
+
+	return nil
+}
+
 // GetUsage returns storage usage information
 func (b *AzureBackend) GetUsage(ctx context.Context) (*models.StorageUsage, error) {
 	// Calculate total size of blobs with our prefix
@@ -241,6 +362,22 @@ func (b *AzureBackend) Close() error {
 	return nil
 }
 
+// ClassifyError maps Azure Blob Storage errors to an error code
+func (b *AzureBackend) ClassifyError(err error) string {
+	switch {
+	case bloberror.HasCode(err, bloberror.AuthenticationFailed, bloberror.AuthorizationFailure,
+		bloberror.AuthorizationPermissionMismatch, bloberror.InsufficientAccountPermissions, bloberror.AccountIsDisabled):
+		return ErrorCodeAuth
+	case bloberror.HasCode(err, bloberror.ContainerNotFound, bloberror.BlobNotFound, bloberror.ResourceNotFound):
+		return ErrorCodeNotFound
+	case bloberror.HasCode(err, bloberror.ServerBusy):
+		return ErrorCodeQuota
+	case bloberror.HasCode(err, bloberror.OperationTimedOut):
+		return ErrorCodeNetwork
+	}
+	return ErrorCodeUnknown
+}
+
 // validateAzureAccessTier validates and returns an Azure access tier
 func validateAzureAccessTier(tier string) (*blob.AccessTier, error) {
 	// Azure access tiers (case-insensitive)
@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"io"
 	"log"
-	"os"
 	"time"
 
 	"github.com/kurin/blazer/b2"
@@ -81,24 +80,13 @@ func (b *B2Backend) Test() error {
 
 // Upload uploads a file to B2
 func (b *B2Backend) Upload(ctx context.Context, localPath string, remotePath string, progress ProgressCallback) error {
-	// Open local file
-	file, err := os.Open(localPath)
-	if err != nil {
-		return fmt.Errorf("failed to open file: %w", err)
-	}
-	defer func() {
-		if err := file.Close(); err != nil {
-			log.Printf("Error closing file: %v", err)
-		}
-	}()
-
-	// Get file size
-	stat, err := file.Stat()
-	if err != nil {
-		return fmt.Errorf("failed to stat file: %w", err)
-	}
-	fileSize := stat.Size()
+	return uploadFile(localPath, func(file io.Reader, size int64) error {
+		return b.UploadReader(ctx, file, size, remotePath, progress)
+	})
+}
 
+// UploadReader uploads a stream to B2
+func (b *B2Backend) UploadReader(ctx context.Context, reader io.Reader, size int64, remotePath string, progress ProgressCallback) error {
 	// Add prefix if configured
 	fileName := remotePath
 	if b.prefix != "" {
@@ -107,14 +95,17 @@ func (b *B2Backend) Upload(ctx context.Context, localPath string, remotePath str
 
 	// Wrap with progress reader
 	progressReader := &progressReader{
-		reader:   file,
-		size:     fileSize,
+		reader:   reader,
+		size:     size,
 		callback: progress,
 	}
 
 	// Upload file
 	obj := b.bucket.Object(fileName)
-	writer := obj.NewWriter(ctx)
+	writer := obj.NewWriter(ctx, b2.WithAttrsOption(&b2.Attrs{
+		ContentType: ContentType(remotePath),
+		Info:        uploadMetadata(ctx),
+	}))
 
 	if _, err := io.Copy(writer, progressReader); err != nil {
 		if closeErr := writer.Close(); closeErr != nil {
@@ -130,6 +121,48 @@ func (b *B2Backend) Upload(ctx context.Context, localPath string, remotePath str
 	return nil
 }
 
+// Download downloads a file from B2, using a range read to resume a partial download
+func (b *B2Backend) Download(ctx context.Context, remotePath string, localPath string, progress ProgressCallback) error {
+	open, err := b.opener(ctx, remotePath)
+	if err != nil {
+		return err
+	}
+	return resumableDownload(ctx, localPath, progress, open)
+}
+
+// Open streams a file from B2
+func (b *B2Backend) Open(ctx context.Context, remotePath string) (io.ReadCloser, int64, error) {
+	open, err := b.opener(ctx, remotePath)
+	if err != nil {
+		return nil, 0, err
+	}
+	body, size, _, err := open(0)
+	return body, size, err
+}
+
+// opener looks up a file and returns a reader of it from a byte offset
+func (b *B2Backend) opener(ctx context.Context, remotePath string) (rangeOpener, error) {
+	// Add prefix if configured
+	fileName := remotePath
+	if b.prefix != "" {
+		fileName = b.prefix + "/" + remotePath
+	}
+
+	obj := b.bucket.Object(fileName)
+	attrs, err := obj.Attrs(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download from B2: %w", err)
+	}
+
+	version := attrs.UploadTimestamp.UTC().Format(time.RFC3339Nano) + "/" + attrs.SHA1
+	return func(offset int64) (io.ReadCloser, int64, string, error) {
+		if offset > attrs.Size {
+			return nil, 0, "", fmt.Errorf("offset %d beyond end of file", offset)
+		}
+		return obj.NewRangeReader(ctx, offset, -1), attrs.Size, version, nil
+	}, nil
+}
+
 // List returns all backups with a given prefix
 func (b *B2Backend) List(ctx context.Context, prefix string) ([]BackupInfo, error) {
 	// Combine backend prefix with query prefix
@@ -163,6 +196,7 @@ func (b *B2Backend) List(ctx context.Context, prefix string) ([]BackupInfo, erro
 			Size:         attrs.Size,
 			LastModified: attrs.UploadTimestamp.Format(time.RFC3339),
 			Hash:         attrs.SHA1,
+			Metadata:     attrs.Info,
 		})
 	}
 
@@ -189,6 +223,11 @@ func (b *B2Backend) Delete(ctx context.Context, remotePath string) error {
 	return nil
 }
 
+// Copy is not supported; the B2 client has no server-side copy
+func (b *B2Backend) Copy(ctx context.Context, srcRemotePath string, dstRemotePath string) error {
+	return ErrCopyNotSupported
+}
+
 // GetUsage returns storage usage information
 func (b *B2Backend) GetUsage(ctx context.Context) (*models.StorageUsage, error) {
 	// Calculate total size of objects with our prefix
@@ -217,6 +256,14 @@ func (b *B2Backend) GetUsage(ctx context.Context) (*models.StorageUsage, error)
 	}, nil
 }
 
+// ClassifyError maps B2 errors to an error code
+func (b *B2Backend) ClassifyError(err error) string {
+	if b2.IsNotExist(err) {
+		return ErrorCodeNotFound
+	}
+	return ErrorCodeUnknown
+}
+
 // Close closes the backend connection
 func (b *B2Backend) Close() error {
 	// B2 client doesn't need explicit cleanup
@@ -2,7 +2,13 @@ package backend
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"time"
 
 	"github.com/nsilverman/archivist/internal/models"
 )
@@ -21,12 +27,27 @@ type StorageBackend interface {
 	// Upload archive to backend
 	Upload(ctx context.Context, localPath string, remotePath string, progress ProgressCallback) error
 
+	// Upload a stream to backend. size is -1 when the length isn't known up front,
+	// and is passed on to progress as the total.
+	UploadReader(ctx context.Context, reader io.Reader, size int64, remotePath string, progress ProgressCallback) error
+
+	// Download a backup to a local file
+	Download(ctx context.Context, remotePath string, localPath string, progress ProgressCallback) error
+
+	// Open a backup for reading as a stream, returning its size (0 if unknown).
+	// ctx must stay live until the reader is closed.
+	Open(ctx context.Context, remotePath string) (io.ReadCloser, int64, error)
+
 	// List backups with a given prefix
 	List(ctx context.Context, prefix string) ([]BackupInfo, error)
 
 	// Delete a backup
 	Delete(ctx context.Context, remotePath string) error
 
+	// Copy a backup to another path on the same backend without transferring
+	// its data; returns ErrCopyNotSupported when the backend can't
+	Copy(ctx context.Context, srcRemotePath string, dstRemotePath string) error
+
 	// Get backend storage usage
 	GetUsage(ctx context.Context) (*models.StorageUsage, error)
 
@@ -34,12 +55,17 @@ type StorageBackend interface {
 	Close() error
 }
 
+// ErrCopyNotSupported is returned by Copy when a backend has no server-side copy.
+// Callers should fall back to uploading the file again.
+var ErrCopyNotSupported = errors.New("server-side copy not supported")
+
 // BackupInfo represents information about a stored backup
 type BackupInfo struct {
 	Path         string
 	Size         int64
 	LastModified string
 	Hash         string
+	Metadata     map[string]string // Object metadata stored with WithMetadata, where the backend lists it
 }
 
 // PathResolver resolves paths relative to a root directory
@@ -47,46 +73,146 @@ type PathResolver interface {
 	ResolvePath(path string) string
 }
 
-// Factory creates a backend from a backend configuration
+// Factory creates a backend from a backend configuration.
+// Secret references (${ENV:NAME}, ${FILE:path}) in the config are resolved here,
+// and the returned backend enforces the configured operation timeouts.
 func Factory(backend *models.Backend, pathResolver PathResolver) (StorageBackend, error) {
+	config, err := resolveSecretReferences(backend.Config, pathResolver)
+	if err != nil {
+		return nil, err
+	}
+
+	var b StorageBackend
 	switch backend.Type {
 	case "local":
-		b := &LocalBackend{}
-		if err := b.Initialize(backend.Config, pathResolver); err != nil {
-			return nil, err
-		}
-		return b, nil
+		b = &LocalBackend{}
 	case "s3":
-		b := &S3Backend{}
-		if err := b.Initialize(backend.Config, pathResolver); err != nil {
-			return nil, err
-		}
-		return b, nil
+		b = &S3Backend{}
 	case "gcs":
-		b := &GCSBackend{}
-		if err := b.Initialize(backend.Config, pathResolver); err != nil {
-			return nil, err
-		}
-		return b, nil
+		b = &GCSBackend{}
 	case "gdrive":
-		b := &GDriveBackend{}
-		if err := b.Initialize(backend.Config, pathResolver); err != nil {
-			return nil, err
-		}
-		return b, nil
+		b = &GDriveBackend{}
 	case "azure":
-		b := &AzureBackend{}
-		if err := b.Initialize(backend.Config, pathResolver); err != nil {
-			return nil, err
-		}
-		return b, nil
+		b = &AzureBackend{}
 	case "b2":
-		b := &B2Backend{}
-		if err := b.Initialize(backend.Config, pathResolver); err != nil {
-			return nil, err
-		}
-		return b, nil
+		b = &B2Backend{}
+	case "memory":
+		b = &MemoryBackend{}
 	default:
 		return nil, fmt.Errorf("unknown backend type: %s", backend.Type)
 	}
+
+	if err := b.Initialize(config, pathResolver); err != nil {
+		return nil, err
+	}
+	b, err = withTimeouts(b, config)
+	if err != nil {
+		return nil, err
+	}
+	if backend.Immutable {
+		b = &immutableBackend{StorageBackend: b, name: backend.Name}
+	}
+	return withListCache(b, backend, config)
+}
+
+// uploadFile opens a local file and hands it to upload along with its size,
+// so backends can implement Upload in terms of UploadReader
+func uploadFile(localPath string, upload func(file io.Reader, size int64) error) error {
+	file, err := os.Open(localPath)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+	defer func() {
+		if err := file.Close(); err != nil {
+			log.Printf("Error closing file: %v", err)
+		}
+	}()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat file: %w", err)
+	}
+
+	return upload(file, stat.Size())
+}
+
+// configBool reads a boolean config value, accepting both JSON booleans and
+// form-submitted strings. Returns def when the key is missing or unparseable.
+func configBool(cfg map[string]interface{}, key string, def bool) bool {
+	switch v := cfg[key].(type) {
+	case bool:
+		return v
+	case string:
+		if parsed, err := strconv.ParseBool(v); err == nil {
+			return parsed
+		}
+	}
+	return def
+}
+
+// configFloat reads a non-negative number from the config, accepting both
+// JSON numbers and form-submitted strings. Returns def when the key is missing.
+func configFloat(cfg map[string]interface{}, key string, def float64) (float64, error) {
+	var value float64
+	switch v := cfg[key].(type) {
+	case nil:
+		return def, nil
+	case float64:
+		value = v
+	case int:
+		value = float64(v)
+	case string:
+		if v == "" {
+			return def, nil
+		}
+		parsed, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return 0, fmt.Errorf("config '%s' must be a number: %w", key, err)
+		}
+		value = parsed
+	default:
+		return 0, fmt.Errorf("config '%s' must be a number", key)
+	}
+
+	if value < 0 {
+		return 0, fmt.Errorf("config '%s' cannot be negative", key)
+	}
+	return value, nil
+}
+
+// configSeconds reads a duration in seconds from the config.
+// Returns def when the key is missing.
+func configSeconds(cfg map[string]interface{}, key string, def time.Duration) (time.Duration, error) {
+	seconds, err := configFloat(cfg, key, def.Seconds())
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+// configInt reads an integer from the config, accepting both JSON numbers
+// and form-submitted strings. Returns def when the key is missing.
+func configInt(cfg map[string]interface{}, key string, def int64) (int64, error) {
+	switch v := cfg[key].(type) {
+	case nil:
+		return def, nil
+	case float64:
+		if v != float64(int64(v)) {
+			return 0, fmt.Errorf("config '%s' must be a whole number", key)
+		}
+		return int64(v), nil
+	case int:
+		return int64(v), nil
+	case string:
+		if v == "" {
+			return def, nil
+		}
+		parsed, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("config '%s' must be a whole number: %w", key, err)
+		}
+		return parsed, nil
+	default:
+		return 0, fmt.Errorf("config '%s' must be a whole number", key)
+	}
 }
@@ -0,0 +1,33 @@
+package backend
+
+import (
+	"mime"
+	"path/filepath"
+	"strings"
+)
+
+// archiveContentTypes covers archive and compression extensions that the
+// system MIME table often lacks or maps inconsistently
+var archiveContentTypes = map[string]string{
+	".gz":  "application/gzip",
+	".tgz": "application/gzip",
+	".tar": "application/x-tar",
+	".zip": "application/zip",
+	".bz2": "application/x-bzip2",
+	".xz":  "application/x-xz",
+	".zst": "application/zstd",
+	".7z":  "application/x-7z-compressed",
+}
+
+// ContentType returns the MIME type to store with an object, based on its name.
+// Compressed names use the type of the outer layer, so "x.tar.gz" is application/gzip.
+func ContentType(remotePath string) string {
+	ext := strings.ToLower(filepath.Ext(remotePath))
+	if contentType, ok := archiveContentTypes[ext]; ok {
+		return contentType
+	}
+	if contentType := mime.TypeByExtension(ext); ext != "" && contentType != "" {
+		return contentType
+	}
+	return "application/octet-stream"
+}
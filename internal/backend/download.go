@@ -0,0 +1,239 @@
+package backend
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"lukechampine.com/blake3"
+)
+
+// PartialSuffix is appended to a download's local path until it completes
+const PartialSuffix = ".partial"
+
+// partialVersionSuffix is appended to a partial download's path for the file
+// recording which version of the object it holds
+const partialVersionSuffix = ".version"
+
+// rangeOpener opens a backup for reading starting at offset. It returns the
+// reader, the total size of the backup (0 if unknown) and its version: a value
+// that changes whenever the object is replaced, such as its ETag ("" if unknown).
+type rangeOpener func(offset int64) (io.ReadCloser, int64, string, error)
+
+// resumableDownload streams a backup into localPath. Data is written to
+// localPath.partial first; if that file exists from an interrupted attempt the
+// download continues from its size instead of starting over, as long as the
+// object is the same version the partial file was started from. A replaced
+// object, or one whose version is unknown, is downloaded from the start.
+func resumableDownload(ctx context.Context, localPath string, progress ProgressCallback, open rangeOpener) error {
+	if err := os.MkdirAll(filepath.Dir(localPath), 0755); err != nil {
+		return fmt.Errorf("failed to create destination directory: %w", err)
+	}
+
+	partialPath := localPath + PartialSuffix
+	versionPath := partialPath + partialVersionSuffix
+	var offset int64
+	var partialVersion string
+	if info, err := os.Stat(partialPath); err == nil {
+		offset = info.Size()
+		if data, err := os.ReadFile(versionPath); err == nil {
+			partialVersion = string(data)
+		}
+	}
+
+	body, total, version, err := open(offset)
+	if offset > 0 {
+		var reason error
+		switch {
+		case err != nil:
+			reason = err // the partial file may be stale or already complete
+		case partialVersion == "" || version != partialVersion:
+			reason = fmt.Errorf("the object changed since the partial download started")
+		case total > 0 && offset > total:
+			reason = fmt.Errorf("the partial download is larger than the object")
+		}
+		if reason != nil {
+			if body != nil {
+				if err := body.Close(); err != nil {
+					log.Printf("Error closing download stream: %v", err)
+				}
+			}
+			log.Printf("Cannot resume download at byte %d, restarting: %v", offset, reason)
+			offset = 0
+			body, total, version, err = open(0)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := body.Close(); err != nil {
+			log.Printf("Error closing download stream: %v", err)
+		}
+	}()
+
+	if offset == 0 {
+		if err := writePartialVersion(versionPath, version); err != nil {
+			return err
+		}
+	}
+
+	flags := os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	if offset == 0 {
+		flags = os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	}
+	dst, err := os.OpenFile(partialPath, flags, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open destination file: %w", err)
+	}
+
+	reader := &progressReader{
+		reader:   &contextReader{ctx: ctx, reader: body},
+		size:     total,
+		read:     offset,
+		callback: progress,
+	}
+
+	// On failure the partial file is kept so the next attempt can resume
+	_, copyErr := io.Copy(dst, reader)
+	if closeErr := dst.Close(); copyErr == nil {
+		copyErr = closeErr
+	}
+	if copyErr != nil {
+		return fmt.Errorf("failed to write download: %w", copyErr)
+	}
+
+	if err := os.Rename(partialPath, localPath); err != nil {
+		return fmt.Errorf("failed to finalize download: %w", err)
+	}
+	if err := os.Remove(versionPath); err != nil && !os.IsNotExist(err) {
+		log.Printf("Warning: failed to remove download version file: %v", err)
+	}
+
+	return nil
+}
+
+// DownloadFiles returns the files a download to localPath may leave behind:
+// the file itself, and the partial file and its version record until it completes
+func DownloadFiles(localPath string) []string {
+	partialPath := localPath + PartialSuffix
+	return []string{localPath, partialPath, partialPath + partialVersionSuffix}
+}
+
+// writePartialVersion records the version a new partial download is of, or
+// removes the record when the version is unknown, so it can't be resumed
+func writePartialVersion(versionPath, version string) error {
+	if version == "" {
+		if err := os.Remove(versionPath); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove download version file: %w", err)
+		}
+		return nil
+	}
+	if err := os.WriteFile(versionPath, []byte(version), 0644); err != nil {
+		return fmt.Errorf("failed to write download version file: %w", err)
+	}
+	return nil
+}
+
+// contextReader stops reading once its context is cancelled
+type contextReader struct {
+	ctx    context.Context
+	reader io.Reader
+}
+
+func (cr *contextReader) Read(p []byte) (int, error) {
+	if err := cr.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return cr.reader.Read(p)
+}
+
+// ErrHashMismatch is returned when a file doesn't match its expected hash
+var ErrHashMismatch = errors.New("hash mismatch")
+
+// ErrUnverifiable is returned when a file can't be checked because the expected
+// hash is empty or in a format that isn't recognized
+var ErrUnverifiable = errors.New("hash cannot be verified")
+
+// DownloadVerified downloads a backup and checks it against expectedHash.
+// On mismatch the local file is removed so a retry starts from scratch. If the
+// hash can't be checked the file is kept and an ErrUnverifiable error returned,
+// so callers decide whether an unverified download will do.
+func DownloadVerified(ctx context.Context, b StorageBackend, remotePath, localPath, expectedHash string, progress ProgressCallback) error {
+	if err := b.Download(ctx, remotePath, localPath, progress); err != nil {
+		return err
+	}
+
+	if err := VerifyFileHash(localPath, expectedHash); err != nil {
+		if errors.Is(err, ErrUnverifiable) {
+			return err
+		}
+		if rmErr := os.Remove(localPath); rmErr != nil {
+			log.Printf("Error removing corrupt download: %v", rmErr)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// VerifyFileHash checks a file against a hash as reported by a backend or
+// recorded for an archive. Accepts "algo:hex" (sha256, sha512, blake3, sha1, md5) or bare hex,
+// in which case the algorithm is inferred from the length. An empty hash, or
+// one in an unrecognized format such as a multipart ETag, gives ErrUnverifiable.
+func VerifyFileHash(path, expected string) error {
+	algo, want := "", strings.ToLower(expected)
+	if i := strings.Index(want, ":"); i >= 0 {
+		algo, want = want[:i], want[i+1:]
+	}
+	if _, err := hex.DecodeString(want); err != nil || want == "" {
+		return fmt.Errorf("%w: %q is not a recognized hash", ErrUnverifiable, expected)
+	}
+
+	var h hash.Hash
+	switch {
+	case algo == "sha256" || (algo == "" && len(want) == 64):
+		h = sha256.New()
+	case algo == "sha512" || (algo == "" && len(want) == 128):
+		h = sha512.New()
+	case algo == "blake3":
+		h = blake3.New(32, nil)
+	case algo == "sha1" || (algo == "" && len(want) == 40):
+		h = sha1.New()
+	case algo == "md5" || (algo == "" && len(want) == 32):
+		h = md5.New()
+	default:
+		return fmt.Errorf("%w: unsupported hash %q", ErrUnverifiable, expected)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open file for verification: %w", err)
+	}
+	defer func() {
+		if err := file.Close(); err != nil {
+			log.Printf("Error closing file: %v", err)
+		}
+	}()
+
+	if _, err := io.Copy(h, file); err != nil {
+		return fmt.Errorf("failed to hash file: %w", err)
+	}
+
+	if got := hex.EncodeToString(h.Sum(nil)); got != want {
+		return fmt.Errorf("%w for %s: expected %s, got %s", ErrHashMismatch, filepath.Base(path), want, got)
+	}
+
+	return nil
+}
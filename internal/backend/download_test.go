@@ -0,0 +1,154 @@
+package backend
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"lukechampine.com/blake3"
+)
+
+// fakeObject serves data from any offset as the given version, recording
+// the offsets it was opened at
+type fakeObject struct {
+	data    []byte
+	version string
+	opened  []int64
+}
+
+func (o *fakeObject) open(offset int64) (io.ReadCloser, int64, string, error) {
+	o.opened = append(o.opened, offset)
+	if offset > int64(len(o.data)) {
+		return nil, 0, "", fmt.Errorf("offset %d beyond end of backup", offset)
+	}
+	return io.NopCloser(bytes.NewReader(o.data[offset:])), int64(len(o.data)), o.version, nil
+}
+
+func TestResumableDownload(t *testing.T) {
+	data := []byte("0123456789abcdefghij")
+
+	tests := []struct {
+		name           string
+		partial        []byte // left by an earlier attempt; nil for none
+		partialVersion string // recorded for the partial file; "" for no record
+		version        string // of the object now
+		wantOpened     []int64
+	}{
+		{name: "fresh download", version: "v1", wantOpened: []int64{0}},
+		{name: "resumes the same version", partial: data[:8], partialVersion: "v1", version: "v1", wantOpened: []int64{8}},
+		{name: "restarts a replaced object", partial: []byte("stale..."), partialVersion: "v1", version: "v2", wantOpened: []int64{8, 0}},
+		{name: "restarts without a version record", partial: data[:8], version: "v1", wantOpened: []int64{8, 0}},
+		{name: "restarts an unknown version", partial: data[:8], partialVersion: "v1", version: "", wantOpened: []int64{8, 0}},
+		{name: "restarts a partial larger than the object", partial: append(append([]byte(nil), data...), "extra"...), partialVersion: "v1", version: "v1", wantOpened: []int64{25, 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			localPath := filepath.Join(t.TempDir(), "archive.tar.gz")
+			files := DownloadFiles(localPath)
+			if tt.partial != nil {
+				if err := os.WriteFile(files[1], tt.partial, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.partialVersion != "" {
+				if err := os.WriteFile(files[2], []byte(tt.partialVersion), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			object := &fakeObject{data: data, version: tt.version}
+			if err := resumableDownload(context.Background(), localPath, nil, object.open); err != nil {
+				t.Fatalf("resumableDownload: %v", err)
+			}
+
+			got, err := os.ReadFile(localPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, data) {
+				t.Errorf("downloaded %q, want %q", got, data)
+			}
+			if fmt.Sprint(object.opened) != fmt.Sprint(tt.wantOpened) {
+				t.Errorf("opened at offsets %v, want %v", object.opened, tt.wantOpened)
+			}
+			for _, leftover := range files[1:] {
+				if _, err := os.Stat(leftover); !os.IsNotExist(err) {
+					t.Errorf("%s left behind after the download completed", filepath.Base(leftover))
+				}
+			}
+		})
+	}
+}
+
+func TestResumableDownloadKeepsVersionOfInterruptedAttempt(t *testing.T) {
+	localPath := filepath.Join(t.TempDir(), "archive.tar.gz")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	object := &fakeObject{data: []byte("data"), version: "v1"}
+	if err := resumableDownload(ctx, localPath, nil, object.open); err == nil {
+		t.Fatal("download with a cancelled context succeeded")
+	}
+	version, err := os.ReadFile(DownloadFiles(localPath)[2])
+	if err != nil {
+		t.Fatalf("no version record for the partial download: %v", err)
+	}
+	if string(version) != "v1" {
+		t.Errorf("recorded version %q, want v1", version)
+	}
+}
+
+func TestVerifyFileHash(t *testing.T) {
+	data := []byte("archive contents")
+	path := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	sha := sha256.Sum256(data)
+	sum := md5.Sum(data)
+	shaHex, md5Hex := hex.EncodeToString(sha[:]), hex.EncodeToString(sum[:])
+	sha512Sum, blake3Sum := sha512.Sum512(data), blake3.Sum256(data)
+	sha512Hex, blake3Hex := hex.EncodeToString(sha512Sum[:]), hex.EncodeToString(blake3Sum[:])
+
+	tests := []struct {
+		name     string
+		expected string
+		wantErr  error // nil for a match
+	}{
+		{"bare sha256", shaHex, nil},
+		{"prefixed sha256", "sha256:" + shaHex, nil},
+		{"upper case", "SHA256:" + string(bytes.ToUpper([]byte(shaHex))), nil},
+		{"bare md5", md5Hex, nil},
+		{"prefixed sha512", "sha512:" + sha512Hex, nil},
+		{"bare sha512", sha512Hex, nil},
+		{"prefixed blake3", "blake3:" + blake3Hex, nil},
+		{"blake3 mismatch", "blake3:" + shaHex, ErrHashMismatch},
+		{"sha256 mismatch", "sha256:" + md5Hex + md5Hex, ErrHashMismatch},
+		{"md5 mismatch", "00000000000000000000000000000000", ErrHashMismatch},
+		{"empty", "", ErrUnverifiable},
+		{"multipart etag", md5Hex + "-3", ErrUnverifiable},
+		{"unknown algorithm", "crc32:1a2b3c4d", ErrUnverifiable},
+		{"unknown length", "abcdef", ErrUnverifiable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := VerifyFileHash(path, tt.expected)
+			if tt.wantErr == nil && err != nil {
+				t.Errorf("VerifyFileHash: %v", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("VerifyFileHash = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
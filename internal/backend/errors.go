@@ -0,0 +1,86 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"syscall"
+)
+
+// Error codes used to classify backend failures
+const (
+	ErrorCodeAuth     = "auth"
+	ErrorCodeNotFound = "not_found"
+	ErrorCodeNetwork  = "network"
+	ErrorCodeQuota    = "quota"
+	ErrorCodeUnknown  = "unknown"
+)
+
+// ErrorClassifier is implemented by backends that can map their SDK errors to an error code
+type ErrorClassifier interface {
+	ClassifyError(err error) string
+}
+
+// ClassifyError maps an error returned by a backend to an error code.
+// The backend's own classifier is consulted first, then generic rules are applied.
+func ClassifyError(b StorageBackend, err error) string {
+	if err == nil {
+		return ""
+	}
+
+	if classifier, ok := b.(ErrorClassifier); ok {
+		if code := classifier.ClassifyError(err); code != "" && code != ErrorCodeUnknown {
+			return code
+		}
+	}
+
+	return classifyGenericError(err)
+}
+
+// classifyGenericError applies SDK-independent classification rules
+func classifyGenericError(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return ErrorCodeNetwork
+	}
+
+	// Checked before net.Error, which syscall.Errno also satisfies
+	if errors.Is(err, os.ErrNotExist) {
+		return ErrorCodeNotFound
+	}
+	if errors.Is(err, os.ErrPermission) {
+		return ErrorCodeAuth
+	}
+	if errors.Is(err, syscall.ENOSPC) || errors.Is(err, syscall.EDQUOT) {
+		return ErrorCodeQuota
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return ErrorCodeNetwork
+	}
+
+	return ErrorCodeUnknown
+}
+
+// classifyHTTPStatus maps an HTTP status code returned by a provider to an error code
+func classifyHTTPStatus(status int) string {
+	switch {
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return ErrorCodeAuth
+	case status == http.StatusNotFound:
+		return ErrorCodeNotFound
+	case status == http.StatusTooManyRequests || status == http.StatusInsufficientStorage:
+		return ErrorCodeQuota
+	case status == http.StatusRequestTimeout || status == http.StatusBadGateway ||
+		status == http.StatusServiceUnavailable || status == http.StatusGatewayTimeout:
+		return ErrorCodeNetwork
+	default:
+		return ErrorCodeUnknown
+	}
+}
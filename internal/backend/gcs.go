@@ -2,15 +2,17 @@ package backend
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log"
-	"os"
+	"strconv"
 	"strings"
 	"time"
 
 	"cloud.google.com/go/storage"
 	"github.com/nsilverman/archivist/internal/models"
+	"google.golang.org/api/googleapi"
 	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
 )
@@ -91,24 +93,13 @@ func (b *GCSBackend) Test() error {
 
 // Upload uploads a file to GCS
 func (b *GCSBackend) Upload(ctx context.Context, localPath string, remotePath string, progress ProgressCallback) error {
-	// Open local file
-	file, err := os.Open(localPath)
-	if err != nil {
-		return fmt.Errorf("failed to open file: %w", err)
-	}
-	defer func() {
-		if err := file.Close(); err != nil {
-			log.Printf("Error closing file: %v", err)
-		}
-	}()
-
-	// Get file size
-	stat, err := file.Stat()
-	if err != nil {
-		return fmt.Errorf("failed to stat file: %w", err)
-	}
-	fileSize := stat.Size()
+	return uploadFile(localPath, func(file io.Reader, size int64) error {
+		return b.UploadReader(ctx, file, size, remotePath, progress)
+	})
+}
 
+// UploadReader uploads a stream to GCS
+func (b *GCSBackend) UploadReader(ctx context.Context, reader io.Reader, size int64, remotePath string, progress ProgressCallback) error {
 	// Add prefix if configured
 	key := remotePath
 	if b.prefix != "" {
@@ -121,11 +112,13 @@ func (b *GCSBackend) Upload(ctx context.Context, localPath string, remotePath st
 
 	// Set storage class if configured
 	writer.StorageClass = b.storageTier
+	writer.ContentType = ContentType(remotePath)
+	writer.Metadata = uploadMetadata(ctx)
 
 	// Wrap with progress reader
 	progressReader := &progressReader{
-		reader:   file,
-		size:     fileSize,
+		reader:   reader,
+		size:     size,
 		callback: progress,
 	}
 
@@ -145,6 +138,34 @@ func (b *GCSBackend) Upload(ctx context.Context, localPath string, remotePath st
 	return nil
 }
 
+// Download downloads a file from GCS, using a range read to resume a partial download
+func (b *GCSBackend) Download(ctx context.Context, remotePath string, localPath string, progress ProgressCallback) error {
+	return resumableDownload(ctx, localPath, progress, b.opener(ctx, remotePath))
+}
+
+// Open streams a file from GCS
+func (b *GCSBackend) Open(ctx context.Context, remotePath string) (io.ReadCloser, int64, error) {
+	body, size, _, err := b.opener(ctx, remotePath)(0)
+	return body, size, err
+}
+
+// opener reads a file from a byte offset
+func (b *GCSBackend) opener(ctx context.Context, remotePath string) rangeOpener {
+	// Add prefix if configured
+	key := remotePath
+	if b.prefix != "" {
+		key = b.prefix + "/" + remotePath
+	}
+
+	return func(offset int64) (io.ReadCloser, int64, string, error) {
+		reader, err := b.client.Bucket(b.bucket).Object(key).NewRangeReader(ctx, offset, -1)
+		if err != nil {
+			return nil, 0, "", fmt.Errorf("failed to download from GCS: %w", err)
+		}
+		return reader, reader.Attrs.Size, strconv.FormatInt(reader.Attrs.Generation, 10), nil
+	}
+}
+
 // List returns all backups with a given prefix
 func (b *GCSBackend) List(ctx context.Context, prefix string) ([]BackupInfo, error) {
 	// Combine backend prefix with query prefix
@@ -182,6 +203,7 @@ func (b *GCSBackend) List(ctx context.Context, prefix string) ([]BackupInfo, err
 			Size:         attrs.Size,
 			LastModified: attrs.Updated.Format(time.RFC3339),
 			Hash:         fmt.Sprintf("md5:%x", attrs.MD5),
+			Metadata:     attrs.Metadata,
 		})
 	}
 
@@ -204,6 +226,22 @@ func (b *GCSBackend) Delete(ctx context.Context, remotePath string) error {
 	return nil
 }
 
+// Copy copies a backup within the bucket without downloading it
+func (b *GCSBackend) Copy(ctx context.Context, srcRemotePath string, dstRemotePath string) error {
+	srcKey, dstKey := srcRemotePath, dstRemotePath
+	if b.prefix != "" {
+		srcKey = b.prefix + "/" + srcRemotePath
+		dstKey = b.prefix + "/" + dstRemotePath
+	}
+
+	bucket := b.client.Bucket(b.bucket)
+	if _, err := bucket.Object(dstKey).CopierFrom(bucket.Object(srcKey)).Run(ctx); err != nil {
+		return fmt.Errorf("failed to copy in GCS: %w", err)
+	}
+
+	return nil
+}
+
 // GetUsage returns storage usage information
 func (b *GCSBackend) GetUsage(ctx context.Context) (*models.StorageUsage, error) {
 	// Calculate total size of objects with our prefix
@@ -240,6 +278,31 @@ func (b *GCSBackend) Close() error {
 	return nil
 }
 
+// ClassifyError maps GCS errors to an error code
+func (b *GCSBackend) ClassifyError(err error) string {
+	if errors.Is(err, storage.ErrBucketNotExist) || errors.Is(err, storage.ErrObjectNotExist) {
+		return ErrorCodeNotFound
+	}
+	return classifyGoogleAPIError(err)
+}
+
+// classifyGoogleAPIError maps a googleapi.Error to an error code (shared by GCS and Drive)
+func classifyGoogleAPIError(err error) string {
+	var apiErr *googleapi.Error
+	if !errors.As(err, &apiErr) {
+		return ErrorCodeUnknown
+	}
+
+	for _, item := range apiErr.Errors {
+		switch item.Reason {
+		case "storageQuotaExceeded", "quotaExceeded", "rateLimitExceeded", "userRateLimitExceeded":
+			return ErrorCodeQuota
+		}
+	}
+
+	return classifyHTTPStatus(apiErr.Code)
+}
+
 // validateGCSStorageClass validates and returns a GCS storage class
 func validateGCSStorageClass(tier string) (string, error) {
 	// GCS storage classes (case-insensitive)
@@ -3,20 +3,32 @@ package backend
 import (
 	"context"
 	"fmt"
+	"io"
 	"log"
-	"os"
+	"net/http"
 	"path/filepath"
 	"time"
 
 	"github.com/nsilverman/archivist/internal/models"
 	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/googleapi"
 	"google.golang.org/api/option"
 )
 
+const (
+	gdriveMinChunkSizeMB   = 1
+	gdriveMaxChunkSizeMB   = 1024
+	gdriveBytesPerMegabyte = 1024 * 1024
+)
+
 // GDriveBackend stores backups on Google Drive
 type GDriveBackend struct {
-	service  *drive.Service
-	folderID string
+	service           *drive.Service
+	folderID          string
+	chunkSize         int           // bytes per resumable upload request
+	chunkRetry        time.Duration // how long a failed chunk is retried; zero uses the client default
+	supportsAllDrives bool          // the folder may be on a shared drive
+	sharedDriveID     string        // shared drive holding the folder; empty for My Drive
 }
 
 // Initialize sets up the Google Drive backend
@@ -42,6 +54,24 @@ func (b *GDriveBackend) Initialize(cfg map[string]interface{}, pathResolver Path
 	}
 	b.service = service
 
+	// Uploads are sent as a resumable session in chunks; a chunk that fails
+	// with a transient or rate-limit error is retried without restarting the upload
+	chunkSizeMB, err := configInt(cfg, "chunk_size", googleapi.DefaultUploadChunkSize/gdriveBytesPerMegabyte)
+	if err != nil {
+		return err
+	}
+	if chunkSizeMB < gdriveMinChunkSizeMB || chunkSizeMB > gdriveMaxChunkSizeMB {
+		return fmt.Errorf("gdrive 'chunk_size' must be between %d and %d MB", gdriveMinChunkSizeMB, gdriveMaxChunkSizeMB)
+	}
+	b.chunkSize = int(chunkSizeMB * gdriveBytesPerMegabyte)
+
+	if b.chunkRetry, err = configSeconds(cfg, "chunk_retry_timeout", 0); err != nil {
+		return err
+	}
+	// A shared drive needs every call to opt in to shared drives, and lists scoped to it
+	b.sharedDriveID, _ = cfg["shared_drive_id"].(string)
+	b.supportsAllDrives = b.sharedDriveID != "" || configBool(cfg, "supports_all_drives", false)
+
 	// Get or create folder
 	folderName := "archivist-backups"
 	if name, ok := cfg["folder_name"].(string); ok && name != "" {
@@ -53,7 +83,7 @@ func (b *GDriveBackend) Initialize(cfg map[string]interface{}, pathResolver Path
 		b.folderID = folderID
 	} else {
 		// Search for folder or create it
-		folderID, err := b.findOrCreateFolder(ctx, folderName)
+		folderID, err := b.findOrCreateFolder(ctx, folderName, configBool(cfg, "create_if_missing", true))
 		if err != nil {
 			return fmt.Errorf("failed to get/create folder: %w", err)
 		}
@@ -63,11 +93,11 @@ func (b *GDriveBackend) Initialize(cfg map[string]interface{}, pathResolver Path
 	return nil
 }
 
-// findOrCreateFolder searches for a folder by name or creates it
-func (b *GDriveBackend) findOrCreateFolder(ctx context.Context, name string) (string, error) {
+// findOrCreateFolder searches for a folder by name or, if create is set, creates it
+func (b *GDriveBackend) findOrCreateFolder(ctx context.Context, name string, create bool) (string, error) {
 	// Search for existing folder
 	query := fmt.Sprintf("name='%s' and mimeType='application/vnd.google-apps.folder' and trashed=false", name)
-	r, err := b.service.Files.List().Q(query).Spaces("drive").Fields("files(id, name)").Context(ctx).Do()
+	r, err := b.listFiles().Q(query).Fields("files(id, name)").Context(ctx).Do()
 	if err != nil {
 		return "", fmt.Errorf("failed to search for folder: %w", err)
 	}
@@ -77,12 +107,18 @@ func (b *GDriveBackend) findOrCreateFolder(ctx context.Context, name string) (st
 	}
 
 	// Create folder if it doesn't exist
+	if !create {
+		return "", fmt.Errorf("folder %s does not exist and create_if_missing is false", name)
+	}
 	folder := &drive.File{
 		Name:     name,
 		MimeType: "application/vnd.google-apps.folder",
 	}
+	if b.sharedDriveID != "" {
+		folder.Parents = []string{b.sharedDriveID}
+	}
 
-	created, err := b.service.Files.Create(folder).Fields("id").Context(ctx).Do()
+	created, err := b.service.Files.Create(folder).SupportsAllDrives(b.supportsAllDrives).Fields("id").Context(ctx).Do()
 	if err != nil {
 		return "", fmt.Errorf("failed to create folder: %w", err)
 	}
@@ -90,13 +126,27 @@ func (b *GDriveBackend) findOrCreateFolder(ctx context.Context, name string) (st
 	return created.Id, nil
 }
 
+// listFiles starts a Files.List call that can see the backend's folder: scoped
+// to its shared drive if one is configured, or across all drives when the
+// folder may be on one
+func (b *GDriveBackend) listFiles() *drive.FilesListCall {
+	call := b.service.Files.List().Spaces("drive")
+	switch {
+	case b.sharedDriveID != "":
+		call = call.Corpora("drive").DriveId(b.sharedDriveID).IncludeItemsFromAllDrives(true).SupportsAllDrives(true)
+	case b.supportsAllDrives:
+		call = call.Corpora("allDrives").IncludeItemsFromAllDrives(true).SupportsAllDrives(true)
+	}
+	return call
+}
+
 // Test checks if the backend is accessible
 func (b *GDriveBackend) Test() error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
 	// Try to get folder metadata
-	_, err := b.service.Files.Get(b.folderID).Fields("id, name").Context(ctx).Do()
+	_, err := b.service.Files.Get(b.folderID).SupportsAllDrives(b.supportsAllDrives).Fields("id, name").Context(ctx).Do()
 	if err != nil {
 		return fmt.Errorf("cannot access folder: %w", err)
 	}
@@ -106,46 +156,49 @@ func (b *GDriveBackend) Test() error {
 
 // Upload uploads a file to Google Drive
 func (b *GDriveBackend) Upload(ctx context.Context, localPath string, remotePath string, progress ProgressCallback) error {
-	// Open local file
-	file, err := os.Open(localPath)
-	if err != nil {
-		return fmt.Errorf("failed to open file: %w", err)
-	}
-	defer func() {
-		if err := file.Close(); err != nil {
-			log.Printf("Error closing file: %v", err)
-		}
-	}()
-
-	// Get file size
-	stat, err := file.Stat()
-	if err != nil {
-		return fmt.Errorf("failed to stat file: %w", err)
-	}
-	fileSize := stat.Size()
+	return uploadFile(localPath, func(file io.Reader, size int64) error {
+		return b.UploadReader(ctx, file, size, remotePath, progress)
+	})
+}
 
+// UploadReader uploads a stream to Google Drive
+func (b *GDriveBackend) UploadReader(ctx context.Context, reader io.Reader, size int64, remotePath string, progress ProgressCallback) error {
 	// Check if file already exists (for updates)
 	fileName := filepath.Base(remotePath)
 	existingFileID, _ := b.findFileInFolder(ctx, fileName)
 
 	// Wrap with progress reader
 	progressReader := &progressReader{
-		reader:   file,
-		size:     fileSize,
+		reader:   reader,
+		size:     size,
 		callback: progress,
 	}
 
 	driveFile := &drive.File{
-		Name:    fileName,
-		Parents: []string{b.folderID},
+		Name:          fileName,
+		Parents:       []string{b.folderID},
+		AppProperties: uploadMetadata(ctx),
 	}
 
+	// Drive sniffs the content type unless one is given, which can misread archives
+	mediaOptions := []googleapi.MediaOption{
+		googleapi.ChunkSize(b.chunkSize),
+		googleapi.ContentType(ContentType(remotePath)),
+	}
+	if b.chunkRetry > 0 {
+		mediaOptions = append(mediaOptions, googleapi.ChunkRetryDeadline(b.chunkRetry))
+	}
+
+	var err error
 	if existingFileID != "" {
-		// Update existing file
-		_, err = b.service.Files.Update(existingFileID, driveFile).Media(progressReader).Context(ctx).Do()
+		// Update existing file; Drive rejects parents in update requests
+		driveFile.Parents = nil
+		_, err = b.service.Files.Update(existingFileID, driveFile).Media(progressReader, mediaOptions...).
+			SupportsAllDrives(b.supportsAllDrives).Context(ctx).Do()
 	} else {
 		// Create new file
-		_, err = b.service.Files.Create(driveFile).Media(progressReader).Context(ctx).Do()
+		_, err = b.service.Files.Create(driveFile).Media(progressReader, mediaOptions...).
+			SupportsAllDrives(b.supportsAllDrives).Context(ctx).Do()
 	}
 
 	if err != nil {
@@ -158,7 +211,7 @@ func (b *GDriveBackend) Upload(ctx context.Context, localPath string, remotePath
 // findFileInFolder searches for a file by name in the folder
 func (b *GDriveBackend) findFileInFolder(ctx context.Context, fileName string) (string, error) {
 	query := fmt.Sprintf("name='%s' and '%s' in parents and trashed=false", fileName, b.folderID)
-	r, err := b.service.Files.List().Q(query).Spaces("drive").Fields("files(id)").Context(ctx).Do()
+	r, err := b.listFiles().Q(query).Fields("files(id)").Context(ctx).Do()
 	if err != nil {
 		return "", err
 	}
@@ -170,6 +223,68 @@ func (b *GDriveBackend) findFileInFolder(ctx context.Context, fileName string) (
 	return "", nil
 }
 
+// Download downloads a file from the folder, using a Range header to resume a partial download
+func (b *GDriveBackend) Download(ctx context.Context, remotePath string, localPath string, progress ProgressCallback) error {
+	open, err := b.opener(ctx, remotePath)
+	if err != nil {
+		return err
+	}
+	return resumableDownload(ctx, localPath, progress, open)
+}
+
+// Open streams a file from the folder
+func (b *GDriveBackend) Open(ctx context.Context, remotePath string) (io.ReadCloser, int64, error) {
+	open, err := b.opener(ctx, remotePath)
+	if err != nil {
+		return nil, 0, err
+	}
+	body, size, _, err := open(0)
+	return body, size, err
+}
+
+// opener finds a file in the folder and returns a reader of it from a byte offset
+func (b *GDriveBackend) opener(ctx context.Context, remotePath string) (rangeOpener, error) {
+	fileName := filepath.Base(remotePath)
+
+	// Find file ID
+	fileID, err := b.findFileInFolder(ctx, fileName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find file: %w", err)
+	}
+	if fileID == "" {
+		return nil, fmt.Errorf("file not found: %s", remotePath)
+	}
+
+	// Replacing a file's content changes its modification time
+	file, err := b.service.Files.Get(fileID).Fields("modifiedTime").SupportsAllDrives(b.supportsAllDrives).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get file metadata: %w", err)
+	}
+	version := fileID + "/" + file.ModifiedTime
+
+	return func(offset int64) (io.ReadCloser, int64, string, error) {
+		call := b.service.Files.Get(fileID).SupportsAllDrives(b.supportsAllDrives).Context(ctx)
+		if offset > 0 {
+			call.Header().Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		}
+
+		resp, err := call.Download()
+		if err != nil {
+			return nil, 0, "", fmt.Errorf("failed to download from Google Drive: %w", err)
+		}
+
+		// A server that ignores the Range header sends the whole file
+		if offset > 0 && resp.StatusCode != http.StatusPartialContent {
+			if err := resp.Body.Close(); err != nil {
+				log.Printf("Error closing Drive response body: %v", err)
+			}
+			return nil, 0, "", fmt.Errorf("range requests not honored")
+		}
+
+		return resp.Body, offset + resp.ContentLength, version, nil
+	}, nil
+}
+
 // List returns all backups in the folder
 func (b *GDriveBackend) List(ctx context.Context, prefix string) ([]BackupInfo, error) {
 	var backups []BackupInfo
@@ -183,10 +298,9 @@ func (b *GDriveBackend) List(ctx context.Context, prefix string) ([]BackupInfo,
 
 	pageToken := ""
 	for {
-		call := b.service.Files.List().
+		call := b.listFiles().
 			Q(query).
-			Spaces("drive").
-			Fields("nextPageToken, files(id, name, size, modifiedTime)").
+			Fields("nextPageToken, files(id, name, size, modifiedTime, appProperties)").
 			PageSize(100).
 			Context(ctx)
 
@@ -206,6 +320,7 @@ func (b *GDriveBackend) List(ctx context.Context, prefix string) ([]BackupInfo,
 				Size:         file.Size,
 				LastModified: modTime.Format(time.RFC3339),
 				Hash:         file.Md5Checksum,
+				Metadata:     file.AppProperties,
 			})
 		}
 
@@ -232,13 +347,18 @@ func (b *GDriveBackend) Delete(ctx context.Context, remotePath string) error {
 	}
 
 	// Delete file
-	if err := b.service.Files.Delete(fileID).Context(ctx).Do(); err != nil {
+	if err := b.service.Files.Delete(fileID).SupportsAllDrives(b.supportsAllDrives).Context(ctx).Do(); err != nil {
 		return fmt.Errorf("failed to delete from Google Drive: %w", err)
 	}
 
 	return nil
 }
 
+// Copy is not supported; backups are re-uploaded instead
+func (b *GDriveBackend) Copy(ctx context.Context, srcRemotePath string, dstRemotePath string) error {
+	return ErrCopyNotSupported
+}
+
 // GetUsage returns storage usage information
 func (b *GDriveBackend) GetUsage(ctx context.Context) (*models.StorageUsage, error) {
 	// Calculate total size of files in folder
@@ -248,9 +368,8 @@ func (b *GDriveBackend) GetUsage(ctx context.Context) (*models.StorageUsage, err
 	pageToken := ""
 
 	for {
-		call := b.service.Files.List().
+		call := b.listFiles().
 			Q(query).
-			Spaces("drive").
 			Fields("nextPageToken, files(size)").
 			PageSize(100).
 			Context(ctx)
@@ -274,6 +393,14 @@ func (b *GDriveBackend) GetUsage(ctx context.Context) (*models.StorageUsage, err
 		}
 	}
 
+	// Shared drives have no quota of their own in the About resource
+	if b.sharedDriveID != "" {
+		return &models.StorageUsage{
+			Used:  totalSize,
+			Total: -1,
+		}, nil
+	}
+
 	// Get account-wide quota
 	about, err := b.service.About.Get().Fields("storageQuota").Context(ctx).Do()
 	if err != nil {
@@ -289,6 +416,11 @@ func (b *GDriveBackend) GetUsage(ctx context.Context) (*models.StorageUsage, err
 	}, nil
 }
 
+// ClassifyError maps Google Drive API errors to an error code
+func (b *GDriveBackend) ClassifyError(err error) string {
+	return classifyGoogleAPIError(err)
+}
+
 // Close closes the backend connection
 func (b *GDriveBackend) Close() error {
 	// Drive service doesn't need explicit cleanup
@@ -0,0 +1,69 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+)
+
+// ErrImmutableBackend is returned when deleting from a backend marked
+// immutable, or overwriting an object already stored on it
+var ErrImmutableBackend = errors.New("backend is immutable")
+
+// immutableBackend refuses deletes and overwrites, so Archivist can add
+// backups to it but never prune or replace them (legal hold, compliance archives)
+type immutableBackend struct {
+	StorageBackend
+	name string
+}
+
+// Upload fails if remotePath is already stored
+func (b *immutableBackend) Upload(ctx context.Context, localPath string, remotePath string, progress ProgressCallback) error {
+	if err := b.refuseOverwrite(ctx, remotePath); err != nil {
+		return err
+	}
+	return b.StorageBackend.Upload(ctx, localPath, remotePath, progress)
+}
+
+// UploadReader fails if remotePath is already stored
+func (b *immutableBackend) UploadReader(ctx context.Context, reader io.Reader, size int64, remotePath string, progress ProgressCallback) error {
+	if err := b.refuseOverwrite(ctx, remotePath); err != nil {
+		return err
+	}
+	return b.StorageBackend.UploadReader(ctx, reader, size, remotePath, progress)
+}
+
+// Copy fails if dstRemotePath is already stored
+func (b *immutableBackend) Copy(ctx context.Context, srcRemotePath string, dstRemotePath string) error {
+	if err := b.refuseOverwrite(ctx, dstRemotePath); err != nil {
+		return err
+	}
+	return b.StorageBackend.Copy(ctx, srcRemotePath, dstRemotePath)
+}
+
+// Delete always fails
+func (b *immutableBackend) Delete(ctx context.Context, remotePath string) error {
+	return fmt.Errorf("cannot delete %s from %s: %w", remotePath, b.name, ErrImmutableBackend)
+}
+
+// ClassifyError delegates to the wrapped backend's classifier
+func (b *immutableBackend) ClassifyError(err error) string {
+	return ClassifyError(b.StorageBackend, err)
+}
+
+// refuseOverwrite returns ErrImmutableBackend if remotePath is already stored.
+// The listing bypasses the list cache, which wraps this backend.
+func (b *immutableBackend) refuseOverwrite(ctx context.Context, remotePath string) error {
+	objects, err := b.StorageBackend.List(ctx, remotePath)
+	if err != nil {
+		return fmt.Errorf("failed to check for an existing %s: %w", remotePath, err)
+	}
+	for _, obj := range objects {
+		if filepath.ToSlash(obj.Path) == remotePath {
+			return fmt.Errorf("cannot overwrite %s on %s: %w", remotePath, b.name, ErrImmutableBackend)
+		}
+	}
+	return nil
+}
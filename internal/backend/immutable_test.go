@@ -0,0 +1,441 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/nsilverman/archivist/internal/models"
+	"google.golang.org/api/googleapi"
+)
+
+// rootResolver resolves paths as given
+type rootResolver struct{}
+
+func (rootResolver) ResolvePath(path string) string { return path }
+
+func TestImmutableBackend(t *testing.T) {
+	ctx := context.Background()
+	localFile := filepath.Join(t.TempDir(), "archive")
+	if err := os.WriteFile(localFile, []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		op      func(b StorageBackend) error
+		wantErr bool
+	}{
+		{"upload a new path", func(b StorageBackend) error { return b.Upload(ctx, localFile, "new.tar.gz", nil) }, false},
+		{"upload over an existing path", func(b StorageBackend) error { return b.Upload(ctx, localFile, "docs/stored.tar.gz", nil) }, true},
+		{"stream a new path", func(b StorageBackend) error {
+			return b.UploadReader(ctx, strings.NewReader("data"), 4, "new.tar.gz", nil)
+		}, false},
+		{"stream over an existing path", func(b StorageBackend) error {
+			return b.UploadReader(ctx, strings.NewReader("data"), 4, "docs/stored.tar.gz", nil)
+		}, true},
+		{"path sharing a prefix", func(b StorageBackend) error { return b.Upload(ctx, localFile, "docs/stored.tar", nil) }, false},
+		{"copy to a new path", func(b StorageBackend) error { return b.Copy(ctx, "docs/stored.tar.gz", "docs/copy.tar.gz") }, false},
+		{"copy over an existing path", func(b StorageBackend) error {
+			return b.Copy(ctx, "docs/stored.tar.gz", "docs/other.tar.gz")
+		}, true},
+		{"delete", func(b StorageBackend) error { return b.Delete(ctx, "docs/stored.tar.gz") }, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storeDir := t.TempDir()
+			for _, name := range []string{"docs/stored.tar.gz", "docs/other.tar.gz"} {
+				path := filepath.Join(storeDir, filepath.FromSlash(name))
+				if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte("stored"), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			b, err := Factory(&models.Backend{
+				ID:        "vault",
+				Name:      "vault",
+				Type:      "local",
+				Immutable: true,
+				Config:    map[string]interface{}{"path": storeDir},
+			}, rootResolver{})
+			if err != nil {
+				t.Fatalf("Factory: %v", err)
+			}
+			defer func() { _ = b.Close() }()
+
+			err = tt.op(b)
+			if tt.wantErr && !errors.Is(err, ErrImmutableBackend) {
+				t.Errorf("got %v, want ErrImmutableBackend", err)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("got %v, want success", err)
+			}
+
+			// Whatever happened, the stored objects are untouched
+			for _, name := range []string{"docs/stored.tar.gz", "docs/other.tar.gz"} {
+				data, err := os.ReadFile(filepath.Join(storeDir, filepath.FromSlash(name)))
+				if err != nil || string(data) != "stored" {
+					t.Errorf("%s changed: %q, %v", name, data, err)
+				}
+			}
+		})
+	}
+}
+
+// dirResolver resolves relative paths against a directory
+type dirResolver string
+
+func (d dirResolver) ResolvePath(path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(string(d), path)
+}
+
+func TestResolveSecretReferences(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "secret"), []byte("from-file\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("ARCHIVIST_TEST_SECRET", "from-env")
+
+	tests := []struct {
+		name    string
+		value   interface{}
+		want    interface{}
+		wantErr string // part of the error; "" for none
+	}{
+		{"environment variable", "${ENV:ARCHIVIST_TEST_SECRET}", "from-env", ""},
+		{"absolute file", "${FILE:" + filepath.Join(dir, "secret") + "}", "from-file", ""},
+		{"relative file", "${FILE:secret}", "from-file", ""},
+		{"surrounding spaces", " ${ENV:ARCHIVIST_TEST_SECRET} ", "from-env", ""},
+		{"plain value", "plain", "plain", ""},
+		{"embedded reference", "prefix-${ENV:ARCHIVIST_TEST_SECRET}", "prefix-${ENV:ARCHIVIST_TEST_SECRET}", ""},
+		{"not a string", 42, 42, ""},
+		{"unset variable", "${ENV:ARCHIVIST_TEST_UNSET}", nil, "unset environment variable: ARCHIVIST_TEST_UNSET"},
+		{"missing file", "${FILE:missing}", nil, "unreadable secret file " + filepath.Join(dir, "missing")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := map[string]interface{}{"secret_key": tt.value}
+			resolved, err := resolveSecretReferences(config, dirResolver(dir))
+			if tt.wantErr != "" {
+				if err == nil {
+					t.Fatalf("resolved to %v, want an error", resolved["secret_key"])
+				}
+				// The error names the config key so the user knows what to fix
+				if !strings.Contains(err.Error(), tt.wantErr) || !strings.Contains(err.Error(), "secret_key") {
+					t.Errorf("error %q, want one naming secret_key and mentioning %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolveSecretReferences: %v", err)
+			}
+			if resolved["secret_key"] != tt.want {
+				t.Errorf("resolved to %v, want %v", resolved["secret_key"], tt.want)
+			}
+			if config["secret_key"] != tt.value {
+				t.Error("the original config was changed")
+			}
+		})
+	}
+}
+
+func TestFactoryResolvesSecrets(t *testing.T) {
+	storeDir := t.TempDir()
+	t.Setenv("ARCHIVIST_TEST_PATH", storeDir)
+	cfg := &models.Backend{
+		ID:     "local",
+		Name:   "local",
+		Type:   "local",
+		Config: map[string]interface{}{"path": "${ENV:ARCHIVIST_TEST_PATH}"},
+	}
+	b, err := Factory(cfg, rootResolver{})
+	if err != nil {
+		t.Fatalf("Factory: %v", err)
+	}
+	defer func() { _ = b.Close() }()
+
+	if err := b.UploadReader(context.Background(), strings.NewReader("data"), 4, "a.txt", nil); err != nil {
+		t.Fatalf("UploadReader: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(storeDir, "a.txt")); err != nil {
+		t.Errorf("upload didn't land in the resolved path: %v", err)
+	}
+	if cfg.Config["path"] != "${ENV:ARCHIVIST_TEST_PATH}" {
+		t.Error("Factory wrote the resolved secret back into the backend config")
+	}
+
+	cfg.Config["path"] = "${ENV:ARCHIVIST_TEST_UNSET}"
+	if _, err := Factory(cfg, rootResolver{}); err == nil {
+		t.Error("Factory succeeded with an unset secret reference")
+	}
+}
+
+func TestClassifyError(t *testing.T) {
+	s3Status := func(status int) error {
+		return &smithyhttp.ResponseError{
+			Response: &smithyhttp.Response{Response: &http.Response{StatusCode: status}},
+			Err:      errors.New("request failed"),
+		}
+	}
+	netErr := &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}
+
+	tests := []struct {
+		name    string
+		backend StorageBackend
+		err     error
+		want    string
+	}{
+		{"s3 access denied", &S3Backend{}, &smithy.GenericAPIError{Code: "AccessDenied"}, ErrorCodeAuth},
+		{"s3 missing key", &S3Backend{}, &smithy.GenericAPIError{Code: "NoSuchKey"}, ErrorCodeNotFound},
+		{"s3 slow down", &S3Backend{}, &smithy.GenericAPIError{Code: "SlowDown"}, ErrorCodeQuota},
+		{"s3 wrapped timeout", &S3Backend{}, fmt.Errorf("upload: %w", &smithy.GenericAPIError{Code: "RequestTimeout"}), ErrorCodeNetwork},
+		{"s3 status only", &S3Backend{}, s3Status(http.StatusForbidden), ErrorCodeAuth},
+		{"s3 unavailable", &S3Backend{}, s3Status(http.StatusServiceUnavailable), ErrorCodeNetwork},
+		{"azure auth", &AzureBackend{}, &azcore.ResponseError{ErrorCode: "AuthenticationFailed", StatusCode: 403}, ErrorCodeAuth},
+		{"azure missing blob", &AzureBackend{}, fmt.Errorf("download: %w", &azcore.ResponseError{ErrorCode: "BlobNotFound", StatusCode: 404}), ErrorCodeNotFound},
+		{"azure busy", &AzureBackend{}, &azcore.ResponseError{ErrorCode: "ServerBusy", StatusCode: 503}, ErrorCodeQuota},
+		{"gcs missing object", &GCSBackend{}, fmt.Errorf("stat: %w", storage.ErrObjectNotExist), ErrorCodeNotFound},
+		{"gcs forbidden", &GCSBackend{}, &googleapi.Error{Code: http.StatusForbidden}, ErrorCodeAuth},
+		{"gcs quota reason", &GCSBackend{}, &googleapi.Error{Code: http.StatusForbidden, Errors: []googleapi.ErrorItem{{Reason: "quotaExceeded"}}}, ErrorCodeQuota},
+		{"drive rate limited", &GDriveBackend{}, &googleapi.Error{Code: http.StatusForbidden, Errors: []googleapi.ErrorItem{{Reason: "userRateLimitExceeded"}}}, ErrorCodeQuota},
+		{"drive missing file", &GDriveBackend{}, &googleapi.Error{Code: http.StatusNotFound}, ErrorCodeNotFound},
+		{"b2 network", &B2Backend{}, fmt.Errorf("upload: %w", netErr), ErrorCodeNetwork},
+		{"local missing", &LocalBackend{}, &fs.PathError{Op: "open", Path: "x", Err: syscall.ENOENT}, ErrorCodeNotFound},
+		{"local permission", &LocalBackend{}, &fs.PathError{Op: "open", Path: "x", Err: syscall.EACCES}, ErrorCodeAuth},
+		{"local disk full", &LocalBackend{}, &fs.PathError{Op: "write", Path: "x", Err: syscall.ENOSPC}, ErrorCodeQuota},
+		{"deadline", &S3Backend{}, fmt.Errorf("list: %w", context.DeadlineExceeded), ErrorCodeNetwork},
+		{"unrecognised", &S3Backend{}, errors.New("boom"), ErrorCodeUnknown},
+		{"no error", &S3Backend{}, nil, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ClassifyError(tt.backend, tt.err); got != tt.want {
+				t.Errorf("ClassifyError() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// blockingBackend is a backend whose provider never answers. Uploads report
+// progress every tick until ticks run out, then hang too.
+type blockingBackend struct {
+	StorageBackend
+	ticks int
+	tick  time.Duration
+}
+
+func (b *blockingBackend) List(ctx context.Context, prefix string) ([]BackupInfo, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (b *blockingBackend) GetUsage(ctx context.Context) (*models.StorageUsage, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (b *blockingBackend) Upload(ctx context.Context, localPath string, remotePath string, progress ProgressCallback) error {
+	for i := 1; i <= b.ticks; i++ {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(b.tick):
+			progress(int64(i), int64(b.ticks))
+		}
+	}
+	if b.ticks > 0 {
+		return nil
+	}
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestTimeoutBackend(t *testing.T) {
+	ctx := context.Background()
+	const limit = 50 * time.Millisecond
+
+	// finishes fails the test if op hangs well past the configured limits
+	finishes := func(t *testing.T, op func() error) error {
+		t.Helper()
+		done := make(chan error, 1)
+		go func() { done <- op() }()
+		select {
+		case err := <-done:
+			return err
+		case <-time.After(5 * time.Second):
+			t.Fatal("operation did not time out")
+			return nil
+		}
+	}
+
+	t.Run("operation timeout", func(t *testing.T) {
+		b := &timeoutBackend{StorageBackend: &blockingBackend{}, operationTimeout: limit}
+		err := finishes(t, func() error {
+			_, err := b.List(ctx, "")
+			return err
+		})
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("List: %v, want a deadline error", err)
+		}
+		err = finishes(t, func() error {
+			_, err := b.GetUsage(ctx)
+			return err
+		})
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("GetUsage: %v, want a deadline error", err)
+		}
+		if code := ClassifyError(b, err); code != ErrorCodeNetwork {
+			t.Errorf("timed out operation classified %q, want %q", code, ErrorCodeNetwork)
+		}
+	})
+
+	t.Run("stalled upload", func(t *testing.T) {
+		b := &timeoutBackend{StorageBackend: &blockingBackend{}, stallTimeout: limit}
+		err := finishes(t, func() error { return b.Upload(ctx, "archive", "archive", nil) })
+		if !errors.Is(err, errTransferStalled) || !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Upload: %v, want a stalled transfer error", err)
+		}
+	})
+
+	t.Run("upload making progress", func(t *testing.T) {
+		// Runs for several stall timeouts, but never goes quiet for one
+		b := &timeoutBackend{
+			StorageBackend: &blockingBackend{ticks: 8, tick: limit / 5},
+			stallTimeout:   limit,
+		}
+		if err := finishes(t, func() error { return b.Upload(ctx, "archive", "archive", nil) }); err != nil {
+			t.Errorf("Upload: %v", err)
+		}
+	})
+
+	t.Run("upload timeout", func(t *testing.T) {
+		b := &timeoutBackend{
+			StorageBackend: &blockingBackend{ticks: 1000, tick: limit / 5},
+			uploadTimeout:  limit,
+			stallTimeout:   time.Minute,
+		}
+		err := finishes(t, func() error { return b.Upload(ctx, "archive", "archive", nil) })
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Upload: %v, want a deadline error", err)
+		}
+	})
+}
+
+// countingBackend counts the calls that reach the provider
+type countingBackend struct {
+	StorageBackend
+	lists, usages atomic.Int32
+}
+
+func (b *countingBackend) List(ctx context.Context, prefix string) ([]BackupInfo, error) {
+	b.lists.Add(1)
+	return []BackupInfo{{Path: prefix + "archive.tar.gz"}}, nil
+}
+
+func (b *countingBackend) GetUsage(ctx context.Context) (*models.StorageUsage, error) {
+	b.usages.Add(1)
+	return &models.StorageUsage{Used: 100, Total: -1}, nil
+}
+
+func (b *countingBackend) Delete(ctx context.Context, remotePath string) error {
+	return nil
+}
+
+func TestListCache(t *testing.T) {
+	ctx := context.Background()
+	provider := &countingBackend{}
+	config := &models.Backend{ID: t.Name(), UpdatedAt: time.Now()}
+	// Factory creates a backend instance per operation, so each call wraps afresh
+	wrap := func(t *testing.T, ttl float64) StorageBackend {
+		t.Helper()
+		b, err := withListCache(provider, config, map[string]interface{}{"list_cache_ttl": ttl})
+		if err != nil {
+			t.Fatalf("withListCache: %v", err)
+		}
+		return b
+	}
+
+	for i := 0; i < 5; i++ {
+		usage, err := wrap(t, 60).GetUsage(ctx)
+		if err != nil {
+			t.Fatalf("GetUsage: %v", err)
+		}
+		if usage.Used != 100 {
+			t.Errorf("cached usage %d bytes, want 100", usage.Used)
+		}
+		usage.Used = 0 // callers can't corrupt the cached copy
+		if _, err := wrap(t, 60).List(ctx, "docs_"); err != nil {
+			t.Fatalf("List: %v", err)
+		}
+	}
+	if n := provider.usages.Load(); n != 1 {
+		t.Errorf("GetUsage reached the provider %d times within the TTL, want 1", n)
+	}
+	if n := provider.lists.Load(); n != 1 {
+		t.Errorf("List reached the provider %d times within the TTL, want 1", n)
+	}
+
+	// Prefixes are cached separately
+	if _, err := wrap(t, 60).List(ctx, "photos_"); err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if n := provider.lists.Load(); n != 2 {
+		t.Errorf("List of a new prefix reached the provider %d times in total, want 2", n)
+	}
+
+	// Changes made through any instance drop the cached results
+	if err := wrap(t, 60).Delete(ctx, "docs_archive.tar.gz"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := wrap(t, 60).GetUsage(ctx); err != nil {
+		t.Fatalf("GetUsage: %v", err)
+	}
+	if n := provider.usages.Load(); n != 2 {
+		t.Errorf("GetUsage after a delete reached the provider %d times in total, want 2", n)
+	}
+
+	// Editing the backend starts afresh
+	config.UpdatedAt = config.UpdatedAt.Add(time.Second)
+	if _, err := wrap(t, 60).GetUsage(ctx); err != nil {
+		t.Fatalf("GetUsage: %v", err)
+	}
+	if n := provider.usages.Load(); n != 3 {
+		t.Errorf("GetUsage after an edit reached the provider %d times in total, want 3", n)
+	}
+
+	// Results expire after the TTL
+	const ttl = 0.05
+	if _, err := wrap(t, ttl).List(ctx, "expiring_"); err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	time.Sleep(time.Duration(2 * ttl * float64(time.Second)))
+	if _, err := wrap(t, ttl).List(ctx, "expiring_"); err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if n := provider.lists.Load(); n != 4 {
+		t.Errorf("List after the TTL reached the provider %d times in total, want 4", n)
+	}
+}
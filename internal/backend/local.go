@@ -7,15 +7,16 @@ import (
 	"log"
 	"os"
 	"path/filepath"
-	"syscall"
 	"time"
 
+	"github.com/nsilverman/archivist/internal/diskspace"
 	"github.com/nsilverman/archivist/internal/models"
 )
 
 // LocalBackend stores backups on the local filesystem
 type LocalBackend struct {
-	basePath string
+	basePath        string
+	createIfMissing bool // create basePath when it doesn't exist, rather than failing
 }
 
 // Initialize sets up the local backend
@@ -28,7 +29,11 @@ func (l *LocalBackend) Initialize(config map[string]interface{}, pathResolver Pa
 	// Resolve path relative to root directory if needed
 	l.basePath = pathResolver.ResolvePath(path)
 
-	// Create base directory if it doesn't exist
+	// Create base directory if it doesn't exist, unless it must already be there
+	l.createIfMissing = configBool(config, "create_if_missing", true)
+	if !l.createIfMissing {
+		return l.checkBasePath()
+	}
 	if err := os.MkdirAll(l.basePath, 0755); err != nil {
 		return fmt.Errorf("failed to create base directory: %w", err)
 	}
@@ -36,6 +41,22 @@ func (l *LocalBackend) Initialize(config map[string]interface{}, pathResolver Pa
 	return nil
 }
 
+// checkBasePath verifies that the base directory exists. Without it, an
+// unmounted volume would have its mount point filled instead.
+func (l *LocalBackend) checkBasePath() error {
+	info, err := os.Stat(l.basePath)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("base directory %s does not exist and create_if_missing is false", l.basePath)
+	}
+	if err != nil {
+		return fmt.Errorf("cannot access base directory: %w", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("base path %s is not a directory", l.basePath)
+	}
+	return nil
+}
+
 // Test checks if the backend is accessible
 func (l *LocalBackend) Test() error {
 	// Check if directory exists and is writable
@@ -62,29 +83,23 @@ func (l *LocalBackend) Test() error {
 
 // Upload copies a file to the local backend
 func (l *LocalBackend) Upload(ctx context.Context, localPath string, remotePath string, progress ProgressCallback) error {
-	// Open source file
-	src, err := os.Open(localPath)
-	if err != nil {
-		return fmt.Errorf("failed to open source file: %w", err)
-	}
-	defer func() {
-		if err := src.Close(); err != nil {
-			log.Printf("Error closing source file: %v", err)
-		}
-	}()
-
-	// Get source file info
-	srcInfo, err := src.Stat()
-	if err != nil {
-		return fmt.Errorf("failed to stat source file: %w", err)
-	}
-	totalSize := srcInfo.Size()
+	return uploadFile(localPath, func(file io.Reader, size int64) error {
+		return l.UploadReader(ctx, file, size, remotePath, progress)
+	})
+}
 
+// UploadReader writes a stream to the local backend. A failed upload leaves no file behind.
+func (l *LocalBackend) UploadReader(ctx context.Context, src io.Reader, totalSize int64, remotePath string, progress ProgressCallback) (err error) {
 	// Create destination path
 	destPath := filepath.Join(l.basePath, remotePath)
 	destDir := filepath.Dir(destPath)
 
-	// Create destination directory
+	// Create destination directory; the base directory may have gone since Initialize
+	if !l.createIfMissing {
+		if err := l.checkBasePath(); err != nil {
+			return err
+		}
+	}
 	if err := os.MkdirAll(destDir, 0755); err != nil {
 		return fmt.Errorf("failed to create destination directory: %w", err)
 	}
@@ -95,8 +110,13 @@ func (l *LocalBackend) Upload(ctx context.Context, localPath string, remotePath
 		return fmt.Errorf("failed to create destination file: %w", err)
 	}
 	defer func() {
-		if err := dst.Close(); err != nil {
-			log.Printf("Error closing destination file: %v", err)
+		if closeErr := dst.Close(); closeErr != nil {
+			log.Printf("Error closing destination file: %v", closeErr)
+		}
+		if err != nil {
+			if removeErr := os.Remove(destPath); removeErr != nil {
+				log.Printf("Error removing incomplete upload: %v", removeErr)
+			}
 		}
 	}()
 
@@ -132,15 +152,67 @@ func (l *LocalBackend) Upload(ctx context.Context, localPath string, remotePath
 		}
 	}
 
+	// A file has no metadata beyond its modification time, so it takes the
+	// source's, which List then reports as LastModified
+	if modTime, parseErr := time.Parse(time.RFC3339Nano, uploadMetadata(ctx)[MetadataSourceMTime]); parseErr == nil {
+		if err := os.Chtimes(destPath, modTime, modTime); err != nil {
+			log.Printf("Warning: failed to set modification time of %s: %v", remotePath, err)
+		}
+	}
+
 	return nil
 }
 
+// Download copies a backup from the local backend, resuming a partial copy
+func (l *LocalBackend) Download(ctx context.Context, remotePath string, localPath string, progress ProgressCallback) error {
+	return resumableDownload(ctx, localPath, progress, l.opener(remotePath))
+}
+
+// Open opens a backup on the local backend for reading
+func (l *LocalBackend) Open(ctx context.Context, remotePath string) (io.ReadCloser, int64, error) {
+	body, size, _, err := l.opener(remotePath)(0)
+	return body, size, err
+}
+
+// opener reads a backup from a byte offset
+func (l *LocalBackend) opener(remotePath string) rangeOpener {
+	return func(offset int64) (io.ReadCloser, int64, string, error) {
+		src, err := os.Open(filepath.Join(l.basePath, remotePath))
+		if err != nil {
+			return nil, 0, "", fmt.Errorf("failed to open backup: %w", err)
+		}
+
+		info, err := src.Stat()
+		if err == nil && offset > info.Size() {
+			err = fmt.Errorf("offset %d beyond end of backup", offset)
+		}
+		if err == nil {
+			_, err = src.Seek(offset, io.SeekStart)
+		}
+		if err != nil {
+			if closeErr := src.Close(); closeErr != nil {
+				log.Printf("Error closing backup file: %v", closeErr)
+			}
+			return nil, 0, "", fmt.Errorf("failed to read backup: %w", err)
+		}
+
+		version := fmt.Sprintf("%d/%d", info.ModTime().UnixNano(), info.Size())
+		return src, info.Size(), version, nil
+	}
+}
+
 // List returns all backups with a given prefix
 func (l *LocalBackend) List(ctx context.Context, prefix string) ([]BackupInfo, error) {
 	searchPath := filepath.Join(l.basePath, prefix)
 	searchDir := filepath.Dir(searchPath)
 	pattern := filepath.Base(searchPath)
 
+	// An empty prefix lists everything; don't walk the parent of basePath
+	if prefix == "" {
+		searchDir = l.basePath
+		pattern = ""
+	}
+
 	var backups []BackupInfo
 
 	// If pattern contains wildcard or is a directory, walk it
@@ -192,21 +264,21 @@ func (l *LocalBackend) Delete(ctx context.Context, remotePath string) error {
 	return nil
 }
 
+// Copy copies a backup to another path within the backend directory
+func (l *LocalBackend) Copy(ctx context.Context, srcRemotePath string, dstRemotePath string) error {
+	return l.Upload(ctx, filepath.Join(l.basePath, srcRemotePath), dstRemotePath, nil)
+}
+
 // GetUsage returns storage usage information
 func (l *LocalBackend) GetUsage(ctx context.Context) (*models.StorageUsage, error) {
-	var stat syscall.Statfs_t
-	if err := syscall.Statfs(l.basePath, &stat); err != nil {
-		return nil, fmt.Errorf("failed to get filesystem stats: %w", err)
+	total, available, err := diskspace.Usage(l.basePath)
+	if err != nil {
+		return nil, err
 	}
 
-	// Calculate used and total space
-	total := stat.Blocks * uint64(stat.Bsize)
-	available := stat.Bavail * uint64(stat.Bsize)
-	used := total - available
-
 	return &models.StorageUsage{
-		Used:  int64(used),
-		Total: int64(total),
+		Used:  total - available,
+		Total: total,
 	}, nil
 }
 
@@ -0,0 +1,289 @@
+package backend
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nsilverman/archivist/internal/models"
+)
+
+// ErrInjectedFailure is returned by a memory backend operation chosen to fail by fail_rate
+var ErrInjectedFailure = errors.New("injected failure")
+
+// memoryObject is one stored backup
+type memoryObject struct {
+	data         []byte
+	lastModified time.Time
+	metadata     map[string]string
+}
+
+// memoryStore holds the objects of one named memory backend. Stores outlive
+// backend instances, since Factory creates a new instance for every operation.
+type memoryStore struct {
+	mu      sync.Mutex
+	objects map[string]memoryObject
+}
+
+var (
+	memoryStoresMu sync.Mutex
+	memoryStores   = make(map[string]*memoryStore)
+)
+
+// getMemoryStore returns the store with the given name, creating it if needed
+func getMemoryStore(name string) *memoryStore {
+	memoryStoresMu.Lock()
+	defer memoryStoresMu.Unlock()
+
+	store, ok := memoryStores[name]
+	if !ok {
+		store = &memoryStore{objects: make(map[string]memoryObject)}
+		memoryStores[name] = store
+	}
+	return store
+}
+
+// MemoryBackend keeps backups in process memory. It is meant for tests and
+// demos: contents are lost on restart, and latency and failures can be injected.
+type MemoryBackend struct {
+	store          *memoryStore
+	latency        time.Duration
+	failRate       float64
+	failOperations map[string]bool
+}
+
+// Initialize sets up the memory backend. Backends configured with the same
+// 'name' share their contents.
+func (m *MemoryBackend) Initialize(config map[string]interface{}, pathResolver PathResolver) error {
+	name, _ := config["name"].(string)
+	if name == "" {
+		name = "default"
+	}
+
+	latency, err := configSeconds(config, "latency", 0)
+	if err != nil {
+		return err
+	}
+	failRate, err := configFloat(config, "fail_rate", 0)
+	if err != nil {
+		return err
+	}
+	if failRate > 1 {
+		return fmt.Errorf("config 'fail_rate' must be between 0 and 1")
+	}
+
+	// fail_operations limits failure injection to some operations, e.g. "upload,delete"
+	var failOperations map[string]bool
+	if ops, _ := config["fail_operations"].(string); ops != "" {
+		failOperations = make(map[string]bool)
+		for _, op := range strings.Split(ops, ",") {
+			failOperations[strings.TrimSpace(strings.ToLower(op))] = true
+		}
+	}
+
+	m.store = getMemoryStore(name)
+	m.latency = latency
+	m.failRate = failRate
+	m.failOperations = failOperations
+	return nil
+}
+
+// simulate applies the configured latency and decides whether the operation fails
+func (m *MemoryBackend) simulate(ctx context.Context, operation string) error {
+	if m.latency > 0 {
+		timer := time.NewTimer(m.latency)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+
+	if m.failRate > 0 && (m.failOperations == nil || m.failOperations[operation]) && rand.Float64() < m.failRate {
+		return fmt.Errorf("%s: %w", operation, ErrInjectedFailure)
+	}
+	return nil
+}
+
+// Test checks if the backend is accessible
+func (m *MemoryBackend) Test() error {
+	return m.simulate(context.Background(), "test")
+}
+
+// Upload stores a file in memory
+func (m *MemoryBackend) Upload(ctx context.Context, localPath string, remotePath string, progress ProgressCallback) error {
+	return uploadFile(localPath, func(file io.Reader, size int64) error {
+		return m.UploadReader(ctx, file, size, remotePath, progress)
+	})
+}
+
+// UploadReader stores a stream in memory
+func (m *MemoryBackend) UploadReader(ctx context.Context, reader io.Reader, size int64, remotePath string, progress ProgressCallback) error {
+	if err := m.simulate(ctx, "upload"); err != nil {
+		return err
+	}
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return fmt.Errorf("failed to read upload: %w", err)
+	}
+	if progress != nil {
+		progress(int64(len(data)), size)
+	}
+
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+	m.store.objects[remotePath] = memoryObject{data: data, lastModified: time.Now(), metadata: copyMetadata(uploadMetadata(ctx))}
+	return nil
+}
+
+// Download writes a stored backup to a local file
+func (m *MemoryBackend) Download(ctx context.Context, remotePath string, localPath string, progress ProgressCallback) error {
+	open, err := m.opener(ctx, remotePath)
+	if err != nil {
+		return err
+	}
+	return resumableDownload(ctx, localPath, progress, open)
+}
+
+// Open returns a reader of a stored backup
+func (m *MemoryBackend) Open(ctx context.Context, remotePath string) (io.ReadCloser, int64, error) {
+	open, err := m.opener(ctx, remotePath)
+	if err != nil {
+		return nil, 0, err
+	}
+	body, size, _, err := open(0)
+	return body, size, err
+}
+
+// opener finds a stored backup and returns a reader of it from a byte offset
+func (m *MemoryBackend) opener(ctx context.Context, remotePath string) (rangeOpener, error) {
+	if err := m.simulate(ctx, "download"); err != nil {
+		return nil, err
+	}
+
+	obj, ok := m.get(remotePath)
+	if !ok {
+		return nil, fmt.Errorf("backup not found: %s: %w", remotePath, os.ErrNotExist)
+	}
+
+	version := obj.lastModified.Format(time.RFC3339Nano)
+	return func(offset int64) (io.ReadCloser, int64, string, error) {
+		if offset > int64(len(obj.data)) {
+			return nil, 0, "", fmt.Errorf("offset %d beyond end of backup", offset)
+		}
+		return io.NopCloser(bytes.NewReader(obj.data[offset:])), int64(len(obj.data)), version, nil
+	}, nil
+}
+
+// List returns all backups with a given prefix, sorted by path
+func (m *MemoryBackend) List(ctx context.Context, prefix string) ([]BackupInfo, error) {
+	if err := m.simulate(ctx, "list"); err != nil {
+		return nil, err
+	}
+
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	var backups []BackupInfo
+	for path, obj := range m.store.objects {
+		if !strings.HasPrefix(path, prefix) {
+			continue
+		}
+		backups = append(backups, BackupInfo{
+			Path:         path,
+			Size:         int64(len(obj.data)),
+			LastModified: obj.lastModified.Format(time.RFC3339),
+			Hash:         fmt.Sprintf("sha256:%x", sha256.Sum256(obj.data)),
+			Metadata:     copyMetadata(obj.metadata),
+		})
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].Path < backups[j].Path
+	})
+	return backups, nil
+}
+
+// Delete removes a backup
+func (m *MemoryBackend) Delete(ctx context.Context, remotePath string) error {
+	if err := m.simulate(ctx, "delete"); err != nil {
+		return err
+	}
+
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	if _, ok := m.store.objects[remotePath]; !ok {
+		return fmt.Errorf("backup not found: %s: %w", remotePath, os.ErrNotExist)
+	}
+	delete(m.store.objects, remotePath)
+	return nil
+}
+
+// Copy duplicates a backup under another path
+func (m *MemoryBackend) Copy(ctx context.Context, srcRemotePath string, dstRemotePath string) error {
+	if err := m.simulate(ctx, "copy"); err != nil {
+		return err
+	}
+
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	obj, ok := m.store.objects[srcRemotePath]
+	if !ok {
+		return fmt.Errorf("backup not found: %s: %w", srcRemotePath, os.ErrNotExist)
+	}
+	m.store.objects[dstRemotePath] = memoryObject{data: obj.data, lastModified: time.Now()}
+	return nil
+}
+
+// GetUsage returns the total size of stored backups
+func (m *MemoryBackend) GetUsage(ctx context.Context) (*models.StorageUsage, error) {
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	var used int64
+	for _, obj := range m.store.objects {
+		used += int64(len(obj.data))
+	}
+	return &models.StorageUsage{
+		Used:  used,
+		Total: -1,
+	}, nil
+}
+
+// Close closes the backend (no-op for memory; contents are kept)
+func (m *MemoryBackend) Close() error {
+	return nil
+}
+
+// get returns a stored object
+func (m *MemoryBackend) get(remotePath string) (memoryObject, bool) {
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+	obj, ok := m.store.objects[remotePath]
+	return obj, ok
+}
+
+// copyMetadata copies object metadata so callers can't change what is stored
+func copyMetadata(metadata map[string]string) map[string]string {
+	if metadata == nil {
+		return nil
+	}
+	copied := make(map[string]string, len(metadata))
+	for key, value := range metadata {
+		copied[key] = value
+	}
+	return copied
+}
@@ -0,0 +1,65 @@
+package backend
+
+import (
+	"context"
+	"strconv"
+	"time"
+)
+
+// Object metadata keys. Sync uploads record the source file's modification time
+// and size, so later runs compare against the source rather than the upload
+// time; archive uploads record a summary of their source. Keys are lowercase
+// letters only, the one form every provider accepts and returns unchanged.
+const (
+	MetadataSourceMTime = "sourcemtime" // RFC 3339 with nanoseconds
+	MetadataSourceSize  = "sourcesize"  // bytes
+	MetadataSourceFiles = "sourcefiles" // files in an archive's source
+)
+
+type metadataKey struct{}
+
+// WithMetadata returns a context whose uploads store metadata with the object.
+// Local backends keep only the source modification time, as the file's own;
+// backends without object metadata ignore it.
+func WithMetadata(ctx context.Context, metadata map[string]string) context.Context {
+	return context.WithValue(ctx, metadataKey{}, metadata)
+}
+
+// uploadMetadata returns the metadata to store with an upload, or nil
+func uploadMetadata(ctx context.Context) map[string]string {
+	metadata, _ := ctx.Value(metadataKey{}).(map[string]string)
+	if len(metadata) == 0 {
+		return nil
+	}
+	return metadata
+}
+
+// FileMetadata describes a source file for WithMetadata
+func FileMetadata(modTime time.Time, size int64) map[string]string {
+	return map[string]string{
+		MetadataSourceMTime: modTime.UTC().Format(time.RFC3339Nano),
+		MetadataSourceSize:  strconv.FormatInt(size, 10),
+	}
+}
+
+// ArchiveMetadata summarizes an archive's source for WithMetadata
+func ArchiveMetadata(files int, size int64) map[string]string {
+	return map[string]string{
+		MetadataSourceFiles: strconv.Itoa(files),
+		MetadataSourceSize:  strconv.FormatInt(size, 10),
+	}
+}
+
+// SourceFile reads back the source modification time and size recorded by
+// FileMetadata. ok is false when the object has no such metadata.
+func (b BackupInfo) SourceFile() (modTime time.Time, size int64, ok bool) {
+	modTime, err := time.Parse(time.RFC3339Nano, b.Metadata[MetadataSourceMTime])
+	if err != nil {
+		return time.Time{}, 0, false
+	}
+	size, err = strconv.ParseInt(b.Metadata[MetadataSourceSize], 10, 64)
+	if err != nil {
+		return time.Time{}, 0, false
+	}
+	return modTime, size, true
+}
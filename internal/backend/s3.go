@@ -2,10 +2,12 @@ package backend
 
 import (
 	"context"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
-	"log"
-	"os"
+	"net/http"
+	"net/url"
 	"strings"
 	"time"
 
@@ -25,6 +27,8 @@ type S3Backend struct {
 	bucket      string
 	prefix      string
 	storageTier types.StorageClass
+	etagHashes  bool
+	region      string
 }
 
 // Initialize sets up the S3 backend
@@ -41,10 +45,19 @@ func (b *S3Backend) Initialize(cfg map[string]interface{}, pathResolver PathReso
 		b.prefix = prefix
 	}
 
-	region, ok := cfg["region"].(string)
-	if !ok || region == "" {
+	// Single-part ETags are MD5s unless the bucket uses SSE-KMS/SSE-C, which
+	// produce opaque ETags; those buckets should set etag_hashes to false
+	b.etagHashes = configBool(cfg, "etag_hashes", true)
+
+	// Some S3-compatible providers reject us-east-1, so it is only assumed for AWS itself
+	region, _ := cfg["region"].(string)
+	if region == "" {
+		if endpoint, _ := cfg["endpoint"].(string); endpoint != "" {
+			return fmt.Errorf("S3 backend with a custom endpoint requires 'region' configuration: use the provider's region, e.g. \"us-west-004\" for Backblaze B2 or \"eu-central-1\" for Wasabi")
+		}
 		region = "us-east-1" // Default region
 	}
+	b.region = region
 
 	// Build AWS config
 	var awsCfg aws.Config
@@ -75,15 +88,7 @@ func (b *S3Backend) Initialize(cfg map[string]interface{}, pathResolver PathReso
 		return fmt.Errorf("failed to load AWS configuration: %w", err)
 	}
 
-	// Support custom endpoint for S3-compatible storage (MinIO, DigitalOcean Spaces, etc.)
-	if endpoint, ok := cfg["endpoint"].(string); ok && endpoint != "" {
-		b.client = s3.NewFromConfig(awsCfg, func(o *s3.Options) {
-			o.BaseEndpoint = aws.String(endpoint)
-			o.UsePathStyle = true // Required for MinIO and some S3-compatible services
-		})
-	} else {
-		b.client = s3.NewFromConfig(awsCfg)
-	}
+	b.client = s3.NewFromConfig(awsCfg, s3ClientOptions(cfg))
 
 	// Create uploader for efficient multipart uploads
 	b.uploader = manager.NewUploader(b.client)
@@ -103,6 +108,24 @@ func (b *S3Backend) Initialize(cfg map[string]interface{}, pathResolver PathReso
 	return nil
 }
 
+// s3ClientOptions applies endpoint-related settings from the backend config
+func s3ClientOptions(cfg map[string]interface{}) func(*s3.Options) {
+	return func(o *s3.Options) {
+		// Support custom endpoint for S3-compatible storage (MinIO, DigitalOcean Spaces, etc.)
+		endpoint, _ := cfg["endpoint"].(string)
+		if endpoint != "" {
+			o.BaseEndpoint = aws.String(endpoint)
+		}
+
+		// Path-style addressing is required for MinIO and most S3-compatible services,
+		// but some providers only accept virtual-hosted style
+		o.UsePathStyle = configBool(cfg, "use_path_style", endpoint != "")
+
+		// Allow plain HTTP for local MinIO deployments
+		o.EndpointOptions.DisableHTTPS = configBool(cfg, "disable_ssl", false)
+	}
+}
+
 // Test checks if the backend is accessible
 func (b *S3Backend) Test() error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -113,32 +136,39 @@ func (b *S3Backend) Test() error {
 		Bucket: aws.String(b.bucket),
 	})
 	if err != nil {
+		if isS3RegionError(err) {
+			return fmt.Errorf("cannot access bucket, check that region %q is the bucket's region: %w", b.region, err)
+		}
 		return fmt.Errorf("cannot access bucket: %w", err)
 	}
 
 	return nil
 }
 
-// Upload uploads a file to S3
-func (b *S3Backend) Upload(ctx context.Context, localPath string, remotePath string, progress ProgressCallback) error {
-	// Open local file
-	file, err := os.Open(localPath)
-	if err != nil {
-		return fmt.Errorf("failed to open file: %w", err)
-	}
-	defer func() {
-		if err := file.Close(); err != nil {
-			log.Printf("Error closing file: %v", err)
+// isS3RegionError reports whether an error looks like a request signed for the wrong region.
+// Providers report this in different ways, and HeadBucket responses carry no error body.
+func isS3RegionError(err error) bool {
+	var apiErr interface{ ErrorCode() string }
+	if errors.As(err, &apiErr) {
+		switch apiErr.ErrorCode() {
+		case "AuthorizationHeaderMalformed", "PermanentRedirect", "IllegalLocationConstraintException", "InvalidRegion":
+			return true
 		}
-	}()
-
-	// Get file size for progress reporting
-	stat, err := file.Stat()
-	if err != nil {
-		return fmt.Errorf("failed to stat file: %w", err)
 	}
-	fileSize := stat.Size()
 
+	var statusErr interface{ HTTPStatusCode() int }
+	return errors.As(err, &statusErr) && statusErr.HTTPStatusCode() == http.StatusMovedPermanently
+}
+
+// Upload uploads a file to S3
+func (b *S3Backend) Upload(ctx context.Context, localPath string, remotePath string, progress ProgressCallback) error {
+	return uploadFile(localPath, func(file io.Reader, size int64) error {
+		return b.UploadReader(ctx, file, size, remotePath, progress)
+	})
+}
+
+// UploadReader uploads a stream to S3
+func (b *S3Backend) UploadReader(ctx context.Context, reader io.Reader, size int64, remotePath string, progress ProgressCallback) error {
 	// Add prefix if configured
 	key := remotePath
 	if b.prefix != "" {
@@ -147,17 +177,19 @@ func (b *S3Backend) Upload(ctx context.Context, localPath string, remotePath str
 
 	// Create a progress reader
 	progressReader := &progressReader{
-		reader:   file,
-		size:     fileSize,
+		reader:   reader,
+		size:     size,
 		callback: progress,
 	}
 
 	// Upload with multipart support
-	_, err = b.uploader.Upload(ctx, &s3.PutObjectInput{
+	_, err := b.uploader.Upload(ctx, &s3.PutObjectInput{
 		Bucket:       aws.String(b.bucket),
 		Key:          aws.String(key),
 		Body:         progressReader,
 		StorageClass: b.storageTier,
+		ContentType:  aws.String(ContentType(remotePath)),
+		Metadata:     uploadMetadata(ctx),
 	})
 
 	if err != nil {
@@ -167,6 +199,42 @@ func (b *S3Backend) Upload(ctx context.Context, localPath string, remotePath str
 	return nil
 }
 
+// Download downloads a file from S3, using a byte-range request to resume a partial download
+func (b *S3Backend) Download(ctx context.Context, remotePath string, localPath string, progress ProgressCallback) error {
+	return resumableDownload(ctx, localPath, progress, b.opener(ctx, remotePath))
+}
+
+// Open streams a file from S3
+func (b *S3Backend) Open(ctx context.Context, remotePath string) (io.ReadCloser, int64, error) {
+	body, size, _, err := b.opener(ctx, remotePath)(0)
+	return body, size, err
+}
+
+// opener reads a file from a byte offset
+func (b *S3Backend) opener(ctx context.Context, remotePath string) rangeOpener {
+	// Add prefix if configured
+	key := remotePath
+	if b.prefix != "" {
+		key = b.prefix + "/" + remotePath
+	}
+
+	return func(offset int64) (io.ReadCloser, int64, string, error) {
+		input := &s3.GetObjectInput{
+			Bucket: aws.String(b.bucket),
+			Key:    aws.String(key),
+		}
+		if offset > 0 {
+			input.Range = aws.String(fmt.Sprintf("bytes=%d-", offset))
+		}
+
+		out, err := b.client.GetObject(ctx, input)
+		if err != nil {
+			return nil, 0, "", fmt.Errorf("failed to download from S3: %w", err)
+		}
+		return out.Body, offset + aws.ToInt64(out.ContentLength), aws.ToString(out.ETag), nil
+	}
+}
+
 // List returns all backups with a given prefix
 func (b *S3Backend) List(ctx context.Context, prefix string) ([]BackupInfo, error) {
 	// Combine backend prefix with query prefix
@@ -198,11 +266,16 @@ func (b *S3Backend) List(ctx context.Context, prefix string) ([]BackupInfo, erro
 				displayPath = displayPath[len(b.prefix)+1:]
 			}
 
+			hash := ""
+			if b.etagHashes {
+				hash = s3ETagHash(aws.ToString(obj.ETag))
+			}
+
 			backups = append(backups, BackupInfo{
 				Path:         displayPath,
 				Size:         *obj.Size,
 				LastModified: obj.LastModified.Format(time.RFC3339),
-				Hash:         "", // S3 ETag is not a standard hash
+				Hash:         hash,
 			})
 		}
 	}
@@ -210,6 +283,20 @@ func (b *S3Backend) List(ctx context.Context, prefix string) ([]BackupInfo, erro
 	return backups, nil
 }
 
+// s3ETagHash converts an S3 ETag into a hash usable for verification.
+// Single-part uploads have the object's MD5 as their ETag; multipart ETags
+// ("<md5 of part md5s>-<parts>") are not a content hash and yield "".
+func s3ETagHash(etag string) string {
+	etag = strings.Trim(etag, `"`)
+	if len(etag) != 32 || strings.Contains(etag, "-") {
+		return ""
+	}
+	if _, err := hex.DecodeString(etag); err != nil {
+		return ""
+	}
+	return "md5:" + strings.ToLower(etag)
+}
+
 // Delete removes a backup file
 func (b *S3Backend) Delete(ctx context.Context, remotePath string) error {
 	// Add prefix if configured
@@ -230,6 +317,45 @@ func (b *S3Backend) Delete(ctx context.Context, remotePath string) error {
 	return nil
 }
 
+// s3MaxCopySize is the largest object CopyObject accepts in a single request
+const s3MaxCopySize = 5 * 1024 * 1024 * 1024
+
+// Copy copies a backup within the bucket using CopyObject. Objects over 5 GiB
+// need a multipart copy, so they report ErrCopyNotSupported and are re-uploaded.
+func (b *S3Backend) Copy(ctx context.Context, srcRemotePath string, dstRemotePath string) error {
+	srcKey, dstKey := srcRemotePath, dstRemotePath
+	if b.prefix != "" {
+		srcKey = b.prefix + "/" + srcRemotePath
+		dstKey = b.prefix + "/" + dstRemotePath
+	}
+
+	head, err := b.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(b.bucket),
+		Key:    aws.String(srcKey),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to stat S3 object: %w", err)
+	}
+	if aws.ToInt64(head.ContentLength) > s3MaxCopySize {
+		return ErrCopyNotSupported
+	}
+
+	input := &s3.CopyObjectInput{
+		Bucket:     aws.String(b.bucket),
+		Key:        aws.String(dstKey),
+		CopySource: aws.String(url.PathEscape(b.bucket + "/" + srcKey)),
+	}
+	if b.storageTier != "" {
+		input.StorageClass = b.storageTier
+	}
+
+	if _, err := b.client.CopyObject(ctx, input); err != nil {
+		return fmt.Errorf("failed to copy in S3: %w", err)
+	}
+
+	return nil
+}
+
 // GetUsage returns storage usage information
 func (b *S3Backend) GetUsage(ctx context.Context) (*models.StorageUsage, error) {
 	// Calculate total size of objects in bucket with our prefix
@@ -264,6 +390,30 @@ func (b *S3Backend) Close() error {
 	return nil
 }
 
+// ClassifyError maps S3 API errors to an error code
+func (b *S3Backend) ClassifyError(err error) string {
+	var apiErr interface{ ErrorCode() string }
+	if errors.As(err, &apiErr) {
+		switch apiErr.ErrorCode() {
+		case "AccessDenied", "InvalidAccessKeyId", "SignatureDoesNotMatch", "ExpiredToken", "InvalidToken", "AllAccessDisabled":
+			return ErrorCodeAuth
+		case "NoSuchBucket", "NoSuchKey", "NotFound":
+			return ErrorCodeNotFound
+		case "SlowDown", "QuotaExceeded", "TooManyBuckets", "EntityTooLarge":
+			return ErrorCodeQuota
+		case "RequestTimeout", "RequestTimeTooSkewed", "ServiceUnavailable", "InternalError":
+			return ErrorCodeNetwork
+		}
+	}
+
+	var statusErr interface{ HTTPStatusCode() int }
+	if errors.As(err, &statusErr) {
+		return classifyHTTPStatus(statusErr.HTTPStatusCode())
+	}
+
+	return ErrorCodeUnknown
+}
+
 // progressReader wraps an io.Reader to report progress
 type progressReader struct {
 	reader   io.Reader
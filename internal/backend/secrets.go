@@ -0,0 +1,52 @@
+package backend
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// secretRefPattern matches config values of the form ${ENV:NAME} or ${FILE:/path/to/secret}
+var secretRefPattern = regexp.MustCompile(`^\$\{(ENV|FILE):([^}]+)\}$`)
+
+// resolveSecretReferences returns a copy of the config with ${ENV:...} and ${FILE:...}
+// references replaced by their values. The original map is never modified so resolved
+// secrets are not written back to the configuration file.
+func resolveSecretReferences(config map[string]interface{}, pathResolver PathResolver) (map[string]interface{}, error) {
+	resolved := make(map[string]interface{}, len(config))
+	for key, value := range config {
+		str, ok := value.(string)
+		if !ok {
+			resolved[key] = value
+			continue
+		}
+
+		match := secretRefPattern.FindStringSubmatch(strings.TrimSpace(str))
+		if match == nil {
+			resolved[key] = value
+			continue
+		}
+
+		switch match[1] {
+		case "ENV":
+			envValue, exists := os.LookupEnv(match[2])
+			if !exists {
+				return nil, fmt.Errorf("config '%s' references unset environment variable: %s", key, match[2])
+			}
+			resolved[key] = envValue
+		case "FILE":
+			path := match[2]
+			if pathResolver != nil {
+				path = pathResolver.ResolvePath(path)
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("config '%s' references unreadable secret file %s: %w", key, path, err)
+			}
+			// Secret files commonly end with a trailing newline
+			resolved[key] = strings.TrimRight(string(data), "\r\n")
+		}
+	}
+	return resolved, nil
+}
@@ -0,0 +1,240 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nsilverman/archivist/internal/models"
+)
+
+// DefaultListCacheTTL is how long List and GetUsage results are reused
+const DefaultListCacheTTL = 30 * time.Second
+
+// Listing a large bucket pages through every object, so results are shared
+// between backend instances (one is created per operation) and calls are
+// spaced out per backend. State is keyed by backend ID and last update time,
+// so editing a backend starts afresh.
+var (
+	listCacheMu sync.Mutex
+	listCache   = make(map[string]listCacheEntry)
+
+	listLimitersMu sync.Mutex
+	listLimiters   = make(map[string]*requestLimiter)
+)
+
+type listCacheEntry struct {
+	expires time.Time
+	backups []BackupInfo
+	usage   *models.StorageUsage
+}
+
+// requestLimiter spaces requests at least interval apart
+type requestLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+// wait blocks until the next request is allowed or ctx is done
+func (l *requestLimiter) wait(ctx context.Context) error {
+	l.mu.Lock()
+	now := time.Now()
+	start := l.next
+	if start.Before(now) {
+		start = now
+	}
+	l.next = start.Add(l.interval)
+	l.mu.Unlock()
+
+	delay := time.Until(start)
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// cachingBackend throttles and caches List and GetUsage. Uploads and deletes
+// invalidate the cache so callers always see their own changes.
+type cachingBackend struct {
+	StorageBackend
+	key     string
+	ttl     time.Duration
+	limiter *requestLimiter // nil when unthrottled
+}
+
+// withListCache wraps a backend using list_qps (requests per second, 0 = unlimited)
+// and list_cache_ttl (seconds, 0 = disabled) from its config. Backends without an
+// ID, such as unsaved configs being tested, are returned unwrapped.
+func withListCache(b StorageBackend, backend *models.Backend, cfg map[string]interface{}) (StorageBackend, error) {
+	ttl, err := configSeconds(cfg, "list_cache_ttl", DefaultListCacheTTL)
+	if err != nil {
+		return nil, err
+	}
+	qps, err := configFloat(cfg, "list_qps", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	if backend.ID == "" || (ttl == 0 && qps == 0) {
+		return b, nil
+	}
+
+	c := &cachingBackend{
+		StorageBackend: b,
+		key:            fmt.Sprintf("%s@%d", backend.ID, backend.UpdatedAt.UnixNano()),
+		ttl:            ttl,
+	}
+
+	if qps > 0 {
+		interval := time.Duration(float64(time.Second) / qps)
+		listLimitersMu.Lock()
+		limiter, ok := listLimiters[c.key]
+		if !ok || limiter.interval != interval {
+			limiter = &requestLimiter{interval: interval}
+			listLimiters[c.key] = limiter
+		}
+		listLimitersMu.Unlock()
+		c.limiter = limiter
+	}
+
+	return c, nil
+}
+
+// List returns cached results for prefix while fresh, otherwise lists under the throttle
+func (c *cachingBackend) List(ctx context.Context, prefix string) ([]BackupInfo, error) {
+	key := c.key + "|list|" + prefix
+	if entry, ok := c.cached(key); ok {
+		return append([]BackupInfo(nil), entry.backups...), nil
+	}
+
+	if err := c.throttle(ctx); err != nil {
+		return nil, err
+	}
+	backups, err := c.StorageBackend.List(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	c.store(key, listCacheEntry{backups: append([]BackupInfo(nil), backups...)})
+	return backups, nil
+}
+
+// GetUsage returns cached usage while fresh, otherwise queries under the throttle
+func (c *cachingBackend) GetUsage(ctx context.Context) (*models.StorageUsage, error) {
+	key := c.key + "|usage"
+	if entry, ok := c.cached(key); ok {
+		usage := *entry.usage
+		return &usage, nil
+	}
+
+	if err := c.throttle(ctx); err != nil {
+		return nil, err
+	}
+	usage, err := c.StorageBackend.GetUsage(ctx)
+	if err != nil || usage == nil {
+		return usage, err
+	}
+
+	cached := *usage
+	c.store(key, listCacheEntry{usage: &cached})
+	return usage, nil
+}
+
+// Upload uploads and invalidates cached listings
+func (c *cachingBackend) Upload(ctx context.Context, localPath string, remotePath string, progress ProgressCallback) error {
+	defer c.invalidate()
+	return c.StorageBackend.Upload(ctx, localPath, remotePath, progress)
+}
+
+// UploadReader uploads a stream and invalidates cached listings
+func (c *cachingBackend) UploadReader(ctx context.Context, reader io.Reader, size int64, remotePath string, progress ProgressCallback) error {
+	defer c.invalidate()
+	return c.StorageBackend.UploadReader(ctx, reader, size, remotePath, progress)
+}
+
+// Delete deletes and invalidates cached listings
+func (c *cachingBackend) Delete(ctx context.Context, remotePath string) error {
+	defer c.invalidate()
+	return c.StorageBackend.Delete(ctx, remotePath)
+}
+
+// Copy copies and invalidates cached listings
+func (c *cachingBackend) Copy(ctx context.Context, srcRemotePath string, dstRemotePath string) error {
+	defer c.invalidate()
+	return c.StorageBackend.Copy(ctx, srcRemotePath, dstRemotePath)
+}
+
+// ClassifyError delegates to the wrapped backend's classifier
+func (c *cachingBackend) ClassifyError(err error) string {
+	return ClassifyError(c.StorageBackend, err)
+}
+
+func (c *cachingBackend) throttle(ctx context.Context) error {
+	if c.limiter == nil {
+		return nil
+	}
+	return c.limiter.wait(ctx)
+}
+
+func (c *cachingBackend) cached(key string) (listCacheEntry, bool) {
+	if c.ttl <= 0 {
+		return listCacheEntry{}, false
+	}
+
+	listCacheMu.Lock()
+	defer listCacheMu.Unlock()
+
+	entry, ok := listCache[key]
+	if !ok {
+		return listCacheEntry{}, false
+	}
+	if time.Now().After(entry.expires) {
+		delete(listCache, key)
+		return listCacheEntry{}, false
+	}
+	return entry, true
+}
+
+func (c *cachingBackend) store(key string, entry listCacheEntry) {
+	if c.ttl <= 0 {
+		return
+	}
+
+	now := time.Now()
+	entry.expires = now.Add(c.ttl)
+
+	listCacheMu.Lock()
+	defer listCacheMu.Unlock()
+
+	// Drop expired entries, including those left behind by edited backends
+	for k, e := range listCache {
+		if now.After(e.expires) {
+			delete(listCache, k)
+		}
+	}
+	listCache[key] = entry
+}
+
+// invalidate drops every cached result for this backend
+func (c *cachingBackend) invalidate() {
+	listCacheMu.Lock()
+	defer listCacheMu.Unlock()
+
+	for key := range listCache {
+		if strings.HasPrefix(key, c.key+"|") {
+			delete(listCache, key)
+		}
+	}
+}
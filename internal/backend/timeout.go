@@ -0,0 +1,151 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/nsilverman/archivist/internal/models"
+)
+
+// Default deadlines applied to backend operations
+const (
+	DefaultOperationTimeout = 5 * time.Minute
+	DefaultStallTimeout     = 5 * time.Minute
+)
+
+// errTransferStalled is the cancellation cause used when a transfer stops making progress
+var errTransferStalled = errors.New("transfer stalled")
+
+// timeoutBackend bounds backend operations with deadlines so a stalled
+// provider fails the operation instead of hanging the caller forever
+type timeoutBackend struct {
+	StorageBackend
+	operationTimeout time.Duration // Deadline for List, Delete and GetUsage (0 = none)
+	uploadTimeout    time.Duration // Overall deadline for Upload and Download (0 = none)
+	stallTimeout     time.Duration // Transfers fail if no data moves for this long (0 = none)
+}
+
+// withTimeouts wraps a backend using the timeout settings from its config:
+// operation_timeout, upload_timeout and stall_timeout, all in seconds
+func withTimeouts(b StorageBackend, cfg map[string]interface{}) (StorageBackend, error) {
+	operationTimeout, err := configSeconds(cfg, "operation_timeout", DefaultOperationTimeout)
+	if err != nil {
+		return nil, err
+	}
+	uploadTimeout, err := configSeconds(cfg, "upload_timeout", 0)
+	if err != nil {
+		return nil, err
+	}
+	stallTimeout, err := configSeconds(cfg, "stall_timeout", DefaultStallTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	return &timeoutBackend{
+		StorageBackend:   b,
+		operationTimeout: operationTimeout,
+		uploadTimeout:    uploadTimeout,
+		stallTimeout:     stallTimeout,
+	}, nil
+}
+
+// Upload uploads with an optional overall deadline and a stall watchdog that is
+// reset every time the backend reads more of the file
+func (t *timeoutBackend) Upload(ctx context.Context, localPath string, remotePath string, progress ProgressCallback) error {
+	return t.transfer(ctx, progress, func(ctx context.Context, progress ProgressCallback) error {
+		return t.StorageBackend.Upload(ctx, localPath, remotePath, progress)
+	})
+}
+
+// UploadReader uploads a stream with the same deadline and stall watchdog as Upload
+func (t *timeoutBackend) UploadReader(ctx context.Context, reader io.Reader, size int64, remotePath string, progress ProgressCallback) error {
+	return t.transfer(ctx, progress, func(ctx context.Context, progress ProgressCallback) error {
+		return t.StorageBackend.UploadReader(ctx, reader, size, remotePath, progress)
+	})
+}
+
+// Download downloads with the same deadline and stall watchdog as Upload
+func (t *timeoutBackend) Download(ctx context.Context, remotePath string, localPath string, progress ProgressCallback) error {
+	return t.transfer(ctx, progress, func(ctx context.Context, progress ProgressCallback) error {
+		return t.StorageBackend.Download(ctx, remotePath, localPath, progress)
+	})
+}
+
+// transfer runs a data transfer under the upload timeout and stall watchdog
+func (t *timeoutBackend) transfer(ctx context.Context, progress ProgressCallback, fn func(context.Context, ProgressCallback) error) error {
+	if t.uploadTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, t.uploadTimeout)
+		defer cancel()
+	}
+
+	if t.stallTimeout <= 0 {
+		return fn(ctx, progress)
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	watchdog := time.AfterFunc(t.stallTimeout, func() {
+		cancel(errTransferStalled)
+	})
+	defer watchdog.Stop()
+
+	err := fn(ctx, func(transferred, total int64) {
+		watchdog.Reset(t.stallTimeout)
+		if progress != nil {
+			progress(transferred, total)
+		}
+	})
+	if err != nil && errors.Is(context.Cause(ctx), errTransferStalled) {
+		return fmt.Errorf("%w: no progress for %s: %w", errTransferStalled, t.stallTimeout, context.DeadlineExceeded)
+	}
+	return err
+}
+
+// List lists backups, failing if the backend does not respond in time
+func (t *timeoutBackend) List(ctx context.Context, prefix string) ([]BackupInfo, error) {
+	ctx, cancel := t.withOperationTimeout(ctx)
+	defer cancel()
+	return t.StorageBackend.List(ctx, prefix)
+}
+
+// Delete deletes a backup, failing if the backend does not respond in time
+func (t *timeoutBackend) Delete(ctx context.Context, remotePath string) error {
+	ctx, cancel := t.withOperationTimeout(ctx)
+	defer cancel()
+	return t.StorageBackend.Delete(ctx, remotePath)
+}
+
+// Copy copies a backup under the upload timeout, since a server-side copy
+// takes time proportional to the object size
+func (t *timeoutBackend) Copy(ctx context.Context, srcRemotePath string, dstRemotePath string) error {
+	if t.uploadTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, t.uploadTimeout)
+		defer cancel()
+	}
+	return t.StorageBackend.Copy(ctx, srcRemotePath, dstRemotePath)
+}
+
+// GetUsage returns storage usage, failing if the backend does not respond in time
+func (t *timeoutBackend) GetUsage(ctx context.Context) (*models.StorageUsage, error) {
+	ctx, cancel := t.withOperationTimeout(ctx)
+	defer cancel()
+	return t.StorageBackend.GetUsage(ctx)
+}
+
+// ClassifyError delegates to the wrapped backend's classifier
+func (t *timeoutBackend) ClassifyError(err error) string {
+	return ClassifyError(t.StorageBackend, err)
+}
+
+func (t *timeoutBackend) withOperationTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if t.operationTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, t.operationTimeout)
+}
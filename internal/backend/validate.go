@@ -0,0 +1,91 @@
+package backend
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/nsilverman/archivist/internal/models"
+)
+
+// configRule describes the fields a backend type needs. Each entry of oneOf is
+// a group of alternatives of which at least one must be set.
+type configRule struct {
+	required []string
+	oneOf    [][]string
+}
+
+var configRules = map[string]configRule{
+	"local": {required: []string{"path"}},
+	"s3":    {required: []string{"bucket"}},
+	"gcs":   {required: []string{"bucket"}}, // Application Default Credentials apply when none are given
+	"gdrive": {
+		oneOf: [][]string{{"credentials_file", "credentials_json"}},
+	},
+	"azure": {
+		required: []string{"container", "account_name"},
+		oneOf:    [][]string{{"account_key", "sas_token", "connection_string"}},
+	},
+	"b2":     {required: []string{"bucket", "key_id", "application_key"}},
+	"memory": {},
+}
+
+// ValidateConfig checks that a backend config has the fields its type requires,
+// so an incomplete backend is rejected when saved rather than on first use.
+// Problems name the missing fields as config.<name>.
+func ValidateConfig(backendType string, cfg map[string]interface{}) []models.ConfigProblem {
+	rule, ok := configRules[backendType]
+	if !ok {
+		return []models.ConfigProblem{{
+			Field:   "type",
+			Message: fmt.Sprintf("unknown backend type: %s", backendType),
+		}}
+	}
+
+	var problems []models.ConfigProblem
+	for _, field := range rule.required {
+		if !configSet(cfg, field) {
+			problems = append(problems, models.ConfigProblem{
+				Field:   "config." + field,
+				Message: fmt.Sprintf("%s backend requires '%s'", backendType, field),
+			})
+		}
+	}
+
+	for _, group := range rule.oneOf {
+		found := false
+		for _, field := range group {
+			if configSet(cfg, field) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			problems = append(problems, models.ConfigProblem{
+				Field:   "config." + group[0],
+				Message: fmt.Sprintf("%s backend requires one of: %s", backendType, strings.Join(group, ", ")),
+			})
+		}
+	}
+
+	// Custom endpoints have no default region; see S3Backend.Initialize
+	if backendType == "s3" && configSet(cfg, "endpoint") && !configSet(cfg, "region") {
+		problems = append(problems, models.ConfigProblem{
+			Field:   "config.region",
+			Message: "s3 backend with a custom endpoint requires 'region'",
+		})
+	}
+
+	return problems
+}
+
+// configSet reports whether a config field has a non-empty value
+func configSet(cfg map[string]interface{}, field string) bool {
+	switch v := cfg[field].(type) {
+	case nil:
+		return false
+	case string:
+		return strings.TrimSpace(v) != ""
+	default:
+		return true
+	}
+}
@@ -0,0 +1,153 @@
+package config
+
+import (
+	"time"
+
+	"github.com/nsilverman/archivist/internal/models"
+)
+
+// The manager hands out and stores copies so callers never share slices,
+// maps or pointers with the configuration it guards.
+
+// cloneConfig returns a deep copy of a configuration
+func cloneConfig(c *models.Config) *models.Config {
+	clone := *c
+	clone.Settings = cloneSettings(c.Settings)
+
+	if c.Backends != nil {
+		clone.Backends = make([]models.Backend, len(c.Backends))
+		for i := range c.Backends {
+			clone.Backends[i] = cloneBackend(c.Backends[i])
+		}
+	}
+
+	if c.Tasks != nil {
+		clone.Tasks = make([]models.Task, len(c.Tasks))
+		for i := range c.Tasks {
+			clone.Tasks[i] = cloneTask(c.Tasks[i])
+		}
+	}
+
+	return &clone
+}
+
+// cloneBackend returns a deep copy of a backend, including its config map
+func cloneBackend(b models.Backend) models.Backend {
+	b.Config = cloneMap(b.Config)
+	b.LastTest = cloneTime(b.LastTest)
+	return b
+}
+
+// cloneTask returns a deep copy of a task
+func cloneTask(t models.Task) models.Task {
+	t.BackendIDs = cloneStrings(t.BackendIDs)
+	t.SyncBackendIDs = cloneStrings(t.SyncBackendIDs)
+	t.DependsOn = cloneStrings(t.DependsOn)
+	t.ArchiveOptions.StoreExtensions = cloneStrings(t.ArchiveOptions.StoreExtensions)
+	t.ArchiveOptions.Redaction = cloneRedaction(t.ArchiveOptions.Redaction)
+	t.LastRun = cloneTime(t.LastRun)
+	t.NextRun = cloneTime(t.NextRun)
+	if t.Notifications != nil {
+		notifications := *t.Notifications
+		notifications.Channels = cloneStrings(notifications.Channels)
+		notifications.Muted = cloneStrings(notifications.Muted)
+		notifications.Events = cloneStrings(notifications.Events)
+		t.Notifications = &notifications
+	}
+	t.Heartbeat = cloneHeartbeat(t.Heartbeat)
+	if t.FailureRetry != nil {
+		retry := *t.FailureRetry
+		t.FailureRetry = &retry
+	}
+	return t
+}
+
+// cloneSettings returns a deep copy of the settings
+func cloneSettings(s models.Settings) models.Settings {
+	if s.NotificationChannels != nil {
+		channels := make([]models.NotificationChannel, len(s.NotificationChannels))
+		for i, channel := range s.NotificationChannels {
+			channel.Events = cloneStrings(channel.Events)
+			channels[i] = channel
+		}
+		s.NotificationChannels = channels
+	}
+	s.Heartbeat = cloneHeartbeat(s.Heartbeat)
+	s.AllowedOrigins = cloneStrings(s.AllowedOrigins)
+	s.Redaction = cloneRedaction(s.Redaction)
+	if s.DefaultRetention != nil {
+		retention := *s.DefaultRetention
+		s.DefaultRetention = &retention
+	}
+	if s.StateBackup != nil {
+		stateBackup := *s.StateBackup
+		s.StateBackup = &stateBackup
+	}
+	return s
+}
+
+// cloneRedaction copies redaction settings
+func cloneRedaction(r *models.Redaction) *models.Redaction {
+	if r == nil {
+		return nil
+	}
+	clone := *r
+	clone.Patterns = cloneStrings(r.Patterns)
+	return &clone
+}
+
+// cloneHeartbeat copies heartbeat settings
+func cloneHeartbeat(h *models.Heartbeat) *models.Heartbeat {
+	if h == nil {
+		return nil
+	}
+	clone := *h
+	return &clone
+}
+
+// cloneStrings copies a string slice, keeping nil as nil
+func cloneStrings(s []string) []string {
+	if s == nil {
+		return nil
+	}
+	return append([]string(nil), s...)
+}
+
+// cloneMap deep copies a JSON-style map, descending into nested maps and slices
+func cloneMap(m map[string]interface{}) map[string]interface{} {
+	if m == nil {
+		return nil
+	}
+	clone := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		clone[k] = cloneValue(v)
+	}
+	return clone
+}
+
+// cloneValue deep copies a value decoded from JSON
+func cloneValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		return cloneMap(v)
+	case []interface{}:
+		clone := make([]interface{}, len(v))
+		for i := range v {
+			clone[i] = cloneValue(v[i])
+		}
+		return clone
+	case []string:
+		return append([]string(nil), v...)
+	default:
+		return v
+	}
+}
+
+// cloneTime copies a time pointer so the copy can't be modified through the original
+func cloneTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	clone := *t
+	return &clone
+}
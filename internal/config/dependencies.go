@@ -0,0 +1,84 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/nsilverman/archivist/internal/models"
+)
+
+// checkDependencies verifies that every task's depends_on names other existing
+// tasks and that following them never leads back to where it started
+func checkDependencies(tasks []models.Task) error {
+	byID := make(map[string]*models.Task, len(tasks))
+	for i := range tasks {
+		byID[tasks[i].ID] = &tasks[i]
+	}
+
+	for _, task := range tasks {
+		for _, id := range task.DependsOn {
+			if id == task.ID {
+				return fmt.Errorf("task %s cannot depend on itself", task.Name)
+			}
+			if byID[id] == nil {
+				return fmt.Errorf("task %s depends on non-existent task: %s", task.Name, id)
+			}
+		}
+	}
+
+	// Depth-first search; reaching a task that is still on the stack is a cycle
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[string]int, len(tasks))
+	var stack []string
+
+	var visit func(id string) error
+	visit = func(id string) error {
+		switch state[id] {
+		case visiting:
+			start := len(stack) - 1
+			for stack[start] != id {
+				start--
+			}
+			var names []string
+			for _, onStack := range append(stack[start:], id) {
+				names = append(names, byID[onStack].Name)
+			}
+			return fmt.Errorf("task dependencies form a cycle: %s", strings.Join(names, " -> "))
+		case done:
+			return nil
+		}
+
+		state[id] = visiting
+		stack = append(stack, id)
+		for _, dep := range byID[id].DependsOn {
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[id] = done
+		return nil
+	}
+
+	for _, task := range tasks {
+		if err := visit(task.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// withTask returns a copy of tasks with task added, or replacing the task with its ID
+func withTask(tasks []models.Task, task *models.Task) []models.Task {
+	result := make([]models.Task, 0, len(tasks)+1)
+	for _, t := range tasks {
+		if t.ID != task.ID {
+			result = append(result, t)
+		}
+	}
+	return append(result, *task)
+}
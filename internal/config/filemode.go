@@ -0,0 +1,44 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// DefaultConfigFileMode is used for the config file when no mode is set, since it holds credentials
+const DefaultConfigFileMode os.FileMode = 0600
+
+// ParseFileMode parses an octal permission string such as "0640".
+// An empty string returns 0, meaning no mode is configured.
+func ParseFileMode(mode string) (os.FileMode, error) {
+	if mode == "" {
+		return 0, nil
+	}
+	value, err := strconv.ParseUint(mode, 8, 32)
+	if err != nil || value > 0777 {
+		return 0, fmt.Errorf("invalid file mode %q: expected octal permissions such as 0600", mode)
+	}
+	return os.FileMode(value), nil
+}
+
+// ArchiveFileMode returns the permissions applied to created archives, or 0 to keep the umask default
+func (m *Manager) ArchiveFileMode() os.FileMode {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	mode, err := ParseFileMode(m.config.Settings.ArchiveFileMode)
+	if err != nil {
+		return 0
+	}
+	return mode
+}
+
+// configFileMode returns the permissions applied to the config file. Callers hold m.mu.
+func (m *Manager) configFileMode() os.FileMode {
+	mode, err := ParseFileMode(m.config.Settings.ConfigFileMode)
+	if err != nil || mode == 0 {
+		return DefaultConfigFileMode
+	}
+	return mode
+}
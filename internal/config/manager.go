@@ -2,15 +2,20 @@ package config
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/nsilverman/archivist/internal/archive"
 	"github.com/nsilverman/archivist/internal/models"
+	"github.com/nsilverman/archivist/internal/notify"
 )
 
 // Manager manages application configuration
@@ -59,6 +64,15 @@ func (m *Manager) Load() error {
 	return nil
 }
 
+// Reload re-reads the configuration from disk and swaps it in.
+// If the file cannot be read or fails validation, the current configuration is kept.
+func (m *Manager) Reload() error {
+	if err := m.Load(); err != nil {
+		return fmt.Errorf("failed to reload configuration: %w", err)
+	}
+	return nil
+}
+
 // Save saves the configuration to disk
 func (m *Manager) Save() error {
 	m.mu.RLock()
@@ -76,10 +90,15 @@ func (m *Manager) saveInternal() error {
 
 	// Write atomically by writing to a temp file and renaming
 	tempPath := m.configPath + ".tmp"
-	if err := os.WriteFile(tempPath, data, 0644); err != nil {
+	if err := os.WriteFile(tempPath, data, 0600); err != nil {
 		return fmt.Errorf("failed to write configuration: %w", err)
 	}
 
+	// WriteFile only applies the mode to new files and is subject to the umask
+	if err := os.Chmod(tempPath, m.configFileMode()); err != nil {
+		return fmt.Errorf("failed to set configuration file mode: %w", err)
+	}
+
 	if err := os.Rename(tempPath, m.configPath); err != nil {
 		if removeErr := os.Remove(tempPath); removeErr != nil {
 			log.Printf("Warning: failed to remove temp file: %v", removeErr)
@@ -122,19 +141,28 @@ func (m *Manager) Get() *models.Config {
 	defer m.mu.RUnlock()
 
 	// Return a deep copy to prevent external modifications
-	configCopy := *m.config
-	return &configCopy
+	return cloneConfig(m.config)
+}
+
+// IsLoaded reports whether a configuration has been loaded or created
+func (m *Manager) IsLoaded() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.config != nil
 }
 
 // GetSettings returns the current settings
 func (m *Manager) GetSettings() models.Settings {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
-	return m.config.Settings
+	return cloneSettings(m.config.Settings)
 }
 
-// ResolvePath resolves a path relative to the root directory if it's not absolute
+// ResolvePath resolves a path relative to the root directory if it's not absolute.
+// Environment variables are expanded first, so "${DATA_DIR}/app" may resolve to
+// an absolute path. Only local paths go through here; remote keys are never expanded.
 func (m *Manager) ResolvePath(path string) string {
+	path = os.ExpandEnv(path)
 	if filepath.IsAbs(path) {
 		return path
 	}
@@ -145,7 +173,19 @@ func (m *Manager) ResolvePath(path string) string {
 func (m *Manager) UpdateSettings(settings models.Settings) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	m.config.Settings = settings
+
+	if err := m.checkSelfBackupAll(m.config.Backends, settings); err != nil {
+		return err
+	}
+
+	// Notification channels must be valid and still cover every task's references
+	candidate := *m.config
+	candidate.Settings = settings
+	if err := m.validate(&candidate); err != nil {
+		return err
+	}
+
+	m.config.Settings = cloneSettings(settings)
 	return m.saveInternal()
 }
 
@@ -156,7 +196,7 @@ func (m *Manager) GetBackend(id string) (*models.Backend, error) {
 
 	for i := range m.config.Backends {
 		if m.config.Backends[i].ID == id {
-			backend := m.config.Backends[i]
+			backend := cloneBackend(m.config.Backends[i])
 			return &backend, nil
 		}
 	}
@@ -169,7 +209,9 @@ func (m *Manager) GetBackends() []models.Backend {
 	defer m.mu.RUnlock()
 
 	backends := make([]models.Backend, len(m.config.Backends))
-	copy(backends, m.config.Backends)
+	for i := range m.config.Backends {
+		backends[i] = cloneBackend(m.config.Backends[i])
+	}
 	return backends
 }
 
@@ -195,7 +237,7 @@ func (m *Manager) AddBackend(backend *models.Backend) error {
 	backend.CreatedAt = now
 	backend.UpdatedAt = now
 
-	m.config.Backends = append(m.config.Backends, *backend)
+	m.config.Backends = append(m.config.Backends, cloneBackend(*backend))
 	return m.saveInternal()
 }
 
@@ -210,7 +252,14 @@ func (m *Manager) UpdateBackend(id string, backend *models.Backend) error {
 			backend.ID = id
 			backend.CreatedAt = m.config.Backends[i].CreatedAt
 			backend.UpdatedAt = time.Now()
-			m.config.Backends[i] = *backend
+
+			backends := append([]models.Backend(nil), m.config.Backends...)
+			backends[i] = *backend
+			if err := m.checkSelfBackupAll(backends, m.config.Settings); err != nil {
+				return err
+			}
+
+			m.config.Backends[i] = cloneBackend(*backend)
 			return m.saveInternal()
 		}
 	}
@@ -224,13 +273,17 @@ func (m *Manager) DeleteBackend(id string) error {
 
 	// Check if backend is used by any task
 	for _, task := range m.config.Tasks {
-		for _, backendID := range task.BackendIDs {
+		for _, backendID := range taskBackendIDs(&task) {
 			if backendID == id {
 				return fmt.Errorf("backend is in use by task: %s", task.Name)
 			}
 		}
 	}
 
+	if stateBackup := m.config.Settings.StateBackup; stateBackup != nil && stateBackup.BackendID == id {
+		return fmt.Errorf("backend is in use by the state backup")
+	}
+
 	// Find and remove backend
 	for i := range m.config.Backends {
 		if m.config.Backends[i].ID == id {
@@ -248,7 +301,7 @@ func (m *Manager) GetTask(id string) (*models.Task, error) {
 
 	for i := range m.config.Tasks {
 		if m.config.Tasks[i].ID == id {
-			task := m.config.Tasks[i]
+			task := cloneTask(m.config.Tasks[i])
 			return &task, nil
 		}
 	}
@@ -261,7 +314,9 @@ func (m *Manager) GetTasks() []models.Task {
 	defer m.mu.RUnlock()
 
 	tasks := make([]models.Task, len(m.config.Tasks))
-	copy(tasks, m.config.Tasks)
+	for i := range m.config.Tasks {
+		tasks[i] = cloneTask(m.config.Tasks[i])
+	}
 	return tasks
 }
 
@@ -278,19 +333,12 @@ func (m *Manager) AddTask(task *models.Task) error {
 	// Check for duplicate ID
 	for _, t := range m.config.Tasks {
 		if t.ID == task.ID {
-			return fmt.Errorf("task with ID %s already exists", task.ID)
+			return &ValidationError{Field: "id", Message: fmt.Sprintf("task with ID %s already exists", task.ID)}
 		}
 	}
 
-	// Validate backends exist - build map for O(n) lookup
-	backendMap := make(map[string]bool, len(m.config.Backends))
-	for _, backend := range m.config.Backends {
-		backendMap[backend.ID] = true
-	}
-	for _, backendID := range task.BackendIDs {
-		if !backendMap[backendID] {
-			return fmt.Errorf("backend not found: %s", backendID)
-		}
+	if err := m.validateTask(m.config, task); err != nil {
+		return err
 	}
 
 	// Set timestamps
@@ -298,10 +346,70 @@ func (m *Manager) AddTask(task *models.Task) error {
 	task.CreatedAt = now
 	task.UpdatedAt = now
 
-	m.config.Tasks = append(m.config.Tasks, *task)
+	m.config.Tasks = append(m.config.Tasks, cloneTask(*task))
 	return m.saveInternal()
 }
 
+// ValidationError is returned when a task is rejected by validation. Field
+// names the offending field in its JSON form, e.g. "retention_policy".
+type ValidationError struct {
+	Field   string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Message
+}
+
+// invalid wraps err from a check of field in a ValidationError, passing nil through
+func invalid(field string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &ValidationError{Field: field, Message: err.Error()}
+}
+
+// validateTask checks a task being added to or updated in cfg, returning a
+// *ValidationError for the first problem found. The caller must hold m.mu.
+func (m *Manager) validateTask(cfg *models.Config, task *models.Task) error {
+	// Validate backends exist - build map for O(n) lookup
+	backendMap := make(map[string]bool, len(cfg.Backends))
+	for _, backend := range cfg.Backends {
+		backendMap[backend.ID] = true
+	}
+	for _, backendID := range task.BackendIDs {
+		if !backendMap[backendID] {
+			return &ValidationError{Field: "backend_ids", Message: fmt.Sprintf("backend not found: %s", backendID)}
+		}
+	}
+	if err := checkSyncBackends(task, backendMap); err != nil {
+		return invalid("sync_backend_ids", err)
+	}
+
+	if err := m.checkSelfBackup(task, cfg.Backends, cfg.Settings); err != nil {
+		return invalid("source_path", err)
+	}
+	if err := checkTaskNotifications(task, cfg.Settings); err != nil {
+		return invalid("notifications", err)
+	}
+	if err := checkHeartbeat(task.Heartbeat); err != nil {
+		return invalid("heartbeat", err)
+	}
+	if err := checkFailureRetry(task.FailureRetry); err != nil {
+		return invalid("failure_retry", err)
+	}
+	if err := archive.ValidateRedaction(task.ArchiveOptions.Redaction); err != nil {
+		return invalid("archive_options.redaction", err)
+	}
+	if err := archive.ValidateCompression(task.ArchiveOptions.Compression); err != nil {
+		return invalid("archive_options.compression", err)
+	}
+	if err := checkRetention(task); err != nil {
+		return invalid("retention_policy", err)
+	}
+	return invalid("depends_on", checkDependencies(withTask(cfg.Tasks, task)))
+}
+
 // UpdateTask updates an existing task
 func (m *Manager) UpdateTask(id string, task *models.Task) error {
 	m.mu.Lock()
@@ -314,18 +422,11 @@ func (m *Manager) UpdateTask(id string, task *models.Task) error {
 			task.CreatedAt = m.config.Tasks[i].CreatedAt
 			task.UpdatedAt = time.Now()
 
-			// Validate backends exist - build map for O(n) lookup
-			backendMap := make(map[string]bool, len(m.config.Backends))
-			for _, backend := range m.config.Backends {
-				backendMap[backend.ID] = true
-			}
-			for _, backendID := range task.BackendIDs {
-				if !backendMap[backendID] {
-					return fmt.Errorf("backend not found: %s", backendID)
-				}
+			if err := m.validateTask(m.config, task); err != nil {
+				return err
 			}
 
-			m.config.Tasks[i] = *task
+			m.config.Tasks[i] = cloneTask(*task)
 			return m.saveInternal()
 		}
 	}
@@ -340,6 +441,18 @@ func (m *Manager) DeleteTask(id string) error {
 	for i := range m.config.Tasks {
 		if m.config.Tasks[i].ID == id {
 			m.config.Tasks = append(m.config.Tasks[:i], m.config.Tasks[i+1:]...)
+
+			// Tasks that ran after the deleted one no longer have it to wait for
+			for j := range m.config.Tasks {
+				dependsOn := m.config.Tasks[j].DependsOn
+				for k := 0; k < len(dependsOn); k++ {
+					if dependsOn[k] == id {
+						dependsOn = append(dependsOn[:k], dependsOn[k+1:]...)
+						k--
+					}
+				}
+				m.config.Tasks[j].DependsOn = dependsOn
+			}
 			return m.saveInternal()
 		}
 	}
@@ -353,67 +466,292 @@ func (m *Manager) UpdateTaskSchedule(id string, lastRun, nextRun *time.Time) err
 
 	for i := range m.config.Tasks {
 		if m.config.Tasks[i].ID == id {
-			m.config.Tasks[i].LastRun = lastRun
-			m.config.Tasks[i].NextRun = nextRun
+			m.config.Tasks[i].LastRun = cloneTime(lastRun)
+			m.config.Tasks[i].NextRun = cloneTime(nextRun)
 			return m.saveInternal()
 		}
 	}
 	return fmt.Errorf("task not found: %s", id)
 }
 
-// validate validates the configuration
+// checkTaskNotifications verifies that a task's notification overrides name
+// existing channels and known events
+func checkTaskNotifications(task *models.Task, settings models.Settings) error {
+	if task.Notifications == nil {
+		return nil
+	}
+
+	channelNames := make(map[string]bool, len(settings.NotificationChannels))
+	for _, channel := range settings.NotificationChannels {
+		channelNames[channel.Name] = true
+	}
+	for _, names := range [][]string{task.Notifications.Channels, task.Notifications.Muted} {
+		for _, name := range names {
+			if !channelNames[name] {
+				return fmt.Errorf("notification channel not found: %s", name)
+			}
+		}
+	}
+	for _, event := range task.Notifications.Events {
+		if err := notify.ValidateEvent(event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkHeartbeat verifies that every heartbeat URL set is an http(s) URL
+func checkHeartbeat(heartbeat *models.Heartbeat) error {
+	if heartbeat == nil {
+		return nil
+	}
+	for _, url := range []string{heartbeat.StartURL, heartbeat.SuccessURL, heartbeat.FailureURL} {
+		if url == "" {
+			continue
+		}
+		if err := notify.ValidateURL(url); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// maxFailureRetries caps how many times a failed scheduled run is re-run
+const maxFailureRetries = 10
+
+// maxScanWorkers caps settings.scan_workers
+const maxScanWorkers = 64
+
+// checkOrigin verifies an allowed_origins entry: "*", or a scheme and host
+// such as "https://ui.example.com:8443" with no path
+func checkOrigin(origin string) error {
+	if origin == "*" {
+		return nil
+	}
+	u, err := url.Parse(origin)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || strings.Trim(u.Path, "/") != "" || u.RawQuery != "" || u.Fragment != "" {
+		return fmt.Errorf("invalid origin %q: expected a scheme and host such as https://ui.example.com", origin)
+	}
+	return nil
+}
+
+// checkRetention verifies a task's retention policy. keep_last is a count of
+// backups, 0 to use the default retention, or -1 for unlimited. Chunked
+// archives share chunks with each other, so retention can't prune them.
+func checkRetention(task *models.Task) error {
+	policy := task.RetentionPolicy
+	if policy.KeepLast < -1 {
+		return fmt.Errorf("keep_last must be -1 (unlimited), 0 (default retention) or a number of backups")
+	}
+	if policy.KeepLast > 0 && task.ArchiveOptions.Format == archive.FormatChunked {
+		return fmt.Errorf("keep_last is not supported for chunked archives; they are kept until deleted")
+	}
+	return nil
+}
+
+// checkFailureRetry verifies a task's re-run settings for failed scheduled runs
+func checkFailureRetry(retry *models.FailureRetry) error {
+	if retry == nil {
+		return nil
+	}
+	if retry.Attempts < 1 || retry.Attempts > maxFailureRetries {
+		return fmt.Errorf("failure retry attempts must be between 1 and %d", maxFailureRetries)
+	}
+	if retry.DelayMinutes < 1 {
+		return fmt.Errorf("failure retry delay must be at least 1 minute")
+	}
+	return nil
+}
+
+// validate validates the configuration, reporting every problem found
 func (m *Manager) validate(config *models.Config) error {
+	problems := m.collectProblems(config)
+	if len(problems) == 0 {
+		return nil
+	}
+
+	errs := make([]error, len(problems))
+	for i, problem := range problems {
+		errs[i] = errors.New(problem.Message)
+	}
+	return errors.Join(errs...)
+}
+
+// Validate checks a configuration without applying it. Besides the checks made
+// on load, tasks are checked for missing sources and for backing up their own output.
+func (m *Manager) Validate(config *models.Config) []models.ConfigProblem {
+	problems := m.collectProblems(config)
+
+	for i := range config.Tasks {
+		task := &config.Tasks[i]
+		field := fmt.Sprintf("tasks[%d]", i)
+
+		if task.SourcePath != "" {
+			if _, err := os.Stat(m.ResolvePath(task.SourcePath)); err != nil {
+				problems = append(problems, models.ConfigProblem{
+					Field:   field + ".source_path",
+					Message: fmt.Sprintf("source path of task %s is not accessible: %v", task.ID, err),
+				})
+			}
+		}
+
+		if err := m.checkSelfBackup(task, config.Backends, config.Settings); err != nil {
+			problems = append(problems, models.ConfigProblem{Field: field, Message: err.Error()})
+		}
+	}
+
+	return problems
+}
+
+// collectProblems runs the structural checks that a configuration must pass to be loaded
+func (m *Manager) collectProblems(config *models.Config) []models.ConfigProblem {
+	var problems []models.ConfigProblem
+	add := func(field, format string, args ...interface{}) {
+		problems = append(problems, models.ConfigProblem{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
 	if config.Version == "" {
-		return fmt.Errorf("version is required")
+		add("version", "version is required")
+	}
+	if _, err := ParseFileMode(config.Settings.ArchiveFileMode); err != nil {
+		add("settings.archive_file_mode", "%v", err)
+	}
+	if _, err := ParseFileMode(config.Settings.ConfigFileMode); err != nil {
+		add("settings.config_file_mode", "%v", err)
+	}
+	if err := archive.ValidateHashAlgorithm(config.Settings.HashAlgorithm); err != nil {
+		add("settings.hash_algorithm", "%v", err)
+	}
+
+	if err := checkHeartbeat(config.Settings.Heartbeat); err != nil {
+		add("settings.heartbeat", "%v", err)
+	}
+	if err := archive.ValidateRedaction(config.Settings.Redaction); err != nil {
+		add("settings.redaction", "%v", err)
+	}
+	if config.Settings.MinFreeTempBytes < -1 {
+		add("settings.min_free_temp_bytes", "min_free_temp_bytes must be -1 (no check), 0 (default) or a number of bytes")
+	}
+	if retention := config.Settings.DefaultRetention; retention != nil && retention.KeepLast < 0 {
+		add("settings.default_retention.keep_last", "default keep_last cannot be negative")
+	}
+	if workers := config.Settings.ScanWorkers; workers < 0 || workers > maxScanWorkers {
+		add("settings.scan_workers", "scan_workers must be between 0 and %d", maxScanWorkers)
+	}
+	for i, origin := range config.Settings.AllowedOrigins {
+		if err := checkOrigin(origin); err != nil {
+			add(fmt.Sprintf("settings.allowed_origins[%d]", i), "%v", err)
+		}
+	}
+
+	// Validate notification channels
+	channelNames := make(map[string]bool)
+	for i, channel := range config.Settings.NotificationChannels {
+		field := fmt.Sprintf("settings.notification_channels[%d]", i)
+
+		if channel.Name == "" {
+			add(field+".name", "notification channel name is required")
+		} else if channelNames[channel.Name] {
+			add(field+".name", "duplicate notification channel: %s", channel.Name)
+		}
+		channelNames[channel.Name] = true
+
+		if err := notify.ValidateURL(channel.URL); err != nil {
+			add(field+".url", "%v", err)
+		}
+		for _, event := range channel.Events {
+			if err := notify.ValidateEvent(event); err != nil {
+				add(field+".events", "%v", err)
+			}
+		}
 	}
 
 	// Validate backends
 	backendIDs := make(map[string]bool)
-	for _, backend := range config.Backends {
+	for i, backend := range config.Backends {
+		field := fmt.Sprintf("backends[%d]", i)
+
 		if backend.ID == "" {
-			return fmt.Errorf("backend ID is required")
-		}
-		if backendIDs[backend.ID] {
-			return fmt.Errorf("duplicate backend ID: %s", backend.ID)
+			add(field+".id", "backend ID is required")
+		} else if backendIDs[backend.ID] {
+			add(field+".id", "duplicate backend ID: %s", backend.ID)
 		}
 		backendIDs[backend.ID] = true
 
 		if backend.Type == "" {
-			return fmt.Errorf("backend type is required for backend: %s", backend.ID)
+			add(field+".type", "backend type is required for backend: %s", backend.ID)
 		}
 		if backend.Name == "" {
-			return fmt.Errorf("backend name is required for backend: %s", backend.ID)
+			add(field+".name", "backend name is required for backend: %s", backend.ID)
+		}
+	}
+
+	if stateBackup := config.Settings.StateBackup; stateBackup != nil {
+		if stateBackup.BackendID == "" {
+			add("settings.state_backup.backend_id", "state backup requires a backend")
+		} else if !backendIDs[stateBackup.BackendID] {
+			add("settings.state_backup.backend_id", "state backup references non-existent backend: %s", stateBackup.BackendID)
+		}
+		if stateBackup.KeepLast < 0 {
+			add("settings.state_backup.keep_last", "keep_last must be 0 (keep every state backup) or a number of backups")
 		}
 	}
 
 	// Validate tasks
 	taskIDs := make(map[string]bool)
-	for _, task := range config.Tasks {
+	for i, task := range config.Tasks {
+		field := fmt.Sprintf("tasks[%d]", i)
+
 		if task.ID == "" {
-			return fmt.Errorf("task ID is required")
-		}
-		if taskIDs[task.ID] {
-			return fmt.Errorf("duplicate task ID: %s", task.ID)
+			add(field+".id", "task ID is required")
+		} else if taskIDs[task.ID] {
+			add(field+".id", "duplicate task ID: %s", task.ID)
 		}
 		taskIDs[task.ID] = true
 
 		if task.Name == "" {
-			return fmt.Errorf("task name is required for task: %s", task.ID)
+			add(field+".name", "task name is required for task: %s", task.ID)
 		}
 		if task.SourcePath == "" {
-			return fmt.Errorf("source path is required for task: %s", task.ID)
+			add(field+".source_path", "source path is required for task: %s", task.ID)
 		}
 		if len(task.BackendIDs) == 0 {
-			return fmt.Errorf("at least one backend is required for task: %s", task.ID)
+			add(field+".backend_ids", "at least one backend is required for task: %s", task.ID)
 		}
 
 		// Validate backend references
 		for _, backendID := range task.BackendIDs {
 			if !backendIDs[backendID] {
-				return fmt.Errorf("task %s references non-existent backend: %s", task.ID, backendID)
+				add(field+".backend_ids", "task %s references non-existent backend: %s", task.ID, backendID)
 			}
 		}
+		if err := checkSyncBackends(&task, backendIDs); err != nil {
+			add(field+".sync_backend_ids", "task %s: %v", task.ID, err)
+		}
+
+		if err := checkTaskNotifications(&task, config.Settings); err != nil {
+			add(field+".notifications", "task %s: %v", task.ID, err)
+		}
+		if err := checkHeartbeat(task.Heartbeat); err != nil {
+			add(field+".heartbeat", "task %s: %v", task.ID, err)
+		}
+		if err := checkFailureRetry(task.FailureRetry); err != nil {
+			add(field+".failure_retry", "task %s: %v", task.ID, err)
+		}
+		if err := archive.ValidateRedaction(task.ArchiveOptions.Redaction); err != nil {
+			add(field+".archive_options.redaction", "task %s: %v", task.ID, err)
+		}
+		if err := archive.ValidateCompression(task.ArchiveOptions.Compression); err != nil {
+			add(field+".archive_options.compression", "task %s: %v", task.ID, err)
+		}
+		if err := checkRetention(&task); err != nil {
+			add(field+".retention_policy", "task %s: %v", task.ID, err)
+		}
 	}
 
-	return nil
+	if err := checkDependencies(config.Tasks); err != nil {
+		add("tasks", "%v", err)
+	}
+
+	return problems
 }
@@ -0,0 +1,340 @@
+package config
+
+import (
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/nsilverman/archivist/internal/models"
+)
+
+// newTestManager returns a manager over a default configuration in a
+// temporary directory, with a local backend "local"
+func newTestManager(t *testing.T) *Manager {
+	t.Helper()
+	dir := t.TempDir()
+	m, err := NewManager(filepath.Join(dir, "config.json"), dir)
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+	if err := m.CreateDefaultWithPaths(filepath.Join(dir, "temp"), filepath.Join(dir, "sources")); err != nil {
+		t.Fatalf("CreateDefaultWithPaths: %v", err)
+	}
+	if err := m.AddBackend(&models.Backend{
+		ID:      "local",
+		Name:    "local",
+		Type:    "local",
+		Enabled: true,
+		Config:  map[string]interface{}{"path": filepath.Join(dir, "store")},
+	}); err != nil {
+		t.Fatalf("AddBackend: %v", err)
+	}
+	return m
+}
+
+// testTask returns a valid archive task writing to the "local" backend
+func testTask(t *testing.T, id string) *models.Task {
+	return &models.Task{
+		ID:             id,
+		Name:           id,
+		SourcePath:     t.TempDir(),
+		BackendIDs:     []string{"local"},
+		Schedule:       models.Schedule{Type: "manual"},
+		ArchiveOptions: models.ArchiveOptions{Format: "tar.gz", UseTimestamp: true},
+		Enabled:        true,
+	}
+}
+
+func TestGetReturnsDeepCopy(t *testing.T) {
+	m := newTestManager(t)
+	task := testTask(t, "task-1")
+	task.ArchiveOptions.StoreExtensions = []string{".jpg"}
+	if err := m.AddTask(task); err != nil {
+		t.Fatalf("AddTask: %v", err)
+	}
+	settings := m.GetSettings()
+	settings.DefaultRetention = &models.RetentionPolicy{KeepLast: 7}
+	settings.StateBackup = &models.StateBackup{BackendID: "local"}
+	settings.AllowedOrigins = []string{"https://example.com"}
+	if err := m.UpdateSettings(settings); err != nil {
+		t.Fatalf("UpdateSettings: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		mutate func(c *models.Config)
+	}{
+		{"backend config map", func(c *models.Config) { c.Backends[0].Config["path"] = "/elsewhere" }},
+		{"task backend IDs", func(c *models.Config) { c.Tasks[0].BackendIDs[0] = "other" }},
+		{"task store extensions", func(c *models.Config) { c.Tasks[0].ArchiveOptions.StoreExtensions[0] = ".png" }},
+		{"default retention", func(c *models.Config) { c.Settings.DefaultRetention.KeepLast = 1 }},
+		{"state backup", func(c *models.Config) { c.Settings.StateBackup.KeepLast = 1 }},
+		{"allowed origins", func(c *models.Config) { c.Settings.AllowedOrigins[0] = "*" }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mutate(m.Get())
+
+			c := m.Get()
+			switch {
+			case c.Backends[0].Config["path"] == "/elsewhere",
+				c.Tasks[0].BackendIDs[0] != "local",
+				c.Tasks[0].ArchiveOptions.StoreExtensions[0] != ".jpg",
+				c.Settings.DefaultRetention.KeepLast != 7,
+				c.Settings.StateBackup.KeepLast != 0,
+				c.Settings.AllowedOrigins[0] != "https://example.com":
+				t.Errorf("mutating the result of Get changed the manager's configuration")
+			}
+		})
+	}
+}
+
+// TestGetConcurrentMutation is meant for -race: callers mutating what Get,
+// GetTask and GetBackend return must not race with each other or with writers
+func TestGetConcurrentMutation(t *testing.T) {
+	m := newTestManager(t)
+	if err := m.AddTask(testTask(t, "task-1")); err != nil {
+		t.Fatalf("AddTask: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				c := m.Get()
+				c.Backends[0].Config["path"] = "/mutated"
+				c.Tasks[0].BackendIDs = append(c.Tasks[0].BackendIDs, "mutated")
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				task, err := m.GetTask("task-1")
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				task.BackendIDs[0] = "mutated"
+				backend, err := m.GetBackend("local")
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				backend.Config["path"] = "/mutated"
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				task, err := m.GetTask("task-1")
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				task.Description = "updated"
+				if err := m.UpdateTask(task.ID, task); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	task, err := m.GetTask("task-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(task.BackendIDs) != 1 || task.BackendIDs[0] != "local" {
+		t.Errorf("task backend IDs = %v, want [local]", task.BackendIDs)
+	}
+	backend, err := m.GetBackend("local")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if backend.Config["path"] == "/mutated" {
+		t.Error("backend config was changed through a copy")
+	}
+}
+
+func TestAddTaskValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(task *models.Task)
+		wantErr string
+	}{
+		{"valid", func(task *models.Task) {}, ""},
+		{"no compression", func(task *models.Task) { task.ArchiveOptions.Compression = "none" }, ""},
+		{"automatic compression", func(task *models.Task) { task.ArchiveOptions.Compression = "auto" }, ""},
+		{"zstd compression", func(task *models.Task) { task.ArchiveOptions.Compression = "zstd" }, ""},
+		{"unknown compression", func(task *models.Task) { task.ArchiveOptions.Compression = "bzip2" }, "unsupported compression"},
+		{"unknown backend", func(task *models.Task) { task.BackendIDs = []string{"missing"} }, "backend not found"},
+		{"keep_last below -1", func(task *models.Task) { task.RetentionPolicy.KeepLast = -2 }, "keep_last"},
+		{"chunked with keep_last", func(task *models.Task) {
+			task.ArchiveOptions.Format = "chunked"
+			task.RetentionPolicy.KeepLast = 5
+		}, "chunked"},
+		{"chunked keeping everything", func(task *models.Task) {
+			task.ArchiveOptions.Format = "chunked"
+			task.RetentionPolicy.KeepLast = -1
+		}, ""},
+		{"depends on itself", func(task *models.Task) { task.DependsOn = []string{task.ID} }, "itself"},
+		{"depends on a missing task", func(task *models.Task) { task.DependsOn = []string{"missing"} }, "non-existent task"},
+		{"sync backend also an archive backend", func(task *models.Task) { task.SyncBackendIDs = []string{"local"} }, "both"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestManager(t)
+			task := testTask(t, "task-1")
+			tt.modify(task)
+
+			err := m.AddTask(task)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("AddTask: %v", err)
+			case tt.wantErr != "" && err == nil:
+				t.Errorf("AddTask succeeded, want an error mentioning %q", tt.wantErr)
+			case tt.wantErr != "" && !strings.Contains(err.Error(), tt.wantErr):
+				t.Errorf("AddTask error %q, want one mentioning %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestUpdateTaskRejectsDependencyCycle(t *testing.T) {
+	m := newTestManager(t)
+	if err := m.AddTask(testTask(t, "dump")); err != nil {
+		t.Fatalf("AddTask: %v", err)
+	}
+	upload := testTask(t, "upload")
+	upload.DependsOn = []string{"dump"}
+	if err := m.AddTask(upload); err != nil {
+		t.Fatalf("AddTask of a two-task chain: %v", err)
+	}
+
+	// Closing the chain into a loop is refused, and the stored task is unchanged
+	dump, err := m.GetTask("dump")
+	if err != nil {
+		t.Fatalf("GetTask: %v", err)
+	}
+	dump.DependsOn = []string{"upload"}
+	err = m.UpdateTask("dump", dump)
+	if err == nil || !strings.Contains(err.Error(), "cycle") {
+		t.Fatalf("UpdateTask error %v, want a dependency cycle", err)
+	}
+	if stored, _ := m.GetTask("dump"); len(stored.DependsOn) != 0 {
+		t.Errorf("rejected update was stored: depends_on = %v", stored.DependsOn)
+	}
+}
+
+func TestValidateProblemFields(t *testing.T) {
+	tests := []struct {
+		name      string
+		modify    func(c *models.Config)
+		wantField string
+	}{
+		{"unknown compression", func(c *models.Config) { c.Tasks[0].ArchiveOptions.Compression = "bzip2" }, "tasks[0].archive_options.compression"},
+		{"chunked with keep_last", func(c *models.Config) {
+			c.Tasks[0].ArchiveOptions.Format = "chunked"
+			c.Tasks[0].RetentionPolicy.KeepLast = 3
+		}, "tasks[0].retention_policy"},
+		{"state backup without a backend", func(c *models.Config) { c.Settings.StateBackup = &models.StateBackup{} }, "settings.state_backup.backend_id"},
+		{"state backup to a missing backend", func(c *models.Config) { c.Settings.StateBackup = &models.StateBackup{BackendID: "missing"} }, "settings.state_backup.backend_id"},
+		{"negative state backup keep_last", func(c *models.Config) {
+			c.Settings.StateBackup = &models.StateBackup{BackendID: "local", KeepLast: -1}
+		}, "settings.state_backup.keep_last"},
+		{"dependency cycle", func(c *models.Config) {
+			second := c.Tasks[0]
+			second.ID, second.Name = "task-2", "task-2"
+			second.DependsOn = []string{"task-1"}
+			c.Tasks[0].DependsOn = []string{"task-2"}
+			c.Tasks = append(c.Tasks, second)
+		}, "tasks"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestManager(t)
+			if err := m.AddTask(testTask(t, "task-1")); err != nil {
+				t.Fatalf("AddTask: %v", err)
+			}
+			c := m.Get()
+			if problems := m.Validate(c); len(problems) != 0 {
+				t.Fatalf("valid configuration has problems: %v", problems)
+			}
+
+			tt.modify(c)
+			problems := m.Validate(c)
+			for _, problem := range problems {
+				if problem.Field == tt.wantField {
+					return
+				}
+			}
+			t.Errorf("Validate = %v, want a problem with field %s", problems, tt.wantField)
+		})
+	}
+}
+
+// TestValidateReportsEveryProblem checks that validation doesn't stop at the
+// first problem it finds
+func TestValidateReportsEveryProblem(t *testing.T) {
+	m := newTestManager(t)
+	if err := m.AddTask(testTask(t, "task-1")); err != nil {
+		t.Fatalf("AddTask: %v", err)
+	}
+	c := m.Get()
+	c.Version = ""
+	c.Settings.ArchiveFileMode = "0999"
+	c.Settings.ScanWorkers = -1
+	c.Backends = append(c.Backends, models.Backend{ID: "local", Name: "duplicate", Type: "local"})
+	c.Tasks[0].ArchiveOptions.Compression = "bzip2"
+	c.Tasks[0].BackendIDs = []string{"missing"}
+
+	problems := m.Validate(c)
+	fields := make(map[string]bool)
+	for _, problem := range problems {
+		fields[problem.Field] = true
+	}
+	for _, want := range []string{
+		"version",
+		"settings.archive_file_mode",
+		"settings.scan_workers",
+		"backends[1].id",
+		"tasks[0].archive_options.compression",
+		"tasks[0].backend_ids",
+	} {
+		if !fields[want] {
+			t.Errorf("no problem reported for %s in %v", want, problems)
+		}
+	}
+}
+
+func TestParseFileMode(t *testing.T) {
+	tests := []struct {
+		mode    string
+		want    uint32
+		wantErr bool
+	}{
+		{"", 0, false},
+		{"0600", 0600, false},
+		{"640", 0640, false},
+		{"0777", 0777, false},
+		{"1777", 0, true},
+		{"0800", 0, true},
+		{"rw-r--r--", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseFileMode(tt.mode)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseFileMode(%q) error = %v, want error %v", tt.mode, err, tt.wantErr)
+			continue
+		}
+		if uint32(got) != tt.want {
+			t.Errorf("ParseFileMode(%q) = %o, want %o", tt.mode, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/nsilverman/archivist/internal/models"
+)
+
+// ValidateTaskPaths checks that a task would not back up its own output:
+// no local backend it writes to, nor its local copy directory or the temp
+// directory, may live inside its source
+func (m *Manager) ValidateTaskPaths(task *models.Task) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.checkSelfBackup(task, m.config.Backends, m.config.Settings)
+}
+
+// checkSelfBackup is ValidateTaskPaths against an explicit set of backends and
+// settings, so changes can be checked before they are applied. Callers hold m.mu.
+func (m *Manager) checkSelfBackup(task *models.Task, backends []models.Backend, settings models.Settings) error {
+	source := canonicalPath(m.ResolvePath(task.SourcePath))
+
+	for _, backendID := range taskBackendIDs(task) {
+		for _, backend := range backends {
+			if backend.ID != backendID || backend.Type != "local" {
+				continue
+			}
+			path, ok := backend.Config["path"].(string)
+			if !ok || path == "" {
+				continue
+			}
+			if isWithinDir(source, canonicalPath(m.ResolvePath(path))) {
+				return fmt.Errorf("backend %s stores backups inside the source of task %s, so each run would back up previous backups", backend.Name, task.Name)
+			}
+		}
+	}
+
+	if dir := task.ArchiveOptions.LocalCopyDir; dir != "" && isWithinDir(source, canonicalPath(m.ResolvePath(dir))) {
+		return fmt.Errorf("local copy directory is inside the source of task %s, so each run would back up previous backups", task.Name)
+	}
+
+	if tempDir := taskTempDir(task, settings); tempDir != "" && isWithinDir(source, canonicalPath(m.ResolvePath(tempDir))) {
+		return fmt.Errorf("temp directory is inside the source of task %s, so archives would include themselves", task.Name)
+	}
+
+	return nil
+}
+
+// TaskTempDir returns the resolved directory a task stages its files in: its
+// own temp directory if set, otherwise the global one
+func (m *Manager) TaskTempDir(task *models.Task) string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.ResolvePath(taskTempDir(task, m.config.Settings))
+}
+
+// taskTempDir returns the unresolved temp directory of a task
+func taskTempDir(task *models.Task, settings models.Settings) string {
+	if task.TempDir != "" {
+		return task.TempDir
+	}
+	return settings.TempDir
+}
+
+// checkSelfBackupAll runs checkSelfBackup for every task. Callers hold m.mu.
+func (m *Manager) checkSelfBackupAll(backends []models.Backend, settings models.Settings) error {
+	for i := range m.config.Tasks {
+		if err := m.checkSelfBackup(&m.config.Tasks[i], backends, settings); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// canonicalPath cleans a path and resolves symlinks where it exists, so a
+// destination reached through a symlink is still recognised
+func canonicalPath(path string) string {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		return resolved
+	}
+	return filepath.Clean(path)
+}
+
+// isWithinDir reports whether path is dir or lies beneath it
+func isWithinDir(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)))
+}
@@ -0,0 +1,41 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/nsilverman/archivist/internal/models"
+)
+
+// checkSyncBackends verifies a task's sync_backend_ids: they name existing
+// backends, only accompany an archive, and stay apart from backend_ids, where
+// a mirror could delete the archives stored beside it
+func checkSyncBackends(task *models.Task, backendIDs map[string]bool) error {
+	if len(task.SyncBackendIDs) == 0 {
+		return nil
+	}
+	if task.ArchiveOptions.Format == "sync" {
+		return fmt.Errorf("task %s already syncs to its backends; sync backends are only for archive tasks", task.Name)
+	}
+
+	archived := make(map[string]bool, len(task.BackendIDs))
+	for _, id := range task.BackendIDs {
+		archived[id] = true
+	}
+	for _, id := range task.SyncBackendIDs {
+		if !backendIDs[id] {
+			return fmt.Errorf("sync backend not found: %s", id)
+		}
+		if archived[id] {
+			return fmt.Errorf("backend %s is both an archive and a sync backend of task %s", id, task.Name)
+		}
+	}
+	return nil
+}
+
+// taskBackendIDs returns every backend a task writes to: its archive or sync
+// backends followed by its sync backends
+func taskBackendIDs(task *models.Task) []string {
+	ids := make([]string, 0, len(task.BackendIDs)+len(task.SyncBackendIDs))
+	ids = append(ids, task.BackendIDs...)
+	return append(ids, task.SyncBackendIDs...)
+}
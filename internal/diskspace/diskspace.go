@@ -0,0 +1,17 @@
+// Package diskspace reports the size and free space of local filesystems
+package diskspace
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// Usage returns the size of the filesystem holding path and the bytes on it
+// available to unprivileged users
+func Usage(path string) (total, available int64, err error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0, 0, fmt.Errorf("failed to get filesystem stats: %w", err)
+	}
+	return int64(stat.Blocks * uint64(stat.Bsize)), int64(stat.Bavail * uint64(stat.Bsize)), nil
+}
@@ -0,0 +1,20 @@
+package diskspace
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestUsage(t *testing.T) {
+	total, available, err := Usage(t.TempDir())
+	if err != nil {
+		t.Fatalf("Usage: %v", err)
+	}
+	if total <= 0 || available < 0 || available > total {
+		t.Errorf("Usage = %d total, %d available", total, available)
+	}
+
+	if _, _, err := Usage(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("Usage of a missing path succeeded")
+	}
+}
@@ -0,0 +1,48 @@
+package executor
+
+import (
+	"context"
+	"log"
+
+	"github.com/nsilverman/archivist/internal/models"
+)
+
+// backendSlots limits how many transfers run against one backend at once
+type backendSlots struct {
+	limit int
+	slots chan struct{}
+}
+
+// acquireBackend waits for a transfer slot on the backend when it sets
+// MaxConcurrentUploads, independently of MaxConcurrentTasks. The returned
+// function releases the slot. Fails only if ctx ends while waiting.
+func (e *Executor) acquireBackend(ctx context.Context, backendCfg *models.Backend) (func(), error) {
+	if backendCfg.MaxConcurrentUploads <= 0 {
+		return func() {}, nil
+	}
+
+	e.slotsMu.Lock()
+	limiter, ok := e.backendSlots[backendCfg.ID]
+	// A changed limit takes a fresh semaphore; transfers holding the old one release into it
+	if !ok || limiter.limit != backendCfg.MaxConcurrentUploads {
+		limiter = &backendSlots{
+			limit: backendCfg.MaxConcurrentUploads,
+			slots: make(chan struct{}, backendCfg.MaxConcurrentUploads),
+		}
+		e.backendSlots[backendCfg.ID] = limiter
+	}
+	e.slotsMu.Unlock()
+
+	select {
+	case limiter.slots <- struct{}{}:
+	default:
+		log.Printf("Waiting for a transfer slot on backend %s (max_concurrent_uploads is %d)", backendCfg.Name, limiter.limit)
+		select {
+		case limiter.slots <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	return func() { <-limiter.slots }, nil
+}
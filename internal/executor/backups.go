@@ -0,0 +1,328 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nsilverman/archivist/internal/archive"
+	"github.com/nsilverman/archivist/internal/backend"
+	"github.com/nsilverman/archivist/internal/models"
+)
+
+// ErrBackupNotFound is returned when deleting or downloading a path that isn't one of the task's backups
+var ErrBackupNotFound = errors.New("backup not found")
+
+// ListBackups lists the task's backups on each of its backends, or only on
+// backendID if it is set. Backends that can't be listed report an error
+// instead of failing the whole listing.
+func (e *Executor) ListBackups(ctx context.Context, taskID, backendID string) ([]models.BackendBackups, error) {
+	task, err := e.config.GetTask(taskID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get task: %w", err)
+	}
+
+	backendIDs := task.BackendIDs
+	if backendID != "" {
+		if !hasBackend(task, backendID) {
+			return nil, fmt.Errorf("task %s does not use backend %s", task.Name, backendID)
+		}
+		backendIDs = []string{backendID}
+	}
+
+	listings := make([]models.BackendBackups, 0, len(backendIDs))
+	for _, id := range backendIDs {
+		listing := models.BackendBackups{
+			BackendID: id,
+			Backups:   make([]models.RemoteBackup, 0),
+		}
+
+		backendCfg, err := e.config.GetBackend(id)
+		if err != nil {
+			listing.Error = fmt.Sprintf("Backend not found: %v", err)
+			listings = append(listings, listing)
+			continue
+		}
+		listing.BackendName = backendCfg.Name
+		listing.Immutable = backendCfg.Immutable
+
+		backups, err := e.listTaskBackups(ctx, task, backendCfg)
+		if err != nil {
+			listing.Error = err.Error()
+		} else {
+			listing.Backups = backups
+		}
+		listings = append(listings, listing)
+	}
+
+	return listings, nil
+}
+
+// DeleteBackup deletes one of the task's backups from a backend, with all its
+// parts if it was split, and records the deletion. Immutable backends refuse.
+func (e *Executor) DeleteBackup(ctx context.Context, taskID, backendID, remotePath string) ([]models.BackupDeletion, error) {
+	task, err := e.config.GetTask(taskID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get task: %w", err)
+	}
+	if !hasBackend(task, backendID) {
+		return nil, fmt.Errorf("task %s does not use backend %s", task.Name, backendID)
+	}
+
+	backendCfg, err := e.config.GetBackend(backendID)
+	if err != nil {
+		return nil, fmt.Errorf("backend not found: %w", err)
+	}
+	if backendCfg.Immutable {
+		return nil, fmt.Errorf("cannot delete %s from %s: %w", remotePath, backendCfg.Name, backend.ErrImmutableBackend)
+	}
+
+	backendInstance, err := backend.Factory(backendCfg, e.config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create backend: %w", err)
+	}
+	defer func() {
+		if err := backendInstance.Close(); err != nil {
+			log.Printf("Error closing backend instance: %v", err)
+		}
+	}()
+
+	target, err := findTaskBackup(ctx, task, backendInstance, backendCfg, remotePath)
+	if err != nil {
+		return nil, err
+	}
+
+	var deletions []models.BackupDeletion
+	for _, path := range backupPaths(target) {
+		if err := backendInstance.Delete(ctx, path); err != nil {
+			return deletions, fmt.Errorf("failed to delete %s: %w", path, err)
+		}
+		log.Printf("Deleted backup by request: %s (%s)", path, backendCfg.Name)
+
+		deletion := models.BackupDeletion{
+			TaskID:      task.ID,
+			BackendID:   backendCfg.ID,
+			BackendName: backendCfg.Name,
+			RemotePath:  path,
+			DeletedAt:   time.Now(),
+		}
+		if dbErr := e.db.AddBackupDeletion(&deletion); dbErr != nil {
+			log.Printf("Error recording backup deletion: %v", dbErr)
+		}
+		deletions = append(deletions, deletion)
+	}
+
+	return deletions, nil
+}
+
+// OpenBackup opens one of the task's backups on a backend for streaming, and
+// returns its size. The parts of a split archive are read one after another, so
+// the stream is the whole archive. Closing the reader closes the backend.
+func (e *Executor) OpenBackup(ctx context.Context, taskID, backendID, remotePath string) (io.ReadCloser, int64, error) {
+	task, err := e.config.GetTask(taskID)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get task: %w", err)
+	}
+	if !hasBackend(task, backendID) {
+		return nil, 0, fmt.Errorf("task %s does not use backend %s", task.Name, backendID)
+	}
+
+	backendCfg, err := e.config.GetBackend(backendID)
+	if err != nil {
+		return nil, 0, fmt.Errorf("backend not found: %w", err)
+	}
+
+	backendInstance, err := backend.Factory(backendCfg, e.config)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to create backend: %w", err)
+	}
+
+	reader := &backupReader{ctx: ctx, backend: backendInstance}
+	target, err := findTaskBackup(ctx, task, backendInstance, backendCfg, remotePath)
+	if err == nil {
+		// Open the first part now, so a failure is reported before streaming starts
+		reader.paths = backupPaths(target)
+		err = reader.next()
+	}
+	if err != nil {
+		if closeErr := reader.Close(); closeErr != nil {
+			log.Printf("Error closing backend instance: %v", closeErr)
+		}
+		return nil, 0, err
+	}
+
+	return reader, target.Size, nil
+}
+
+// findTaskBackup looks remotePath up among the task's backups on a backend.
+// Only the task's own backups can be deleted or downloaded, so the path must
+// be in its listing.
+func findTaskBackup(ctx context.Context, task *models.Task, backendInstance backend.StorageBackend, backendCfg *models.Backend, remotePath string) (*models.RemoteBackup, error) {
+	files, err := backendInstance.List(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list backups: %w", err)
+	}
+	for _, backup := range groupTaskBackups(task, files) {
+		if backup.Path == remotePath {
+			return &backup, nil
+		}
+	}
+	return nil, fmt.Errorf("%s on %s: %w", remotePath, backendCfg.Name, ErrBackupNotFound)
+}
+
+// backupPaths returns the objects a backup is stored as: its parts, or its own path
+func backupPaths(backup *models.RemoteBackup) []string {
+	if len(backup.Parts) > 0 {
+		return backup.Parts
+	}
+	return []string{backup.Path}
+}
+
+// backupReader reads a backup's objects in order, opening each as the previous one ends
+type backupReader struct {
+	ctx     context.Context
+	backend backend.StorageBackend
+	paths   []string      // objects not opened yet
+	current io.ReadCloser // object being read
+}
+
+// next closes the current object and opens the next one
+func (r *backupReader) next() error {
+	if r.current != nil {
+		err := r.current.Close()
+		r.current = nil
+		if err != nil {
+			return err
+		}
+	}
+	if len(r.paths) == 0 {
+		return io.EOF
+	}
+
+	current, _, err := r.backend.Open(r.ctx, r.paths[0])
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", r.paths[0], err)
+	}
+	r.current = current
+	r.paths = r.paths[1:]
+	return nil
+}
+
+func (r *backupReader) Read(p []byte) (int, error) {
+	for {
+		if r.current == nil {
+			return 0, io.EOF
+		}
+		n, err := r.current.Read(p)
+		if err != io.EOF {
+			return n, err
+		}
+		if err := r.next(); err != nil && err != io.EOF {
+			return n, err
+		}
+		if n > 0 {
+			return n, nil
+		}
+	}
+}
+
+// Close closes the current object and the backend
+func (r *backupReader) Close() error {
+	var err error
+	if r.current != nil {
+		err = r.current.Close()
+		r.current = nil
+	}
+	if closeErr := r.backend.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// listTaskBackups lists a task's backups on one backend
+func (e *Executor) listTaskBackups(ctx context.Context, task *models.Task, backendCfg *models.Backend) ([]models.RemoteBackup, error) {
+	backendInstance, err := backend.Factory(backendCfg, e.config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create backend: %w", err)
+	}
+	defer func() {
+		if err := backendInstance.Close(); err != nil {
+			log.Printf("Error closing backend instance: %v", err)
+		}
+	}()
+
+	// Archives are uploaded under their base filename, so list from the root
+	files, err := backendInstance.List(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list backups: %w", err)
+	}
+	return groupTaskBackups(task, files), nil
+}
+
+// groupTaskBackups picks the task's archives out of a backend listing, newest
+// first. The parts of a split archive are grouped under the archive name.
+func groupTaskBackups(task *models.Task, files []backend.BackupInfo) []models.RemoteBackup {
+	prefix := archive.TaskPrefix(task.Name)
+
+	var backups []*models.RemoteBackup
+	byPath := make(map[string]*models.RemoteBackup)
+	for _, file := range files {
+		fileName := filepath.Base(file.Path)
+		split := false
+		if archiveName, ok := archive.SplitPartName(fileName); ok {
+			fileName = archiveName
+			split = true
+		}
+		if !strings.HasPrefix(fileName, prefix) {
+			continue
+		}
+
+		backupPath := filepath.ToSlash(filepath.Join(filepath.Dir(file.Path), fileName))
+		b, ok := byPath[backupPath]
+		if !ok {
+			b = &models.RemoteBackup{Path: backupPath}
+			byPath[backupPath] = b
+			backups = append(backups, b)
+		}
+		b.Size += file.Size
+		if split {
+			b.Parts = append(b.Parts, file.Path)
+		}
+		if b.LastModified == "" || modifiedBefore(b.LastModified, file.LastModified) {
+			b.LastModified = file.LastModified
+		}
+	}
+
+	sort.SliceStable(backups, func(i, j int) bool {
+		if modifiedBefore(backups[j].LastModified, backups[i].LastModified) {
+			return true
+		}
+		if modifiedBefore(backups[i].LastModified, backups[j].LastModified) {
+			return false
+		}
+		return backups[i].Path > backups[j].Path
+	})
+
+	result := make([]models.RemoteBackup, 0, len(backups))
+	for _, b := range backups {
+		archive.SortParts(b.Parts)
+		result = append(result, *b)
+	}
+	return result
+}
+
+// hasBackend reports whether the task uploads to backendID
+func hasBackend(task *models.Task, backendID string) bool {
+	for _, id := range task.BackendIDs {
+		if id == backendID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,345 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nsilverman/archivist/internal/archive"
+	"github.com/nsilverman/archivist/internal/backend"
+	"github.com/nsilverman/archivist/internal/models"
+)
+
+// prepareChunkedArchive splits a built archive into chunks, records the chunk
+// index against the execution and writes the manifest next to the archive
+func (e *Executor) prepareChunkedArchive(execution *models.Execution, archivePath string) (*archive.Manifest, string, error) {
+	manifest, err := archive.ChunkFile(archivePath)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to chunk archive: %w", err)
+	}
+
+	manifestPath := archivePath + archive.ManifestSuffix
+	if err := archive.WriteManifest(manifest, manifestPath); err != nil {
+		return nil, "", err
+	}
+
+	if err := e.db.AddArchiveChunks(execution.ID, manifest.Chunks); err != nil {
+		log.Printf("Error recording chunk index: %v", err)
+	}
+
+	log.Printf("Split archive %s into %d chunks", manifest.ArchiveName, len(manifest.Chunks))
+	return manifest, manifestPath, nil
+}
+
+// uploadChunkedToBackend uploads the chunks a backend doesn't already have,
+// followed by the manifest. The result size is the number of new bytes stored.
+func (e *Executor) uploadChunkedToBackend(ctx context.Context, backendID string, archivePath, manifestPath string, manifest *archive.Manifest, execution *models.Execution) (result models.BackendResult) {
+	startTime := time.Now()
+	defer func() {
+		result.DurationMs = time.Since(startTime).Milliseconds()
+	}()
+
+	result = models.BackendResult{
+		BackendID: backendID,
+		Mode:      "archive",
+	}
+
+	backendCfg, err := e.config.GetBackend(backendID)
+	if err != nil {
+		result.Status = "failed"
+		result.ErrorMessage = fmt.Sprintf("Backend not found: %v", err)
+		return result
+	}
+
+	result.BackendName = backendCfg.Name
+
+	release, err := e.acquireBackend(ctx, backendCfg)
+	if err != nil {
+		result.Status = "failed"
+		result.ErrorMessage = fmt.Sprintf("Cancelled while waiting for a backend slot: %v", err)
+		return result
+	}
+	defer release()
+
+	backendInstance, err := backend.Factory(backendCfg, e.config)
+	if err != nil {
+		result.Status = "failed"
+		result.ErrorMessage = fmt.Sprintf("Failed to create backend: %v", err)
+		result.ErrorCode = backend.ClassifyError(nil, err)
+		return result
+	}
+	defer func() {
+		if err := backendInstance.Close(); err != nil {
+			log.Printf("Error closing backend instance: %v", err)
+		}
+	}()
+
+	fail := func(err error) models.BackendResult {
+		result.Status = "failed"
+		result.ErrorMessage = err.Error()
+		result.ErrorCode = backend.ClassifyError(backendInstance, err)
+		return result
+	}
+
+	existing, err := backendInstance.List(ctx, archive.ChunkPrefix)
+	if err != nil {
+		return fail(fmt.Errorf("failed to list chunks: %w", err))
+	}
+	stored := make(map[string]int64, len(existing))
+	for _, obj := range existing {
+		stored[filepath.ToSlash(obj.Path)] = obj.Size
+	}
+
+	// Work out which chunks are new; a chunk may repeat within one archive
+	var missing []archive.Chunk
+	var bytesTotal int64
+	for _, chunk := range manifest.Chunks {
+		remotePath := archive.ChunkPath(chunk.Hash)
+		if size, ok := stored[remotePath]; ok && size == chunk.Size {
+			continue
+		}
+		stored[remotePath] = chunk.Size
+		missing = append(missing, chunk)
+		bytesTotal += chunk.Size
+	}
+
+	log.Printf("Uploading %d of %d chunks to backend: %s", len(missing), len(manifest.Chunks), backendCfg.Name)
+
+	var bytesUploaded int64
+	for _, chunk := range missing {
+		if err := e.uploadChunk(ctx, backendInstance, archivePath, chunk); err != nil {
+			return fail(err)
+		}
+
+		bytesUploaded += chunk.Size
+		e.broadcastEvent(models.ProgressEvent{
+			Type: "upload_progress",
+			Data: models.UploadProgress{
+				ExecutionID:     execution.ID,
+				BackendID:       backendID,
+				BackendName:     backendCfg.Name,
+				ProgressPercent: float64(bytesUploaded) / float64(bytesTotal) * 100,
+				BytesUploaded:   bytesUploaded,
+				BytesTotal:      bytesTotal,
+			},
+		})
+	}
+
+	// The manifest goes last so it never references chunks that aren't stored
+	remotePath := filepath.Base(manifestPath)
+	if err := backendInstance.Upload(ctx, manifestPath, remotePath, nil); err != nil {
+		return fail(fmt.Errorf("failed to upload manifest: %w", err))
+	}
+
+	now := time.Now()
+	result.Status = "success"
+	result.UploadedAt = &now
+	result.Size = bytesUploaded
+	result.RemotePath = remotePath
+
+	log.Printf("Successfully uploaded chunked archive to backend: %s (%d new bytes)", backendCfg.Name, bytesUploaded)
+	return result
+}
+
+// uploadChunk stages one chunk in a temporary file and uploads it
+func (e *Executor) uploadChunk(ctx context.Context, backendInstance backend.StorageBackend, archivePath string, chunk archive.Chunk) error {
+	tempFile, err := os.CreateTemp(filepath.Dir(archivePath), "archivist-chunk-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	tempPath := tempFile.Name()
+	if err := tempFile.Close(); err != nil {
+		log.Printf("Error closing temp file: %v", err)
+	}
+	defer func() {
+		if err := os.Remove(tempPath); err != nil && !os.IsNotExist(err) {
+			log.Printf("Error removing temp file: %v", err)
+		}
+	}()
+
+	if err := archive.WriteChunk(archivePath, chunk, tempPath); err != nil {
+		return err
+	}
+	if err := backendInstance.Upload(ctx, tempPath, archive.ChunkPath(chunk.Hash), nil); err != nil {
+		return fmt.Errorf("failed to upload chunk %s: %w", chunk.Hash, err)
+	}
+	return nil
+}
+
+// RestoreChunked reassembles a chunked archive from a backend into the temp directory.
+// The archive is identified by its remote manifest, or by the execution that created
+// it, in which case the chunk index recorded in the database is used.
+// The restore runs in the background; progress is reported through restore_* events.
+func (e *Executor) RestoreChunked(backendID, manifestPath, executionID string) (string, error) {
+	backendCfg, err := e.config.GetBackend(backendID)
+	if err != nil {
+		return "", fmt.Errorf("backend not found: %w", err)
+	}
+	if manifestPath == "" && executionID == "" {
+		return "", fmt.Errorf("a manifest path or execution ID is required")
+	}
+
+	var manifest *archive.Manifest
+	if manifestPath == "" {
+		manifest, err = e.manifestFromExecution(executionID)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	restoreID := uuid.New().String()
+	restoreDir := filepath.Join(e.config.ResolvePath(e.config.GetSettings().TempDir), "restore")
+
+	e.broadcastEvent(models.ProgressEvent{
+		Type: "restore_started",
+		Data: map[string]interface{}{
+			"restore_id": restoreID,
+			"backend_id": backendID,
+		},
+	})
+
+	go func() {
+		localPath, err := e.runChunkedRestore(context.Background(), backendCfg, manifestPath, manifest, restoreDir)
+		if err != nil {
+			log.Printf("Restore %s failed: %v", restoreID, err)
+			e.broadcastEvent(models.ProgressEvent{
+				Type: "restore_failed",
+				Data: map[string]interface{}{
+					"restore_id":    restoreID,
+					"error_message": err.Error(),
+				},
+			})
+			return
+		}
+
+		log.Printf("Restore %s completed: %s", restoreID, localPath)
+		e.broadcastEvent(models.ProgressEvent{
+			Type: "restore_completed",
+			Data: map[string]interface{}{
+				"restore_id": restoreID,
+				"local_path": localPath,
+			},
+		})
+	}()
+
+	return restoreID, nil
+}
+
+// manifestFromExecution rebuilds a manifest from the chunk index of an execution
+func (e *Executor) manifestFromExecution(executionID string) (*archive.Manifest, error) {
+	execution, err := e.db.GetExecution(executionID)
+	if err != nil {
+		return nil, fmt.Errorf("execution not found: %w", err)
+	}
+
+	chunks, err := e.db.GetArchiveChunks(executionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load chunk index: %w", err)
+	}
+	if len(chunks) == 0 {
+		return nil, fmt.Errorf("execution %s has no chunk index", executionID)
+	}
+
+	name := ""
+	for _, result := range execution.BackendResults {
+		if result.RemotePath != "" {
+			name = strings.TrimSuffix(result.RemotePath, archive.ManifestSuffix)
+			break
+		}
+	}
+	if name == "" {
+		name = executionID + ".tar"
+	}
+
+	return &archive.Manifest{
+		ArchiveName: name,
+		ArchiveHash: execution.ArchiveHash,
+		Size:        execution.ArchiveSize,
+		Chunks:      chunks,
+	}, nil
+}
+
+// runChunkedRestore downloads the manifest if needed, then every chunk in order
+func (e *Executor) runChunkedRestore(ctx context.Context, backendCfg *models.Backend, manifestPath string, manifest *archive.Manifest, restoreDir string) (string, error) {
+	backendInstance, err := backend.Factory(backendCfg, e.config)
+	if err != nil {
+		return "", fmt.Errorf("failed to create backend: %w", err)
+	}
+	defer func() {
+		if err := backendInstance.Close(); err != nil {
+			log.Printf("Error closing backend instance: %v", err)
+		}
+	}()
+
+	if err := os.MkdirAll(restoreDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create restore directory: %w", err)
+	}
+
+	if manifest == nil {
+		localManifest := filepath.Join(restoreDir, filepath.Base(manifestPath))
+		if err := backendInstance.Download(ctx, manifestPath, localManifest, nil); err != nil {
+			return "", fmt.Errorf("failed to download manifest: %w", err)
+		}
+		manifest, err = archive.ReadManifest(localManifest)
+		if removeErr := os.Remove(localManifest); removeErr != nil {
+			log.Printf("Error removing manifest: %v", removeErr)
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+
+	localPath := filepath.Join(restoreDir, filepath.Base(manifest.ArchiveName))
+	out, err := os.Create(localPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to create restored archive: %w", err)
+	}
+
+	err = archive.Reassemble(ctx, manifest, func(ctx context.Context, chunk archive.Chunk) (io.ReadCloser, error) {
+		return downloadChunk(ctx, backendInstance, chunk, restoreDir)
+	}, out)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		if removeErr := os.Remove(localPath); removeErr != nil {
+			log.Printf("Error removing incomplete restore: %v", removeErr)
+		}
+		return "", err
+	}
+
+	return localPath, nil
+}
+
+// downloadChunk fetches a chunk to a temporary file that is removed when the returned reader is closed
+func downloadChunk(ctx context.Context, backendInstance backend.StorageBackend, chunk archive.Chunk, dir string) (io.ReadCloser, error) {
+	tempPath := filepath.Join(dir, chunk.Hash+".chunk")
+	if err := backendInstance.Download(ctx, archive.ChunkPath(chunk.Hash), tempPath, nil); err != nil {
+		return nil, fmt.Errorf("failed to download chunk: %w", err)
+	}
+
+	file, err := os.Open(tempPath)
+	if err != nil {
+		return nil, err
+	}
+	return &removeOnClose{File: file}, nil
+}
+
+// removeOnClose deletes its file once closed
+type removeOnClose struct {
+	*os.File
+}
+
+func (f *removeOnClose) Close() error {
+	err := f.File.Close()
+	if removeErr := os.Remove(f.Name()); removeErr != nil && err == nil {
+		err = removeErr
+	}
+	return err
+}
@@ -2,6 +2,7 @@ package executor
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
@@ -15,26 +16,48 @@ import (
 	"github.com/nsilverman/archivist/internal/archive"
 	"github.com/nsilverman/archivist/internal/backend"
 	"github.com/nsilverman/archivist/internal/config"
+	"github.com/nsilverman/archivist/internal/ignore"
 	"github.com/nsilverman/archivist/internal/models"
+	"github.com/nsilverman/archivist/internal/notify"
 	"github.com/nsilverman/archivist/internal/storage"
 	filesync "github.com/nsilverman/archivist/internal/sync"
 )
 
+// ErrTaskRunning is returned when a task is started while a previous run is still in progress
+var ErrTaskRunning = errors.New("task is already running")
+
 // Executor handles backup task execution
 type Executor struct {
 	config   *config.Manager
-	db       *storage.Database
+	db       storage.Store
 	running  map[string]*RunningExecution
+	queue    []*queuedRun // runs waiting for a slot under MaxConcurrentTasks, oldest first
 	mu       sync.RWMutex
 	progress ProgressBroadcaster
+	notifier *notify.Notifier
+	finished []func(models.Execution) // called after each execution ends, see OnFinished
+
+	migrations map[string]context.CancelFunc // migration ID -> cancel, while it runs
+
+	slotsMu      sync.Mutex
+	backendSlots map[string]*backendSlots // per-backend transfer limits, by backend ID
+}
+
+// queuedRun is a task run waiting to start
+type queuedRun struct {
+	task       *models.Task
+	execution  *models.Execution
+	enqueuedAt time.Time
 }
 
 // RunningExecution tracks a currently running execution
 type RunningExecution struct {
 	ID        string
 	TaskID    string
+	TaskName  string
 	StartedAt time.Time
 	Cancel    context.CancelFunc
+	Progress  *models.ProgressEvent // latest progress event, for clients that connect mid-run
 }
 
 // ProgressBroadcaster is an interface for broadcasting progress updates
@@ -43,11 +66,16 @@ type ProgressBroadcaster interface {
 }
 
 // NewExecutor creates a new backup executor
-func NewExecutor(cfg *config.Manager, db *storage.Database) *Executor {
+func NewExecutor(cfg *config.Manager, db storage.Store) *Executor {
 	return &Executor{
-		config:  cfg,
-		db:      db,
-		running: make(map[string]*RunningExecution),
+		config:   cfg,
+		db:       db,
+		running:  make(map[string]*RunningExecution),
+		notifier: notify.NewNotifier(),
+
+		migrations: make(map[string]context.CancelFunc),
+
+		backendSlots: make(map[string]*backendSlots),
 	}
 }
 
@@ -56,8 +84,74 @@ func (e *Executor) SetProgressBroadcaster(broadcaster ProgressBroadcaster) {
 	e.progress = broadcaster
 }
 
+// OnFinished registers fn to be called with the final record of each execution
+// once it has ended and its task is no longer running
+func (e *Executor) OnFinished(fn func(execution models.Execution)) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.finished = append(e.finished, fn)
+}
+
 // Execute runs a backup task
 func (e *Executor) Execute(taskID string) (string, error) {
+	return e.ExecuteRequest(taskID, "", "")
+}
+
+// ExecuteScheduled runs a backup task for its schedule. attempt is 1 for the
+// scheduled run and counts up for re-runs after it failed, see FailureRetry.
+func (e *Executor) ExecuteScheduled(taskID string, attempt int) (string, error) {
+	task, err := e.config.GetTask(taskID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get task: %w", err)
+	}
+
+	if !task.Enabled {
+		return "", fmt.Errorf("task is disabled")
+	}
+
+	return e.start(task, "", "", attempt)
+}
+
+// ExecuteStartup runs a backup task as Archivist starts. Startup runs are not
+// scheduled runs: they are recorded with attempt 0 and the given label, and
+// a failure isn't retried under FailureRetry.
+func (e *Executor) ExecuteStartup(taskID, label string) (string, error) {
+	task, err := e.config.GetTask(taskID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get task: %w", err)
+	}
+
+	if !task.Enabled {
+		return "", fmt.Errorf("task is disabled")
+	}
+
+	return e.start(task, "", label, 0)
+}
+
+// FailureRetryPending reports whether a failed scheduled execution is going
+// to be re-run under its task's failure retry settings
+func FailureRetryPending(task *models.Task, execution *models.Execution) bool {
+	return execution.Status == "failed" && execution.Attempt > 0 &&
+		task.FailureRetry != nil && execution.Attempt <= task.FailureRetry.Attempts
+}
+
+// minFreeTempSpace returns the free space archive builds keep in the temp
+// directory, 0 when the check is turned off
+func minFreeTempSpace(settings models.Settings) int64 {
+	switch {
+	case settings.MinFreeTempBytes < 0:
+		return 0
+	case settings.MinFreeTempBytes == 0:
+		return archive.DefaultMinFreeSpace
+	default:
+		return settings.MinFreeTempBytes
+	}
+}
+
+// ExecuteRequest runs a backup task on behalf of an API request. The request ID
+// and the caller's label, if any, are recorded with the execution and passed on
+// to dependents; the request ID also appears in its logs.
+func (e *Executor) ExecuteRequest(taskID, requestID, label string) (string, error) {
 	// Get task configuration
 	task, err := e.config.GetTask(taskID)
 	if err != nil {
@@ -68,13 +162,25 @@ func (e *Executor) Execute(taskID string) (string, error) {
 		return "", fmt.Errorf("task is disabled")
 	}
 
-	// Check if task is already running
+	return e.start(task, requestID, label, 0)
+}
+
+// start creates an execution record for task and runs it in the background.
+// When MaxConcurrentTasks runs are already in progress, the run is queued
+// and starts once a slot frees up.
+func (e *Executor) start(task *models.Task, requestID, label string, attempt int) (string, error) {
+	taskID := task.ID
+	task = withRetention(task, e.config.GetSettings())
+
+	// Check if task is already running or waiting to run
 	e.mu.RLock()
-	if _, exists := e.running[taskID]; exists {
-		e.mu.RUnlock()
-		return "", fmt.Errorf("task is already running")
-	}
+	_, exists := e.running[taskID]
+	queued := e.queueIndex(taskID) >= 0
+	full := e.atCapacity()
 	e.mu.RUnlock()
+	if exists || queued {
+		return "", ErrTaskRunning
+	}
 
 	// Create execution record
 	executionID := uuid.New().String()
@@ -84,25 +190,112 @@ func (e *Executor) Execute(taskID string) (string, error) {
 		TaskName:  task.Name,
 		StartedAt: time.Now(),
 		Status:    "running",
+		RequestID: requestID,
+		Attempt:   attempt,
+		Label:     label,
+	}
+	if full {
+		execution.Status = "queued"
 	}
 
 	if err := e.db.CreateExecution(execution); err != nil {
 		return "", fmt.Errorf("failed to create execution record: %w", err)
 	}
 
-	// Create cancellation context
-	ctx, cancel := context.WithCancel(context.Background())
-
-	// Track running execution
+	// Another call for the task may have got in while the record was created
 	e.mu.Lock()
-	e.running[taskID] = &RunningExecution{
-		ID:        executionID,
-		TaskID:    taskID,
-		StartedAt: execution.StartedAt,
-		Cancel:    cancel,
+	if _, exists := e.running[taskID]; exists || e.queueIndex(taskID) >= 0 {
+		e.mu.Unlock()
+		if err := e.db.DeleteExecution(executionID); err != nil {
+			log.Printf("Error deleting execution: %v", err)
+		}
+		return "", ErrTaskRunning
 	}
+	e.queue = append(e.queue, &queuedRun{
+		task:       task,
+		execution:  execution,
+		enqueuedAt: execution.StartedAt,
+	})
 	e.mu.Unlock()
 
+	if full {
+		e.broadcastEvent(models.ProgressEvent{
+			Type: "execution_queued",
+			Data: map[string]interface{}{
+				"execution_id": executionID,
+				"task_id":      taskID,
+				"task_name":    task.Name,
+			},
+		})
+	}
+
+	e.dispatch()
+	return executionID, nil
+}
+
+// atCapacity reports whether MaxConcurrentTasks runs are in progress. The caller must hold e.mu.
+func (e *Executor) atCapacity() bool {
+	limit := e.config.GetSettings().MaxConcurrentTasks
+	return limit > 0 && len(e.running) >= limit
+}
+
+// queueIndex returns the position of taskID in the queue, or -1. The caller must hold e.mu.
+func (e *Executor) queueIndex(taskID string) int {
+	for i, run := range e.queue {
+		if run.task.ID == taskID {
+			return i
+		}
+	}
+	return -1
+}
+
+// dispatch starts queued runs, oldest first, while there are free slots
+func (e *Executor) dispatch() {
+	for {
+		e.mu.Lock()
+		if len(e.queue) == 0 || e.atCapacity() {
+			e.mu.Unlock()
+			return
+		}
+		run := e.queue[0]
+		e.queue = e.queue[1:]
+
+		// Create cancellation context
+		ctx, cancel := context.WithCancel(context.Background())
+
+		// Track running execution
+		if run.execution.Status == "queued" {
+			run.execution.StartedAt = time.Now()
+		}
+		e.running[run.task.ID] = &RunningExecution{
+			ID:        run.execution.ID,
+			TaskID:    run.task.ID,
+			TaskName:  run.task.Name,
+			StartedAt: run.execution.StartedAt,
+			Cancel:    cancel,
+		}
+		e.mu.Unlock()
+
+		e.launch(ctx, cancel, run.task, run.execution)
+	}
+}
+
+// launch runs an execution that has been given a slot in the background
+func (e *Executor) launch(ctx context.Context, cancel context.CancelFunc, task *models.Task, execution *models.Execution) {
+	taskID := task.ID
+	executionID := execution.ID
+
+	if execution.Status == "queued" {
+		execution.Status = "running"
+		if err := e.db.UpdateExecution(execution); err != nil {
+			log.Printf("Error updating execution: %v", err)
+		}
+	}
+
+	if execution.RequestID != "" {
+		log.Printf("Execution %s of task %s started by request %s", executionID, task.Name, execution.RequestID)
+	}
+
 	// Broadcast execution started
 	e.broadcastEvent(models.ProgressEvent{
 		Type: "execution_started",
@@ -120,7 +313,12 @@ func (e *Executor) Execute(taskID string) (string, error) {
 		defer func() {
 			e.mu.Lock()
 			delete(e.running, taskID)
+			finished := e.finished
 			e.mu.Unlock()
+			e.dispatch()
+			for _, fn := range finished {
+				fn(*execution)
+			}
 		}()
 		defer func() {
 			if r := recover(); r != nil {
@@ -135,12 +333,54 @@ func (e *Executor) Execute(taskID string) (string, error) {
 			}
 		}()
 
+		heartbeat := e.heartbeat(task)
+		if heartbeat != nil {
+			e.ping(heartbeat.StartURL)
+		}
+
 		if err := e.runExecution(ctx, task, execution); err != nil {
 			log.Printf("Execution failed for task %s: %v", task.Name, err)
 		}
+
+		if heartbeat != nil {
+			if execution.Status == "success" {
+				e.ping(heartbeat.SuccessURL)
+			} else {
+				e.ping(heartbeat.FailureURL)
+			}
+		}
+		e.pruneHistory(task)
+
+		if execution.Status == "success" {
+			e.runDependents(task, execution.RequestID, execution.Label)
+		}
 	}()
+}
 
-	return executionID, nil
+// runDependents starts the enabled tasks that depend on task, after it succeeded,
+// under the request ID and label that started it. Config validation rejects
+// dependency cycles, so chains always end.
+func (e *Executor) runDependents(task *models.Task, requestID, label string) {
+	for _, dependent := range e.config.GetTasks() {
+		if !dependent.Enabled || !dependsOn(&dependent, task.ID) {
+			continue
+		}
+
+		log.Printf("Starting task %s after %s succeeded", dependent.Name, task.Name)
+		if _, err := e.ExecuteRequest(dependent.ID, requestID, label); err != nil {
+			log.Printf("Error starting dependent task %s: %v", dependent.Name, err)
+		}
+	}
+}
+
+// dependsOn reports whether task runs after the task with taskID
+func dependsOn(task *models.Task, taskID string) bool {
+	for _, id := range task.DependsOn {
+		if id == taskID {
+			return true
+		}
+	}
+	return false
 }
 
 // ExecuteDryRun performs a dry run analysis without making changes
@@ -168,9 +408,11 @@ func (e *Executor) ExecuteDryRun(taskID string, backendIDs []string) (*models.Dr
 		AnalyzedAt: startTime,
 	}
 
-	// Use task backends if none specified
+	// Use task backends if none specified, including the sync backends of an archive task
+	var syncBackendIDs []string
 	if len(backendIDs) == 0 {
 		backendIDs = task.BackendIDs
+		syncBackendIDs = task.SyncBackendIDs
 	}
 
 	// Determine mode and execute appropriate dry run
@@ -184,10 +426,30 @@ func (e *Executor) ExecuteDryRun(taskID string, backendIDs []string) (*models.Dr
 		if err := e.dryRunArchive(task, sourcePath, result); err != nil {
 			return nil, err
 		}
+		if len(syncBackendIDs) > 0 {
+			if err := e.dryRunSync(task, sourcePath, syncBackendIDs, result); err != nil {
+				return nil, err
+			}
+		}
 	}
 
 	// Analyze backends
 	result.BackendPlans = e.analyzeBackends(task, backendIDs)
+	if len(syncBackendIDs) > 0 {
+		mirror := *task
+		mirror.ArchiveOptions.Format = "sync"
+		result.BackendPlans = append(result.BackendPlans, e.analyzeBackends(&mirror, syncBackendIDs)...)
+	}
+
+	// Show what retention would prune once this run's archive is uploaded
+	if result.ArchiveDetails != nil {
+		pending := &backend.BackupInfo{
+			Path:         result.ArchiveDetails.ArchiveName,
+			Size:         result.ArchiveDetails.EstimatedArchiveSize,
+			LastModified: time.Now().UTC().Format(time.RFC3339),
+		}
+		result.RetentionPlan = e.previewRetention(context.Background(), task, backendIDs, pending)
+	}
 
 	result.DurationMs = time.Since(startTime).Milliseconds()
 	return result, nil
@@ -196,7 +458,7 @@ func (e *Executor) ExecuteDryRun(taskID string, backendIDs []string) (*models.Dr
 // dryRunArchive analyzes what an archive operation would do
 func (e *Executor) dryRunArchive(task *models.Task, sourcePath string, result *models.DryRunResult) error {
 	// Scan source directory
-	summary, err := e.scanSourceDirectory(sourcePath)
+	summary, err := e.scanSourceDirectory(context.Background(), sourcePath)
 	if err != nil {
 		return fmt.Errorf("failed to scan source: %w", err)
 	}
@@ -209,10 +471,24 @@ func (e *Executor) dryRunArchive(task *models.Task, sourcePath string, result *m
 		return fmt.Errorf("failed to generate archive name: %w", err)
 	}
 
-	// Estimate compression (use heuristic: ~30% reduction for gzip on typical data)
-	compressionRatio := 0.7
-	if task.ArchiveOptions.Compression == "none" {
-		compressionRatio = 1.0
+	// Estimate compression (use heuristic: ~30% reduction for gzip on typical data,
+	// none for files that are already compressed)
+	compress := task.ArchiveOptions.Compression != "none"
+	worthCompressing := archive.ShouldCompress(summary.IncompressibleSize, summary.TotalSize)
+	// Zip stores already-compressed files as they are instead of choosing for the whole archive
+	perEntry := task.ArchiveOptions.Format == archive.FormatZip
+	if task.ArchiveOptions.Compression == "auto" && !perEntry {
+		compress = worthCompressing
+	} else if compress && !worthCompressing && !perEntry {
+		result.Warnings = append(result.Warnings, fmt.Sprintf(
+			"%.0f%% of the source is already-compressed data (images, video, archives); consider compression \"none\" or \"auto\" to save CPU time",
+			float64(summary.IncompressibleSize)/float64(summary.TotalSize)*100))
+	}
+
+	compressionRatio := 1.0
+	if compress && summary.TotalSize > 0 {
+		compressibleSize := summary.TotalSize - summary.IncompressibleSize
+		compressionRatio = (float64(compressibleSize)*0.7 + float64(summary.IncompressibleSize)) / float64(summary.TotalSize)
 	}
 
 	result.ArchiveDetails = &models.ArchiveDetails{
@@ -230,7 +506,7 @@ func (e *Executor) dryRunSync(task *models.Task, sourcePath string, backendIDs [
 	ctx := context.Background()
 
 	// Scan local files
-	summary, err := e.scanSourceDirectory(sourcePath)
+	summary, err := e.scanSourceDirectory(context.Background(), sourcePath)
 	if err != nil {
 		return fmt.Errorf("failed to scan source: %w", err)
 	}
@@ -259,9 +535,13 @@ func (e *Executor) dryRunSync(task *models.Task, sourcePath string, backendIDs [
 			remotePath = filepath.Join(prefix, remotePath)
 		}
 
-		// Perform dry run sync analysis
+		// Perform dry run sync analysis, mirroring the immutable override of the real sync
+		syncOptions := task.ArchiveOptions.SyncOptions
+		if backendCfg.Immutable {
+			syncOptions.DeleteRemote = false
+		}
 		syncer := filesync.NewSyncer(sourcePath, backendInstance, remotePath,
-			task.ArchiveOptions.SyncOptions, nil)
+			syncOptions, nil)
 		details, dryRunErr := syncer.DryRun(ctx)
 
 		if closeErr := backendInstance.Close(); closeErr != nil {
@@ -288,44 +568,52 @@ func (e *Executor) dryRunSync(task *models.Task, sourcePath string, backendIDs [
 	return nil
 }
 
-// scanSourceDirectory scans a directory and returns summary
-func (e *Executor) scanSourceDirectory(sourcePath string) (*models.FilesSummary, error) {
+// ScanSource recursively summarizes a source directory. If ctx is cancelled
+// the partial summary collected so far is returned along with ctx's error.
+func (e *Executor) ScanSource(ctx context.Context, sourcePath string) (*models.FilesSummary, error) {
+	return e.scanSourceDirectory(ctx, sourcePath)
+}
+
+// scanSourceDirectory scans a directory and returns summary. Directories are
+// read by the scan_workers setting's number of goroutines.
+func (e *Executor) scanSourceDirectory(ctx context.Context, sourcePath string) (*models.FilesSummary, error) {
 	summary := &models.FilesSummary{
 		FileTypes: make(map[string]int),
 		TopFiles:  make([]models.FileDetail, 0),
 	}
 
 	var allFiles []models.FileDetail
+	root := archive.SourceRoot(sourcePath)
 
-	err := filepath.Walk(sourcePath, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-
+	var mu sync.Mutex
+	err := ignore.WalkParallel(ctx, sourcePath, e.config.GetSettings().ScanWorkers, func(path string, info os.FileInfo) error {
 		if info.IsDir() {
+			mu.Lock()
 			summary.TotalDirs++
+			mu.Unlock()
 			return nil
 		}
 
-		summary.TotalFiles++
-		summary.TotalSize += info.Size()
-
-		// Track file types
+		incompressible := archive.IsIncompressible(path)
 		ext := filepath.Ext(path)
 		if ext == "" {
 			ext = "[no extension]"
 		}
-		summary.FileTypes[ext]++
+		relPath, _ := filepath.Rel(root, path)
+
+		mu.Lock()
+		defer mu.Unlock()
 
-		// Track largest file
-		if info.Size() > summary.LargestFileSize {
-			summary.LargestFileSize = info.Size()
-			relPath, _ := filepath.Rel(sourcePath, path)
-			summary.LargestFile = relPath
+		summary.TotalFiles++
+		summary.TotalSize += info.Size()
+		if incompressible {
+			summary.IncompressibleSize += info.Size()
 		}
 
+		// Track file types
+		summary.FileTypes[ext]++
+
 		// Collect for top files
-		relPath, _ := filepath.Rel(sourcePath, path)
 		allFiles = append(allFiles, models.FileDetail{
 			RelativePath: relPath,
 			Size:         info.Size(),
@@ -335,21 +623,29 @@ func (e *Executor) scanSourceDirectory(sourcePath string) (*models.FilesSummary,
 		return nil
 	})
 
-	if err != nil {
+	if err != nil && ctx.Err() == nil {
 		return nil, err
 	}
 
-	// Sort and get top 10 files by size
+	// Sort and get top 10 files by size. Ties go by path, so parallel scans
+	// give the same result as serial ones.
 	sort.Slice(allFiles, func(i, j int) bool {
-		return allFiles[i].Size > allFiles[j].Size
+		if allFiles[i].Size != allFiles[j].Size {
+			return allFiles[i].Size > allFiles[j].Size
+		}
+		return allFiles[i].RelativePath < allFiles[j].RelativePath
 	})
+	if len(allFiles) > 0 && allFiles[0].Size > 0 {
+		summary.LargestFile = allFiles[0].RelativePath
+		summary.LargestFileSize = allFiles[0].Size
+	}
 	if len(allFiles) > 10 {
 		summary.TopFiles = allFiles[:10]
 	} else {
 		summary.TopFiles = allFiles
 	}
 
-	return summary, nil
+	return summary, err
 }
 
 // analyzeBackends checks which backends are available
@@ -399,6 +695,9 @@ func (e *Executor) analyzeBackends(task *models.Task, backendIDs []string) []mod
 			// Would be the archive filename
 			builder := archive.NewBuilder("", "", task.ArchiveOptions, nil)
 			filename, _ := builder.GenerateFilename(task.Name)
+			if task.ArchiveOptions.Format == archive.FormatChunked {
+				filename += archive.ManifestSuffix
+			}
 			plan.RemotePath = filename
 		}
 
@@ -412,12 +711,9 @@ func (e *Executor) analyzeBackends(task *models.Task, backendIDs []string) []mod
 func (e *Executor) runExecution(ctx context.Context, task *models.Task, execution *models.Execution) error {
 	startTime := time.Now()
 
-	// Get settings
-	settings := e.config.GetSettings()
-
 	// Resolve paths relative to root directory first
 	sourcePath := e.config.ResolvePath(task.SourcePath)
-	tempDir := e.config.ResolvePath(settings.TempDir)
+	tempDir := e.config.TaskTempDir(task)
 
 	// Verify source path exists
 	if _, err := os.Stat(sourcePath); err != nil {
@@ -433,6 +729,50 @@ func (e *Executor) runExecution(ctx context.Context, task *models.Task, executio
 		return err
 	}
 
+	// Refuse to run if the task would back up its own destination or temp files
+	if err := e.config.ValidateTaskPaths(task); err != nil {
+		execution.Status = "failed"
+		execution.ErrorMessage = err.Error()
+		now := time.Now()
+		execution.CompletedAt = &now
+		execution.DurationMs = time.Since(startTime).Milliseconds()
+		if dbErr := e.db.UpdateExecution(execution); dbErr != nil {
+			log.Printf("Error updating execution: %v", dbErr)
+		}
+		e.broadcastExecutionFailed(execution)
+		return err
+	}
+
+	// An empty or much smaller source usually means a volume failed to mount
+	sourceFiles, err := e.checkSourceGuard(ctx, task, sourcePath)
+	execution.SourceFiles = sourceFiles
+	if err != nil {
+		execution.Status = "failed"
+		execution.ErrorMessage = err.Error()
+		now := time.Now()
+		execution.CompletedAt = &now
+		execution.DurationMs = time.Since(startTime).Milliseconds()
+		if dbErr := e.db.UpdateExecution(execution); dbErr != nil {
+			log.Printf("Error updating execution: %v", dbErr)
+		}
+		e.broadcastExecutionFailed(execution)
+		return err
+	}
+
+	// The task may stage files somewhere other than the global temp directory
+	if err := os.MkdirAll(tempDir, 0755); err != nil {
+		execution.Status = "failed"
+		execution.ErrorMessage = fmt.Sprintf("Failed to create temp directory: %v", err)
+		now := time.Now()
+		execution.CompletedAt = &now
+		execution.DurationMs = time.Since(startTime).Milliseconds()
+		if dbErr := e.db.UpdateExecution(execution); dbErr != nil {
+			log.Printf("Error updating execution: %v", dbErr)
+		}
+		e.broadcastExecutionFailed(execution)
+		return err
+	}
+
 	// Check if this is sync mode or archive mode
 	if task.ArchiveOptions.Format == "sync" {
 		// Sync mode: upload files directly without creating archive
@@ -442,19 +782,30 @@ func (e *Executor) runExecution(ctx context.Context, task *models.Task, executio
 	// Archive mode: create archive then upload
 	// Create archive
 	log.Printf("Creating archive for task: %s (source: %s)", task.Name, sourcePath)
+	filesProcessed := 0
 	builder := archive.NewBuilder(
 		sourcePath,
 		tempDir,
 		task.ArchiveOptions,
 		func(current, total int64, file string) {
-			// Broadcast archive progress
+			filesProcessed++
+			// Broadcast archive progress, by file when the source wasn't sized
+			var percent float64
+			switch {
+			case total > 0:
+				percent = float64(current) / float64(total) * 100
+			case sourceFiles > 0:
+				percent = min(float64(filesProcessed)/float64(sourceFiles)*100, 100)
+			}
 			e.broadcastEvent(models.ProgressEvent{
 				Type: "archive_progress",
 				Data: models.ArchiveProgress{
 					ExecutionID:     execution.ID,
 					Phase:           "creating_archive",
-					ProgressPercent: float64(current) / float64(total) * 100,
+					ProgressPercent: percent,
 					CurrentFile:     file,
+					FilesProcessed:  filesProcessed,
+					FilesTotal:      int(sourceFiles),
 					BytesProcessed:  current,
 					BytesTotal:      total,
 				},
@@ -462,6 +813,18 @@ func (e *Executor) runExecution(ctx context.Context, task *models.Task, executio
 		},
 	)
 
+	settings := e.config.GetSettings()
+	builder.FileMode = e.config.ArchiveFileMode()
+	builder.SourceFiles = sourceFiles
+	builder.ScanWorkers = settings.ScanWorkers
+	builder.HashAlgorithm = settings.HashAlgorithm
+	builder.Redactor = archive.NewRedactor(settings.Redaction, task.ArchiveOptions.Redaction)
+	builder.MinFreeSpace = minFreeTempSpace(settings)
+
+	if canStream(task.ArchiveOptions) {
+		return e.runStreamedExecution(ctx, task, execution, builder, startTime)
+	}
+
 	archivePath, hash, size, err := builder.Build(task.Name)
 	if err != nil {
 		execution.Status = "failed"
@@ -479,28 +842,93 @@ func (e *Executor) runExecution(ctx context.Context, task *models.Task, executio
 	// Update execution with archive info
 	execution.ArchiveSize = size
 	execution.ArchiveHash = hash
+	execution.Compression = builder.Codec()
+
+	contents := builder.Contents()
+	contents.ExecutionID = execution.ID
+	if err := e.db.SaveArchiveContents(&contents); err != nil {
+		log.Printf("Error recording archive contents: %v", err)
+	}
 
-	// Clean up archive on completion
+	// Split archives exist only as their parts
+	parts := builder.Parts()
+
+	// Clean up archive on completion, unless the task keeps a local copy
 	defer func() {
-		if err := os.Remove(archivePath); err != nil {
-			log.Printf("Error removing archive file: %v", err)
+		localFiles := parts
+		if localFiles == nil {
+			localFiles = []string{archivePath}
+		}
+		if task.ArchiveOptions.LocalCopyDir != "" && task.ArchiveOptions.Format != archive.FormatChunked {
+			if err := e.keepLocalCopy(task, localFiles); err != nil {
+				log.Printf("Error keeping local copy of archive: %v", err)
+			}
+		}
+		for _, path := range localFiles {
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				log.Printf("Error removing archive file: %v", err)
+			}
 		}
 	}()
 
+	// Chunked archives are uploaded as content-addressed chunks plus a manifest
+	var manifest *archive.Manifest
+	var manifestPath string
+	if task.ArchiveOptions.Format == archive.FormatChunked {
+		manifest, manifestPath, err = e.prepareChunkedArchive(execution, archivePath)
+		if err != nil {
+			execution.Status = "failed"
+			execution.ErrorMessage = err.Error()
+			now := time.Now()
+			execution.CompletedAt = &now
+			execution.DurationMs = time.Since(startTime).Milliseconds()
+			if dbErr := e.db.UpdateExecution(execution); dbErr != nil {
+				log.Printf("Error updating execution: %v", dbErr)
+			}
+			e.broadcastExecutionFailed(execution)
+			return err
+		}
+		execution.ArchiveHash = manifest.ArchiveHash
+		defer func() {
+			if err := os.Remove(manifestPath); err != nil {
+				log.Printf("Error removing manifest file: %v", err)
+			}
+		}()
+	}
+
 	// Upload to all configured backends
 	log.Printf("Uploading to %d backend(s)", len(task.BackendIDs))
 	var backendResults []models.BackendResult
-	var uploadErrors []error
+	uploadCtx := backend.WithMetadata(ctx, backend.ArchiveMetadata(contents.FileCount, contents.TotalSize))
 
 	for _, backendID := range task.BackendIDs {
-		result := e.uploadToBackend(ctx, backendID, task, archivePath, execution)
+		var result models.BackendResult
+		if manifest != nil {
+			result = e.uploadChunkedToBackend(ctx, backendID, archivePath, manifestPath, manifest, execution)
+		} else {
+			result = e.uploadToBackend(uploadCtx, backendID, task, archivePath, parts, execution)
+		}
 		backendResults = append(backendResults, result)
 
 		// Store backend upload result
 		if dbErr := e.db.AddBackendUpload(execution.ID, &result); dbErr != nil {
 			log.Printf("Error adding backend upload: %v", dbErr)
 		}
+	}
 
+	return e.finishArchiveExecution(ctx, task, execution, backendResults, startTime)
+}
+
+// finishArchiveExecution records the outcome of an archive run from its backend
+// results, then applies retention and broadcasts completion
+func (e *Executor) finishArchiveExecution(ctx context.Context, task *models.Task, execution *models.Execution, backendResults []models.BackendResult, startTime time.Time) error {
+	// Sync backends are mirrored once the archive is stored, in the same run
+	if len(task.SyncBackendIDs) > 0 {
+		backendResults = append(backendResults, e.syncToSyncBackends(ctx, task, execution)...)
+	}
+
+	var uploadErrors []error
+	for _, result := range backendResults {
 		if result.Status == "failed" {
 			uploadErrors = append(uploadErrors, fmt.Errorf("backend %s: %s", result.BackendName, result.ErrorMessage))
 		}
@@ -509,7 +937,7 @@ func (e *Executor) runExecution(ctx context.Context, task *models.Task, executio
 	execution.BackendResults = backendResults
 
 	// Determine overall status
-	if len(uploadErrors) == len(task.BackendIDs) {
+	if len(uploadErrors) == len(backendResults) {
 		// All uploads failed
 		execution.Status = "failed"
 		// Include detailed error messages
@@ -525,7 +953,7 @@ func (e *Executor) runExecution(ctx context.Context, task *models.Task, executio
 		for i, err := range uploadErrors {
 			errorDetails[i] = err.Error()
 		}
-		execution.ErrorMessage = fmt.Sprintf("%d of %d backends failed: %s", len(uploadErrors), len(task.BackendIDs), strings.Join(errorDetails, "; "))
+		execution.ErrorMessage = fmt.Sprintf("%d of %d backends failed: %s", len(uploadErrors), len(backendResults), strings.Join(errorDetails, "; "))
 	} else {
 		// All succeeded
 		execution.Status = "success"
@@ -544,9 +972,14 @@ func (e *Executor) runExecution(ctx context.Context, task *models.Task, executio
 		log.Printf("Error updating task schedule: %v", err)
 	}
 
-	// Apply retention policy if configured
+	// Apply retention policy if configured. A partially failed run only prunes
+	// backends that received the new backup, unless full success is required.
 	if task.RetentionPolicy.KeepLast > 0 {
-		e.applyRetentionPolicy(ctx, task, backendResults)
+		if task.RetentionPolicy.RequireFullSuccess && len(uploadErrors) > 0 {
+			log.Printf("Skipping retention for task %s: %d of %d backends failed", task.Name, len(uploadErrors), len(backendResults))
+		} else {
+			e.applyRetentionPolicy(ctx, task, execution, backendResults)
+		}
 	}
 
 	// Broadcast completion
@@ -559,14 +992,34 @@ func (e *Executor) runExecution(ctx context.Context, task *models.Task, executio
 			"completed_at":       execution.CompletedAt,
 			"duration_ms":        execution.DurationMs,
 			"archive_size":       execution.ArchiveSize,
-			"backends_succeeded": len(task.BackendIDs) - len(uploadErrors),
+			"backends_succeeded": len(backendResults) - len(uploadErrors),
 			"backends_failed":    len(uploadErrors),
+			"backends":           backendSummaries(backendResults),
 		},
 	})
+	e.notifyExecution(execution)
 
 	return nil
 }
 
+// syncToSyncBackends mirrors the source of an archive task to its sync backends
+func (e *Executor) syncToSyncBackends(ctx context.Context, task *models.Task, execution *models.Execution) []models.BackendResult {
+	sourcePath := e.config.ResolvePath(task.SourcePath)
+	log.Printf("Syncing task %s to %d sync backend(s)", task.Name, len(task.SyncBackendIDs))
+
+	results := make([]models.BackendResult, 0, len(task.SyncBackendIDs))
+	for _, backendID := range task.SyncBackendIDs {
+		result := e.syncToBackend(ctx, backendID, task, sourcePath, execution)
+		results = append(results, result)
+
+		// Store backend upload result
+		if dbErr := e.db.AddBackendUpload(execution.ID, &result); dbErr != nil {
+			log.Printf("Error adding backend upload: %v", dbErr)
+		}
+	}
+	return results
+}
+
 // runSyncExecution performs file-by-file sync execution
 func (e *Executor) runSyncExecution(ctx context.Context, task *models.Task, execution *models.Execution, sourcePath string, startTime time.Time) error {
 	log.Printf("Starting sync for task: %s (source: %s)", task.Name, sourcePath)
@@ -644,16 +1097,40 @@ func (e *Executor) runSyncExecution(ctx context.Context, task *models.Task, exec
 			"archive_size":       execution.ArchiveSize,
 			"backends_succeeded": len(task.BackendIDs) - len(syncErrors),
 			"backends_failed":    len(syncErrors),
+			"backends":           backendSummaries(backendResults),
 		},
 	})
+	e.notifyExecution(execution)
 
 	return nil
 }
 
+// backendSummaries reduces backend results to the per-backend breakdown sent with execution_completed
+func backendSummaries(results []models.BackendResult) []map[string]interface{} {
+	summaries := make([]map[string]interface{}, 0, len(results))
+	for _, result := range results {
+		summaries = append(summaries, map[string]interface{}{
+			"backend_id":   result.BackendID,
+			"backend_name": result.BackendName,
+			"mode":         result.Mode,
+			"status":       result.Status,
+			"bytes":        result.Size,
+			"duration_ms":  result.DurationMs,
+		})
+	}
+	return summaries
+}
+
 // syncToBackend syncs files to a specific backend
-func (e *Executor) syncToBackend(ctx context.Context, backendID string, task *models.Task, sourcePath string, execution *models.Execution) models.BackendResult {
-	result := models.BackendResult{
+func (e *Executor) syncToBackend(ctx context.Context, backendID string, task *models.Task, sourcePath string, execution *models.Execution) (result models.BackendResult) {
+	startTime := time.Now()
+	defer func() {
+		result.DurationMs = time.Since(startTime).Milliseconds()
+	}()
+
+	result = models.BackendResult{
 		BackendID: backendID,
+		Mode:      "sync",
 	}
 
 	// Get backend configuration
@@ -666,11 +1143,20 @@ func (e *Executor) syncToBackend(ctx context.Context, backendID string, task *mo
 
 	result.BackendName = backendCfg.Name
 
+	release, err := e.acquireBackend(ctx, backendCfg)
+	if err != nil {
+		result.Status = "failed"
+		result.ErrorMessage = fmt.Sprintf("Cancelled while waiting for a backend slot: %v", err)
+		return result
+	}
+	defer release()
+
 	// Create backend instance
 	backendInstance, err := backend.Factory(backendCfg, e.config)
 	if err != nil {
 		result.Status = "failed"
 		result.ErrorMessage = fmt.Sprintf("Failed to create backend: %v", err)
+		result.ErrorCode = backend.ClassifyError(nil, err)
 		return result
 	}
 	defer func() {
@@ -687,13 +1173,20 @@ func (e *Executor) syncToBackend(ctx context.Context, backendID string, task *mo
 		remotePath = filepath.Join(prefix, remotePath)
 	}
 
+	// Immutable backends keep files that were removed from the source
+	syncOptions := task.ArchiveOptions.SyncOptions
+	if backendCfg.Immutable && syncOptions.DeleteRemote {
+		log.Printf("Backend %s is immutable; remote files will not be deleted", backendCfg.Name)
+		syncOptions.DeleteRemote = false
+	}
+
 	// Create syncer
 	log.Printf("Syncing to backend: %s (remote path: %s)", backendCfg.Name, remotePath)
 	syncer := filesync.NewSyncer(
 		sourcePath,
 		backendInstance,
 		remotePath,
-		task.ArchiveOptions.SyncOptions,
+		syncOptions,
 		func(phase string, current, total int, file string) {
 			// Broadcast sync progress
 			percent := 0.0
@@ -717,41 +1210,59 @@ func (e *Executor) syncToBackend(ctx context.Context, backendID string, task *mo
 		},
 	)
 
+	syncer.TempDir = e.config.TaskTempDir(task)
+
 	// Perform sync
 	syncResult, err := syncer.Sync(ctx)
 	if err != nil {
 		result.Status = "failed"
 		result.ErrorMessage = err.Error()
+		result.ErrorCode = backend.ClassifyError(backendInstance, err)
 		return result
 	}
 
 	// Check for errors during sync
-	if len(syncResult.Errors) > 0 {
+	if syncResult.Failed(task.ArchiveOptions.SyncOptions) {
 		result.Status = "failed"
 		errorMsgs := make([]string, len(syncResult.Errors))
 		for i, err := range syncResult.Errors {
 			errorMsgs[i] = err.Error()
 		}
 		result.ErrorMessage = strings.Join(errorMsgs, "; ")
+		if syncResult.Aborted {
+			result.ErrorMessage = fmt.Sprintf("sync stopped after %d failed files: %s", len(syncResult.Errors), result.ErrorMessage)
+		}
+		result.ErrorCode = backend.ClassifyError(backendInstance, syncResult.Errors[0])
 		return result
 	}
 
-	// Success
+	// Success, possibly with a tolerated number of failed files
 	now := time.Now()
 	result.Status = "success"
 	result.UploadedAt = &now
 	result.Size = syncResult.BytesUploaded
 	result.RemotePath = remotePath
+	if len(syncResult.FailedFiles) > 0 {
+		result.ErrorMessage = fmt.Sprintf("%d files failed: %s", len(syncResult.FailedFiles), strings.Join(syncResult.FailedFiles, ", "))
+		log.Printf("Sync to backend %s completed with %d failed files", backendCfg.Name, len(syncResult.FailedFiles))
+	}
 
-	log.Printf("Successfully synced to backend: %s (%d files uploaded, %d deleted, %d skipped)",
-		backendCfg.Name, syncResult.FilesUploaded, syncResult.FilesDeleted, syncResult.FilesSkipped)
+	log.Printf("Successfully synced to backend: %s (%d files uploaded, %d deleted, %d skipped, %d deletions held)",
+		backendCfg.Name, syncResult.FilesUploaded, syncResult.FilesDeleted, syncResult.FilesSkipped, syncResult.DeletesHeld)
 	return result
 }
 
-// uploadToBackend uploads the archive to a specific backend
-func (e *Executor) uploadToBackend(ctx context.Context, backendID string, task *models.Task, archivePath string, execution *models.Execution) models.BackendResult {
-	result := models.BackendResult{
+// uploadToBackend uploads the archive to a specific backend. A split archive is
+// uploaded as its parts, and the result's remote path is the name they share.
+func (e *Executor) uploadToBackend(ctx context.Context, backendID string, task *models.Task, archivePath string, parts []string, execution *models.Execution) (result models.BackendResult) {
+	startTime := time.Now()
+	defer func() {
+		result.DurationMs = time.Since(startTime).Milliseconds()
+	}()
+
+	result = models.BackendResult{
 		BackendID: backendID,
+		Mode:      "archive",
 	}
 
 	// Get backend configuration
@@ -764,11 +1275,20 @@ func (e *Executor) uploadToBackend(ctx context.Context, backendID string, task *
 
 	result.BackendName = backendCfg.Name
 
+	release, err := e.acquireBackend(ctx, backendCfg)
+	if err != nil {
+		result.Status = "failed"
+		result.ErrorMessage = fmt.Sprintf("Cancelled while waiting for a backend slot: %v", err)
+		return result
+	}
+	defer release()
+
 	// Create backend instance
 	backendInstance, err := backend.Factory(backendCfg, e.config)
 	if err != nil {
 		result.Status = "failed"
 		result.ErrorMessage = fmt.Sprintf("Failed to create backend: %v", err)
+		result.ErrorCode = backend.ClassifyError(nil, err)
 		return result
 	}
 	defer func() {
@@ -782,7 +1302,7 @@ func (e *Executor) uploadToBackend(ctx context.Context, backendID string, task *
 
 	// Upload with progress
 	log.Printf("Uploading to backend: %s", backendCfg.Name)
-	err = backendInstance.Upload(ctx, archivePath, remotePath, func(uploaded, total int64) {
+	progress := func(uploaded, total int64) {
 		e.broadcastEvent(models.ProgressEvent{
 			Type: "upload_progress",
 			Data: models.UploadProgress{
@@ -794,11 +1314,17 @@ func (e *Executor) uploadToBackend(ctx context.Context, backendID string, task *
 				BytesTotal:      total,
 			},
 		})
-	})
+	}
+	if len(parts) > 0 {
+		err = uploadParts(ctx, backendInstance, parts, execution.ArchiveSize, progress)
+	} else {
+		err = backendInstance.Upload(ctx, archivePath, remotePath, progress)
+	}
 
 	if err != nil {
 		result.Status = "failed"
 		result.ErrorMessage = err.Error()
+		result.ErrorCode = backend.ClassifyError(backendInstance, err)
 		return result
 	}
 
@@ -810,15 +1336,72 @@ func (e *Executor) uploadToBackend(ctx context.Context, backendID string, task *
 	result.RemotePath = remotePath
 
 	log.Printf("Successfully uploaded to backend: %s", backendCfg.Name)
+
+	if task.ArchiveOptions.UseTimestamp && task.ArchiveOptions.KeepLatest && len(parts) == 0 {
+		// The latest copy is replaced on every run, which an immutable backend refuses
+		if backendCfg.Immutable {
+			log.Printf("Skipping latest copy on immutable backend: %s", backendCfg.Name)
+		} else if err := updateLatestAlias(ctx, backendInstance, task, archivePath, remotePath); err != nil {
+			log.Printf("Warning: failed to update latest copy on backend %s: %v", backendCfg.Name, err)
+		}
+	}
+
 	return result
 }
 
+// uploadParts uploads the parts of a split archive in order. If a part fails,
+// the parts already uploaded are removed so no incomplete set is left behind.
+func uploadParts(ctx context.Context, backendInstance backend.StorageBackend, parts []string, total int64, progress backend.ProgressCallback) error {
+	var done int64
+	for i, part := range parts {
+		info, err := os.Stat(part)
+		if err != nil {
+			return fmt.Errorf("failed to stat archive part: %w", err)
+		}
+
+		err = backendInstance.Upload(ctx, part, filepath.Base(part), func(uploaded, _ int64) {
+			progress(done+uploaded, total)
+		})
+		if err != nil {
+			for _, uploaded := range parts[:i] {
+				if delErr := backendInstance.Delete(ctx, filepath.Base(uploaded)); delErr != nil {
+					log.Printf("Error removing uploaded archive part %s: %v", filepath.Base(uploaded), delErr)
+				}
+			}
+			return fmt.Errorf("failed to upload part %d of %d: %w", i+1, len(parts), err)
+		}
+		done += info.Size()
+	}
+	return nil
+}
+
+// updateLatestAlias points the task's _latest archive at the one just uploaded,
+// copying it server-side where the backend supports that
+func updateLatestAlias(ctx context.Context, backendInstance backend.StorageBackend, task *models.Task, archivePath, remotePath string) error {
+	latestPath := filepath.Join(filepath.Dir(remotePath), archive.LatestFilename(task.Name, remotePath))
+	if latestPath == remotePath {
+		return nil
+	}
+
+	err := backendInstance.Copy(ctx, remotePath, latestPath)
+	if errors.Is(err, backend.ErrCopyNotSupported) {
+		err = backendInstance.Upload(ctx, archivePath, latestPath, nil)
+	}
+	return err
+}
+
 // applyRetentionPolicy removes old backups according to retention policy
-func (e *Executor) applyRetentionPolicy(ctx context.Context, task *models.Task, backendResults []models.BackendResult) {
+// Each deletion is recorded against the execution that triggered it.
+func (e *Executor) applyRetentionPolicy(ctx context.Context, task *models.Task, execution *models.Execution, backendResults []models.BackendResult) {
 	for _, result := range backendResults {
+		// Never prune a backend whose new upload failed; its old backups may be the only good copies
 		if result.Status != "success" {
 			continue
 		}
+		// A sync backend holds a mirror of the source, not archives to prune
+		if result.Mode == "sync" {
+			continue
+		}
 
 		// Get backend
 		backendCfg, err := e.config.GetBackend(result.BackendID)
@@ -826,6 +1409,11 @@ func (e *Executor) applyRetentionPolicy(ctx context.Context, task *models.Task,
 			continue
 		}
 
+		if backendCfg.Immutable {
+			log.Printf("Skipping retention on immutable backend: %s", backendCfg.Name)
+			continue
+		}
+
 		backendInstance, err := backend.Factory(backendCfg, e.config)
 		if err != nil {
 			continue
@@ -848,31 +1436,23 @@ func (e *Executor) applyRetentionPolicy(ctx context.Context, task *models.Task,
 			continue
 		}
 
-		// Filter to only include files matching this task's backup pattern
-		// Backup files follow pattern: <taskname>_YYYYMMDD_HHMMSS.tar.gz
-		var backups []backend.BackupInfo
-		taskPrefix := task.Name + "_"
-		for _, file := range allFiles {
-			fileName := filepath.Base(file.Path)
-			// Only consider files that start with task name and end with .tar.gz
-			if len(fileName) > len(taskPrefix) &&
-				fileName[:len(taskPrefix)] == taskPrefix &&
-				filepath.Ext(fileName) == ".gz" {
-				backups = append(backups, file)
+		// Delete the oldest backups beyond KeepLast
+		for _, old := range selectRetentionDeletions(task, allFiles) {
+			if err := backendInstance.Delete(ctx, old.Path); err != nil {
+				log.Printf("Failed to delete old backup %s: %v", old.Path, err)
+				continue
 			}
-		}
+			log.Printf("Deleted old backup: %s", old.Path)
 
-		// If we have more than KeepLast, delete oldest
-		if len(backups) > task.RetentionPolicy.KeepLast {
-			// Sort by last modified (oldest first)
-			// For now, delete excess backups
-			toDelete := len(backups) - task.RetentionPolicy.KeepLast
-			for i := 0; i < toDelete; i++ {
-				if err := backendInstance.Delete(ctx, backups[i].Path); err != nil {
-					log.Printf("Failed to delete old backup %s: %v", backups[i].Path, err)
-				} else {
-					log.Printf("Deleted old backup: %s", backups[i].Path)
-				}
+			deletion := models.RetentionDeletion{
+				BackendID:   backendCfg.ID,
+				BackendName: backendCfg.Name,
+				RemotePath:  old.Path,
+				DeletedAt:   time.Now(),
+			}
+			execution.RetentionDeletions = append(execution.RetentionDeletions, deletion)
+			if dbErr := e.db.AddRetentionDeletion(execution.ID, &deletion); dbErr != nil {
+				log.Printf("Error recording retention deletion: %v", dbErr)
 			}
 		}
 
@@ -882,8 +1462,12 @@ func (e *Executor) applyRetentionPolicy(ctx context.Context, task *models.Task,
 	}
 }
 
-// Cancel cancels a running execution
+// Cancel cancels a running execution, or removes it from the queue if it hasn't started
 func (e *Executor) Cancel(executionID string) error {
+	if err := e.Dequeue(executionID); err == nil {
+		return nil
+	}
+
 	e.mu.RLock()
 	defer e.mu.RUnlock()
 
@@ -897,33 +1481,169 @@ func (e *Executor) Cancel(executionID string) error {
 	return fmt.Errorf("execution not found or not running")
 }
 
-// IsRunning checks if a task is currently running
+// Dequeue removes a run that is waiting to start from the queue and records it as cancelled
+func (e *Executor) Dequeue(executionID string) error {
+	e.mu.Lock()
+	var run *queuedRun
+	for i, queued := range e.queue {
+		if queued.execution.ID == executionID {
+			run = queued
+			e.queue = append(e.queue[:i], e.queue[i+1:]...)
+			break
+		}
+	}
+	e.mu.Unlock()
+
+	if run == nil {
+		return fmt.Errorf("execution not found or not queued")
+	}
+
+	execution := run.execution
+	execution.Status = "cancelled"
+	execution.ErrorMessage = "Removed from the queue before starting"
+	now := time.Now()
+	execution.CompletedAt = &now
+	if err := e.db.UpdateExecution(execution); err != nil {
+		log.Printf("Error updating execution: %v", err)
+	}
+
+	e.broadcastEvent(models.ProgressEvent{
+		Type: "execution_dequeued",
+		Data: map[string]interface{}{
+			"execution_id": execution.ID,
+			"task_id":      execution.TaskID,
+		},
+	})
+	return nil
+}
+
+// GetQueuedExecutions returns the runs waiting to start, in the order they will start
+func (e *Executor) GetQueuedExecutions() []models.QueuedExecution {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	queued := make([]models.QueuedExecution, 0, len(e.queue))
+	for i, run := range e.queue {
+		queued = append(queued, models.QueuedExecution{
+			ExecutionID: run.execution.ID,
+			TaskID:      run.task.ID,
+			TaskName:    run.task.Name,
+			EnqueuedAt:  run.enqueuedAt,
+			Position:    i + 1,
+		})
+	}
+	return queued
+}
+
+// IsRunning checks if a task is currently running or queued to run
 func (e *Executor) IsRunning(taskID string) bool {
 	e.mu.RLock()
 	defer e.mu.RUnlock()
 	_, exists := e.running[taskID]
-	return exists
+	return exists || e.queueIndex(taskID) >= 0
 }
 
-// GetRunningExecutions returns all running executions
-func (e *Executor) GetRunningExecutions() []string {
+// GetRunningExecutions returns all running executions, oldest first
+func (e *Executor) GetRunningExecutions() []models.RunningExecution {
 	e.mu.RLock()
 	defer e.mu.RUnlock()
 
-	var ids []string
+	now := time.Now()
+	executions := make([]models.RunningExecution, 0, len(e.running))
 	for _, running := range e.running {
+		executions = append(executions, models.RunningExecution{
+			ExecutionID: running.ID,
+			TaskID:      running.TaskID,
+			TaskName:    running.TaskName,
+			StartedAt:   running.StartedAt,
+			ElapsedMs:   now.Sub(running.StartedAt).Milliseconds(),
+			Progress:    running.Progress,
+		})
+	}
+
+	sort.Slice(executions, func(i, j int) bool {
+		return executions[i].StartedAt.Before(executions[j].StartedAt)
+	})
+	return executions
+}
+
+// CancelAll cancels every running execution, empties the queue and returns their IDs
+func (e *Executor) CancelAll() []string {
+	e.mu.RLock()
+	ids := make([]string, 0, len(e.running)+len(e.queue))
+	for _, running := range e.running {
+		running.Cancel()
 		ids = append(ids, running.ID)
 	}
+	queued := make([]string, 0, len(e.queue))
+	for _, run := range e.queue {
+		queued = append(queued, run.execution.ID)
+	}
+	e.mu.RUnlock()
+
+	for _, id := range queued {
+		if err := e.Dequeue(id); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
 	return ids
 }
 
+// pruneHistory enforces the task's and the global execution history limits
+func (e *Executor) pruneHistory(task *models.Task) {
+	if task.MaxExecutionHistory > 0 {
+		if pruned, err := e.db.PruneExecutions(task.ID, task.MaxExecutionHistory); err != nil {
+			log.Printf("Error pruning execution history for task %s: %v", task.Name, err)
+		} else if pruned > 0 {
+			log.Printf("Pruned %d old executions of task %s", pruned, task.Name)
+		}
+	}
+
+	if limit := e.config.GetSettings().MaxExecutionHistory; limit > 0 {
+		if pruned, err := e.db.PruneExecutions("", limit); err != nil {
+			log.Printf("Error pruning execution history: %v", err)
+		} else if pruned > 0 {
+			log.Printf("Pruned %d old executions", pruned)
+		}
+	}
+}
+
 // broadcastEvent broadcasts a progress event
 func (e *Executor) broadcastEvent(event models.ProgressEvent) {
+	e.recordProgress(event)
 	if e.progress != nil {
 		e.progress.BroadcastProgress(event)
 	}
 }
 
+// recordProgress keeps the latest progress event of a running execution
+func (e *Executor) recordProgress(event models.ProgressEvent) {
+	var executionID string
+	switch data := event.Data.(type) {
+	case models.ArchiveProgress:
+		executionID = data.ExecutionID
+	case models.UploadProgress:
+		executionID = data.ExecutionID
+	case map[string]interface{}:
+		if strings.HasSuffix(event.Type, "_progress") {
+			executionID, _ = data["execution_id"].(string)
+		}
+	}
+	if executionID == "" {
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, running := range e.running {
+		if running.ID == executionID {
+			running.Progress = &event
+			return
+		}
+	}
+}
+
 // broadcastExecutionFailed broadcasts an execution failed event
 func (e *Executor) broadcastExecutionFailed(execution *models.Execution) {
 	e.broadcastEvent(models.ProgressEvent{
@@ -936,4 +1656,63 @@ func (e *Executor) broadcastExecutionFailed(execution *models.Execution) {
 			"error_message": execution.ErrorMessage,
 		},
 	})
+	e.notifyExecution(execution)
+}
+
+// heartbeat returns the monitoring URLs for a task: its own, or else the global ones
+func (e *Executor) heartbeat(task *models.Task) *models.Heartbeat {
+	if task.Heartbeat != nil {
+		return task.Heartbeat
+	}
+	return e.config.GetSettings().Heartbeat
+}
+
+// ping requests a heartbeat URL, if set. Failures are only logged.
+func (e *Executor) ping(url string) {
+	if url == "" {
+		return
+	}
+	if err := e.notifier.Ping(url); err != nil {
+		log.Printf("Error pinging heartbeat URL: %v", err)
+	}
+}
+
+// notifyExecution sends a finished execution to the notification channels
+// routed for its task
+func (e *Executor) notifyExecution(execution *models.Execution) {
+	channels := e.config.GetSettings().NotificationChannels
+	if len(channels) == 0 {
+		return
+	}
+
+	task, err := e.config.GetTask(execution.TaskID)
+	if err != nil {
+		return
+	}
+	if FailureRetryPending(task, execution) {
+		return // notified if the last attempt fails too
+	}
+
+	var event string
+	switch {
+	case execution.Status == "failed":
+		event = notify.EventFailure
+	case execution.Status == "success" && execution.ErrorMessage != "":
+		event = notify.EventPartial
+	case execution.Status == "success":
+		event = notify.EventSuccess
+	default:
+		return
+	}
+
+	e.notifier.Send(notify.Route(channels, task, event), notify.Payload{
+		Event:        event,
+		TaskID:       task.ID,
+		TaskName:     task.Name,
+		ExecutionID:  execution.ID,
+		Status:       execution.Status,
+		ErrorMessage: execution.ErrorMessage,
+		CompletedAt:  execution.CompletedAt,
+		DurationMs:   execution.DurationMs,
+	})
 }
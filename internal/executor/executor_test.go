@@ -0,0 +1,263 @@
+package executor
+
+import (
+	"errors"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nsilverman/archivist/internal/archive"
+	"github.com/nsilverman/archivist/internal/models"
+)
+
+func TestExecuteRunsTaskOnce(t *testing.T) {
+	e, cfg, db, _ := newTestExecutor(t)
+	source := t.TempDir()
+	if err := os.WriteFile(filepath.Join(source, "a.txt"), []byte("contents"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.AddTask(&models.Task{
+		ID:             "task-1",
+		Name:           "documents",
+		SourcePath:     source,
+		BackendIDs:     []string{"local"},
+		Schedule:       models.Schedule{Type: "manual"},
+		ArchiveOptions: models.ArchiveOptions{Format: "tar.gz", UseTimestamp: true},
+		Enabled:        true,
+	}); err != nil {
+		t.Fatalf("AddTask: %v", err)
+	}
+
+	finished := make(chan models.Execution, 10)
+	e.OnFinished(func(execution models.Execution) { finished <- execution })
+
+	// Hold the only slot so the run stays queued while the callers race,
+	// rather than finishing before the later ones check
+	settings := cfg.GetSettings()
+	settings.MaxConcurrentTasks = 1
+	if err := cfg.UpdateSettings(settings); err != nil {
+		t.Fatalf("UpdateSettings: %v", err)
+	}
+	e.mu.Lock()
+	e.running["other"] = &RunningExecution{ID: "other", TaskID: "other", Cancel: func() {}}
+	e.mu.Unlock()
+
+	const callers = 10
+	var wg sync.WaitGroup
+	results := make(chan error, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := e.Execute("task-1")
+			results <- err
+		}()
+	}
+	wg.Wait()
+	close(results)
+
+	started := 0
+	for err := range results {
+		switch {
+		case err == nil:
+			started++
+		case !errors.Is(err, ErrTaskRunning):
+			t.Errorf("Execute: %v", err)
+		}
+	}
+	if started != 1 {
+		t.Fatalf("%d runs started, want 1", started)
+	}
+
+	e.mu.Lock()
+	delete(e.running, "other")
+	e.mu.Unlock()
+	e.dispatch()
+
+	select {
+	case execution := <-finished:
+		if execution.Status != "success" {
+			t.Errorf("execution %s: %s", execution.Status, execution.ErrorMessage)
+		}
+	case <-time.After(30 * time.Second):
+		t.Fatal("execution did not finish")
+	}
+
+	// Only the run that started left a record
+	executions, err := db.ListExecutions("task-1", "", 100, 0)
+	if err != nil {
+		t.Fatalf("ListExecutions: %v", err)
+	}
+	if len(executions) != 1 {
+		t.Errorf("%d executions recorded, want 1", len(executions))
+	}
+
+	// With the run over, the task can start again
+	if _, err := e.Execute("task-1"); err != nil {
+		t.Errorf("Execute after the run finished: %v", err)
+	}
+	select {
+	case <-finished:
+	case <-time.After(30 * time.Second):
+		t.Fatal("second execution did not finish")
+	}
+}
+
+func TestDependentRunsAfterParentSucceeds(t *testing.T) {
+	e, cfg, _, _ := newTestExecutor(t)
+	addTask := func(id, source string, dependsOn ...string) {
+		t.Helper()
+		if err := cfg.AddTask(&models.Task{
+			ID:             id,
+			Name:           id,
+			SourcePath:     source,
+			BackendIDs:     []string{"local"},
+			Schedule:       models.Schedule{Type: "manual"},
+			ArchiveOptions: models.ArchiveOptions{Format: "tar.gz", UseTimestamp: true},
+			DependsOn:      dependsOn,
+			Enabled:        true,
+		}); err != nil {
+			t.Fatalf("AddTask %s: %v", id, err)
+		}
+	}
+	source := t.TempDir()
+	if err := os.WriteFile(filepath.Join(source, "dump.sql"), []byte("contents"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	addTask("dump", source)
+	addTask("upload", source, "dump")
+	addTask("broken", filepath.Join(source, "missing"))
+	addTask("after-broken", source, "broken")
+
+	finished := make(chan models.Execution, 10)
+	e.OnFinished(func(execution models.Execution) { finished <- execution })
+	next := func() models.Execution {
+		t.Helper()
+		select {
+		case execution := <-finished:
+			return execution
+		case <-time.After(30 * time.Second):
+			t.Fatal("execution did not finish")
+			return models.Execution{}
+		}
+	}
+
+	if _, err := e.ExecuteRequest("dump", "req-1", ""); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	parent, dependent := next(), next()
+	if parent.TaskID != "dump" || dependent.TaskID != "upload" {
+		t.Fatalf("ran %s then %s, want dump then upload", parent.TaskID, dependent.TaskID)
+	}
+	if parent.Status != "success" || dependent.Status != "success" {
+		t.Errorf("dump %s, upload %s; want both to succeed", parent.Status, dependent.Status)
+	}
+	if parent.CompletedAt == nil || dependent.StartedAt.Before(*parent.CompletedAt) {
+		t.Errorf("upload started at %v, before dump completed at %v", dependent.StartedAt, parent.CompletedAt)
+	}
+	if dependent.RequestID != "req-1" {
+		t.Errorf("upload ran under request %q, want the parent's req-1", dependent.RequestID)
+	}
+
+	// A failed parent leaves its dependents alone
+	if _, err := e.Execute("broken"); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if execution := next(); execution.TaskID != "broken" || execution.Status != "failed" {
+		t.Fatalf("broken finished as %s %s, want a failure", execution.TaskID, execution.Status)
+	}
+	select {
+	case execution := <-finished:
+		t.Errorf("%s ran after its parent failed", execution.TaskID)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func TestAutoCompressionRecordsCodec(t *testing.T) {
+	e, cfg, db, storeDir := newTestExecutor(t)
+	finished := make(chan models.Execution, 2)
+	e.OnFinished(func(execution models.Execution) { finished <- execution })
+
+	random := make([]byte, 256<<10)
+	rand.New(rand.NewSource(1)).Read(random)
+	text := []byte(strings.Repeat("The quick brown fox jumps over the lazy dog.\n", 6000))
+
+	tests := []struct {
+		name          string
+		data          []byte
+		wantCodecs    []string
+		wantExtension string
+	}{
+		{"random", random, []string{archive.CodecNone}, ".tar"},
+		{"text", text, []string{archive.CodecZstd, archive.CodecZstdBest}, ".tar.zst"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := t.TempDir()
+			if err := os.WriteFile(filepath.Join(source, "data.bin"), tt.data, 0644); err != nil {
+				t.Fatal(err)
+			}
+			if err := cfg.AddTask(&models.Task{
+				ID:             tt.name,
+				Name:           tt.name,
+				SourcePath:     source,
+				BackendIDs:     []string{"local"},
+				Schedule:       models.Schedule{Type: "manual"},
+				ArchiveOptions: models.ArchiveOptions{Format: "tar.gz", Compression: "auto", UseTimestamp: true},
+				Enabled:        true,
+			}); err != nil {
+				t.Fatalf("AddTask: %v", err)
+			}
+
+			executionID, err := e.Execute(tt.name)
+			if err != nil {
+				t.Fatalf("Execute: %v", err)
+			}
+			select {
+			case execution := <-finished:
+				if execution.Status != "success" {
+					t.Fatalf("execution %s: %s", execution.Status, execution.ErrorMessage)
+				}
+			case <-time.After(30 * time.Second):
+				t.Fatal("execution did not finish")
+			}
+
+			execution, err := db.GetExecution(executionID)
+			if err != nil {
+				t.Fatalf("GetExecution: %v", err)
+			}
+			if !slices.Contains(tt.wantCodecs, execution.Compression) {
+				t.Errorf("execution recorded compression %q, want one of %v", execution.Compression, tt.wantCodecs)
+			}
+
+			// The uploaded archive is named after the codec and readable
+			if len(execution.BackendResults) != 1 {
+				t.Fatalf("backend results %+v, want one upload", execution.BackendResults)
+			}
+			remotePath := execution.BackendResults[0].RemotePath
+			if got := archive.ArchiveExtension(remotePath); got != tt.wantExtension {
+				t.Errorf("archive %s, want extension %s", remotePath, tt.wantExtension)
+			}
+			f, err := os.Open(filepath.Join(storeDir, remotePath))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() { _ = f.Close() }()
+			var size int64
+			if err := archive.InspectTar(f, func(entry archive.Entry) error {
+				size += entry.Size
+				return nil
+			}); err != nil {
+				t.Fatalf("InspectTar: %v", err)
+			}
+			if size != int64(len(tt.data)) {
+				t.Errorf("archive holds %d bytes, want %d", size, len(tt.data))
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package executor
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/nsilverman/archivist/internal/backend"
+	"github.com/nsilverman/archivist/internal/models"
+)
+
+// keepLocalCopy moves the finished archive files into the task's local copy
+// directory, then applies the task's retention policy there
+func (e *Executor) keepLocalCopy(task *models.Task, files []string) error {
+	dir := e.config.ResolvePath(task.ArchiveOptions.LocalCopyDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create local copy directory: %w", err)
+	}
+
+	for _, file := range files {
+		if err := moveFile(file, filepath.Join(dir, filepath.Base(file))); err != nil {
+			return err
+		}
+	}
+	log.Printf("Kept local copy of archive in %s", dir)
+
+	pruneLocalCopies(task, dir)
+	return nil
+}
+
+// pruneLocalCopies deletes the archives in dir that the task's retention policy would remove
+func pruneLocalCopies(task *models.Task, dir string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		log.Printf("Error listing local copies: %v", err)
+		return
+	}
+
+	files := make([]backend.BackupInfo, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, backend.BackupInfo{
+			Path:         entry.Name(),
+			Size:         info.Size(),
+			LastModified: info.ModTime().Format(time.RFC3339),
+		})
+	}
+
+	for _, old := range selectRetentionDeletions(task, files) {
+		if err := os.Remove(filepath.Join(dir, old.Path)); err != nil {
+			log.Printf("Error deleting old local copy %s: %v", old.Path, err)
+			continue
+		}
+		log.Printf("Deleted old local copy: %s", old.Path)
+	}
+}
+
+// moveFile renames src to dst, copying when they are on different filesystems
+func moveFile(src, dst string) error {
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("failed to open archive: %w", err)
+	}
+	defer func() {
+		if err := in.Close(); err != nil {
+			log.Printf("Error closing archive: %v", err)
+		}
+	}()
+
+	info, err := in.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat archive: %w", err)
+	}
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return fmt.Errorf("failed to create local copy: %w", err)
+	}
+
+	_, err = io.Copy(out, in)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		if removeErr := os.Remove(dst); removeErr != nil {
+			log.Printf("Error removing incomplete local copy: %v", removeErr)
+		}
+		return fmt.Errorf("failed to copy archive: %w", err)
+	}
+
+	return os.Remove(src)
+}
@@ -0,0 +1,227 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nsilverman/archivist/internal/backend"
+	"github.com/nsilverman/archivist/internal/models"
+)
+
+// migrationConcurrency limits how many objects are copied at once during a migration
+const migrationConcurrency = 4
+
+// Migrate copies every object under prefix from one backend to another.
+// The copy runs in the background; progress is reported through migration_* events.
+// CancelMigration stops it early.
+func (e *Executor) Migrate(sourceID, destinationID, prefix string) (string, error) {
+	if sourceID == destinationID {
+		return "", fmt.Errorf("source and destination backends must differ")
+	}
+
+	sourceCfg, err := e.config.GetBackend(sourceID)
+	if err != nil {
+		return "", fmt.Errorf("source backend not found: %w", err)
+	}
+	destinationCfg, err := e.config.GetBackend(destinationID)
+	if err != nil {
+		return "", fmt.Errorf("destination backend not found: %w", err)
+	}
+
+	migrationID := uuid.New().String()
+
+	e.broadcastEvent(models.ProgressEvent{
+		Type: "migration_started",
+		Data: map[string]interface{}{
+			"migration_id":           migrationID,
+			"source_backend_id":      sourceID,
+			"destination_backend_id": destinationID,
+			"prefix":                 prefix,
+		},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	e.mu.Lock()
+	e.migrations[migrationID] = cancel
+	e.mu.Unlock()
+
+	go func() {
+		defer func() {
+			e.mu.Lock()
+			delete(e.migrations, migrationID)
+			e.mu.Unlock()
+			cancel()
+		}()
+
+		result, err := e.runMigration(ctx, migrationID, sourceCfg, destinationCfg, prefix)
+		if errors.Is(err, context.Canceled) && result != nil {
+			log.Printf("Migration %s cancelled: %d copied, %d failed", migrationID, result.ObjectsCopied, result.ObjectsFailed)
+			e.broadcastEvent(models.ProgressEvent{
+				Type: "migration_cancelled",
+				Data: result,
+			})
+			return
+		}
+		if err != nil {
+			log.Printf("Migration %s failed: %v", migrationID, err)
+			e.broadcastEvent(models.ProgressEvent{
+				Type: "migration_failed",
+				Data: map[string]interface{}{
+					"migration_id":  migrationID,
+					"error_message": err.Error(),
+				},
+			})
+			return
+		}
+
+		log.Printf("Migration %s completed: %d copied, %d failed", migrationID, result.ObjectsCopied, result.ObjectsFailed)
+		e.broadcastEvent(models.ProgressEvent{
+			Type: "migration_completed",
+			Data: result,
+		})
+	}()
+
+	return migrationID, nil
+}
+
+// CancelMigration stops a running migration. Objects already copied stay on
+// the destination; the migration_cancelled event reports how far it got.
+func (e *Executor) CancelMigration(migrationID string) error {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	cancel, ok := e.migrations[migrationID]
+	if !ok {
+		return fmt.Errorf("migration not found or not running")
+	}
+	cancel()
+	return nil
+}
+
+// runMigration performs the copy, downloading each object to a temporary file
+// and uploading it to the destination under the same path. If ctx is cancelled,
+// no further objects are started and the progress so far is returned with the error.
+func (e *Executor) runMigration(ctx context.Context, migrationID string, sourceCfg, destinationCfg *models.Backend, prefix string) (*models.MigrationProgress, error) {
+	source, err := backend.Factory(sourceCfg, e.config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create source backend: %w", err)
+	}
+	defer func() {
+		if err := source.Close(); err != nil {
+			log.Printf("Error closing backend instance: %v", err)
+		}
+	}()
+
+	destination, err := backend.Factory(destinationCfg, e.config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create destination backend: %w", err)
+	}
+	defer func() {
+		if err := destination.Close(); err != nil {
+			log.Printf("Error closing backend instance: %v", err)
+		}
+	}()
+
+	objects, err := source.List(ctx, prefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list source backend: %w", err)
+	}
+
+	tempDir := e.config.ResolvePath(e.config.GetSettings().TempDir)
+	if err := os.MkdirAll(tempDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create temp directory: %w", err)
+	}
+
+	progress := &models.MigrationProgress{
+		MigrationID:          migrationID,
+		SourceBackendID:      sourceCfg.ID,
+		DestinationBackendID: destinationCfg.ID,
+		ObjectsTotal:         len(objects),
+		StartedAt:            time.Now(),
+	}
+	var mu sync.Mutex
+
+	work := make(chan backend.BackupInfo)
+	var wg sync.WaitGroup
+	for i := 0; i < migrationConcurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for obj := range work {
+				err := copyObject(ctx, source, destination, obj, tempDir)
+
+				mu.Lock()
+				if err != nil {
+					progress.ObjectsFailed++
+					progress.Errors = append(progress.Errors, fmt.Sprintf("%s: %v", obj.Path, err))
+				} else {
+					progress.ObjectsCopied++
+					progress.BytesCopied += obj.Size
+				}
+				progress.CurrentObject = obj.Path
+				snapshot := *progress
+				snapshot.Errors = nil
+				mu.Unlock()
+
+				e.broadcastEvent(models.ProgressEvent{
+					Type: "migration_progress",
+					Data: snapshot,
+				})
+			}
+		}()
+	}
+
+feed:
+	for _, obj := range objects {
+		select {
+		case work <- obj:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(work)
+	wg.Wait()
+
+	progress.CurrentObject = ""
+	return progress, ctx.Err()
+}
+
+// copyObject transfers a single object between backends through a temporary file
+func copyObject(ctx context.Context, source, destination backend.StorageBackend, obj backend.BackupInfo, tempDir string) error {
+	tempFile, err := os.CreateTemp(tempDir, "archivist-migrate-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	tempPath := tempFile.Name()
+	if err := tempFile.Close(); err != nil {
+		log.Printf("Error closing temp file: %v", err)
+	}
+	defer func() {
+		for _, path := range backend.DownloadFiles(tempPath) {
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				log.Printf("Error removing temp file: %v", err)
+			}
+		}
+	}()
+
+	err = backend.DownloadVerified(ctx, source, obj.Path, tempPath, obj.Hash, nil)
+	if errors.Is(err, backend.ErrUnverifiable) {
+		// Not every backend reports a hash it can be checked against
+		log.Printf("Copying %s without verifying it: %v", obj.Path, err)
+		err = nil
+	}
+	if err != nil {
+		return fmt.Errorf("download failed: %w", err)
+	}
+	if err := destination.Upload(ctx, tempPath, obj.Path, nil); err != nil {
+		return fmt.Errorf("upload failed: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,133 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/nsilverman/archivist/internal/models"
+)
+
+// eventRecorder passes broadcast events on to a channel
+type eventRecorder chan models.ProgressEvent
+
+func (r eventRecorder) BroadcastProgress(event models.ProgressEvent) { r <- event }
+
+// addDestination adds a second local backend "dest" and returns its directory
+func addDestination(t *testing.T, e *Executor) string {
+	t.Helper()
+	dir := t.TempDir()
+	if err := e.config.AddBackend(&models.Backend{
+		ID:      "dest",
+		Name:    "dest",
+		Type:    "local",
+		Enabled: true,
+		Config:  map[string]interface{}{"path": dir},
+	}); err != nil {
+		t.Fatalf("AddBackend: %v", err)
+	}
+	return dir
+}
+
+// storeObjects writes count objects under docs/ in storeDir
+func storeObjects(t *testing.T, storeDir string, count int) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Join(storeDir, "docs"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < count; i++ {
+		name := filepath.Join(storeDir, "docs", fmt.Sprintf("docs_20250127_%06d.tar.gz", i))
+		if err := os.WriteFile(name, []byte("archive"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	e, _, _, storeDir := newTestExecutor(t)
+	destDir := addDestination(t, e)
+	storeObjects(t, storeDir, 5)
+
+	events := make(eventRecorder, 100)
+	e.SetProgressBroadcaster(events)
+	migrationID, err := e.Migrate("local", "dest", "docs")
+	if err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+
+	deadline := time.After(30 * time.Second)
+	for done := false; !done; {
+		select {
+		case event := <-events:
+			switch event.Type {
+			case "migration_completed":
+				progress := event.Data.(*models.MigrationProgress)
+				if progress.ObjectsCopied != 5 || progress.ObjectsFailed != 0 {
+					t.Errorf("copied %d and failed %d, want 5 and 0", progress.ObjectsCopied, progress.ObjectsFailed)
+				}
+				done = true
+			case "migration_failed", "migration_cancelled":
+				t.Fatalf("migration ended with %s: %v", event.Type, event.Data)
+			}
+		case <-deadline:
+			t.Fatal("migration did not finish")
+		}
+	}
+
+	copied, err := os.ReadDir(filepath.Join(destDir, "docs"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(copied) != 5 {
+		t.Errorf("%d objects on the destination, want 5", len(copied))
+	}
+
+	// Once finished, the migration can't be cancelled; it unregisters just
+	// after the completed event is sent
+	for start := time.Now(); e.CancelMigration(migrationID) == nil; time.Sleep(time.Millisecond) {
+		if time.Since(start) > 5*time.Second {
+			t.Fatal("finished migration can still be cancelled")
+		}
+	}
+}
+
+func TestCancelMigration(t *testing.T) {
+	e, cfg, _, storeDir := newTestExecutor(t)
+	addDestination(t, e)
+	storeObjects(t, storeDir, 20)
+
+	if err := e.CancelMigration("missing"); err == nil {
+		t.Error("cancelling an unknown migration succeeded")
+	}
+
+	source, err := cfg.GetBackend("local")
+	if err != nil {
+		t.Fatal(err)
+	}
+	destination, err := cfg.GetBackend("dest")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Cancelled before it starts, the migration reports what it got through
+	// and stops feeding objects
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	progress, err := e.runMigration(ctx, "migration-1", source, destination, "docs")
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("runMigration error = %v, want context.Canceled", err)
+	}
+	if progress == nil {
+		t.Fatal("cancelled migration reported no progress")
+	}
+	if progress.ObjectsTotal != 20 {
+		t.Errorf("objects total = %d, want 20", progress.ObjectsTotal)
+	}
+	if handled := progress.ObjectsCopied + progress.ObjectsFailed; handled >= progress.ObjectsTotal {
+		t.Errorf("cancelled migration handled all %d objects", handled)
+	}
+}
@@ -0,0 +1,388 @@
+package executor
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/nsilverman/archivist/internal/backend"
+	"github.com/nsilverman/archivist/internal/models"
+	"github.com/nsilverman/archivist/internal/storage"
+)
+
+// recordUpload records a successful upload of data to remotePath on the
+// "local" backend, as a finished execution would
+func recordUpload(t *testing.T, db storage.Store, remotePath string, data []byte) {
+	t.Helper()
+	sum := sha256.Sum256(data)
+	exec := &models.Execution{
+		ID:          "exec-" + filepath.Base(remotePath),
+		TaskID:      "task-1",
+		TaskName:    "documents",
+		StartedAt:   time.Now(),
+		Status:      "success",
+		ArchiveHash: "sha256:" + hex.EncodeToString(sum[:]),
+	}
+	if err := db.CreateExecution(exec); err != nil {
+		t.Fatalf("CreateExecution: %v", err)
+	}
+	if err := db.AddBackendUpload(exec.ID, &models.BackendResult{
+		BackendID:   "local",
+		BackendName: "local",
+		Status:      "success",
+		RemotePath:  remotePath,
+	}); err != nil {
+		t.Fatalf("AddBackendUpload: %v", err)
+	}
+}
+
+// gzipped compresses data as a sync task's compress_files does, naming the
+// original file in the header when name is set
+func gzipped(t *testing.T, data []byte, name string, modTime time.Time) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	gz.Name = name
+	gz.ModTime = modTime
+	if _, err := gz.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestRunArchiveRestore(t *testing.T) {
+	archiveData := []byte("archive contents, split or not")
+	modTime := time.Date(2025, 1, 27, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name         string
+		objects      map[string][]byte
+		remotePath   string
+		recorded     []byte // what the recorded hash is of; nil for no record
+		wantFile     string
+		wantData     []byte
+		wantVerified bool
+		wantErr      error
+	}{
+		{
+			name:         "verified against the recorded hash",
+			objects:      map[string][]byte{"docs_20250127_120000.tar.gz": archiveData},
+			remotePath:   "docs_20250127_120000.tar.gz",
+			recorded:     archiveData,
+			wantFile:     "docs_20250127_120000.tar.gz",
+			wantData:     archiveData,
+			wantVerified: true,
+		},
+		{
+			name:       "no hash to check",
+			objects:    map[string][]byte{"docs_20250127_120000.tar.gz": archiveData},
+			remotePath: "docs_20250127_120000.tar.gz",
+			wantFile:   "docs_20250127_120000.tar.gz",
+			wantData:   archiveData,
+		},
+		{
+			name:       "corrupted archive",
+			objects:    map[string][]byte{"docs_20250127_120000.tar.gz": []byte("bit rot")},
+			remotePath: "docs_20250127_120000.tar.gz",
+			recorded:   archiveData,
+			wantErr:    backend.ErrHashMismatch,
+		},
+		{
+			name: "split archive",
+			objects: map[string][]byte{
+				"docs_20250127_120000.tar.gz.001": archiveData[:10],
+				"docs_20250127_120000.tar.gz.002": archiveData[10:20],
+				"docs_20250127_120000.tar.gz.003": archiveData[20:],
+			},
+			remotePath:   "docs_20250127_120000.tar.gz",
+			recorded:     archiveData,
+			wantFile:     "docs_20250127_120000.tar.gz",
+			wantData:     archiveData,
+			wantVerified: true,
+		},
+		{
+			name: "corrupted split archive",
+			objects: map[string][]byte{
+				"docs_20250127_120000.tar.gz.001": archiveData[:10],
+				"docs_20250127_120000.tar.gz.002": []byte("bit rot"),
+			},
+			remotePath: "docs_20250127_120000.tar.gz",
+			recorded:   archiveData,
+			wantErr:    backend.ErrHashMismatch,
+		},
+		{
+			name:         "compressed sync file",
+			objects:      map[string][]byte{"docs/notes.txt.gz": gzipped(t, []byte("notes"), "notes.txt", modTime)},
+			remotePath:   "docs/notes.txt.gz",
+			recorded:     gzipped(t, []byte("notes"), "notes.txt", modTime),
+			wantFile:     "notes.txt",
+			wantData:     []byte("notes"),
+			wantVerified: true,
+		},
+		{
+			name:       "gzip file not compressed by a sync",
+			objects:    map[string][]byte{"docs/data.gz": gzipped(t, []byte("data"), "", time.Time{})},
+			remotePath: "docs/data.gz",
+			wantFile:   "data.gz",
+			wantData:   gzipped(t, []byte("data"), "", time.Time{}),
+		},
+		{
+			name:       "missing archive",
+			objects:    map[string][]byte{"docs_20250127_120000.tar.gz": archiveData},
+			remotePath: "docs_20250128_120000.tar.gz",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, cfg, db, storeDir := newTestExecutor(t)
+			for name, data := range tt.objects {
+				path := filepath.Join(storeDir, filepath.FromSlash(name))
+				if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, data, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.recorded != nil {
+				recordUpload(t, db, tt.remotePath, tt.recorded)
+			}
+			backendCfg, err := cfg.GetBackend("local")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			restoreDir := t.TempDir()
+			localPath, verified, err := e.runArchiveRestore(context.Background(), backendCfg, tt.remotePath, restoreDir)
+			if tt.wantFile == "" {
+				if err == nil {
+					t.Fatal("restore succeeded, want an error")
+				}
+				if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+					t.Errorf("restore error = %v, want %v", err, tt.wantErr)
+				}
+				// Nothing is left behind: no corrupt archive, no parts
+				leftovers, _ := os.ReadDir(restoreDir)
+				for _, leftover := range leftovers {
+					t.Errorf("%s left in the restore directory", leftover.Name())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("restore: %v", err)
+			}
+
+			if localPath != filepath.Join(restoreDir, tt.wantFile) {
+				t.Errorf("restored to %s, want %s", localPath, filepath.Join(restoreDir, tt.wantFile))
+			}
+			data, err := os.ReadFile(localPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(data, tt.wantData) {
+				t.Errorf("restored %q, want %q", data, tt.wantData)
+			}
+			if verified != tt.wantVerified {
+				t.Errorf("verified = %v, want %v", verified, tt.wantVerified)
+			}
+			entries, _ := os.ReadDir(restoreDir)
+			if len(entries) != 1 {
+				t.Errorf("restore directory holds %d files, want only the restored one", len(entries))
+			}
+		})
+	}
+}
+
+func TestDecompressSyncFileRestoresModTime(t *testing.T) {
+	modTime := time.Date(2024, 6, 1, 8, 30, 0, 0, time.UTC)
+	path := filepath.Join(t.TempDir(), "report.csv.gz")
+	if err := os.WriteFile(path, gzipped(t, []byte("a,b\n"), "report.csv", modTime), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	restored, err := decompressSyncFile(path)
+	if err != nil {
+		t.Fatalf("decompressSyncFile: %v", err)
+	}
+	info, err := os.Stat(restored)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.ModTime().Equal(modTime) {
+		t.Errorf("modification time = %v, want %v", info.ModTime(), modTime)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("compressed file left behind")
+	}
+}
+
+// writeTarGz writes a tar.gz archive of the given headers; regular files
+// contain as many bytes as their size
+func writeTarGz(t *testing.T, path string, headers []*tar.Header) {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, hdr := range headers {
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if hdr.Typeflag == tar.TypeReg {
+			if _, err := tw.Write(bytes.Repeat([]byte("x"), int(hdr.Size))); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPlanRestore(t *testing.T) {
+	tests := []struct {
+		name            string
+		headers         []*tar.Header
+		existing        map[string]bool // paths under the restore directory; true for a directory
+		wantUnsafe      []string
+		wantConflicts   []string // relative to the restore directory
+		wantExtractable bool
+	}{
+		{
+			name: "safe archive",
+			headers: []*tar.Header{
+				{Name: "docs/", Typeflag: tar.TypeDir, Mode: 0755},
+				{Name: "docs/a.txt", Typeflag: tar.TypeReg, Size: 3, Mode: 0644},
+			},
+			wantExtractable: true,
+		},
+		{
+			name: "entry climbing out of the directory",
+			headers: []*tar.Header{
+				{Name: "docs/a.txt", Typeflag: tar.TypeReg, Size: 3, Mode: 0644},
+				{Name: "../escape.txt", Typeflag: tar.TypeReg, Size: 3, Mode: 0644},
+				{Name: "docs/../../escape.txt", Typeflag: tar.TypeReg, Size: 3, Mode: 0644},
+			},
+			wantUnsafe: []string{"../escape.txt", "docs/../../escape.txt"},
+		},
+		{
+			name: "symlink out of the directory",
+			headers: []*tar.Header{
+				{Name: "docs/link", Typeflag: tar.TypeSymlink, Linkname: "../../etc/passwd"},
+			},
+			wantUnsafe: []string{"docs/link"},
+		},
+		{
+			name: "existing files conflict, existing directories merge",
+			headers: []*tar.Header{
+				{Name: "docs/", Typeflag: tar.TypeDir, Mode: 0755},
+				{Name: "docs/a.txt", Typeflag: tar.TypeReg, Size: 3, Mode: 0644},
+				{Name: "docs/b.txt", Typeflag: tar.TypeReg, Size: 3, Mode: 0644},
+				{Name: "notes", Typeflag: tar.TypeReg, Size: 3, Mode: 0644},
+			},
+			existing:        map[string]bool{"docs": true, "docs/a.txt": false, "notes": true},
+			wantConflicts:   []string{"docs/a.txt", "notes"},
+			wantExtractable: true,
+		},
+		{
+			name: "unsafe entries aren't reported as conflicts",
+			headers: []*tar.Header{
+				{Name: "../outside.txt", Typeflag: tar.TypeReg, Size: 3, Mode: 0644},
+			},
+			wantUnsafe: []string{"../outside.txt"},
+		},
+	}
+
+	const remotePath = "docs_20250127_120000.tar.gz"
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, cfg, _, storeDir := newTestExecutor(t)
+			writeTarGz(t, filepath.Join(storeDir, remotePath), tt.headers)
+
+			restoreDir := filepath.Join(cfg.ResolvePath(cfg.GetSettings().TempDir), "restore")
+			if err := os.MkdirAll(restoreDir, 0755); err != nil {
+				t.Fatal(err)
+			}
+			// Something the restore would write outside its directory
+			if err := os.WriteFile(filepath.Join(filepath.Dir(restoreDir), "outside.txt"), nil, 0644); err != nil {
+				t.Fatal(err)
+			}
+			for name, isDir := range tt.existing {
+				path := filepath.Join(restoreDir, filepath.FromSlash(name))
+				dir := filepath.Dir(path)
+				if isDir {
+					dir = path
+				}
+				if err := os.MkdirAll(dir, 0755); err != nil {
+					t.Fatal(err)
+				}
+				if !isDir {
+					if err := os.WriteFile(path, nil, 0644); err != nil {
+						t.Fatal(err)
+					}
+				}
+			}
+
+			plan, err := e.PlanRestore(context.Background(), "local", remotePath)
+			if err != nil {
+				t.Fatalf("PlanRestore: %v", err)
+			}
+
+			var unsafe []string
+			for _, entry := range plan.Unsafe {
+				unsafe = append(unsafe, entry.Path)
+			}
+			if !equalStrings(unsafe, tt.wantUnsafe) {
+				t.Errorf("unsafe entries = %v, want %v", unsafe, tt.wantUnsafe)
+			}
+			var wantConflicts []string
+			for _, name := range tt.wantConflicts {
+				wantConflicts = append(wantConflicts, filepath.Join(restoreDir, filepath.FromSlash(name)))
+			}
+			if !equalStrings(plan.Conflicts, wantConflicts) {
+				t.Errorf("conflicts = %v, want %v", plan.Conflicts, wantConflicts)
+			}
+			if plan.Extractable != tt.wantExtractable {
+				t.Errorf("extractable = %v, want %v", plan.Extractable, tt.wantExtractable)
+			}
+			if len(plan.Entries) != len(tt.headers) {
+				t.Errorf("plan lists %d entries, want %d", len(plan.Entries), len(tt.headers))
+			}
+
+			// A dry run writes nothing
+			if _, err := os.Stat(filepath.Join(restoreDir, remotePath)); !os.IsNotExist(err) {
+				t.Error("dry run downloaded the archive")
+			}
+		})
+	}
+}
+
+// equalStrings reports whether a and b hold the same strings in the same order
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,162 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/nsilverman/archivist/internal/archive"
+	"github.com/nsilverman/archivist/internal/backend"
+	"github.com/nsilverman/archivist/internal/models"
+)
+
+// restorePlanEntryLimit caps the entries a restore plan lists; the counts still cover every entry
+const restorePlanEntryLimit = 1000
+
+// PlanRestore is a dry run of RestoreArchive. It streams the archive from the
+// backend and reads every entry without writing any of them, reporting the
+// contents, entries that would extract outside their directory, and existing
+// files the restore would overwrite: the archive itself, and each entry's
+// destination if it were extracted into the restore directory. A zip archive
+// can't be read as a stream, so it is downloaded to the temp directory and
+// removed afterwards.
+func (e *Executor) PlanRestore(ctx context.Context, backendID, remotePath string) (*models.RestorePlan, error) {
+	backendCfg, err := e.config.GetBackend(backendID)
+	if err != nil {
+		return nil, fmt.Errorf("backend not found: %w", err)
+	}
+	if remotePath == "" {
+		return nil, fmt.Errorf("a remote path is required")
+	}
+
+	start := time.Now()
+	restoreDir := filepath.Join(e.config.ResolvePath(e.config.GetSettings().TempDir), "restore")
+	plan := &models.RestorePlan{
+		BackendID:  backendID,
+		RemotePath: remotePath,
+		LocalPath:  filepath.Join(restoreDir, filepath.Base(remotePath)),
+		Entries:    make([]models.RestoreEntry, 0),
+	}
+	for _, path := range []string{plan.LocalPath, plan.LocalPath + backend.PartialSuffix} {
+		if _, err := os.Lstat(path); err == nil {
+			plan.Conflicts = append(plan.Conflicts, path)
+		}
+	}
+
+	backendInstance, err := backend.Factory(backendCfg, e.config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create backend: %w", err)
+	}
+	reader := &backupReader{ctx: ctx, backend: backendInstance}
+	defer func() {
+		if err := reader.Close(); err != nil {
+			log.Printf("Error closing backend instance: %v", err)
+		}
+	}()
+
+	objects, err := backendInstance.List(ctx, remotePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list backend: %w", err)
+	}
+	reader.paths, err = archiveObjects(objects, remotePath)
+	if err != nil {
+		return nil, err
+	}
+	if len(reader.paths) > 1 {
+		plan.Parts = reader.paths
+	}
+	if err := reader.next(); err != nil {
+		return nil, err
+	}
+
+	record := func(entry archive.Entry) error {
+		switch entry.Type {
+		case "file":
+			plan.FileCount++
+			plan.TotalSize += entry.Size
+		case "dir":
+			plan.DirCount++
+		}
+		if err := archive.CheckEntry(entry); err != nil {
+			plan.Unsafe = append(plan.Unsafe, models.UnsafeRestoreEntry{Path: entry.Name, Reason: err.Error()})
+		} else if destPath, ok := entryConflict(restoreDir, entry); ok {
+			if len(plan.Conflicts) < restorePlanEntryLimit {
+				plan.Conflicts = append(plan.Conflicts, destPath)
+			} else {
+				plan.ConflictsTruncated = true
+			}
+		}
+		if len(plan.Entries) < restorePlanEntryLimit {
+			plan.Entries = append(plan.Entries, models.RestoreEntry{
+				Path:     entry.Name,
+				Type:     entry.Type,
+				Size:     entry.Size,
+				Linkname: entry.Linkname,
+			})
+		} else {
+			plan.EntriesTruncated = true
+		}
+		return nil
+	}
+
+	if strings.HasSuffix(strings.ToLower(remotePath), ".zip") {
+		err = inspectZipStream(reader, restoreDir, record)
+	} else {
+		err = archive.InspectTar(reader, record)
+	}
+	if err != nil {
+		plan.Error = err.Error()
+	}
+	plan.Extractable = err == nil && len(plan.Unsafe) == 0
+	plan.DurationMs = time.Since(start).Milliseconds()
+	return plan, nil
+}
+
+// entryConflict reports whether extracting a safe entry into dir would
+// overwrite something, returning its destination. An existing directory is
+// only a conflict for an entry that isn't one, since extraction merges into it.
+func entryConflict(dir string, entry archive.Entry) (string, bool) {
+	destPath := filepath.Join(dir, filepath.FromSlash(strings.ReplaceAll(entry.Name, `\`, "/")))
+	if destPath == filepath.Clean(dir) {
+		return "", false
+	}
+	info, err := os.Lstat(destPath)
+	if err != nil {
+		return "", false
+	}
+	if info.IsDir() && entry.Type == "dir" {
+		return "", false
+	}
+	return destPath, true
+}
+
+// inspectZipStream copies a zip archive to a temporary file in dir, which zip
+// needs for random access, and inspects it there
+func inspectZipStream(reader io.Reader, dir string, fn func(archive.Entry) error) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create restore directory: %w", err)
+	}
+	file, err := os.CreateTemp(dir, "restore-plan-*.zip")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer func() {
+		if err := file.Close(); err != nil {
+			log.Printf("Error closing temporary file: %v", err)
+		}
+		if err := os.Remove(file.Name()); err != nil {
+			log.Printf("Error removing temporary file: %v", err)
+		}
+	}()
+
+	size, err := io.Copy(file, reader)
+	if err != nil {
+		return fmt.Errorf("failed to download archive: %w", err)
+	}
+	return archive.InspectZip(file, size, fn)
+}
@@ -0,0 +1,213 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nsilverman/archivist/internal/archive"
+	"github.com/nsilverman/archivist/internal/backend"
+	"github.com/nsilverman/archivist/internal/models"
+)
+
+// retentionBackup is one backup as seen by retention: a single archive, or all
+// the parts of a split archive
+type retentionBackup struct {
+	path         string
+	lastModified string
+	files        []backend.BackupInfo
+}
+
+// withRetention returns the task with the retention policy it runs under. A
+// keep_last of 0 takes the settings' default retention, keeping the task's own
+// require_full_success if set; -1 opts out of the default and keeps everything.
+// Chunked tasks never take the default, since their archives can't be pruned.
+func withRetention(task *models.Task, settings models.Settings) *models.Task {
+	policy := task.RetentionPolicy
+	switch {
+	case policy.KeepLast < 0:
+		policy.KeepLast = 0
+	case policy.KeepLast == 0 && settings.DefaultRetention != nil && task.ArchiveOptions.Format != archive.FormatChunked:
+		policy.KeepLast = settings.DefaultRetention.KeepLast
+		policy.RequireFullSuccess = policy.RequireFullSuccess || settings.DefaultRetention.RequireFullSuccess
+	}
+	if policy == task.RetentionPolicy {
+		return task
+	}
+
+	resolved := *task
+	resolved.RetentionPolicy = policy
+	return &resolved
+}
+
+// selectRetentionDeletions returns the backups the task's retention policy would
+// remove: the task's archives sorted oldest first, minus the newest KeepLast.
+// The parts of a split archive count as one backup and are removed together.
+func selectRetentionDeletions(task *models.Task, files []backend.BackupInfo) []backend.BackupInfo {
+	keepLast := task.RetentionPolicy.KeepLast
+	if keepLast <= 0 {
+		return nil
+	}
+
+	// Filter to only include files matching this task's backup pattern
+	// Backup files follow pattern: <taskname>_YYYYMMDD_HHMMSS.tar.gz[.NNN], or .tar.zst, .tar or .zip
+	var backups []*retentionBackup
+	byPath := make(map[string]*retentionBackup)
+	taskPrefix := archive.TaskPrefix(task.Name)
+	for _, file := range files {
+		fileName := filepath.Base(file.Path)
+		if archiveName, ok := archive.SplitPartName(fileName); ok {
+			fileName = archiveName
+		}
+		// The latest alias is a copy of the newest archive, not a backup of its own
+		if !strings.HasPrefix(fileName, taskPrefix) || len(fileName) <= len(taskPrefix) ||
+			archive.ArchiveExtension(fileName) == "" ||
+			fileName == archive.LatestFilename(task.Name, fileName) {
+			continue
+		}
+
+		backupPath := filepath.Join(filepath.Dir(file.Path), fileName)
+		b, ok := byPath[backupPath]
+		if !ok {
+			b = &retentionBackup{path: backupPath}
+			byPath[backupPath] = b
+			backups = append(backups, b)
+		}
+		b.files = append(b.files, file)
+		if b.lastModified == "" || modifiedBefore(b.lastModified, file.LastModified) {
+			b.lastModified = file.LastModified
+		}
+	}
+
+	if len(backups) <= keepLast {
+		return nil
+	}
+
+	// Sort by last modified (oldest first); fall back to the timestamped name on ties
+	sort.SliceStable(backups, func(i, j int) bool {
+		if modifiedBefore(backups[i].lastModified, backups[j].lastModified) {
+			return true
+		}
+		if modifiedBefore(backups[j].lastModified, backups[i].lastModified) {
+			return false
+		}
+		return backups[i].path < backups[j].path
+	})
+
+	var deletions []backend.BackupInfo
+	for _, b := range backups[:len(backups)-keepLast] {
+		deletions = append(deletions, b.files...)
+	}
+	return deletions
+}
+
+// modifiedBefore reports whether timestamp a is strictly earlier than b.
+// Unparseable timestamps never compare as earlier.
+func modifiedBefore(a, b string) bool {
+	ta, errA := time.Parse(time.RFC3339, a)
+	tb, errB := time.Parse(time.RFC3339, b)
+	return errA == nil && errB == nil && ta.Before(tb)
+}
+
+// PreviewRetention lists the backups the task's retention policy would delete
+// from each of its backends, without deleting anything
+func (e *Executor) PreviewRetention(ctx context.Context, taskID string) (*models.RetentionPreview, error) {
+	task, err := e.config.GetTask(taskID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get task: %w", err)
+	}
+
+	return e.previewRetention(ctx, task, task.BackendIDs, nil), nil
+}
+
+// previewRetention builds a retention preview for the given backends. When pending
+// is set, it is treated as already uploaded to each backend, showing what retention
+// would prune after a successful run that produced it.
+func (e *Executor) previewRetention(ctx context.Context, task *models.Task, backendIDs []string, pending *backend.BackupInfo) *models.RetentionPreview {
+	task = withRetention(task, e.config.GetSettings())
+	preview := &models.RetentionPreview{
+		TaskID:   task.ID,
+		TaskName: task.Name,
+		KeepLast: task.RetentionPolicy.KeepLast,
+		Backends: make([]models.BackendRetentionPreview, 0, len(backendIDs)),
+	}
+
+	// Retention only prunes archives; sync mode mirrors the source instead
+	if task.ArchiveOptions.Format == "sync" || task.RetentionPolicy.KeepLast <= 0 {
+		return preview
+	}
+
+	for _, backendID := range backendIDs {
+		preview.Backends = append(preview.Backends, e.previewBackendRetention(ctx, task, backendID, pending))
+	}
+
+	return preview
+}
+
+// previewBackendRetention computes the retention preview for a single backend
+func (e *Executor) previewBackendRetention(ctx context.Context, task *models.Task, backendID string, pending *backend.BackupInfo) models.BackendRetentionPreview {
+	result := models.BackendRetentionPreview{
+		BackendID: backendID,
+		ToDelete:  make([]models.RetentionCandidate, 0),
+	}
+
+	backendCfg, err := e.config.GetBackend(backendID)
+	if err != nil {
+		result.Error = fmt.Sprintf("Backend not found: %v", err)
+		return result
+	}
+	result.BackendName = backendCfg.Name
+
+	if backendCfg.Immutable {
+		result.Error = "Backend is immutable; retention never deletes from it"
+		return result
+	}
+
+	backendInstance, err := backend.Factory(backendCfg, e.config)
+	if err != nil {
+		result.Error = fmt.Sprintf("Failed to create backend: %v", err)
+		return result
+	}
+	defer func() {
+		if err := backendInstance.Close(); err != nil {
+			log.Printf("Error closing backend instance: %v", err)
+		}
+	}()
+
+	// Archives are uploaded under their base filename, so list from the root
+	allFiles, err := backendInstance.List(ctx, "")
+	if err != nil {
+		result.Error = fmt.Sprintf("Failed to list backups: %v", err)
+		return result
+	}
+
+	if pending != nil {
+		allFiles = withPendingBackup(allFiles, *pending)
+	}
+
+	for _, old := range selectRetentionDeletions(task, allFiles) {
+		result.ToDelete = append(result.ToDelete, models.RetentionCandidate{
+			Path:         old.Path,
+			Size:         old.Size,
+			LastModified: old.LastModified,
+		})
+	}
+
+	return result
+}
+
+// withPendingBackup returns files with pending added, replacing any existing
+// backup at the same path as an upload would
+func withPendingBackup(files []backend.BackupInfo, pending backend.BackupInfo) []backend.BackupInfo {
+	result := make([]backend.BackupInfo, 0, len(files)+1)
+	for _, file := range files {
+		if file.Path != pending.Path {
+			result = append(result, file)
+		}
+	}
+	return append(result, pending)
+}
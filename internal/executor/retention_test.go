@@ -0,0 +1,229 @@
+package executor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/nsilverman/archivist/internal/backend"
+	"github.com/nsilverman/archivist/internal/models"
+)
+
+func TestSelectRetentionDeletions(t *testing.T) {
+	at := func(hour string) string { return "2025-01-27T" + hour + ":00:00Z" }
+
+	tests := []struct {
+		name     string
+		keepLast int
+		files    []backend.BackupInfo
+		want     []string
+	}{
+		{
+			name:     "keeps everything when unset",
+			keepLast: 0,
+			files: []backend.BackupInfo{
+				{Path: "docs_20250127_010000.tar.gz", LastModified: at("01")},
+				{Path: "docs_20250127_020000.tar.gz", LastModified: at("02")},
+			},
+		},
+		{
+			name:     "removes the oldest of each format",
+			keepLast: 2,
+			files: []backend.BackupInfo{
+				{Path: "docs_20250127_040000.zip", LastModified: at("04")},
+				{Path: "docs_20250127_010000.tar.gz", LastModified: at("01")},
+				{Path: "docs_20250127_030000.tar", LastModified: at("03")},
+				{Path: "docs_20250127_020000.tar.gz", LastModified: at("02")},
+			},
+			want: []string{"docs_20250127_010000.tar.gz", "docs_20250127_020000.tar.gz"},
+		},
+		{
+			name:     "latest alias is not a backup",
+			keepLast: 1,
+			files: []backend.BackupInfo{
+				{Path: "docs_20250127_010000.tar.gz", LastModified: at("01")},
+				{Path: "docs_latest.tar.gz", LastModified: at("02")},
+				{Path: "docs_20250127_020000.tar.gz", LastModified: at("02")},
+			},
+			want: []string{"docs_20250127_010000.tar.gz"},
+		},
+		{
+			name:     "parts of a split archive go together",
+			keepLast: 1,
+			files: []backend.BackupInfo{
+				{Path: "docs_20250127_010000.tar.gz.001", LastModified: at("01")},
+				{Path: "docs_20250127_010000.tar.gz.002", LastModified: at("01")},
+				{Path: "docs_20250127_020000.tar.gz.001", LastModified: at("02")},
+				{Path: "docs_20250127_020000.tar.gz.002", LastModified: at("02")},
+			},
+			want: []string{"docs_20250127_010000.tar.gz.001", "docs_20250127_010000.tar.gz.002"},
+		},
+		{
+			name:     "other tasks and files are ignored",
+			keepLast: 1,
+			files: []backend.BackupInfo{
+				{Path: "docs_20250127_010000.tar.gz", LastModified: at("01")},
+				{Path: "docs-old_20250127_000000.tar.gz", LastModified: at("00")},
+				{Path: "photos_20250127_000000.tar.gz", LastModified: at("00")},
+				{Path: "docs_notes.txt", LastModified: at("00")},
+				{Path: "docs_", LastModified: at("00")},
+				{Path: "docs_20250127_020000.tar.gz", LastModified: at("02")},
+			},
+			want: []string{"docs_20250127_010000.tar.gz"},
+		},
+		{
+			name:     "timestamped name breaks ties",
+			keepLast: 1,
+			files: []backend.BackupInfo{
+				{Path: "docs_20250127_020000.tar.gz", LastModified: at("05")},
+				{Path: "docs_20250127_010000.tar.gz", LastModified: at("05")},
+			},
+			want: []string{"docs_20250127_010000.tar.gz"},
+		},
+		{
+			name:     "backups in a subdirectory",
+			keepLast: 1,
+			files: []backend.BackupInfo{
+				{Path: "nightly/docs_20250127_010000.tar.gz", LastModified: at("01")},
+				{Path: "nightly/docs_20250127_020000.tar.gz", LastModified: at("02")},
+			},
+			want: []string{"nightly/docs_20250127_010000.tar.gz"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			task := &models.Task{Name: "docs", RetentionPolicy: models.RetentionPolicy{KeepLast: tt.keepLast}}
+			var got []string
+			for _, file := range selectRetentionDeletions(task, tt.files) {
+				got = append(got, file.Path)
+			}
+			if !equalStrings(got, tt.want) {
+				t.Errorf("deletions = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithRetention(t *testing.T) {
+	defaultRetention := models.Settings{DefaultRetention: &models.RetentionPolicy{KeepLast: 7, RequireFullSuccess: true}}
+
+	tests := []struct {
+		name     string
+		format   string
+		policy   models.RetentionPolicy
+		settings models.Settings
+		want     models.RetentionPolicy
+	}{
+		{"no default", "tar.gz", models.RetentionPolicy{}, models.Settings{}, models.RetentionPolicy{}},
+		{"takes the default", "tar.gz", models.RetentionPolicy{}, defaultRetention, models.RetentionPolicy{KeepLast: 7, RequireFullSuccess: true}},
+		{"own keep_last wins", "tar.gz", models.RetentionPolicy{KeepLast: 3}, defaultRetention, models.RetentionPolicy{KeepLast: 3}},
+		{"opts out of the default", "tar.gz", models.RetentionPolicy{KeepLast: -1}, defaultRetention, models.RetentionPolicy{}},
+		{"chunked ignores the default", "chunked", models.RetentionPolicy{}, defaultRetention, models.RetentionPolicy{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			task := &models.Task{
+				Name:            "docs",
+				ArchiveOptions:  models.ArchiveOptions{Format: tt.format},
+				RetentionPolicy: tt.policy,
+			}
+			got := withRetention(task, tt.settings)
+			if got.RetentionPolicy != tt.want {
+				t.Errorf("retention = %+v, want %+v", got.RetentionPolicy, tt.want)
+			}
+			if task.RetentionPolicy != tt.policy {
+				t.Error("withRetention changed the task it was given")
+			}
+		})
+	}
+}
+
+func TestRetentionPreviewMatchesDeletions(t *testing.T) {
+	e, cfg, db, storeDir := newTestExecutor(t)
+	source := t.TempDir()
+	if err := os.WriteFile(filepath.Join(source, "a.txt"), []byte("contents"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// Archive names are sanitized, so they don't start with the raw task name
+	if err := cfg.AddTask(&models.Task{
+		ID:              "task-1",
+		Name:            "My Documents",
+		SourcePath:      source,
+		BackendIDs:      []string{"local"},
+		Schedule:        models.Schedule{Type: "manual"},
+		ArchiveOptions:  models.ArchiveOptions{Format: "tar.gz", UseTimestamp: true},
+		RetentionPolicy: models.RetentionPolicy{KeepLast: 2},
+		Enabled:         true,
+	}); err != nil {
+		t.Fatalf("AddTask: %v", err)
+	}
+
+	existing := []string{
+		"my-documents_20250127_010000.tar.gz",
+		"my-documents_20250127_020000.tar.gz",
+		"my-documents_20250127_030000.tar.gz",
+		"photos_20250127_000000.tar.gz",
+	}
+	for i, name := range existing {
+		path := filepath.Join(storeDir, name)
+		if err := os.WriteFile(path, []byte("archive"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		modified := time.Now().Add(time.Duration(i-10) * time.Hour)
+		if err := os.Chtimes(path, modified, modified); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	plan, err := e.ExecuteDryRun("task-1", nil)
+	if err != nil {
+		t.Fatalf("ExecuteDryRun: %v", err)
+	}
+	if plan.RetentionPlan == nil || len(plan.RetentionPlan.Backends) != 1 {
+		t.Fatalf("retention plan = %+v, want one backend", plan.RetentionPlan)
+	}
+	var previewed []string
+	for _, candidate := range plan.RetentionPlan.Backends[0].ToDelete {
+		previewed = append(previewed, candidate.Path)
+	}
+	want := existing[:2]
+	if !equalStrings(previewed, want) {
+		t.Errorf("preview deletes %v, want %v", previewed, want)
+	}
+
+	finished := make(chan models.Execution, 1)
+	e.OnFinished(func(execution models.Execution) { finished <- execution })
+	executionID, err := e.Execute("task-1")
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	select {
+	case execution := <-finished:
+		if execution.Status != "success" {
+			t.Fatalf("execution %s: %s", execution.Status, execution.ErrorMessage)
+		}
+	case <-time.After(30 * time.Second):
+		t.Fatal("execution did not finish")
+	}
+
+	execution, err := db.GetExecution(executionID)
+	if err != nil {
+		t.Fatalf("GetExecution: %v", err)
+	}
+	var deleted []string
+	for _, deletion := range execution.RetentionDeletions {
+		deleted = append(deleted, deletion.RemotePath)
+	}
+	if !equalStrings(deleted, previewed) {
+		t.Errorf("retention deleted %v, preview promised %v", deleted, previewed)
+	}
+	for _, name := range existing {
+		_, err := os.Stat(filepath.Join(storeDir, name))
+		if gone := os.IsNotExist(err); gone != (name == existing[0] || name == existing[1]) {
+			t.Errorf("%s deleted = %v", name, gone)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package executor
+
+import (
+	"fmt"
+
+	"github.com/nsilverman/archivist/internal/models"
+)
+
+// Retry starts a fresh run of the task behind an earlier execution.
+// When failedOnly is set, only the backends that failed in that execution are targeted.
+// requestID identifies the API request asking for the retry, as for ExecuteRequest;
+// the earlier execution's label is kept.
+func (e *Executor) Retry(executionID string, failedOnly bool, requestID string) (string, error) {
+	execution, err := e.db.GetExecution(executionID)
+	if err != nil {
+		return "", fmt.Errorf("execution not found: %w", err)
+	}
+
+	if execution.Status == "running" {
+		return "", ErrTaskRunning
+	}
+
+	task, err := e.config.GetTask(execution.TaskID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get task: %w", err)
+	}
+
+	if !task.Enabled {
+		return "", fmt.Errorf("task is disabled")
+	}
+
+	if !failedOnly {
+		return e.start(task, requestID, execution.Label, 0)
+	}
+
+	backendIDs := failedBackendIDs(task.BackendIDs, execution)
+	syncBackendIDs := failedBackendIDs(task.SyncBackendIDs, execution)
+	if len(backendIDs) == 0 && len(syncBackendIDs) == 0 {
+		return "", fmt.Errorf("execution has no failed backends to retry")
+	}
+
+	retryTask := *task
+	retryTask.BackendIDs = backendIDs
+	retryTask.SyncBackendIDs = syncBackendIDs
+	if len(backendIDs) == 0 {
+		// Only sync backends failed, so no archive needs to be built
+		retryTask.ArchiveOptions.Format = "sync"
+		retryTask.BackendIDs = syncBackendIDs
+		retryTask.SyncBackendIDs = nil
+	}
+	return e.start(&retryTask, requestID, execution.Label, 0)
+}
+
+// failedBackendIDs returns those of taskBackendIDs that failed in execution.
+// An execution that failed before reaching any backend counts all of them as failed.
+func failedBackendIDs(taskBackendIDs []string, execution *models.Execution) []string {
+	if execution.Status == "failed" && len(execution.BackendResults) == 0 {
+		return taskBackendIDs
+	}
+
+	failed := make(map[string]bool)
+	for _, result := range execution.BackendResults {
+		if result.Status == "failed" {
+			failed[result.BackendID] = true
+		}
+	}
+
+	// Keep the task's ordering and drop backends that have since been removed from it
+	var backendIDs []string
+	for _, id := range taskBackendIDs {
+		if failed[id] {
+			backendIDs = append(backendIDs, id)
+		}
+	}
+	return backendIDs
+}
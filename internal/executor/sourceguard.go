@@ -0,0 +1,56 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/nsilverman/archivist/internal/ignore"
+	"github.com/nsilverman/archivist/internal/models"
+)
+
+// checkSourceGuard counts the files in the source and refuses the run if the
+// source is empty, unless the task allows it, or has fewer files than
+// MinSourcePercent of the last successful run. Either usually means the
+// source volume isn't mounted, and a sync would then delete the remote copy.
+func (e *Executor) checkSourceGuard(ctx context.Context, task *models.Task, sourcePath string) (int64, error) {
+	count, err := countSourceFiles(ctx, sourcePath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to scan source: %w", err)
+	}
+
+	if count == 0 && !task.AllowEmptySource {
+		return 0, fmt.Errorf("source %s has no files; check that it is mounted, or set allow_empty_source to back up an empty source", task.SourcePath)
+	}
+
+	if task.MinSourcePercent > 0 {
+		previous, ok, err := e.db.LastSourceFileCount(task.ID)
+		if err != nil {
+			log.Printf("Error reading previous source file count: %v", err)
+		} else if ok && count*100 < previous*int64(task.MinSourcePercent) {
+			return count, fmt.Errorf("source %s has %d files, down from %d in the last successful run (min_source_percent is %d%%); check that it is mounted, or lower the limit",
+				task.SourcePath, count, previous, task.MinSourcePercent)
+		}
+	}
+
+	return count, nil
+}
+
+// countSourceFiles counts the files under sourcePath, or 1 for a single-file source
+func countSourceFiles(ctx context.Context, sourcePath string) (int64, error) {
+	var count int64
+	err := ignore.Walk(sourcePath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			count++
+		}
+		return nil
+	})
+	return count, err
+}
@@ -0,0 +1,207 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/nsilverman/archivist/internal/archive"
+	"github.com/nsilverman/archivist/internal/backend"
+	"github.com/nsilverman/archivist/internal/models"
+	filesync "github.com/nsilverman/archivist/internal/sync"
+)
+
+// RestoreArchive downloads an archive from a backend into the temp directory.
+// If the archive was split, its parts are downloaded and joined back together.
+// The result is checked against the hash recorded when the archive was
+// uploaded, or else the hash the backend reports; an archive that doesn't
+// match is removed and the restore fails. A file a sync task gzipped with
+// compress_files is decompressed after it is checked. The restore runs in the background;
+// progress is reported through restore_* events.
+func (e *Executor) RestoreArchive(backendID, remotePath string) (string, error) {
+	backendCfg, err := e.config.GetBackend(backendID)
+	if err != nil {
+		return "", fmt.Errorf("backend not found: %w", err)
+	}
+	if remotePath == "" {
+		return "", fmt.Errorf("a remote path is required")
+	}
+
+	restoreID := uuid.New().String()
+	restoreDir := filepath.Join(e.config.ResolvePath(e.config.GetSettings().TempDir), "restore")
+
+	e.broadcastEvent(models.ProgressEvent{
+		Type: "restore_started",
+		Data: map[string]interface{}{
+			"restore_id": restoreID,
+			"backend_id": backendID,
+		},
+	})
+
+	go func() {
+		localPath, verified, err := e.runArchiveRestore(context.Background(), backendCfg, remotePath, restoreDir)
+		if err != nil {
+			log.Printf("Restore %s failed: %v", restoreID, err)
+			e.broadcastEvent(models.ProgressEvent{
+				Type: "restore_failed",
+				Data: map[string]interface{}{
+					"restore_id":    restoreID,
+					"error_message": err.Error(),
+				},
+			})
+			return
+		}
+
+		log.Printf("Restore %s completed: %s", restoreID, localPath)
+		e.broadcastEvent(models.ProgressEvent{
+			Type: "restore_completed",
+			Data: map[string]interface{}{
+				"restore_id": restoreID,
+				"local_path": localPath,
+				"verified":   verified,
+			},
+		})
+	}()
+
+	return restoreID, nil
+}
+
+// archiveObjects finds the archive at remotePath in a listing: the archive
+// itself, or else its parts in order if it was split
+func archiveObjects(objects []backend.BackupInfo, remotePath string) ([]string, error) {
+	var parts []string
+	for _, obj := range objects {
+		objPath := filepath.ToSlash(obj.Path)
+		if objPath == remotePath {
+			return []string{remotePath}, nil
+		}
+		if archiveName, ok := archive.SplitPartName(objPath); ok && archiveName == remotePath {
+			parts = append(parts, objPath)
+		}
+	}
+	if len(parts) == 0 {
+		return nil, fmt.Errorf("archive %s not found", remotePath)
+	}
+	archive.SortParts(parts)
+	return parts, nil
+}
+
+// runArchiveRestore downloads the archive at remotePath, or its parts if it
+// was split, and verifies it. verified is false when there was no hash to check.
+func (e *Executor) runArchiveRestore(ctx context.Context, backendCfg *models.Backend, remotePath, restoreDir string) (localPath string, verified bool, err error) {
+	expected, err := e.db.RecordedArchiveHash(backendCfg.ID, remotePath)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to load recorded hash: %w", err)
+	}
+
+	backendInstance, err := backend.Factory(backendCfg, e.config)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to create backend: %w", err)
+	}
+	defer func() {
+		if err := backendInstance.Close(); err != nil {
+			log.Printf("Error closing backend instance: %v", err)
+		}
+	}()
+
+	if err := os.MkdirAll(restoreDir, 0755); err != nil {
+		return "", false, fmt.Errorf("failed to create restore directory: %w", err)
+	}
+
+	objects, err := backendInstance.List(ctx, remotePath)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to list backend: %w", err)
+	}
+
+	localPath = filepath.Join(restoreDir, filepath.Base(remotePath))
+	remoteParts, err := archiveObjects(objects, remotePath)
+	if err != nil {
+		return "", false, err
+	}
+	if len(remoteParts) == 1 && remoteParts[0] == remotePath {
+		if expected == "" {
+			expected = listedHash(objects, remotePath)
+		}
+		verified = true
+		err := backend.DownloadVerified(ctx, backendInstance, remotePath, localPath, expected, nil)
+		if errors.Is(err, backend.ErrUnverifiable) {
+			log.Printf("Restored %s without verifying it: %v", remotePath, err)
+			verified = false
+		} else if err != nil {
+			return "", false, fmt.Errorf("failed to download archive: %w", err)
+		}
+		localPath, err = decompressSyncFile(localPath)
+		if err != nil {
+			return "", false, err
+		}
+		return localPath, verified, nil
+	}
+
+	// JoinParts puts the parts back in order
+	localParts := make([]string, 0, len(remoteParts))
+	defer func() {
+		for _, part := range localParts {
+			if err := os.Remove(part); err != nil && !os.IsNotExist(err) {
+				log.Printf("Error removing archive part: %v", err)
+			}
+		}
+	}()
+
+	for _, remotePart := range remoteParts {
+		localPart := filepath.Join(restoreDir, filepath.Base(remotePart))
+		localParts = append(localParts, localPart)
+		if err := backendInstance.Download(ctx, remotePart, localPart, nil); err != nil {
+			return "", false, fmt.Errorf("failed to download archive part %s: %w", remotePart, err)
+		}
+	}
+
+	if err := archive.JoinParts(localParts, localPath); err != nil {
+		return "", false, err
+	}
+
+	// Parts have no hashes of their own on record, so only a recorded one applies
+	err = backend.VerifyFileHash(localPath, expected)
+	if errors.Is(err, backend.ErrUnverifiable) {
+		log.Printf("Restored %s without verifying it: %v", remotePath, err)
+		return localPath, false, nil
+	}
+	if err != nil {
+		if rmErr := os.Remove(localPath); rmErr != nil {
+			log.Printf("Error removing corrupt restore: %v", rmErr)
+		}
+		return "", false, err
+	}
+	return localPath, true, nil
+}
+
+// decompressSyncFile undoes a sync task's compress_files: a file that was
+// gzipped on upload is restored to its original name and modification time.
+// The hash was checked against the object as stored, so this runs after it.
+func decompressSyncFile(localPath string) (string, error) {
+	if !filesync.IsCompressedFile(localPath) {
+		return localPath, nil
+	}
+	destPath := strings.TrimSuffix(localPath, filesync.CompressedSuffix)
+	if err := filesync.DecompressFile(localPath, destPath); err != nil {
+		return "", err
+	}
+	if err := os.Remove(localPath); err != nil {
+		log.Printf("Error removing compressed restore: %v", err)
+	}
+	return destPath, nil
+}
+
+// listedHash returns the hash a backend listing reports for remotePath, or ""
+func listedHash(objects []backend.BackupInfo, remotePath string) string {
+	for _, obj := range objects {
+		if filepath.ToSlash(obj.Path) == remotePath {
+			return obj.Hash
+		}
+	}
+	return ""
+}
@@ -0,0 +1,140 @@
+package executor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/nsilverman/archivist/internal/archive"
+	"github.com/nsilverman/archivist/internal/backend"
+	"github.com/nsilverman/archivist/internal/models"
+)
+
+// stateBackupName names state backup archives, as archivist-state_<timestamp>.tar.gz
+const stateBackupName = "archivist-state"
+
+// BackupState uploads Archivist's own state to a backend: config.json and a
+// consistent snapshot of the database, together in one tar.gz archive. The
+// configuration is stored as it is, backend credentials included. When the
+// state_backup setting has a keep_last, older state backups on the backend
+// are then removed.
+func (e *Executor) BackupState(ctx context.Context, backendID string) (*models.StateBackupResult, error) {
+	backendCfg, err := e.config.GetBackend(backendID)
+	if err != nil {
+		return nil, fmt.Errorf("backend not found: %w", err)
+	}
+
+	start := time.Now()
+	settings := e.config.GetSettings()
+	tempDir := e.config.ResolvePath(settings.TempDir)
+	if err := os.MkdirAll(tempDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	workDir, err := os.MkdirTemp(tempDir, ".archivist_state_*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create work directory: %w", err)
+	}
+	defer func() {
+		if err := os.RemoveAll(workDir); err != nil {
+			log.Printf("Warning: failed to remove state backup directory: %v", err)
+		}
+	}()
+
+	// The archive is built from stateDir into workDir, so it doesn't include itself
+	stateDir := filepath.Join(workDir, "state")
+	if err := os.Mkdir(stateDir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create state directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(e.config.Get(), "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal configuration: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(stateDir, "config.json"), data, 0600); err != nil {
+		return nil, fmt.Errorf("failed to write configuration: %w", err)
+	}
+
+	dbPath := filepath.Join(stateDir, "archivist.db")
+	if err := e.db.Snapshot(ctx, dbPath); err != nil {
+		return nil, err
+	}
+	dbInfo, err := os.Stat(dbPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat database snapshot: %w", err)
+	}
+
+	builder := archive.NewBuilder(stateDir, workDir, models.ArchiveOptions{Format: "tar.gz", UseTimestamp: true}, nil)
+	builder.HashAlgorithm = settings.HashAlgorithm
+	builder.MinFreeSpace = minFreeTempSpace(settings)
+	archivePath, hash, size, err := builder.Build(stateBackupName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build archive: %w", err)
+	}
+
+	backendInstance, err := backend.Factory(backendCfg, e.config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create backend: %w", err)
+	}
+	defer func() {
+		if err := backendInstance.Close(); err != nil {
+			log.Printf("Error closing backend instance: %v", err)
+		}
+	}()
+
+	remotePath := filepath.Base(archivePath)
+	if err := backendInstance.Upload(ctx, archivePath, remotePath, nil); err != nil {
+		return nil, fmt.Errorf("failed to upload state backup: %w", err)
+	}
+
+	log.Printf("Backed up configuration and database to %s: %s", backendCfg.Name, remotePath)
+	result := &models.StateBackupResult{
+		BackendID:    backendID,
+		RemotePath:   remotePath,
+		Size:         size,
+		Hash:         hash,
+		DatabaseSize: dbInfo.Size(),
+		CreatedAt:    start,
+	}
+
+	if stateBackup := settings.StateBackup; stateBackup != nil && stateBackup.KeepLast > 0 {
+		if backendCfg.Immutable {
+			log.Printf("Skipping state backup retention on immutable backend: %s", backendCfg.Name)
+		} else {
+			result.Pruned = pruneStateBackups(ctx, backendInstance, stateBackup.KeepLast)
+		}
+	}
+
+	result.DurationMs = time.Since(start).Milliseconds()
+	return result, nil
+}
+
+// pruneStateBackups removes all but the newest keepLast state backups from a
+// backend and returns the paths it removed. Failures are logged, not returned,
+// since the new backup is already stored.
+func pruneStateBackups(ctx context.Context, backendInstance backend.StorageBackend, keepLast int) []string {
+	files, err := backendInstance.List(ctx, stateBackupName+"_")
+	if err != nil {
+		log.Printf("Failed to list state backups for retention: %v", err)
+		return nil
+	}
+
+	// State backups are named like a task's timestamped archives, so the task rules apply
+	stateTask := &models.Task{
+		Name:            stateBackupName,
+		RetentionPolicy: models.RetentionPolicy{KeepLast: keepLast},
+	}
+	var pruned []string
+	for _, old := range selectRetentionDeletions(stateTask, files) {
+		if err := backendInstance.Delete(ctx, old.Path); err != nil {
+			log.Printf("Failed to delete old state backup %s: %v", old.Path, err)
+			continue
+		}
+		log.Printf("Deleted old state backup: %s", old.Path)
+		pruned = append(pruned, old.Path)
+	}
+	return pruned
+}
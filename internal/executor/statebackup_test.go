@@ -0,0 +1,232 @@
+package executor
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/nsilverman/archivist/internal/config"
+	"github.com/nsilverman/archivist/internal/models"
+	"github.com/nsilverman/archivist/internal/storage"
+)
+
+// newTestExecutor returns an executor over a fresh config and database in a
+// temporary directory, with a local backend "local" stored in storeDir
+func newTestExecutor(t *testing.T) (e *Executor, cfg *config.Manager, db storage.Store, storeDir string) {
+	t.Helper()
+	dir := t.TempDir()
+	cfg, err := config.NewManager(filepath.Join(dir, "config", "config.json"), dir)
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+	if err := cfg.CreateDefaultWithPaths(filepath.Join(dir, "temp"), filepath.Join(dir, "sources")); err != nil {
+		t.Fatalf("CreateDefaultWithPaths: %v", err)
+	}
+
+	storeDir = filepath.Join(dir, "store")
+	if err := os.MkdirAll(storeDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.AddBackend(&models.Backend{
+		ID:      "local",
+		Name:    "local",
+		Type:    "local",
+		Enabled: true,
+		Config:  map[string]interface{}{"path": storeDir},
+	}); err != nil {
+		t.Fatalf("AddBackend: %v", err)
+	}
+
+	db, err = storage.NewDatabase(filepath.Join(dir, "config", "archivist.db"))
+	if err != nil {
+		t.Fatalf("NewDatabase: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := db.Close(); err != nil {
+			t.Errorf("closing database: %v", err)
+		}
+	})
+
+	return NewExecutor(cfg, db), cfg, db, storeDir
+}
+
+// extractTarGz unpacks a tar.gz archive into dir
+func extractTarGz(t *testing.T, archivePath, dir string) {
+	t.Helper()
+	f, err := os.Open(archivePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = f.Close() }()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, filepath.Base(hdr.Name)), data, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestBackupStateRestoresIntoFreshInstance(t *testing.T) {
+	e, cfg, db, storeDir := newTestExecutor(t)
+
+	task := &models.Task{
+		ID:             "task-1",
+		Name:           "documents",
+		SourcePath:     t.TempDir(),
+		BackendIDs:     []string{"local"},
+		Schedule:       models.Schedule{Type: "manual"},
+		ArchiveOptions: models.ArchiveOptions{Format: "tar.gz", UseTimestamp: true},
+		Enabled:        true,
+	}
+	if err := cfg.AddTask(task); err != nil {
+		t.Fatalf("AddTask: %v", err)
+	}
+	if err := db.CreateExecution(&models.Execution{
+		ID:        "exec-1",
+		TaskID:    task.ID,
+		TaskName:  task.Name,
+		StartedAt: time.Now(),
+		Status:    "success",
+	}); err != nil {
+		t.Fatalf("CreateExecution: %v", err)
+	}
+
+	result, err := e.BackupState(context.Background(), "local")
+	if err != nil {
+		t.Fatalf("BackupState: %v", err)
+	}
+
+	// Restore as the README describes: both files into a new config directory
+	freshDir := t.TempDir()
+	extractTarGz(t, filepath.Join(storeDir, result.RemotePath), freshDir)
+
+	restoredCfg, err := config.NewManager(filepath.Join(freshDir, "config.json"), freshDir)
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+	if err := restoredCfg.Load(); err != nil {
+		t.Fatalf("loading restored config: %v", err)
+	}
+	restoredTask, err := restoredCfg.GetTask(task.ID)
+	if err != nil {
+		t.Fatalf("restored config is missing the task: %v", err)
+	}
+	if restoredTask.Name != task.Name {
+		t.Errorf("restored task name = %q, want %q", restoredTask.Name, task.Name)
+	}
+	if _, err := restoredCfg.GetBackend("local"); err != nil {
+		t.Errorf("restored config is missing the backend: %v", err)
+	}
+
+	restoredDB, err := storage.NewDatabase(filepath.Join(freshDir, "archivist.db"))
+	if err != nil {
+		t.Fatalf("opening restored database: %v", err)
+	}
+	defer func() { _ = restoredDB.Close() }()
+	execution, err := restoredDB.GetExecution("exec-1")
+	if err != nil {
+		t.Fatalf("restored database is missing the execution: %v", err)
+	}
+	if execution.Status != "success" || execution.TaskID != task.ID {
+		t.Errorf("restored execution = %s/%s, want success/%s", execution.Status, execution.TaskID, task.ID)
+	}
+}
+
+func TestBackupStateKeepLast(t *testing.T) {
+	old := []string{
+		"archivist-state_20250101_030000.tar.gz",
+		"archivist-state_20250102_030000.tar.gz",
+		"archivist-state_20250103_030000.tar.gz",
+	}
+
+	tests := []struct {
+		name       string
+		keepLast   int
+		immutable  bool
+		wantPruned []string
+	}{
+		{name: "unset keeps everything", keepLast: 0},
+		{name: "keeps the newest", keepLast: 2, wantPruned: old[:2]},
+		{name: "more than stored", keepLast: 10},
+		{name: "immutable backend is not pruned", keepLast: 1, immutable: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, cfg, _, storeDir := newTestExecutor(t)
+
+			// Older backups, and an unrelated file that retention must leave alone
+			for i, name := range append(old, "notes.txt") {
+				path := filepath.Join(storeDir, name)
+				if err := os.WriteFile(path, []byte("old"), 0644); err != nil {
+					t.Fatal(err)
+				}
+				modTime := time.Now().Add(-time.Duration(len(old)-i) * time.Hour)
+				if err := os.Chtimes(path, modTime, modTime); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			backendCfg, err := cfg.GetBackend("local")
+			if err != nil {
+				t.Fatal(err)
+			}
+			backendCfg.Immutable = tt.immutable
+			if err := cfg.UpdateBackend("local", backendCfg); err != nil {
+				t.Fatalf("UpdateBackend: %v", err)
+			}
+			settings := cfg.GetSettings()
+			settings.StateBackup = &models.StateBackup{BackendID: "local", KeepLast: tt.keepLast}
+			if err := cfg.UpdateSettings(settings); err != nil {
+				t.Fatalf("UpdateSettings: %v", err)
+			}
+
+			result, err := e.BackupState(context.Background(), "local")
+			if err != nil {
+				t.Fatalf("BackupState: %v", err)
+			}
+
+			pruned := append([]string(nil), result.Pruned...)
+			sort.Strings(pruned)
+			if len(pruned) != len(tt.wantPruned) {
+				t.Fatalf("pruned %v, want %v", pruned, tt.wantPruned)
+			}
+			for i := range pruned {
+				if pruned[i] != tt.wantPruned[i] {
+					t.Fatalf("pruned %v, want %v", pruned, tt.wantPruned)
+				}
+			}
+			for _, name := range append(pruned, result.RemotePath, "notes.txt") {
+				_, err := os.Stat(filepath.Join(storeDir, name))
+				wantGone := name != result.RemotePath && name != "notes.txt"
+				if gone := os.IsNotExist(err); gone != wantGone {
+					t.Errorf("%s removed = %v, want %v", name, gone, wantGone)
+				}
+			}
+		})
+	}
+}
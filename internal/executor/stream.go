@@ -0,0 +1,215 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/nsilverman/archivist/internal/archive"
+	"github.com/nsilverman/archivist/internal/backend"
+	"github.com/nsilverman/archivist/internal/models"
+)
+
+// canStream reports whether a task's archives can be written straight to its
+// backends. Chunked and split archives and local copies need a file on disk.
+func canStream(options models.ArchiveOptions) bool {
+	return options.Stream &&
+		options.Format != archive.FormatChunked &&
+		options.SplitSizeBytes == 0 &&
+		options.LocalCopyDir == ""
+}
+
+// streamTarget is one backend receiving a streamed archive through a pipe
+type streamTarget struct {
+	result models.BackendResult
+	writer *io.PipeWriter
+	failed bool
+}
+
+// runStreamedExecution builds the archive once and pipes it to every backend
+// concurrently, so it is never written to the temp directory
+func (e *Executor) runStreamedExecution(ctx context.Context, task *models.Task, execution *models.Execution, builder *archive.Builder, startTime time.Time) error {
+	fail := func(err error) error {
+		execution.Status = "failed"
+		execution.ErrorMessage = fmt.Sprintf("Failed to create archive: %v", err)
+		now := time.Now()
+		execution.CompletedAt = &now
+		execution.DurationMs = time.Since(startTime).Milliseconds()
+		if dbErr := e.db.UpdateExecution(execution); dbErr != nil {
+			log.Printf("Error updating execution: %v", dbErr)
+		}
+		e.broadcastExecutionFailed(execution)
+		return err
+	}
+
+	if err := builder.Prepare(); err != nil {
+		return fail(err)
+	}
+	filename, err := builder.GenerateFilename(task.Name)
+	if err != nil {
+		return fail(err)
+	}
+
+	log.Printf("Streaming archive for task %s to %d backend(s)", task.Name, len(task.BackendIDs))
+
+	targets := make([]*streamTarget, 0, len(task.BackendIDs))
+	var wg sync.WaitGroup
+	for _, backendID := range task.BackendIDs {
+		target := &streamTarget{
+			result: models.BackendResult{BackendID: backendID, Mode: "archive"},
+		}
+		targets = append(targets, target)
+
+		backendCfg, err := e.config.GetBackend(backendID)
+		if err != nil {
+			target.failed = true
+			target.result.Status = "failed"
+			target.result.ErrorMessage = fmt.Sprintf("Backend not found: %v", err)
+			continue
+		}
+		target.result.BackendName = backendCfg.Name
+
+		backendInstance, err := backend.Factory(backendCfg, e.config)
+		if err != nil {
+			target.failed = true
+			target.result.Status = "failed"
+			target.result.ErrorMessage = fmt.Sprintf("Failed to create backend: %v", err)
+			target.result.ErrorCode = backend.ClassifyError(nil, err)
+			continue
+		}
+
+		reader, writer := io.Pipe()
+		target.writer = writer
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() {
+				if err := backendInstance.Close(); err != nil {
+					log.Printf("Error closing backend instance: %v", err)
+				}
+			}()
+
+			release, err := e.acquireBackend(ctx, backendCfg)
+			if err != nil {
+				reader.CloseWithError(err)
+				target.result.Status = "failed"
+				target.result.ErrorMessage = fmt.Sprintf("Cancelled while waiting for a backend slot: %v", err)
+				return
+			}
+			defer release()
+
+			uploadStart := time.Now()
+			err = backendInstance.UploadReader(ctx, reader, -1, filename, nil)
+			// Unblock the archive writer if the upload gave up early
+			reader.CloseWithError(fmt.Errorf("upload stopped"))
+
+			target.result.DurationMs = time.Since(uploadStart).Milliseconds()
+			if err != nil {
+				target.result.Status = "failed"
+				target.result.ErrorMessage = err.Error()
+				target.result.ErrorCode = backend.ClassifyError(backendInstance, err)
+				return
+			}
+
+			now := time.Now()
+			target.result.Status = "success"
+			target.result.UploadedAt = &now
+			target.result.RemotePath = filename
+		}()
+	}
+
+	hash, size, buildErr := builder.Stream(&fanoutWriter{targets: targets})
+	for _, target := range targets {
+		if target.writer == nil {
+			continue
+		}
+		if buildErr != nil {
+			target.writer.CloseWithError(buildErr)
+		} else if err := target.writer.Close(); err != nil {
+			log.Printf("Error closing upload stream: %v", err)
+		}
+	}
+	wg.Wait()
+
+	execution.ArchiveSize = size
+	execution.ArchiveHash = hash
+	execution.Compression = builder.Codec()
+
+	var backendResults []models.BackendResult
+	for _, target := range targets {
+		if target.result.Status == "success" {
+			// Either way the backend received a truncated archive
+			switch {
+			case buildErr != nil:
+				target.result.Status = "failed"
+				target.result.ErrorMessage = fmt.Sprintf("archive stream failed: %v", buildErr)
+			case target.failed:
+				target.result.Status = "failed"
+				target.result.ErrorMessage = "upload ended before the archive was complete"
+			default:
+				target.result.Size = size
+			}
+		}
+		backendResults = append(backendResults, target.result)
+	}
+
+	// When every upload failed the stream error is just a symptom, so the
+	// backend errors are reported instead
+	if buildErr != nil && !allFailed(targets) {
+		return fail(buildErr)
+	}
+	if buildErr == nil {
+		contents := builder.Contents()
+		contents.ExecutionID = execution.ID
+		if err := e.db.SaveArchiveContents(&contents); err != nil {
+			log.Printf("Error recording archive contents: %v", err)
+		}
+	}
+
+	for i := range backendResults {
+		if dbErr := e.db.AddBackendUpload(execution.ID, &backendResults[i]); dbErr != nil {
+			log.Printf("Error adding backend upload: %v", dbErr)
+		}
+	}
+
+	if task.ArchiveOptions.KeepLatest && task.ArchiveOptions.UseTimestamp {
+		log.Printf("Skipping latest copy for streamed task: %s", task.Name)
+	}
+
+	return e.finishArchiveExecution(ctx, task, execution, backendResults, startTime)
+}
+
+// fanoutWriter copies each write to every backend that is still accepting data.
+// A backend that fails is dropped; the write only fails once all of them have.
+type fanoutWriter struct {
+	targets []*streamTarget
+}
+
+func (f *fanoutWriter) Write(p []byte) (int, error) {
+	for _, target := range f.targets {
+		if target.failed {
+			continue
+		}
+		if _, err := target.writer.Write(p); err != nil {
+			target.failed = true
+		}
+	}
+	if allFailed(f.targets) {
+		return 0, fmt.Errorf("all backend uploads failed")
+	}
+	return len(p), nil
+}
+
+// allFailed reports whether no target is still receiving the stream
+func allFailed(targets []*streamTarget) bool {
+	for _, target := range targets {
+		if !target.failed {
+			return false
+		}
+	}
+	return true
+}
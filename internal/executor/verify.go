@@ -0,0 +1,274 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nsilverman/archivist/internal/archive"
+	"github.com/nsilverman/archivist/internal/backend"
+	"github.com/nsilverman/archivist/internal/models"
+)
+
+// VerifyBackups checks every backup of a task on each of its backends against
+// its recorded hash. The check runs in the background; progress is reported
+// through verification_* events and the report is stored in the database.
+func (e *Executor) VerifyBackups(taskID string) (*models.BackupVerification, error) {
+	task, err := e.config.GetTask(taskID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get task: %w", err)
+	}
+	if task.ArchiveOptions.Format == "sync" {
+		return nil, fmt.Errorf("task %s uses sync mode; only archives can be verified", task.Name)
+	}
+
+	verification := &models.BackupVerification{
+		ID:        uuid.New().String(),
+		TaskID:    task.ID,
+		Status:    "running",
+		StartedAt: time.Now(),
+		Results:   make([]models.VerifiedBackup, 0),
+	}
+	if err := e.db.SaveBackupVerification(verification); err != nil {
+		return nil, fmt.Errorf("failed to record verification: %w", err)
+	}
+
+	e.broadcastEvent(models.ProgressEvent{
+		Type: "verification_started",
+		Data: map[string]interface{}{
+			"verification_id": verification.ID,
+			"task_id":         task.ID,
+		},
+	})
+
+	report := *verification
+	go func() {
+		e.runVerification(context.Background(), task, &report)
+
+		now := time.Now()
+		report.CompletedAt = &now
+		if err := e.db.SaveBackupVerification(&report); err != nil {
+			log.Printf("Error recording verification: %v", err)
+		}
+
+		if report.Status == "failed" {
+			log.Printf("Verification %s failed: %s", report.ID, report.ErrorMessage)
+			e.broadcastEvent(models.ProgressEvent{
+				Type: "verification_failed",
+				Data: map[string]interface{}{
+					"verification_id": report.ID,
+					"task_id":         task.ID,
+					"error_message":   report.ErrorMessage,
+				},
+			})
+			return
+		}
+
+		log.Printf("Verification %s completed: %d intact, %d corrupt, %d unverified", report.ID, report.Intact, report.Corrupt, report.Unverified)
+		e.broadcastEvent(models.ProgressEvent{
+			Type: "verification_completed",
+			Data: report,
+		})
+	}()
+
+	return verification, nil
+}
+
+// runVerification checks the task's backups on each backend, filling in the report
+func (e *Executor) runVerification(ctx context.Context, task *models.Task, report *models.BackupVerification) {
+	workDir := filepath.Join(e.config.ResolvePath(e.config.GetSettings().TempDir), "verify", report.ID)
+	if err := os.MkdirAll(workDir, 0755); err != nil {
+		report.Status = "failed"
+		report.ErrorMessage = fmt.Sprintf("failed to create work directory: %v", err)
+		return
+	}
+	defer func() {
+		if err := os.RemoveAll(workDir); err != nil {
+			log.Printf("Error removing verification directory: %v", err)
+		}
+	}()
+
+	for _, backendID := range task.BackendIDs {
+		report.Results = append(report.Results, e.verifyBackend(ctx, task, backendID, workDir)...)
+	}
+
+	for _, result := range report.Results {
+		switch result.Status {
+		case "intact":
+			report.Intact++
+		case "corrupt":
+			report.Corrupt++
+		default:
+			report.Unverified++
+		}
+	}
+	report.Status = "completed"
+}
+
+// verifyBackend checks the task's backups on one backend. A backend that can't
+// be listed yields a single unverified result carrying the error.
+func (e *Executor) verifyBackend(ctx context.Context, task *models.Task, backendID, workDir string) []models.VerifiedBackup {
+	failed := models.VerifiedBackup{
+		BackendID: backendID,
+		Status:    "unverified",
+	}
+
+	backendCfg, err := e.config.GetBackend(backendID)
+	if err != nil {
+		failed.ErrorMessage = fmt.Sprintf("Backend not found: %v", err)
+		return []models.VerifiedBackup{failed}
+	}
+	failed.BackendName = backendCfg.Name
+
+	recorded, err := e.db.RecordedArchiveHashes(task.ID, backendID)
+	if err != nil {
+		failed.ErrorMessage = fmt.Sprintf("Failed to load recorded hashes: %v", err)
+		return []models.VerifiedBackup{failed}
+	}
+
+	backendInstance, err := backend.Factory(backendCfg, e.config)
+	if err != nil {
+		failed.ErrorMessage = fmt.Sprintf("Failed to create backend: %v", err)
+		return []models.VerifiedBackup{failed}
+	}
+	defer func() {
+		if err := backendInstance.Close(); err != nil {
+			log.Printf("Error closing backend instance: %v", err)
+		}
+	}()
+
+	files, err := backendInstance.List(ctx, "")
+	if err != nil {
+		failed.ErrorMessage = fmt.Sprintf("Failed to list backups: %v", err)
+		return []models.VerifiedBackup{failed}
+	}
+	listedHashes := make(map[string]string, len(files))
+	for _, file := range files {
+		listedHashes[filepath.ToSlash(file.Path)] = file.Hash
+	}
+
+	var results []models.VerifiedBackup
+	for _, backup := range groupTaskBackups(task, files) {
+		result := models.VerifiedBackup{
+			BackendID:    backendCfg.ID,
+			BackendName:  backendCfg.Name,
+			Path:         backup.Path,
+			ExpectedHash: recorded[filepath.Base(backup.Path)],
+		}
+		verifyBackup(ctx, backendInstance, backup, listedHashes[backup.Path], workDir, &result)
+		results = append(results, result)
+	}
+	return results
+}
+
+// verifyBackup checks one backup and sets its status. The hash the backend
+// reports is compared with the recorded one when both use the same algorithm;
+// otherwise the backup is downloaded and rehashed. Without a recorded hash the
+// backend's own hash is checked, and chunked archives are reassembled against
+// their manifest.
+func verifyBackup(ctx context.Context, backendInstance backend.StorageBackend, backup models.RemoteBackup, listedHash, workDir string, result *models.VerifiedBackup) {
+	expected := result.ExpectedHash
+	if expected == "" && len(backup.Parts) == 0 {
+		expected = listedHash
+	}
+
+	var err error
+	switch {
+	case strings.HasSuffix(backup.Path, archive.ManifestSuffix):
+		result.Method = "download"
+		err = verifyChunkedBackup(ctx, backendInstance, backup.Path, workDir)
+	case expected == "":
+		result.Status = "unverified"
+		result.ErrorMessage = "No recorded or remote hash to check against"
+		return
+	case result.ExpectedHash != "" && len(backup.Parts) == 0 && sameHashAlgorithm(result.ExpectedHash, listedHash):
+		result.Method = "remote_hash"
+		if !strings.EqualFold(listedHash, result.ExpectedHash) {
+			err = fmt.Errorf("%w: expected %s, backend reports %s", backend.ErrHashMismatch, result.ExpectedHash, listedHash)
+		}
+	default:
+		result.Method = "download"
+		err = verifyDownloadedBackup(ctx, backendInstance, backup, expected, workDir)
+	}
+
+	switch {
+	case err == nil:
+		result.Status = "intact"
+	case errors.Is(err, backend.ErrHashMismatch), errors.Is(err, archive.ErrManifestMismatch):
+		result.Status = "corrupt"
+		result.ErrorMessage = err.Error()
+	default:
+		result.Status = "unverified"
+		result.ErrorMessage = err.Error()
+	}
+}
+
+// verifyDownloadedBackup downloads a backup, joining its parts if it was split, and checks its hash
+func verifyDownloadedBackup(ctx context.Context, backendInstance backend.StorageBackend, backup models.RemoteBackup, expected, workDir string) error {
+	localPath := filepath.Join(workDir, filepath.Base(backup.Path))
+	defer removeVerifyFile(localPath)
+
+	if len(backup.Parts) == 0 {
+		if err := backendInstance.Download(ctx, backup.Path, localPath, nil); err != nil {
+			return fmt.Errorf("failed to download: %w", err)
+		}
+		return backend.VerifyFileHash(localPath, expected)
+	}
+
+	localParts := make([]string, 0, len(backup.Parts))
+	defer func() {
+		for _, part := range localParts {
+			removeVerifyFile(part)
+		}
+	}()
+	for _, remotePart := range backup.Parts {
+		localPart := filepath.Join(workDir, filepath.Base(remotePart))
+		localParts = append(localParts, localPart)
+		if err := backendInstance.Download(ctx, remotePart, localPart, nil); err != nil {
+			return fmt.Errorf("failed to download part %s: %w", remotePart, err)
+		}
+	}
+	if err := archive.JoinParts(localParts, localPath); err != nil {
+		return err
+	}
+	return backend.VerifyFileHash(localPath, expected)
+}
+
+// verifyChunkedBackup downloads a manifest and reassembles its archive without
+// keeping it, which checks every chunk and the archive hash
+func verifyChunkedBackup(ctx context.Context, backendInstance backend.StorageBackend, manifestPath, workDir string) error {
+	localManifest := filepath.Join(workDir, filepath.Base(manifestPath))
+	if err := backendInstance.Download(ctx, manifestPath, localManifest, nil); err != nil {
+		return fmt.Errorf("failed to download manifest: %w", err)
+	}
+	manifest, err := archive.ReadManifest(localManifest)
+	removeVerifyFile(localManifest)
+	if err != nil {
+		return err
+	}
+
+	return archive.Reassemble(ctx, manifest, func(ctx context.Context, chunk archive.Chunk) (io.ReadCloser, error) {
+		return downloadChunk(ctx, backendInstance, chunk, workDir)
+	}, io.Discard)
+}
+
+// sameHashAlgorithm reports whether two "algo:hex" hashes use the same algorithm
+func sameHashAlgorithm(a, b string) bool {
+	algoA, _, okA := strings.Cut(a, ":")
+	algoB, _, okB := strings.Cut(b, ":")
+	return okA && okB && strings.EqualFold(algoA, algoB)
+}
+
+// removeVerifyFile removes a file downloaded for verification
+func removeVerifyFile(path string) {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		log.Printf("Error removing verification file: %v", err)
+	}
+}
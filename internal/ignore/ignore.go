@@ -0,0 +1,227 @@
+package ignore
+
+import (
+	"bufio"
+	"bytes"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// FileName is the name of the ignore files read from a source tree. Each one
+// applies to its own directory and everything below it, like .gitignore.
+const FileName = ".archivistignore"
+
+// rule is one pattern line of an ignore file
+type rule struct {
+	segments []string // pattern split on "/"; "**" matches any number of segments
+	negate   bool     // "!pattern" re-includes what an earlier rule excluded
+	dirOnly  bool     // "pattern/" only matches directories
+}
+
+// parseRules reads the rules of an ignore file, skipping blank lines and comments
+func parseRules(data []byte) []rule {
+	var rules []rule
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var r rule
+		switch {
+		case strings.HasPrefix(line, "!"):
+			r.negate = true
+			line = line[1:]
+		case strings.HasPrefix(line, `\!`), strings.HasPrefix(line, `\#`):
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			r.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+
+		// A pattern without a slash matches at any depth; one with a slash is
+		// relative to the directory holding the ignore file
+		if strings.Contains(line, "/") {
+			line = strings.TrimPrefix(line, "/")
+		} else {
+			line = "**/" + line
+		}
+		r.segments = strings.Split(line, "/")
+		rules = append(rules, r)
+	}
+	return rules
+}
+
+// match reports whether the rule matches relPath, a slash-separated path
+// relative to the directory of the rule's ignore file
+func (r rule) match(relPath string, isDir bool) bool {
+	if r.dirOnly && !isDir {
+		return false
+	}
+	return matchSegments(r.segments, strings.Split(relPath, "/"))
+}
+
+// matchSegments matches path segments against pattern segments
+func matchSegments(pattern, parts []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			rest := pattern[1:]
+			// A trailing "**" matches everything inside, but not the directory itself
+			if len(rest) == 0 {
+				return len(parts) > 0
+			}
+			for i := 0; i <= len(parts); i++ {
+				if matchSegments(rest, parts[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+
+		if len(parts) == 0 {
+			return false
+		}
+		if ok, err := path.Match(pattern[0], parts[0]); err != nil || !ok {
+			return false
+		}
+		pattern, parts = pattern[1:], parts[1:]
+	}
+	return len(parts) == 0
+}
+
+// Patterns is a list of patterns in ignore file syntax, applied as if they
+// were an ignore file at the root of a tree
+type Patterns []rule
+
+// ParsePatterns parses patterns given one per entry instead of read from a file
+func ParsePatterns(patterns []string) Patterns {
+	return parseRules([]byte(strings.Join(patterns, "\n")))
+}
+
+// Matches reports whether relPath, relative to the root, or a directory it lies
+// in is matched. As in an ignore file, the last matching pattern wins.
+func (p Patterns) Matches(relPath string, isDir bool) bool {
+	parts := strings.Split(filepath.ToSlash(relPath), "/")
+	for i := 1; i < len(parts); i++ {
+		if p.match(strings.Join(parts[:i], "/"), true) {
+			return true
+		}
+	}
+	return p.match(strings.Join(parts, "/"), isDir)
+}
+
+// match applies the patterns to one path
+func (p Patterns) match(relPath string, isDir bool) bool {
+	matched := false
+	for _, r := range p {
+		if r.match(relPath, isDir) {
+			matched = !r.negate
+		}
+	}
+	return matched
+}
+
+// Matcher applies the ignore files of one source tree. Ignore files are read
+// the first time a path below their directory is checked.
+type Matcher struct {
+	root string
+
+	mu    sync.Mutex
+	rules map[string][]rule // by directory, slash-separated and relative to root
+}
+
+// NewMatcher creates a matcher for the tree at root
+func NewMatcher(root string) *Matcher {
+	return &Matcher{
+		root:  root,
+		rules: make(map[string][]rule),
+	}
+}
+
+// Ignored reports whether relPath, relative to the root, is excluded. Rules in
+// deeper ignore files take precedence, and within a file the last matching
+// rule wins. Callers skip the contents of ignored directories themselves.
+func (m *Matcher) Ignored(relPath string, isDir bool) bool {
+	relPath = filepath.ToSlash(relPath)
+
+	ignored := false
+	dir := "."
+	remaining := relPath
+	for {
+		for _, r := range m.load(dir) {
+			if r.match(remaining, isDir) {
+				ignored = !r.negate
+			}
+		}
+
+		next, rest, found := strings.Cut(remaining, "/")
+		if !found {
+			return ignored
+		}
+		dir = path.Join(dir, next)
+		remaining = rest
+	}
+}
+
+// Excludes reports whether a file is left out of walks: it is ignored itself
+// or lies in an ignored directory
+func (m *Matcher) Excludes(relPath string) bool {
+	parts := strings.Split(filepath.ToSlash(relPath), "/")
+	for i := 1; i < len(parts); i++ {
+		if m.Ignored(strings.Join(parts[:i], "/"), true) {
+			return true
+		}
+	}
+	return m.Ignored(relPath, false)
+}
+
+// load returns the rules of the ignore file in dir, reading it on first use
+func (m *Matcher) load(dir string) []rule {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if rules, ok := m.rules[dir]; ok {
+		return rules
+	}
+
+	var rules []rule
+	data, err := os.ReadFile(filepath.Join(m.root, filepath.FromSlash(dir), FileName))
+	if err == nil {
+		rules = parseRules(data)
+	} else if !os.IsNotExist(err) {
+		log.Printf("Error reading %s in %s: %v", FileName, dir, err)
+	}
+	m.rules[dir] = rules
+	return rules
+}
+
+// Walk walks the tree at root like filepath.Walk, leaving out the files and
+// directories excluded by its ignore files. A single-file root is walked as is.
+func Walk(root string, fn filepath.WalkFunc) error {
+	info, err := os.Stat(root)
+	if err != nil || !info.IsDir() {
+		return filepath.Walk(root, fn)
+	}
+
+	matcher := NewMatcher(root)
+	return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err == nil && path != root {
+			if relPath, relErr := filepath.Rel(root, path); relErr == nil && matcher.Ignored(relPath, info.IsDir()) {
+				if info.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+		}
+		return fn(path, info, err)
+	})
+}
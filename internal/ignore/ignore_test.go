@@ -0,0 +1,172 @@
+package ignore
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestPatternsMatches(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+		path     string
+		isDir    bool
+		want     bool
+	}{
+		{"name at any depth", []string{"*.log"}, "a/b/debug.log", false, true},
+		{"name at the root", []string{"*.log"}, "debug.log", false, true},
+		{"no match", []string{"*.log"}, "a/notes.txt", false, false},
+		{"anchored pattern", []string{"/build"}, "build", true, true},
+		{"anchored pattern below the root", []string{"/build"}, "src/build", true, false},
+		{"pattern with a slash is anchored", []string{"src/*.tmp"}, "lib/src/a.tmp", false, false},
+		{"directory only pattern on a file", []string{"cache/"}, "cache", false, false},
+		{"directory only pattern on a directory", []string{"cache/"}, "cache", true, true},
+		{"inside a matched directory", []string{"node_modules/"}, "web/node_modules/pkg/index.js", false, true},
+		{"double star", []string{"docs/**/*.pdf"}, "docs/a/b/c.pdf", false, true},
+		{"double star matches no directories", []string{"docs/**/*.pdf"}, "docs/c.pdf", false, true},
+		{"trailing double star", []string{"tmp/**"}, "tmp/a", false, true},
+		{"trailing double star skips the directory itself", []string{"tmp/**"}, "tmp", true, false},
+		{"negation", []string{"*.log", "!keep.log"}, "keep.log", false, false},
+		{"last match wins", []string{"!keep.log", "*.log"}, "keep.log", false, true},
+		{"comment", []string{"# *.log"}, "debug.log", false, false},
+		{"escaped hash", []string{`\#notes`}, "#notes", false, true},
+		{"escaped bang", []string{`\!important`}, "!important", false, true},
+		{"native separators", []string{"cache/"}, filepath.Join("cache", "a"), false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParsePatterns(tt.patterns).Matches(tt.path, tt.isDir); got != tt.want {
+				t.Errorf("%v matches %s = %v, want %v", tt.patterns, tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWalkSkipsIgnored(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		FileName:                "*.tmp\nbuild/\n",
+		"keep.txt":              "",
+		"scratch.tmp":           "",
+		"build/out.bin":         "",
+		"src/main.go":           "",
+		"src/" + FileName:       "!important.tmp\n*.go\n",
+		"src/important.tmp":     "",
+		"src/deep/other.tmp":    "",
+		"src/deep/readme.md":    "",
+		"docs/build/index.html": "",
+	}
+	for name, data := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var walked []string
+	err := Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			rel, _ := filepath.Rel(root, path)
+			walked = append(walked, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Walk: %v", err)
+	}
+	sort.Strings(walked)
+
+	want := []string{FileName, "keep.txt", "src/" + FileName, "src/deep/readme.md", "src/important.tmp"}
+	if len(walked) != len(want) {
+		t.Fatalf("walked %v, want %v", walked, want)
+	}
+	for i := range want {
+		if walked[i] != want[i] {
+			t.Fatalf("walked %v, want %v", walked, want)
+		}
+	}
+}
+
+func TestWalkParallelMatchesSerial(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, FileName), []byte("*.tmp\nskip/\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for d := range 20 {
+		for sub := range 3 {
+			dir := filepath.Join(root, fmt.Sprintf("d%02d", d), fmt.Sprintf("s%d", sub))
+			if d%5 == 0 {
+				dir = filepath.Join(dir, "skip")
+			}
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				t.Fatal(err)
+			}
+			for f := range 5 {
+				name := fmt.Sprintf("f%d.dat", f)
+				if f == 4 {
+					name = fmt.Sprintf("f%d.tmp", f)
+				}
+				data := make([]byte, d*100+sub*10+f)
+				if err := os.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+	}
+
+	type totals struct {
+		files, dirs int
+		size        int64
+		paths       []string
+	}
+	walk := func(workers int) totals {
+		var mu sync.Mutex
+		var got totals
+		err := WalkParallel(context.Background(), root, workers, func(path string, info os.FileInfo) error {
+			rel, _ := filepath.Rel(root, path)
+			mu.Lock()
+			defer mu.Unlock()
+			got.paths = append(got.paths, filepath.ToSlash(rel))
+			if info.IsDir() {
+				got.dirs++
+				return nil
+			}
+			got.files++
+			got.size += info.Size()
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("WalkParallel with %d workers: %v", workers, err)
+		}
+		sort.Strings(got.paths)
+		return got
+	}
+
+	serial := walk(1)
+	// 16 unignored directories with 3 subdirectories of 4 files, plus the ignore file
+	if serial.files != 16*3*4+1 {
+		t.Fatalf("serial walk found %d files, want %d", serial.files, 16*3*4+1)
+	}
+	for _, workers := range []int{2, 8, 64} {
+		parallel := walk(workers)
+		if parallel.files != serial.files || parallel.dirs != serial.dirs || parallel.size != serial.size {
+			t.Errorf("%d workers: %d files, %d dirs, %d bytes; serial: %d files, %d dirs, %d bytes",
+				workers, parallel.files, parallel.dirs, parallel.size, serial.files, serial.dirs, serial.size)
+		}
+		if strings.Join(parallel.paths, "\n") != strings.Join(serial.paths, "\n") {
+			t.Errorf("%d workers walked different paths than the serial walk", workers)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package ignore
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// WalkParallel walks the tree at root like Walk, leaving out ignored files and
+// directories, but reads up to workers directories at once. That hides the
+// per-stat latency of network filesystems. fn is called concurrently and in no
+// particular order, so callers must only aggregate results that don't depend on
+// order. The first error, from fn, the filesystem or ctx, stops the walk. With
+// workers of 1 or less the tree is walked serially, in lexical order.
+func WalkParallel(ctx context.Context, root string, workers int, fn func(path string, info os.FileInfo) error) error {
+	if workers <= 1 {
+		return Walk(root, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			return fn(path, info)
+		})
+	}
+
+	info, err := os.Lstat(root)
+	if err != nil {
+		return err
+	}
+	if err := fn(root, info); err != nil || !info.IsDir() {
+		return err
+	}
+
+	w := &parallelWalk{
+		ctx:     ctx,
+		root:    root,
+		matcher: NewMatcher(root),
+		fn:      fn,
+		queue:   []string{root},
+		pending: 1,
+	}
+	w.cond = sync.NewCond(&w.mu)
+
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.work()
+		}()
+	}
+	wg.Wait()
+	return w.err
+}
+
+// parallelWalk is the shared state of a WalkParallel call
+type parallelWalk struct {
+	ctx     context.Context
+	root    string
+	matcher *Matcher
+	fn      func(path string, info os.FileInfo) error
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	queue   []string // directories waiting to be read
+	pending int      // directories queued or being read
+	err     error
+}
+
+// work reads queued directories until the walk finishes or fails
+func (w *parallelWalk) work() {
+	for {
+		w.mu.Lock()
+		for len(w.queue) == 0 && w.pending > 0 && w.err == nil {
+			w.cond.Wait()
+		}
+		if w.pending == 0 || w.err != nil {
+			w.mu.Unlock()
+			return
+		}
+		dir := w.queue[len(w.queue)-1]
+		w.queue = w.queue[:len(w.queue)-1]
+		w.mu.Unlock()
+
+		subdirs, err := w.readDir(dir)
+
+		w.mu.Lock()
+		if err != nil && w.err == nil {
+			w.err = err
+		}
+		w.queue = append(w.queue, subdirs...)
+		w.pending += len(subdirs) - 1
+		w.mu.Unlock()
+		w.cond.Broadcast()
+	}
+}
+
+// readDir calls fn for each entry of dir that isn't ignored and returns its subdirectories
+func (w *parallelWalk) readDir(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var subdirs []string
+	for _, entry := range entries {
+		if err := w.ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		path := filepath.Join(dir, entry.Name())
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		if relPath, relErr := filepath.Rel(w.root, path); relErr == nil && w.matcher.Ignored(relPath, info.IsDir()) {
+			continue
+		}
+
+		if err := w.fn(path, info); err != nil {
+			return nil, err
+		}
+		if info.IsDir() {
+			subdirs = append(subdirs, path)
+		}
+	}
+	return subdirs, nil
+}
@@ -12,32 +12,71 @@ type Config struct {
 
 // Backend represents a storage backend configuration
 type Backend struct {
-	ID             string                 `json:"id"`
-	Type           string                 `json:"type"` // s3, gcs, gdrive, azure, b2, local
-	Name           string                 `json:"name"`
-	Config         map[string]interface{} `json:"config"`
-	Enabled        bool                   `json:"enabled"`
-	CreatedAt      time.Time              `json:"created_at"`
-	UpdatedAt      time.Time              `json:"updated_at"`
-	LastTest       *time.Time             `json:"last_test,omitempty"`
-	LastTestStatus string                 `json:"last_test_status,omitempty"`
+	ID                   string                 `json:"id"`
+	Type                 string                 `json:"type"` // s3, gcs, gdrive, azure, b2, local
+	Name                 string                 `json:"name"`
+	Config               map[string]interface{} `json:"config"`
+	Enabled              bool                   `json:"enabled"`
+	Immutable            bool                   `json:"immutable,omitempty"`              // Never delete or overwrite objects on this backend (retention and sync deletes are refused)
+	MaxConcurrentUploads int                    `json:"max_concurrent_uploads,omitempty"` // Transfers to this backend across all tasks at once (0 = no limit)
+	CreatedAt            time.Time              `json:"created_at"`
+	UpdatedAt            time.Time              `json:"updated_at"`
+	LastTest             *time.Time             `json:"last_test,omitempty"`
+	LastTestStatus       string                 `json:"last_test_status,omitempty"`
 }
 
 // Task represents a backup task configuration
 type Task struct {
-	ID              string          `json:"id"`
-	Name            string          `json:"name"`
-	Description     string          `json:"description"`
-	SourcePath      string          `json:"source_path"`
-	BackendIDs      []string        `json:"backend_ids"`
-	Schedule        Schedule        `json:"schedule"`
-	ArchiveOptions  ArchiveOptions  `json:"archive_options"`
-	RetentionPolicy RetentionPolicy `json:"retention_policy"`
-	Enabled         bool            `json:"enabled"`
-	CreatedAt       time.Time       `json:"created_at"`
-	UpdatedAt       time.Time       `json:"updated_at"`
-	LastRun         *time.Time      `json:"last_run,omitempty"`
-	NextRun         *time.Time      `json:"next_run,omitempty"`
+	ID                  string             `json:"id"`
+	Name                string             `json:"name"`
+	Description         string             `json:"description"`
+	SourcePath          string             `json:"source_path"`
+	TempDir             string             `json:"temp_dir,omitempty"` // Overrides the global temp directory for this task
+	BackendIDs          []string           `json:"backend_ids"`
+	SyncBackendIDs      []string           `json:"sync_backend_ids,omitempty"` // Archive tasks also mirror the source file by file to these backends in the same run
+	Schedule            Schedule           `json:"schedule"`
+	ArchiveOptions      ArchiveOptions     `json:"archive_options"`
+	RetentionPolicy     RetentionPolicy    `json:"retention_policy"`
+	MaxAgeHours         int                `json:"max_age_hours,omitempty"`         // Flag task as stale if no successful run within this window (0 = disabled)
+	MaxExecutionHistory int                `json:"max_execution_history,omitempty"` // Keep only this many of the newest executions of the task (0 = no per-task limit)
+	RunOnStartup        bool               `json:"run_on_startup,omitempty"`        // Run once every time Archivist starts, whatever the schedule
+	RunMissedOnStartup  bool               `json:"run_missed_on_startup,omitempty"` // Run once on startup if a scheduled run was missed while stopped
+	AllowEmptySource    bool               `json:"allow_empty_source,omitempty"`    // Run even when the source has no files (normally a sign of a missing mount)
+	MinSourcePercent    int                `json:"min_source_percent,omitempty"`    // Abort if the source has fewer files than this percentage of the last successful run (0 = disabled)
+	DependsOn           []string           `json:"depends_on,omitempty"`            // Run this task after any of these tasks completes successfully
+	Notifications       *TaskNotifications `json:"notifications,omitempty"`         // Overrides which notification channels and events apply to this task
+	Heartbeat           *Heartbeat         `json:"heartbeat,omitempty"`             // Monitoring URLs for this task, replacing the global heartbeat
+	FailureRetry        *FailureRetry      `json:"failure_retry,omitempty"`         // Re-run a failed scheduled run after a delay
+	Enabled             bool               `json:"enabled"`
+	CreatedAt           time.Time          `json:"created_at"`
+	UpdatedAt           time.Time          `json:"updated_at"`
+	LastRun             *time.Time         `json:"last_run,omitempty"`
+	NextRun             *time.Time         `json:"next_run,omitempty"`
+}
+
+// TaskNotifications adjusts the global notification channels for one task
+type TaskNotifications struct {
+	Silent   bool     `json:"silent,omitempty"`   // Send no notifications for this task
+	Channels []string `json:"channels,omitempty"` // Extra channels to notify, including task_only ones
+	Muted    []string `json:"muted,omitempty"`    // Global channels not to notify for this task
+	Events   []string `json:"events,omitempty"`   // Replaces the events each channel subscribes to
+}
+
+// Heartbeat holds monitoring URLs requested around each run, for dead man's
+// switch services such as Healthchecks.io. Unset URLs are skipped.
+type Heartbeat struct {
+	StartURL   string `json:"start_url,omitempty"`
+	SuccessURL string `json:"success_url,omitempty"`
+	FailureURL string `json:"failure_url,omitempty"` // Also used for cancelled runs
+}
+
+// FailureRetry re-runs a task whose scheduled run failed, DelayMinutes after
+// each failed attempt and up to Attempts times. Failure notifications are held
+// back until the last attempt fails. Unlike upload and file retries, the whole
+// run is repeated.
+type FailureRetry struct {
+	Attempts     int `json:"attempts"`
+	DelayMinutes int `json:"delay_minutes"`
 }
 
 // Schedule represents a task schedule configuration
@@ -49,21 +88,87 @@ type Schedule struct {
 
 // ArchiveOptions represents archive creation options
 type ArchiveOptions struct {
-	Format       string      `json:"format"`        // tar.gz, tar.bz2, tar.xz, tar.zst, zip, sync
-	Compression  string      `json:"compression"`   // none, gzip, bzip2, xz, zstd
-	NamePattern  string      `json:"name_pattern"`  // e.g., "{task}_{timestamp}.tar.gz" or "{task}_latest.tar.gz"
-	UseTimestamp bool        `json:"use_timestamp"` // If false, creates static filename (mirror strategy)
-	SyncOptions  SyncOptions `json:"sync_options"`  // Options for sync mode
+	Format       string      `json:"format"`                // tar.gz, chunked, zip, sync
+	Compression  string      `json:"compression"`           // gzip, zstd, none or auto (codec chosen from a sample of the source)
+	NamePattern  string      `json:"name_pattern"`          // e.g., "{task}_{timestamp}.tar.gz" or "{task}_latest.tar.gz"
+	UseTimestamp bool        `json:"use_timestamp"`         // If false, creates static filename (mirror strategy)
+	KeepLatest   bool        `json:"keep_latest,omitempty"` // With timestamps, also maintain a {task}_latest copy of the newest archive
+	SyncOptions  SyncOptions `json:"sync_options"`          // Options for sync mode
+
+	// SplitSizeBytes splits archives into numbered parts of at most this size (0 = single file)
+	SplitSizeBytes int64 `json:"split_size_bytes,omitempty"`
+
+	// LocalCopyDir, if set, receives the finished archive instead of it being deleted
+	// after upload. The task's retention policy is applied there too.
+	LocalCopyDir string `json:"local_copy_dir,omitempty"`
+
+	// Stream uploads the archive to every backend as it is built, without a temp
+	// file. Ignored for chunked or split archives and when keeping a local copy.
+	Stream bool `json:"stream,omitempty"`
+
+	// StoreExtensions lists the file extensions a zip archive stores without
+	// compression, e.g. ".jpg". Empty uses the built-in list of already-compressed formats.
+	StoreExtensions []string `json:"store_extensions,omitempty"`
+
+	// Redaction adds to the redaction in the settings for this task's archives
+	Redaction *Redaction `json:"redaction,omitempty"`
+}
+
+// Redaction is a safety net that keeps sensitive files, such as .env files or
+// private keys, out of archives even when nothing else excludes them
+type Redaction struct {
+	Patterns []string `json:"patterns"`       // .archivistignore syntax, relative to the source
+	Mode     string   `json:"mode,omitempty"` // skip (default) leaves matches out; zero stores them empty
 }
 
 // SyncOptions represents file-by-file sync options
 type SyncOptions struct {
-	DeleteRemote bool `json:"delete_remote"` // If true, delete remote files not in source (true mirror)
+	DeleteRemote  bool  `json:"delete_remote"`            // If true, delete remote files not in source (true mirror)
+	CompressFiles bool  `json:"compress_files,omitempty"` // If true, gzip each file on upload and append .gz to the remote name
+	MaxFileBytes  int64 `json:"max_file_bytes,omitempty"` // Skip files larger than this (0 = no limit)
+	FileRetries   int   `json:"file_retries,omitempty"`   // Extra attempts for each failed file upload or delete
+	MaxFailures   int   `json:"max_failures,omitempty"`   // Failed files tolerated before the backend is marked failed (0 = none)
+
+	// DeleteAfterSuccessfulUpload skips deleting remote files in a run where any upload failed
+	DeleteAfterSuccessfulUpload bool `json:"delete_after_successful_upload,omitempty"`
+	// MaxDeletePercent fails the sync instead of deleting more than this share
+	// of the remote files in one run, e.g. after the source was emptied (0 = no limit)
+	MaxDeletePercent int `json:"max_delete_percent,omitempty"`
+
+	// Layout is "preserve" (default) to mirror the source's directories, or
+	// "flatten" to upload every file into one folder
+	Layout string `json:"layout,omitempty"`
+	// RemotePrefix is a sub-folder under the task's remote folder to sync into
+	RemotePrefix string `json:"remote_prefix,omitempty"`
 }
 
 // RetentionPolicy represents backup retention configuration
 type RetentionPolicy struct {
-	KeepLast int `json:"keep_last"` // Number of backups to keep (0 = unlimited)
+	KeepLast           int  `json:"keep_last"`                      // Number of backups to keep (0 = settings default or unlimited, -1 = unlimited)
+	RequireFullSuccess bool `json:"require_full_success,omitempty"` // Only prune when every backend succeeded
+}
+
+// RetentionPreview lists the backups a task's retention policy would delete
+type RetentionPreview struct {
+	TaskID   string                    `json:"task_id"`
+	TaskName string                    `json:"task_name"`
+	KeepLast int                       `json:"keep_last"`
+	Backends []BackendRetentionPreview `json:"backends"`
+}
+
+// BackendRetentionPreview lists the backups that would be deleted from one backend
+type BackendRetentionPreview struct {
+	BackendID   string               `json:"backend_id"`
+	BackendName string               `json:"backend_name"`
+	ToDelete    []RetentionCandidate `json:"to_delete"`
+	Error       string               `json:"error,omitempty"`
+}
+
+// RetentionCandidate is a remote backup selected for deletion by retention
+type RetentionCandidate struct {
+	Path         string `json:"path"`
+	Size         int64  `json:"size"`
+	LastModified string `json:"last_modified"`
 }
 
 // Settings represents application settings
@@ -72,6 +177,91 @@ type Settings struct {
 	SourcesDir         string `json:"sources_dir"`
 	MaxConcurrentTasks int    `json:"max_concurrent_tasks"`
 	LogLevel           string `json:"log_level"`
+
+	// AutoVacuumSchedule is a cron expression for compacting the database; empty disables it
+	AutoVacuumSchedule string `json:"auto_vacuum_schedule,omitempty"`
+
+	// MaxExecutionHistory keeps only this many of the newest executions across all tasks (0 = unlimited)
+	MaxExecutionHistory int `json:"max_execution_history,omitempty"`
+
+	// File permissions as octal strings such as "0600". Archives keep the umask
+	// default when unset; the config file defaults to 0600.
+	ArchiveFileMode string `json:"archive_file_mode,omitempty"`
+	ConfigFileMode  string `json:"config_file_mode,omitempty"`
+
+	// HashAlgorithm is used for archive hashes: sha256 (default), sha512 or blake3
+	HashAlgorithm string `json:"hash_algorithm,omitempty"`
+
+	// NotificationChannels receive a webhook when executions finish. Tasks can
+	// mute channels or add task_only ones through their notifications settings.
+	NotificationChannels []NotificationChannel `json:"notification_channels,omitempty"`
+
+	// Heartbeat is used by tasks that don't set their own
+	Heartbeat *Heartbeat `json:"heartbeat,omitempty"`
+
+	// ScanWorkers reads this many source directories at once when sizing sources
+	// for archives and dry runs, which helps on network filesystems (0 or 1 = serial)
+	ScanWorkers int `json:"scan_workers,omitempty"`
+
+	// AllowedOrigins may call the API from a browser, such as "https://ui.example.com";
+	// "*" allows any. The API's own origin is always allowed.
+	AllowedOrigins []string `json:"allowed_origins,omitempty"`
+
+	// Redaction applies to every task's archives
+	Redaction *Redaction `json:"redaction,omitempty"`
+
+	// DefaultRetention applies to tasks whose keep_last is 0. Tasks opt out
+	// with a keep_last of -1.
+	DefaultRetention *RetentionPolicy `json:"default_retention,omitempty"`
+
+	// MinFreeTempBytes is the free space archive builds keep in temp_dir; a
+	// build fails early once less is left. 0 keeps 256 MiB, -1 turns the check off.
+	MinFreeTempBytes int64 `json:"min_free_temp_bytes,omitempty"`
+
+	// StateBackup uploads the configuration and the database to a backend
+	StateBackup *StateBackup `json:"state_backup,omitempty"`
+}
+
+// StateBackup backs up Archivist's own state: config.json and a snapshot of
+// the database, in one tar.gz archive
+type StateBackup struct {
+	BackendID string `json:"backend_id"`
+	Schedule  string `json:"schedule,omitempty"`  // cron expression; empty backs up only on request
+	KeepLast  int    `json:"keep_last,omitempty"` // newest state backups kept on the backend; 0 keeps them all
+}
+
+// NotificationChannel is a webhook notified about finished executions
+type NotificationChannel struct {
+	Name     string   `json:"name"`
+	URL      string   `json:"url"`
+	Events   []string `json:"events,omitempty"`    // success, partial, failure; empty means all
+	TaskOnly bool     `json:"task_only,omitempty"` // Only notify for tasks that list this channel
+}
+
+// VacuumResult reports the outcome of compacting the database
+type VacuumResult struct {
+	SizeBefore     int64 `json:"size_before"`
+	SizeAfter      int64 `json:"size_after"`
+	ReclaimedBytes int64 `json:"reclaimed_bytes"`
+	DurationMs     int64 `json:"duration_ms"`
+}
+
+// StateBackupResult reports an upload of the configuration and database
+type StateBackupResult struct {
+	BackendID    string    `json:"backend_id"`
+	RemotePath   string    `json:"remote_path"`
+	Size         int64     `json:"size"`
+	Hash         string    `json:"hash"`
+	DatabaseSize int64     `json:"database_size"`
+	Pruned       []string  `json:"pruned,omitempty"` // older state backups removed under keep_last
+	CreatedAt    time.Time `json:"created_at"`
+	DurationMs   int64     `json:"duration_ms"`
+}
+
+// ConfigProblem is a single issue found while validating a configuration
+type ConfigProblem struct {
+	Field   string `json:"field"` // e.g. "tasks[2].schedule"
+	Message string `json:"message"`
 }
 
 // Execution represents a backup task execution record
@@ -87,27 +277,176 @@ type Execution struct {
 	BackendResults []BackendResult `json:"backend_results,omitempty"`
 	ErrorMessage   string          `json:"error_message,omitempty"`
 	DurationMs     int64           `json:"duration_ms,omitempty"`
+	Compression    string          `json:"compression,omitempty"`  // codec the archive was written with: none, gzip, zstd, zstd-best, deflate
+	SourceFiles    int64           `json:"source_files,omitempty"` // files found in the source when the run started
+	RequestID      string          `json:"request_id,omitempty"`   // API request that started the run, if any
+	Attempt        int             `json:"attempt,omitempty"`      // 1 for a scheduled run, higher for its re-runs after failure; 0 if not scheduled
+	Label          string          `json:"label,omitempty"`        // reason given when the run was started, such as "pre-deploy backup" or "run on startup"
+
+	RetentionDeletions []RetentionDeletion `json:"retention_deletions,omitempty"`
+}
+
+// ArchiveChunk is one content-defined chunk of a chunked archive
+type ArchiveChunk struct {
+	Hash   string `json:"hash"` // hex sha256 of the chunk contents
+	Offset int64  `json:"offset"`
+	Size   int64  `json:"size"`
+}
+
+// ArchiveContents lists the files stored in an execution's archive. Very large
+// trees keep only the first files; the counts always cover the whole archive.
+type ArchiveContents struct {
+	ExecutionID string         `json:"execution_id"`
+	FileCount   int            `json:"file_count"`
+	TotalSize   int64          `json:"total_size"`
+	Truncated   bool           `json:"truncated"`
+	Files       []ArchiveFile  `json:"files"`
+	Redacted    []RedactedFile `json:"redacted,omitempty"`
+}
+
+// RedactedFile is a source file or directory that matched the redaction patterns
+type RedactedFile struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`   // size in the source
+	Action string `json:"action"` // skipped or zeroed
+}
+
+// ArchiveFile is one file in an archive
+type ArchiveFile struct {
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+}
+
+// RunningExecution describes an execution that is currently in progress
+type RunningExecution struct {
+	ExecutionID string    `json:"execution_id"`
+	TaskID      string    `json:"task_id"`
+	TaskName    string    `json:"task_name"`
+	StartedAt   time.Time `json:"started_at"`
+	ElapsedMs   int64     `json:"elapsed_ms"`
+
+	// Progress is the latest progress event for the execution, if any
+	Progress *ProgressEvent `json:"progress,omitempty"`
+}
+
+// QueuedExecution describes a task run waiting for a free execution slot
+type QueuedExecution struct {
+	ExecutionID string    `json:"execution_id"`
+	TaskID      string    `json:"task_id"`
+	TaskName    string    `json:"task_name"`
+	EnqueuedAt  time.Time `json:"enqueued_at"`
+	Position    int       `json:"position"` // 1 is the next to start
+}
+
+// RetentionDeletion records a remote backup removed by the retention policy
+type RetentionDeletion struct {
+	BackendID   string    `json:"backend_id"`
+	BackendName string    `json:"backend_name"`
+	RemotePath  string    `json:"remote_path"`
+	DeletedAt   time.Time `json:"deleted_at"`
+}
+
+// BackupDeletion records a remote backup deleted by hand through the API
+type BackupDeletion struct {
+	TaskID      string    `json:"task_id"`
+	BackendID   string    `json:"backend_id"`
+	BackendName string    `json:"backend_name"`
+	RemotePath  string    `json:"remote_path"`
+	DeletedAt   time.Time `json:"deleted_at"`
+}
+
+// RemoteBackup is one of a task's backups on a backend. A split archive is
+// listed once under its archive name, with its parts.
+type RemoteBackup struct {
+	Path         string   `json:"path"`
+	Size         int64    `json:"size"`
+	LastModified string   `json:"last_modified"`
+	Parts        []string `json:"parts,omitempty"`
+}
+
+// BackendBackups lists a task's backups on one backend
+type BackendBackups struct {
+	BackendID   string         `json:"backend_id"`
+	BackendName string         `json:"backend_name"`
+	Immutable   bool           `json:"immutable"`
+	Total       int            `json:"total"` // backups matching the filter, across all pages
+	Backups     []RemoteBackup `json:"backups"`
+	Error       string         `json:"error,omitempty"`
+}
+
+// BackupVerification is the report of an integrity check of a task's backups
+type BackupVerification struct {
+	ID           string           `json:"id"`
+	TaskID       string           `json:"task_id"`
+	Status       string           `json:"status"` // running, completed, failed
+	StartedAt    time.Time        `json:"started_at"`
+	CompletedAt  *time.Time       `json:"completed_at,omitempty"`
+	Intact       int              `json:"intact"`
+	Corrupt      int              `json:"corrupt"`
+	Unverified   int              `json:"unverified"`
+	ErrorMessage string           `json:"error_message,omitempty"`
+	Results      []VerifiedBackup `json:"results"`
+}
+
+// VerifiedBackup is the outcome of checking one backup
+type VerifiedBackup struct {
+	BackendID    string `json:"backend_id"`
+	BackendName  string `json:"backend_name"`
+	Path         string `json:"path"`
+	Status       string `json:"status"`           // intact, corrupt, unverified
+	Method       string `json:"method,omitempty"` // remote_hash (compared without downloading), download
+	ExpectedHash string `json:"expected_hash,omitempty"`
+	ErrorMessage string `json:"error_message,omitempty"`
 }
 
 // BackendResult represents the result of uploading to a backend
 type BackendResult struct {
 	BackendID    string     `json:"backend_id"`
 	BackendName  string     `json:"backend_name"`
-	Status       string     `json:"status"` // success, failed
+	Status       string     `json:"status"`         // success, failed
+	Mode         string     `json:"mode,omitempty"` // archive, sync
 	UploadedAt   *time.Time `json:"uploaded_at,omitempty"`
 	Size         int64      `json:"size,omitempty"`
 	RemotePath   string     `json:"remote_path,omitempty"`
 	ErrorMessage string     `json:"error_message,omitempty"`
+	ErrorCode    string     `json:"error_code,omitempty"` // auth, not_found, network, quota, unknown
+	DurationMs   int64      `json:"duration_ms"`          // Time spent on this backend, including failed attempts
 }
 
 // TaskStats represents statistics for a task
 type TaskStats struct {
-	TotalExecutions     int    `json:"total_executions"`
-	SuccessCount        int    `json:"success_count"`
-	FailureCount        int    `json:"failure_count"`
-	LastExecutionStatus string `json:"last_execution_status"`
-	AverageDurationMs   int64  `json:"average_duration_ms"`
-	LastArchiveSize     int64  `json:"last_archive_size"`
+	TotalExecutions     int        `json:"total_executions"`
+	SuccessCount        int        `json:"success_count"`
+	FailureCount        int        `json:"failure_count"`
+	SuccessRate         float64    `json:"success_rate"` // percent of successful and failed runs that succeeded
+	LastRun             *time.Time `json:"last_run,omitempty"`
+	LastExecutionStatus string     `json:"last_execution_status"`
+	AverageDurationMs   int64      `json:"average_duration_ms"`
+	LastArchiveSize     int64      `json:"last_archive_size"`
+	LastSuccessfulRun   *time.Time `json:"last_successful_run,omitempty"`
+	Stale               bool       `json:"stale"`
+}
+
+// TaskTrends holds time-bucketed execution size and duration for a task
+type TaskTrends struct {
+	TaskID        string        `json:"task_id"`
+	Start         time.Time     `json:"start"`
+	End           time.Time     `json:"end"`
+	BucketSeconds int64         `json:"bucket_seconds"`
+	Buckets       []TrendBucket `json:"buckets"`
+}
+
+// TrendBucket aggregates the executions started within one bucket.
+// Buckets without executions are included with zero values so series stay continuous.
+type TrendBucket struct {
+	Start          time.Time `json:"start"`
+	Executions     int       `json:"executions"`
+	SuccessCount   int       `json:"success_count"`
+	FailureCount   int       `json:"failure_count"`
+	AvgArchiveSize int64     `json:"avg_archive_size"`
+	MaxArchiveSize int64     `json:"max_archive_size"`
+	AvgDurationMs  int64     `json:"avg_duration_ms"`
+	MaxDurationMs  int64     `json:"max_duration_ms"`
 }
 
 // SourceInfo represents information about a source directory
@@ -127,6 +466,24 @@ type StorageUsage struct {
 	Total int64 `json:"total"` // -1 if unlimited
 }
 
+// StorageOverview sums storage usage across enabled backends
+type StorageOverview struct {
+	Used          int64                 `json:"used"`
+	Total         int64                 `json:"total"`          // Sum of known capacities, -1 if none is known
+	CapacityKnown bool                  `json:"capacity_known"` // False if any backend's capacity is unlimited or unavailable
+	Backends      []BackendStorageUsage `json:"backends"`
+	CheckedAt     time.Time             `json:"checked_at"`
+}
+
+// BackendStorageUsage is one backend's share of the storage overview
+type BackendStorageUsage struct {
+	BackendID   string `json:"backend_id"`
+	BackendName string `json:"backend_name"`
+	Used        int64  `json:"used"`
+	Total       int64  `json:"total"` // -1 if unlimited or unknown
+	Error       string `json:"error,omitempty"`
+}
+
 // SystemStats represents system statistics
 type SystemStats struct {
 	Tasks      TasksStats      `json:"tasks"`
@@ -202,6 +559,20 @@ type UploadProgress struct {
 	SpeedBytesPerSec int64   `json:"speed_bytes_per_sec"`
 }
 
+// MigrationProgress represents progress of copying objects between backends
+type MigrationProgress struct {
+	MigrationID          string    `json:"migration_id"`
+	SourceBackendID      string    `json:"source_backend_id"`
+	DestinationBackendID string    `json:"destination_backend_id"`
+	ObjectsTotal         int       `json:"objects_total"`
+	ObjectsCopied        int       `json:"objects_copied"`
+	ObjectsFailed        int       `json:"objects_failed"`
+	BytesCopied          int64     `json:"bytes_copied"`
+	CurrentObject        string    `json:"current_object,omitempty"`
+	StartedAt            time.Time `json:"started_at"`
+	Errors               []string  `json:"errors,omitempty"`
+}
+
 // DryRunResult represents the result of a dry run operation
 type DryRunResult struct {
 	TaskID         string          `json:"task_id"`
@@ -213,8 +584,48 @@ type DryRunResult struct {
 	SyncDetails    *SyncDetails    `json:"sync_details,omitempty"`
 	BackendPlans   []BackendPlan   `json:"backend_plans"`
 	AnalyzedAt     time.Time       `json:"analyzed_at"`
-	DurationMs     int64           `json:"duration_ms"`
-	Errors         []string        `json:"errors,omitempty"`
+
+	// RetentionPlan lists the backups retention would prune after this run, assuming every upload succeeds
+	RetentionPlan *RetentionPreview `json:"retention_plan,omitempty"`
+	DurationMs    int64             `json:"duration_ms"`
+	Errors        []string          `json:"errors,omitempty"`
+	Warnings      []string          `json:"warnings,omitempty"`
+}
+
+// RestorePlan is the result of a dry-run restore: what restoring an archive
+// would write, and whether the archive reads back cleanly and safely
+type RestorePlan struct {
+	BackendID   string   `json:"backend_id"`
+	RemotePath  string   `json:"remote_path"`
+	Parts       []string `json:"parts,omitempty"`     // the objects of a split archive, in order
+	LocalPath   string   `json:"local_path"`          // where a restore would write the archive
+	Conflicts   []string `json:"conflicts,omitempty"` // existing files a restore would overwrite or resume from, or its entries overwrite if extracted there
+	Extractable bool     `json:"extractable"`         // read to the end without errors or unsafe entries
+	Error       string   `json:"error,omitempty"`
+
+	ConflictsTruncated bool `json:"conflicts_truncated,omitempty"`
+
+	FileCount        int                  `json:"file_count"`
+	DirCount         int                  `json:"dir_count"`
+	TotalSize        int64                `json:"total_size"` // uncompressed bytes of all files
+	Entries          []RestoreEntry       `json:"entries"`
+	EntriesTruncated bool                 `json:"entries_truncated,omitempty"`
+	Unsafe           []UnsafeRestoreEntry `json:"unsafe,omitempty"`
+	DurationMs       int64                `json:"duration_ms"`
+}
+
+// RestoreEntry is an entry of an archive, as listed by a dry-run restore
+type RestoreEntry struct {
+	Path     string `json:"path"`
+	Type     string `json:"type"` // file, dir, symlink, hardlink or other
+	Size     int64  `json:"size"`
+	Linkname string `json:"linkname,omitempty"`
+}
+
+// UnsafeRestoreEntry is an archive entry that extracting would write outside its directory
+type UnsafeRestoreEntry struct {
+	Path   string `json:"path"`
+	Reason string `json:"reason"`
 }
 
 // FilesSummary summarizes files to be backed up
@@ -226,6 +637,8 @@ type FilesSummary struct {
 	LargestFileSize int64          `json:"largest_file_size"`
 	FileTypes       map[string]int `json:"file_types"` // extension -> count
 	TopFiles        []FileDetail   `json:"top_files"`  // Top 10 largest files
+
+	IncompressibleSize int64 `json:"incompressible_size"` // Bytes in already-compressed formats
 }
 
 // ArchiveDetails provides details about archive that would be created
@@ -0,0 +1,171 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/nsilverman/archivist/internal/models"
+)
+
+// Events a notification channel can subscribe to
+const (
+	EventSuccess = "success" // every backend succeeded
+	EventPartial = "partial" // some backends failed
+	EventFailure = "failure" // the execution failed
+)
+
+// requestTimeout bounds each webhook delivery
+const requestTimeout = 10 * time.Second
+
+// Payload is the JSON body posted to a channel
+type Payload struct {
+	Event        string     `json:"event"`
+	TaskID       string     `json:"task_id"`
+	TaskName     string     `json:"task_name"`
+	ExecutionID  string     `json:"execution_id"`
+	Status       string     `json:"status"`
+	ErrorMessage string     `json:"error_message,omitempty"`
+	CompletedAt  *time.Time `json:"completed_at,omitempty"`
+	DurationMs   int64      `json:"duration_ms"`
+}
+
+// ValidateEvent checks that event is one channels can subscribe to
+func ValidateEvent(event string) error {
+	switch event {
+	case EventSuccess, EventPartial, EventFailure:
+		return nil
+	default:
+		return fmt.Errorf("unknown notification event %q (use %s, %s or %s)", event, EventSuccess, EventPartial, EventFailure)
+	}
+}
+
+// ValidateURL checks that a channel or heartbeat URL is an absolute http(s) URL
+func ValidateURL(rawURL string) error {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("URL must be an http or https URL: %q", rawURL)
+	}
+	return nil
+}
+
+// Route returns the channels that should receive event for a task. Global
+// channels apply unless muted by the task; task_only channels apply only to
+// tasks that list them. The task's events, if set, replace each channel's own.
+func Route(channels []models.NotificationChannel, task *models.Task, event string) []models.NotificationChannel {
+	overrides := task.Notifications
+	if overrides == nil {
+		overrides = &models.TaskNotifications{}
+	}
+	if overrides.Silent {
+		return nil
+	}
+
+	var routed []models.NotificationChannel
+	for _, channel := range channels {
+		listed := contains(overrides.Channels, channel.Name)
+		if !listed && (channel.TaskOnly || contains(overrides.Muted, channel.Name)) {
+			continue
+		}
+
+		events := channel.Events
+		if len(overrides.Events) > 0 {
+			events = overrides.Events
+		}
+		if len(events) > 0 && !contains(events, event) {
+			continue
+		}
+
+		routed = append(routed, channel)
+	}
+	return routed
+}
+
+// Notifier delivers payloads to webhook channels
+type Notifier struct {
+	client *http.Client
+}
+
+// NewNotifier creates a notifier
+func NewNotifier() *Notifier {
+	return &Notifier{
+		client: &http.Client{Timeout: requestTimeout},
+	}
+}
+
+// Send posts the payload to every channel in the background. Delivery
+// failures are logged; they never affect the execution being reported.
+func (n *Notifier) Send(channels []models.NotificationChannel, payload Payload) {
+	if len(channels) == 0 {
+		return
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("Error encoding notification: %v", err)
+		return
+	}
+
+	for _, channel := range channels {
+		go func(channel models.NotificationChannel) {
+			if err := n.request(http.MethodPost, channel.URL, body); err != nil {
+				log.Printf("Error sending notification to channel %s: %v", channel.Name, err)
+			}
+		}(channel)
+	}
+}
+
+// Ping requests a heartbeat URL. It waits for the response so pings for one
+// run arrive in order.
+func (n *Notifier) Ping(target string) error {
+	return n.request(http.MethodGet, target, nil)
+}
+
+// request sends one notification or ping, with a JSON body if given
+func (n *Notifier) request(method, target string, body []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			log.Printf("Error closing response: %v", err)
+		}
+	}()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+	return nil
+}
+
+// contains reports whether list holds value
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
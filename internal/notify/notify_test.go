@@ -0,0 +1,80 @@
+package notify
+
+import (
+	"testing"
+
+	"github.com/nsilverman/archivist/internal/models"
+)
+
+func TestValidateEvent(t *testing.T) {
+	for _, event := range []string{EventSuccess, EventPartial, EventFailure} {
+		if err := ValidateEvent(event); err != nil {
+			t.Errorf("ValidateEvent(%s): %v", event, err)
+		}
+	}
+	for _, event := range []string{"", "Success", "started"} {
+		if err := ValidateEvent(event); err == nil {
+			t.Errorf("ValidateEvent(%q) succeeded", event)
+		}
+	}
+}
+
+func TestValidateURL(t *testing.T) {
+	tests := []struct {
+		url     string
+		wantErr bool
+	}{
+		{"https://hooks.example.com/archivist", false},
+		{"http://localhost:8080/ping", false},
+		{"", true},
+		{"hooks.example.com/archivist", true},
+		{"ftp://example.com/", true},
+		{"https://", true},
+		{"https://exa mple.com/%zz", true},
+	}
+	for _, tt := range tests {
+		if err := ValidateURL(tt.url); (err != nil) != tt.wantErr {
+			t.Errorf("ValidateURL(%q) = %v, want error %v", tt.url, err, tt.wantErr)
+		}
+	}
+}
+
+func TestRoute(t *testing.T) {
+	channels := []models.NotificationChannel{
+		{Name: "all"},
+		{Name: "failures", Events: []string{EventFailure, EventPartial}},
+		{Name: "oncall", TaskOnly: true},
+	}
+
+	tests := []struct {
+		name          string
+		notifications *models.TaskNotifications
+		event         string
+		want          []string
+	}{
+		{"global channels", nil, EventSuccess, []string{"all"}},
+		{"channel events", nil, EventFailure, []string{"all", "failures"}},
+		{"silent", &models.TaskNotifications{Silent: true}, EventFailure, nil},
+		{"task only channel listed", &models.TaskNotifications{Channels: []string{"oncall"}}, EventSuccess, []string{"all", "oncall"}},
+		{"muted", &models.TaskNotifications{Muted: []string{"all"}}, EventFailure, []string{"failures"}},
+		{"listed wins over muted", &models.TaskNotifications{Channels: []string{"all"}, Muted: []string{"all"}}, EventSuccess, []string{"all"}},
+		{"task events replace the channel's", &models.TaskNotifications{Events: []string{EventSuccess}}, EventSuccess, []string{"all", "failures"}},
+		{"task events filter", &models.TaskNotifications{Events: []string{EventSuccess}}, EventFailure, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, channel := range Route(channels, &models.Task{Notifications: tt.notifications}, tt.event) {
+				got = append(got, channel.Name)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("routed to %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("routed to %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package scheduler
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"sync"
@@ -9,26 +10,43 @@ import (
 	"github.com/nsilverman/archivist/internal/config"
 	"github.com/nsilverman/archivist/internal/executor"
 	"github.com/nsilverman/archivist/internal/models"
+	"github.com/nsilverman/archivist/internal/storage"
 	"github.com/robfig/cron/v3"
 )
 
+// Labels recorded on runs started at startup, so history tells them apart
+// from scheduled runs
+const (
+	StartupLabel   = "run on startup"
+	MissedRunLabel = "missed scheduled run"
+)
+
 // Scheduler manages task scheduling
 type Scheduler struct {
 	cron     *cron.Cron
 	config   *config.Manager
 	executor *executor.Executor
+	db       storage.Store
 	entries  map[string]cron.EntryID // taskID -> entryID
+	vacuum   cron.EntryID            // zero when auto-vacuum is disabled
+	state    cron.EntryID            // zero when the state backup isn't scheduled
+	retries  map[string]*time.Timer  // taskID -> pending re-run of a failed scheduled run
+	running  bool
 	mu       sync.RWMutex
 }
 
 // NewScheduler creates a new scheduler
-func NewScheduler(exec *executor.Executor, cfg *config.Manager) *Scheduler {
-	return &Scheduler{
+func NewScheduler(exec *executor.Executor, cfg *config.Manager, db storage.Store) *Scheduler {
+	s := &Scheduler{
 		cron:     cron.New(),
 		config:   cfg,
 		executor: exec,
+		db:       db,
 		entries:  make(map[string]cron.EntryID),
+		retries:  make(map[string]*time.Timer),
 	}
+	exec.OnFinished(s.executionFinished)
+	return s
 }
 
 // Start starts the scheduler
@@ -43,17 +61,89 @@ func (s *Scheduler) Start() error {
 		}
 	}
 
+	if err := s.ScheduleMaintenance(); err != nil {
+		log.Printf("Failed to schedule database maintenance: %v", err)
+	}
+
 	s.cron.Start()
+
+	s.mu.Lock()
+	s.running = true
+	s.mu.Unlock()
+
 	log.Println("Scheduler started")
+
+	s.runAtStartup(tasks, time.Now())
 	return nil
 }
 
+// runAtStartup starts one run of each enabled task that runs on every startup,
+// and one catch-up run of each task that opted in and missed a scheduled run
+// while the process was down. A task that is both gets a single run. Runs go
+// through the executor's queue, so max_concurrent_tasks still applies. They
+// are labelled with the reason and, unlike scheduled runs, not retried.
+func (s *Scheduler) runAtStartup(tasks []models.Task, now time.Time) {
+	for _, task := range tasks {
+		var label string
+		switch {
+		case task.RunOnStartup && task.Enabled:
+			log.Printf("Task %s runs on startup, running it now", task.Name)
+			label = StartupLabel
+		case missedRun(&task, now):
+			log.Printf("Task %s missed a scheduled run (last run %s), running it now", task.Name, task.LastRun.Format(time.RFC3339))
+			label = MissedRunLabel
+		default:
+			continue
+		}
+
+		if _, err := s.executor.ExecuteStartup(task.ID, label); err != nil {
+			log.Printf("Failed to execute startup run of task %s: %v", task.Name, err)
+		}
+	}
+}
+
+// missedRun reports whether a scheduled run of task fell between its last run
+// and now. However many runs were missed, the answer is a single yes, so at
+// most one catch-up runs. Tasks that have never run have nothing to catch up.
+func missedRun(task *models.Task, now time.Time) bool {
+	if !task.RunMissedOnStartup || !task.Enabled || task.Schedule.Type == "manual" || task.LastRun == nil {
+		return false
+	}
+
+	cronExpr, err := scheduleToCron(task.Schedule)
+	if err != nil {
+		return false
+	}
+	schedule, err := cron.ParseStandard(cronExpr)
+	if err != nil {
+		return false
+	}
+
+	return schedule.Next(*task.LastRun).Before(now)
+}
+
 // Stop stops the scheduler
 func (s *Scheduler) Stop() {
 	s.cron.Stop()
+
+	s.mu.Lock()
+	s.running = false
+	for taskID, timer := range s.retries {
+		timer.Stop()
+		delete(s.retries, taskID)
+	}
+	s.mu.Unlock()
+
 	log.Println("Scheduler stopped")
 }
 
+// IsRunning reports whether the scheduler has been started and not stopped
+func (s *Scheduler) IsRunning() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.running
+}
+
 // ScheduleTask adds or updates a task in the scheduler
 func (s *Scheduler) ScheduleTask(taskID string) error {
 	task, err := s.config.GetTask(taskID)
@@ -82,12 +172,13 @@ func (s *Scheduler) UnscheduleTask(taskID string) {
 		delete(s.entries, taskID)
 		log.Printf("Unscheduled task: %s", taskID)
 	}
+	s.cancelRetry(taskID)
 }
 
 // scheduleTask adds a task to the cron scheduler
 func (s *Scheduler) scheduleTask(task *models.Task) error {
 	// Convert schedule to cron expression
-	cronExpr, err := s.scheduleToCron(task.Schedule)
+	cronExpr, err := scheduleToCron(task.Schedule)
 	if err != nil {
 		return fmt.Errorf("invalid schedule: %w", err)
 	}
@@ -95,7 +186,13 @@ func (s *Scheduler) scheduleTask(task *models.Task) error {
 	// Add to cron
 	entryID, err := s.cron.AddFunc(cronExpr, func() {
 		log.Printf("Executing scheduled task: %s", task.Name)
-		if _, err := s.executor.Execute(task.ID); err != nil {
+
+		// A new scheduled run replaces any pending re-run of the last one
+		s.mu.Lock()
+		s.cancelRetry(task.ID)
+		s.mu.Unlock()
+
+		if _, err := s.executor.ExecuteScheduled(task.ID, 1); err != nil {
 			log.Printf("Failed to execute task %s: %v", task.Name, err)
 		}
 	})
@@ -119,11 +216,143 @@ func (s *Scheduler) scheduleTask(task *models.Task) error {
 	return nil
 }
 
+// executionFinished schedules a re-run of a failed scheduled execution when
+// its task's failure retry settings allow another attempt
+func (s *Scheduler) executionFinished(execution models.Execution) {
+	if execution.Attempt == 0 || execution.Status != "failed" {
+		return
+	}
+	task, err := s.config.GetTask(execution.TaskID)
+	if err != nil {
+		return
+	}
+	if !executor.FailureRetryPending(task, &execution) {
+		if task.FailureRetry != nil {
+			log.Printf("Task %s failed on attempt %d, not retrying again", task.Name, execution.Attempt)
+		}
+		return
+	}
+
+	taskID := task.ID
+	attempt := execution.Attempt + 1
+	delay := time.Duration(task.FailureRetry.DelayMinutes) * time.Minute
+	log.Printf("Scheduled run of task %s failed, running it again in %s (attempt %d of %d)", task.Name, delay, attempt, task.FailureRetry.Attempts+1)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cancelRetry(taskID)
+	var timer *time.Timer
+	timer = time.AfterFunc(delay, func() {
+		s.mu.Lock()
+		current := s.retries[taskID] == timer
+		if current {
+			delete(s.retries, taskID)
+		}
+		s.mu.Unlock()
+		if !current {
+			return
+		}
+
+		if _, err := s.executor.ExecuteScheduled(taskID, attempt); err != nil {
+			log.Printf("Failed to re-run task %s: %v", taskID, err)
+		}
+	})
+	s.retries[taskID] = timer
+}
+
+// cancelRetry stops a pending re-run of the task. The caller must hold s.mu.
+func (s *Scheduler) cancelRetry(taskID string) {
+	if timer, ok := s.retries[taskID]; ok {
+		timer.Stop()
+		delete(s.retries, taskID)
+	}
+}
+
+// ScheduleMaintenance registers the periodic database vacuum from the
+// auto_vacuum_schedule setting and the state backup from the state_backup
+// setting, replacing any previous registrations
+func (s *Scheduler) ScheduleMaintenance() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.vacuum != 0 {
+		s.cron.Remove(s.vacuum)
+		s.vacuum = 0
+	}
+	if s.state != 0 {
+		s.cron.Remove(s.state)
+		s.state = 0
+	}
+
+	settings := s.config.GetSettings()
+	if err := s.scheduleStateBackup(settings.StateBackup); err != nil {
+		return err
+	}
+
+	cronExpr := settings.AutoVacuumSchedule
+	if cronExpr == "" {
+		return nil
+	}
+
+	entryID, err := s.cron.AddFunc(cronExpr, func() {
+		result, err := s.db.Vacuum(context.Background())
+		if err != nil {
+			log.Printf("Scheduled database vacuum failed: %v", err)
+			return
+		}
+		log.Printf("Scheduled database vacuum reclaimed %d bytes", result.ReclaimedBytes)
+	})
+	if err != nil {
+		return fmt.Errorf("invalid auto-vacuum schedule: %w", err)
+	}
+
+	s.vacuum = entryID
+	log.Printf("Scheduled database vacuum with expression: %s", cronExpr)
+	return nil
+}
+
+// scheduleStateBackup registers the periodic state backup. The caller must hold s.mu.
+func (s *Scheduler) scheduleStateBackup(stateBackup *models.StateBackup) error {
+	if stateBackup == nil || stateBackup.Schedule == "" {
+		return nil
+	}
+
+	backendID := stateBackup.BackendID
+	entryID, err := s.cron.AddFunc(stateBackup.Schedule, func() {
+		if _, err := s.executor.BackupState(context.Background(), backendID); err != nil {
+			log.Printf("Scheduled state backup failed: %v", err)
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("invalid state backup schedule: %w", err)
+	}
+
+	s.state = entryID
+	log.Printf("Scheduled state backup with expression: %s", stateBackup.Schedule)
+	return nil
+}
+
+// ValidateSchedule checks that a schedule can be registered. Manual schedules are always valid.
+func ValidateSchedule(schedule models.Schedule) error {
+	if schedule.Type == "manual" {
+		return nil
+	}
+
+	cronExpr, err := scheduleToCron(schedule)
+	if err != nil {
+		return err
+	}
+	if _, err := cron.ParseStandard(cronExpr); err != nil {
+		return fmt.Errorf("invalid cron expression %q: %w", cronExpr, err)
+	}
+	return nil
+}
+
 // scheduleToCron converts a Schedule to a cron expression
-func (s *Scheduler) scheduleToCron(schedule models.Schedule) (string, error) {
+func scheduleToCron(schedule models.Schedule) (string, error) {
 	switch schedule.Type {
 	case "simple":
-		return s.simpleScheduleToCron(schedule.SimpleType)
+		return simpleScheduleToCron(schedule.SimpleType)
 	case "cron":
 		if schedule.CronExpr == "" {
 			return "", fmt.Errorf("cron expression is empty")
@@ -137,7 +366,7 @@ func (s *Scheduler) scheduleToCron(schedule models.Schedule) (string, error) {
 }
 
 // simpleScheduleToCron converts simple schedule types to cron expressions
-func (s *Scheduler) simpleScheduleToCron(simpleType string) (string, error) {
+func simpleScheduleToCron(simpleType string) (string, error) {
 	switch simpleType {
 	case "hourly":
 		return "0 * * * *", nil // Every hour at minute 0
@@ -191,6 +420,11 @@ func (s *Scheduler) ReloadSchedules() error {
 		}
 	}
 
+	if err := s.ScheduleMaintenance(); err != nil {
+		log.Printf("Failed to schedule database maintenance: %v", err)
+		errors = append(errors, err)
+	}
+
 	if len(errors) > 0 {
 		return fmt.Errorf("failed to schedule %d task(s)", len(errors))
 	}
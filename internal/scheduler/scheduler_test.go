@@ -0,0 +1,228 @@
+package scheduler
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/nsilverman/archivist/internal/config"
+	"github.com/nsilverman/archivist/internal/executor"
+	"github.com/nsilverman/archivist/internal/models"
+	"github.com/nsilverman/archivist/internal/storage"
+)
+
+// newTestScheduler returns a scheduler over a fresh config and database in
+// a temporary directory, with a local backend "local"
+func newTestScheduler(t *testing.T) (*Scheduler, *config.Manager, storage.Store) {
+	t.Helper()
+	dir := t.TempDir()
+	cfg, err := config.NewManager(filepath.Join(dir, "config", "config.json"), dir)
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+	if err := cfg.CreateDefaultWithPaths(filepath.Join(dir, "temp"), filepath.Join(dir, "sources")); err != nil {
+		t.Fatalf("CreateDefaultWithPaths: %v", err)
+	}
+	if err := cfg.AddBackend(&models.Backend{
+		ID:      "local",
+		Name:    "local",
+		Type:    "local",
+		Enabled: true,
+		Config:  map[string]interface{}{"path": filepath.Join(dir, "store")},
+	}); err != nil {
+		t.Fatalf("AddBackend: %v", err)
+	}
+
+	db, err := storage.NewDatabase(filepath.Join(dir, "config", "archivist.db"))
+	if err != nil {
+		t.Fatalf("NewDatabase: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := db.Close(); err != nil {
+			t.Errorf("closing database: %v", err)
+		}
+	})
+
+	return NewScheduler(executor.NewExecutor(cfg, db), cfg, db), cfg, db
+}
+
+// runStartup adds the tasks, each archiving a one-file source to "local", and
+// starts them as at startup. It waits for wantRuns runs to finish and returns
+// the executions recorded for each task.
+func runStartup(t *testing.T, tasks []*models.Task, wantRuns int) map[string][]models.Execution {
+	t.Helper()
+	s, cfg, db := newTestScheduler(t)
+	for _, task := range tasks {
+		task.Name = task.ID
+		task.SourcePath = t.TempDir()
+		if err := os.WriteFile(filepath.Join(task.SourcePath, "a.txt"), []byte("contents"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		task.BackendIDs = []string{"local"}
+		task.ArchiveOptions = models.ArchiveOptions{Format: "tar.gz", UseTimestamp: true}
+		if err := cfg.AddTask(task); err != nil {
+			t.Fatalf("AddTask(%s): %v", task.ID, err)
+		}
+	}
+
+	finished := make(chan models.Execution, len(tasks))
+	s.executor.OnFinished(func(execution models.Execution) { finished <- execution })
+
+	s.runAtStartup(cfg.GetTasks(), time.Now())
+
+	for i := 0; i < wantRuns; i++ {
+		select {
+		case <-finished:
+		case <-time.After(30 * time.Second):
+			t.Fatalf("%d of %d startup runs finished", i, wantRuns)
+		}
+	}
+
+	// Runs are recorded as they start, so anything extra is already here
+	executions := make(map[string][]models.Execution)
+	for _, task := range tasks {
+		recorded, err := db.ListExecutions(task.ID, "", 10, 0)
+		if err != nil {
+			t.Fatalf("ListExecutions: %v", err)
+		}
+		executions[task.ID] = recorded
+	}
+	return executions
+}
+
+// checkStartupRuns checks that each task ran once with the given label and
+// attempt 0, or not at all for a label of ""
+func checkStartupRuns(t *testing.T, executions map[string][]models.Execution, wantLabels map[string]string) {
+	t.Helper()
+	for taskID, wantLabel := range wantLabels {
+		recorded := executions[taskID]
+		if wantLabel == "" {
+			if len(recorded) != 0 {
+				t.Errorf("task %s ran at startup", taskID)
+			}
+			continue
+		}
+		if len(recorded) != 1 {
+			t.Errorf("task %s ran %d times, want once", taskID, len(recorded))
+			continue
+		}
+		if recorded[0].Label != wantLabel || recorded[0].Attempt != 0 {
+			t.Errorf("task %s run recorded as %q attempt %d, want %q attempt 0",
+				taskID, recorded[0].Label, recorded[0].Attempt, wantLabel)
+		}
+	}
+}
+
+func TestRunOnStartup(t *testing.T) {
+	daily := models.Schedule{Type: "simple", SimpleType: "daily"}
+	executions := runStartup(t, []*models.Task{
+		{ID: "startup", RunOnStartup: true, Enabled: true, Schedule: daily},
+		{ID: "manual", RunOnStartup: true, Enabled: true, Schedule: models.Schedule{Type: "manual"}},
+		{ID: "disabled", RunOnStartup: true, Schedule: daily},
+		{ID: "scheduled", Enabled: true, Schedule: daily},
+	}, 2)
+
+	checkStartupRuns(t, executions, map[string]string{
+		"startup":   StartupLabel,
+		"manual":    StartupLabel,
+		"disabled":  "",
+		"scheduled": "",
+	})
+}
+
+func TestValidateSchedule(t *testing.T) {
+	tests := []struct {
+		name     string
+		schedule models.Schedule
+		wantErr  bool
+	}{
+		{"manual", models.Schedule{Type: "manual"}, false},
+		{"hourly", models.Schedule{Type: "simple", SimpleType: "hourly"}, false},
+		{"monthly", models.Schedule{Type: "simple", SimpleType: "monthly"}, false},
+		{"unknown simple type", models.Schedule{Type: "simple", SimpleType: "fortnightly"}, true},
+		{"cron", models.Schedule{Type: "cron", CronExpr: "*/15 * * * *"}, false},
+		{"cron descriptor", models.Schedule{Type: "cron", CronExpr: "@daily"}, false},
+		{"empty cron", models.Schedule{Type: "cron"}, true},
+		{"invalid cron", models.Schedule{Type: "cron", CronExpr: "61 * * * *"}, true},
+		{"cron with seconds", models.Schedule{Type: "cron", CronExpr: "0 0 2 * * *"}, true},
+		{"unknown type", models.Schedule{Type: "yearly"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateSchedule(tt.schedule)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateSchedule = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMissedRun(t *testing.T) {
+	now := time.Date(2025, 1, 27, 12, 0, 0, 0, time.Local)
+	ago := func(d time.Duration) *time.Time {
+		at := now.Add(-d)
+		return &at
+	}
+	daily := models.Schedule{Type: "simple", SimpleType: "daily"} // 2:00 AM
+
+	tests := []struct {
+		name   string
+		task   models.Task
+		missed bool
+	}{
+		{"missed a run", models.Task{Enabled: true, RunMissedOnStartup: true, Schedule: daily, LastRun: ago(48 * time.Hour)}, true},
+		{"missed several runs", models.Task{Enabled: true, RunMissedOnStartup: true, Schedule: daily, LastRun: ago(30 * 24 * time.Hour)}, true},
+		{"ran since the last scheduled time", models.Task{Enabled: true, RunMissedOnStartup: true, Schedule: daily, LastRun: ago(time.Hour)}, false},
+		{"not opted in", models.Task{Enabled: true, Schedule: daily, LastRun: ago(48 * time.Hour)}, false},
+		{"disabled", models.Task{RunMissedOnStartup: true, Schedule: daily, LastRun: ago(48 * time.Hour)}, false},
+		{"never run", models.Task{Enabled: true, RunMissedOnStartup: true, Schedule: daily}, false},
+		{"manual", models.Task{Enabled: true, RunMissedOnStartup: true, Schedule: models.Schedule{Type: "manual"}, LastRun: ago(48 * time.Hour)}, false},
+		{"invalid schedule", models.Task{Enabled: true, RunMissedOnStartup: true, Schedule: models.Schedule{Type: "cron", CronExpr: "bad"}, LastRun: ago(48 * time.Hour)}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := missedRun(&tt.task, now); got != tt.missed {
+				t.Errorf("missedRun = %v, want %v", got, tt.missed)
+			}
+		})
+	}
+}
+
+// TestRunAtStartupCatchesUpOnce starts tasks whose last run predates several
+// scheduled runs: each gets exactly one catch-up, not one per missed run
+func TestRunAtStartupCatchesUpOnce(t *testing.T) {
+	hourly := models.Schedule{Type: "simple", SimpleType: "hourly"}
+	longAgo := time.Now().Add(-10 * time.Hour)
+	justNow := time.Now()
+	executions := runStartup(t, []*models.Task{
+		{ID: "missed", RunMissedOnStartup: true, LastRun: &longAgo, Enabled: true, Schedule: hourly},
+		{ID: "both", RunOnStartup: true, RunMissedOnStartup: true, LastRun: &longAgo, Enabled: true, Schedule: hourly},
+		{ID: "up-to-date", RunMissedOnStartup: true, LastRun: &justNow, Enabled: true, Schedule: hourly},
+		{ID: "not-opted-in", LastRun: &longAgo, Enabled: true, Schedule: hourly},
+	}, 2)
+
+	checkStartupRuns(t, executions, map[string]string{
+		"missed":       MissedRunLabel,
+		"both":         StartupLabel,
+		"up-to-date":   "",
+		"not-opted-in": "",
+	})
+}
+
+func TestStartAndStop(t *testing.T) {
+	s, _, _ := newTestScheduler(t)
+	if s.IsRunning() {
+		t.Error("scheduler running before it was started")
+	}
+	if err := s.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if !s.IsRunning() {
+		t.Error("scheduler not running after Start")
+	}
+	s.Stop()
+	if s.IsRunning() {
+		t.Error("scheduler still running after Stop")
+	}
+}
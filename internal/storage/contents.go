@@ -0,0 +1,79 @@
+package storage
+
+import (
+	"bytes"
+	"compress/gzip"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/nsilverman/archivist/internal/models"
+)
+
+// SaveArchiveContents records the file listing of an execution's archive
+func (d *Database) SaveArchiveContents(contents *models.ArchiveContents) error {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if err := json.NewEncoder(gz).Encode(contents.Files); err != nil {
+		return fmt.Errorf("failed to encode archive contents: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return fmt.Errorf("failed to compress archive contents: %w", err)
+	}
+
+	var redacted interface{} // NULL unless files were redacted
+	if len(contents.Redacted) > 0 {
+		data, err := json.Marshal(contents.Redacted)
+		if err != nil {
+			return fmt.Errorf("failed to encode redacted files: %w", err)
+		}
+		redacted = string(data)
+	}
+
+	d.writeMu.RLock()
+	defer d.writeMu.RUnlock()
+
+	_, err := d.db.Exec(`
+		INSERT OR REPLACE INTO archive_contents (execution_id, file_count, total_size, truncated, files, redacted)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, contents.ExecutionID, contents.FileCount, contents.TotalSize, contents.Truncated, buf.Bytes(), redacted)
+	return err
+}
+
+// GetArchiveContents returns the file listing of an execution's archive, or nil if none was recorded
+func (d *Database) GetArchiveContents(executionID string) (*models.ArchiveContents, error) {
+	contents := &models.ArchiveContents{ExecutionID: executionID}
+	var files []byte
+	var redacted sql.NullString
+
+	err := d.db.QueryRow(`
+		SELECT file_count, total_size, truncated, files, redacted
+		FROM archive_contents WHERE execution_id = ?
+	`, executionID).Scan(&contents.FileCount, &contents.TotalSize, &contents.Truncated, &files, &redacted)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	gz, err := gzip.NewReader(bytes.NewReader(files))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress archive contents: %w", err)
+	}
+	data, err := io.ReadAll(gz)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress archive contents: %w", err)
+	}
+	if err := json.Unmarshal(data, &contents.Files); err != nil {
+		return nil, fmt.Errorf("failed to decode archive contents: %w", err)
+	}
+	if redacted.Valid {
+		if err := json.Unmarshal([]byte(redacted.String), &contents.Redacted); err != nil {
+			return nil, fmt.Errorf("failed to decode redacted files: %w", err)
+		}
+	}
+
+	return contents, nil
+}
@@ -1,26 +1,41 @@
 package storage
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"log"
+	"sync"
 	"time"
 
 	_ "github.com/mattn/go-sqlite3"
 	"github.com/nsilverman/archivist/internal/models"
 )
 
+// maxOpenConns bounds the connection pool. WAL lets readers run alongside the
+// single writer, so a few connections are useful; more only add lock contention.
+const maxOpenConns = 4
+
 // Database handles all database operations
 type Database struct {
 	db *sql.DB
+
+	// writeMu is shared by writers and held exclusively by Vacuum
+	writeMu sync.RWMutex
 }
 
-// NewDatabase creates a new database connection
-func NewDatabase(path string) (*Database, error) {
-	db, err := sql.Open("sqlite3", path)
+// NewDatabase opens the SQLite database at path, creating its schema as needed
+func NewDatabase(path string) (Store, error) {
+	// Pragmas go in the DSN so they apply to every pooled connection:
+	// WAL for concurrent readers, a busy timeout instead of immediate
+	// "database is locked" errors, and enforced foreign keys
+	dsn := path + "?_journal_mode=WAL&_busy_timeout=5000&_foreign_keys=on"
+	db, err := sql.Open("sqlite3", dsn)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
+	db.SetMaxOpenConns(maxOpenConns)
 
 	// Test connection
 	if err := db.Ping(); err != nil {
@@ -42,6 +57,11 @@ func (d *Database) Close() error {
 	return d.db.Close()
 }
 
+// Ping verifies the database connection is still alive
+func (d *Database) Ping(ctx context.Context) error {
+	return d.db.PingContext(ctx)
+}
+
 // initSchema creates the database schema
 func (d *Database) initSchema() error {
 	schema := `
@@ -79,17 +99,195 @@ func (d *Database) initSchema() error {
 	CREATE INDEX IF NOT EXISTS idx_backend_uploads_execution_id ON backend_uploads(execution_id);
 	`
 
-	_, err := d.db.Exec(schema)
-	return err
+	if _, err := d.db.Exec(schema); err != nil {
+		return err
+	}
+
+	return d.migrate()
+}
+
+// migrations are applied in order on top of the base schema.
+// The index of each entry (plus one) is its schema version, tracked with PRAGMA user_version.
+// Never edit or reorder existing entries; append new ones.
+var migrations = []string{
+	// 1: classify backend failures
+	`ALTER TABLE backend_uploads ADD COLUMN error_code TEXT`,
+	// 2: audit trail of backups removed by retention
+	`CREATE TABLE retention_deletions (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		execution_id TEXT NOT NULL,
+		backend_id TEXT NOT NULL,
+		backend_name TEXT NOT NULL,
+		remote_path TEXT NOT NULL,
+		deleted_at TIMESTAMP NOT NULL,
+		FOREIGN KEY (execution_id) REFERENCES executions(id)
+	);
+	CREATE INDEX idx_retention_deletions_execution_id ON retention_deletions(execution_id);`,
+	// 3: per-backend upload timing
+	`ALTER TABLE backend_uploads ADD COLUMN duration_ms INTEGER`,
+	// 4: chunk index for chunked archives
+	`CREATE TABLE archive_chunks (
+		execution_id TEXT NOT NULL,
+		seq INTEGER NOT NULL,
+		hash TEXT NOT NULL,
+		chunk_offset INTEGER NOT NULL,
+		size INTEGER NOT NULL,
+		PRIMARY KEY (execution_id, seq),
+		FOREIGN KEY (execution_id) REFERENCES executions(id)
+	);
+	CREATE INDEX idx_archive_chunks_hash ON archive_chunks(hash);`,
+	// 5: delete execution details along with the execution. SQLite can't alter a
+	// foreign key, so each child table is rebuilt; orphaned rows are dropped.
+	`CREATE TABLE backend_uploads_new (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		execution_id TEXT NOT NULL,
+		backend_id TEXT NOT NULL,
+		backend_name TEXT NOT NULL,
+		status TEXT NOT NULL,
+		uploaded_at TIMESTAMP,
+		size INTEGER,
+		remote_path TEXT,
+		error_message TEXT,
+		error_code TEXT,
+		duration_ms INTEGER,
+		FOREIGN KEY (execution_id) REFERENCES executions(id) ON DELETE CASCADE
+	);
+	INSERT INTO backend_uploads_new
+		SELECT id, execution_id, backend_id, backend_name, status, uploaded_at,
+			size, remote_path, error_message, error_code, duration_ms
+		FROM backend_uploads WHERE execution_id IN (SELECT id FROM executions);
+	DROP TABLE backend_uploads;
+	ALTER TABLE backend_uploads_new RENAME TO backend_uploads;
+	CREATE INDEX idx_backend_uploads_execution_id ON backend_uploads(execution_id);
+
+	CREATE TABLE retention_deletions_new (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		execution_id TEXT NOT NULL,
+		backend_id TEXT NOT NULL,
+		backend_name TEXT NOT NULL,
+		remote_path TEXT NOT NULL,
+		deleted_at TIMESTAMP NOT NULL,
+		FOREIGN KEY (execution_id) REFERENCES executions(id) ON DELETE CASCADE
+	);
+	INSERT INTO retention_deletions_new
+		SELECT id, execution_id, backend_id, backend_name, remote_path, deleted_at
+		FROM retention_deletions WHERE execution_id IN (SELECT id FROM executions);
+	DROP TABLE retention_deletions;
+	ALTER TABLE retention_deletions_new RENAME TO retention_deletions;
+	CREATE INDEX idx_retention_deletions_execution_id ON retention_deletions(execution_id);
+
+	CREATE TABLE archive_chunks_new (
+		execution_id TEXT NOT NULL,
+		seq INTEGER NOT NULL,
+		hash TEXT NOT NULL,
+		chunk_offset INTEGER NOT NULL,
+		size INTEGER NOT NULL,
+		PRIMARY KEY (execution_id, seq),
+		FOREIGN KEY (execution_id) REFERENCES executions(id) ON DELETE CASCADE
+	);
+	INSERT INTO archive_chunks_new
+		SELECT execution_id, seq, hash, chunk_offset, size
+		FROM archive_chunks WHERE execution_id IN (SELECT id FROM executions);
+	DROP TABLE archive_chunks;
+	ALTER TABLE archive_chunks_new RENAME TO archive_chunks;
+	CREATE INDEX idx_archive_chunks_hash ON archive_chunks(hash);`,
+	// 6: file listing of each archive, stored as gzipped JSON
+	`CREATE TABLE archive_contents (
+		execution_id TEXT PRIMARY KEY,
+		file_count INTEGER NOT NULL,
+		total_size INTEGER NOT NULL,
+		truncated BOOLEAN NOT NULL,
+		files BLOB NOT NULL,
+		FOREIGN KEY (execution_id) REFERENCES executions(id) ON DELETE CASCADE
+	);`,
+	// 7: codec each archive was written with
+	`ALTER TABLE executions ADD COLUMN compression TEXT`,
+	// 8: audit trail of backups deleted by hand
+	`CREATE TABLE backup_deletions (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		task_id TEXT NOT NULL,
+		backend_id TEXT NOT NULL,
+		backend_name TEXT NOT NULL,
+		remote_path TEXT NOT NULL,
+		deleted_at TIMESTAMP NOT NULL
+	);
+	CREATE INDEX idx_backup_deletions_task_id ON backup_deletions(task_id);`,
+	// 9: source file count, compared against the next run to catch a missing source
+	`ALTER TABLE executions ADD COLUMN source_files INTEGER`,
+	// 10: integrity checks of a task's backups, with per-backup results as JSON
+	`CREATE TABLE backup_verifications (
+		id TEXT PRIMARY KEY,
+		task_id TEXT NOT NULL,
+		status TEXT NOT NULL,
+		started_at TIMESTAMP NOT NULL,
+		completed_at TIMESTAMP,
+		intact INTEGER NOT NULL,
+		corrupt INTEGER NOT NULL,
+		unverified INTEGER NOT NULL,
+		error_message TEXT,
+		results TEXT NOT NULL
+	);
+	CREATE INDEX idx_backup_verifications_task_id ON backup_verifications(task_id);`,
+	// 11: whether each backend received the archive or a file-by-file sync
+	`ALTER TABLE backend_uploads ADD COLUMN mode TEXT`,
+	// 12: API request that started each execution, for correlating logs
+	`ALTER TABLE executions ADD COLUMN request_id TEXT`,
+	// 13: scheduled attempt of each execution, counting re-runs after failure
+	`ALTER TABLE executions ADD COLUMN attempt INTEGER`,
+	// 14: label given when each execution was started
+	`ALTER TABLE executions ADD COLUMN label TEXT`,
+	// 15: files redacted from each archive
+	`ALTER TABLE archive_contents ADD COLUMN redacted TEXT`,
+}
+
+// migrate applies any pending schema migrations
+func (d *Database) migrate() error {
+	var version int
+	if err := d.db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+
+	for i := version; i < len(migrations); i++ {
+		tx, err := d.db.Begin()
+		if err != nil {
+			return fmt.Errorf("failed to begin migration %d: %w", i+1, err)
+		}
+
+		if _, err := tx.Exec(migrations[i]); err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				log.Printf("Error rolling back migration %d: %v", i+1, rbErr)
+			}
+			return fmt.Errorf("failed to apply migration %d: %w", i+1, err)
+		}
+
+		// PRAGMA does not support bound parameters
+		if _, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", i+1)); err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				log.Printf("Error rolling back migration %d: %v", i+1, rbErr)
+			}
+			return fmt.Errorf("failed to set schema version %d: %w", i+1, err)
+		}
+
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit migration %d: %w", i+1, err)
+		}
+		log.Printf("Applied database migration %d", i+1)
+	}
+
+	return nil
 }
 
 // CreateExecution creates a new execution record
 func (d *Database) CreateExecution(exec *models.Execution) error {
+	d.writeMu.RLock()
+	defer d.writeMu.RUnlock()
+
 	query := `
 		INSERT INTO executions (
 			id, task_id, task_name, started_at, completed_at, status,
-			archive_size, archive_hash, backend_results, error_message, duration_ms
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			archive_size, archive_hash, backend_results, error_message, duration_ms,
+			compression, source_files, request_id, attempt, label
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
 	_, err := d.db.Exec(query,
@@ -104,6 +302,11 @@ func (d *Database) CreateExecution(exec *models.Execution) error {
 		nil, // backend_results stored separately
 		exec.ErrorMessage,
 		exec.DurationMs,
+		exec.Compression,
+		exec.SourceFiles,
+		exec.RequestID,
+		exec.Attempt,
+		exec.Label,
 	)
 
 	return err
@@ -111,43 +314,87 @@ func (d *Database) CreateExecution(exec *models.Execution) error {
 
 // UpdateExecution updates an existing execution record
 func (d *Database) UpdateExecution(exec *models.Execution) error {
+	d.writeMu.RLock()
+	defer d.writeMu.RUnlock()
+
 	query := `
 		UPDATE executions SET
+			started_at = ?,
 			completed_at = ?,
 			status = ?,
 			archive_size = ?,
 			archive_hash = ?,
 			error_message = ?,
-			duration_ms = ?
+			duration_ms = ?,
+			compression = ?,
+			source_files = ?
 		WHERE id = ?
 	`
 
 	_, err := d.db.Exec(query,
+		exec.StartedAt,
 		exec.CompletedAt,
 		exec.Status,
 		exec.ArchiveSize,
 		exec.ArchiveHash,
 		exec.ErrorMessage,
 		exec.DurationMs,
+		exec.Compression,
+		exec.SourceFiles,
 		exec.ID,
 	)
 
 	return err
 }
 
+// DeleteExecution deletes an execution record and, by cascade, its details
+func (d *Database) DeleteExecution(id string) error {
+	d.writeMu.RLock()
+	defer d.writeMu.RUnlock()
+
+	if _, err := d.db.Exec("DELETE FROM executions WHERE id = ?", id); err != nil {
+		return fmt.Errorf("failed to delete execution: %w", err)
+	}
+	return nil
+}
+
+// ResolveUnfinishedExecutions closes the executions a previous process left
+// running or queued when it stopped: queued ones never started and become
+// cancelled, running ones become failed. It returns how many were closed.
+func (d *Database) ResolveUnfinishedExecutions(now time.Time) (int64, error) {
+	d.writeMu.RLock()
+	defer d.writeMu.RUnlock()
+
+	result, err := d.db.Exec(`
+		UPDATE executions SET
+			completed_at = ?,
+			status = CASE status WHEN 'queued' THEN 'cancelled' ELSE 'failed' END,
+			error_message = CASE status
+				WHEN 'queued' THEN 'Archivist stopped before the run started'
+				ELSE 'Interrupted: Archivist stopped during the run'
+			END
+		WHERE status IN ('running', 'queued')
+	`, now)
+	if err != nil {
+		return 0, fmt.Errorf("failed to resolve unfinished executions: %w", err)
+	}
+	return result.RowsAffected()
+}
+
 // GetExecution retrieves an execution by ID
 func (d *Database) GetExecution(id string) (*models.Execution, error) {
 	query := `
 		SELECT id, task_id, task_name, started_at, completed_at, status,
-			archive_size, archive_hash, error_message, duration_ms
+			archive_size, archive_hash, error_message, duration_ms, compression,
+			source_files, request_id, attempt, label
 		FROM executions WHERE id = ?
 	`
 
 	var exec models.Execution
 	var completedAt sql.NullTime
 	var archiveSize sql.NullInt64
-	var archiveHash, errorMessage sql.NullString
-	var durationMs sql.NullInt64
+	var archiveHash, errorMessage, compression, requestID, label sql.NullString
+	var durationMs, sourceFiles, attempt sql.NullInt64
 
 	err := d.db.QueryRow(query, id).Scan(
 		&exec.ID,
@@ -160,6 +407,11 @@ func (d *Database) GetExecution(id string) (*models.Execution, error) {
 		&archiveHash,
 		&errorMessage,
 		&durationMs,
+		&compression,
+		&sourceFiles,
+		&requestID,
+		&attempt,
+		&label,
 	)
 
 	if err != nil {
@@ -184,6 +436,21 @@ func (d *Database) GetExecution(id string) (*models.Execution, error) {
 	if durationMs.Valid {
 		exec.DurationMs = durationMs.Int64
 	}
+	if compression.Valid {
+		exec.Compression = compression.String
+	}
+	if sourceFiles.Valid {
+		exec.SourceFiles = sourceFiles.Int64
+	}
+	if requestID.Valid {
+		exec.RequestID = requestID.String
+	}
+	if attempt.Valid {
+		exec.Attempt = int(attempt.Int64)
+	}
+	if label.Valid {
+		exec.Label = label.String
+	}
 
 	// Load backend results
 	exec.BackendResults, err = d.getBackendUploads(id)
@@ -191,6 +458,12 @@ func (d *Database) GetExecution(id string) (*models.Execution, error) {
 		return nil, err
 	}
 
+	// Load backups removed by retention during this run
+	exec.RetentionDeletions, err = d.getRetentionDeletions(id)
+	if err != nil {
+		return nil, err
+	}
+
 	return &exec, nil
 }
 
@@ -198,7 +471,8 @@ func (d *Database) GetExecution(id string) (*models.Execution, error) {
 func (d *Database) ListExecutions(taskID string, status string, limit, offset int) ([]models.Execution, error) {
 	query := `
 		SELECT id, task_id, task_name, started_at, completed_at, status,
-			archive_size, archive_hash, error_message, duration_ms
+			archive_size, archive_hash, error_message, duration_ms, compression,
+			source_files, request_id, attempt, label
 		FROM executions
 		WHERE 1=1
 	`
@@ -232,8 +506,8 @@ func (d *Database) ListExecutions(taskID string, status string, limit, offset in
 		var exec models.Execution
 		var completedAt sql.NullTime
 		var archiveSize sql.NullInt64
-		var archiveHash, errorMessage sql.NullString
-		var durationMs sql.NullInt64
+		var archiveHash, errorMessage, compression, requestID, label sql.NullString
+		var durationMs, sourceFiles, attempt sql.NullInt64
 
 		err := rows.Scan(
 			&exec.ID,
@@ -246,6 +520,11 @@ func (d *Database) ListExecutions(taskID string, status string, limit, offset in
 			&archiveHash,
 			&errorMessage,
 			&durationMs,
+			&compression,
+			&sourceFiles,
+			&requestID,
+			&attempt,
+			&label,
 		)
 		if err != nil {
 			return nil, err
@@ -266,27 +545,75 @@ func (d *Database) ListExecutions(taskID string, status string, limit, offset in
 		if durationMs.Valid {
 			exec.DurationMs = durationMs.Int64
 		}
-
-		// Load backend results
-		backendResults, loadErr := d.getBackendUploads(exec.ID)
-		if loadErr != nil {
-			log.Printf("failed to load backend results for execution %s: %v", exec.ID, loadErr)
+		if compression.Valid {
+			exec.Compression = compression.String
+		}
+		if sourceFiles.Valid {
+			exec.SourceFiles = sourceFiles.Int64
+		}
+		if requestID.Valid {
+			exec.RequestID = requestID.String
+		}
+		if attempt.Valid {
+			exec.Attempt = int(attempt.Int64)
+		}
+		if label.Valid {
+			exec.Label = label.String
 		}
-		exec.BackendResults = backendResults
 
 		executions = append(executions, exec)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Release the connection before the per-execution queries so they
+	// can't exhaust the pool while it's held
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+
+	// Load backend results
+	for i := range executions {
+		backendResults, loadErr := d.getBackendUploads(executions[i].ID)
+		if loadErr != nil {
+			log.Printf("failed to load backend results for execution %s: %v", executions[i].ID, loadErr)
+		}
+		executions[i].BackendResults = backendResults
+	}
+
+	return executions, nil
+}
+
+// LastSourceFileCount returns the source file count of the task's most recent
+// successful execution that recorded one. ok is false if there is none.
+func (d *Database) LastSourceFileCount(taskID string) (count int64, ok bool, err error) {
+	query := `
+		SELECT source_files FROM executions
+		WHERE task_id = ? AND status = 'success' AND source_files IS NOT NULL
+		ORDER BY started_at DESC LIMIT 1
+	`
 
-	return executions, rows.Err()
+	err = d.db.QueryRow(query, taskID).Scan(&count)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	return count, true, nil
 }
 
 // AddBackendUpload records a backend upload result
 func (d *Database) AddBackendUpload(executionID string, result *models.BackendResult) error {
+	d.writeMu.RLock()
+	defer d.writeMu.RUnlock()
+
 	query := `
 		INSERT INTO backend_uploads (
 			execution_id, backend_id, backend_name, status, uploaded_at,
-			size, remote_path, error_message
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+			size, remote_path, error_message, error_code, duration_ms, mode
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
 	_, err := d.db.Exec(query,
@@ -298,6 +625,9 @@ func (d *Database) AddBackendUpload(executionID string, result *models.BackendRe
 		result.Size,
 		result.RemotePath,
 		result.ErrorMessage,
+		result.ErrorCode,
+		result.DurationMs,
+		result.Mode,
 	)
 
 	return err
@@ -306,7 +636,7 @@ func (d *Database) AddBackendUpload(executionID string, result *models.BackendRe
 // getBackendUploads retrieves backend upload results for an execution
 func (d *Database) getBackendUploads(executionID string) ([]models.BackendResult, error) {
 	query := `
-		SELECT backend_id, backend_name, status, uploaded_at, size, remote_path, error_message
+		SELECT backend_id, backend_name, status, uploaded_at, size, remote_path, error_message, error_code, duration_ms, mode
 		FROM backend_uploads WHERE execution_id = ?
 	`
 
@@ -324,8 +654,8 @@ func (d *Database) getBackendUploads(executionID string) ([]models.BackendResult
 	for rows.Next() {
 		var result models.BackendResult
 		var uploadedAt sql.NullTime
-		var size sql.NullInt64
-		var remotePath, errorMessage sql.NullString
+		var size, durationMs sql.NullInt64
+		var remotePath, errorMessage, errorCode, mode sql.NullString
 
 		err := rows.Scan(
 			&result.BackendID,
@@ -335,6 +665,9 @@ func (d *Database) getBackendUploads(executionID string) ([]models.BackendResult
 			&size,
 			&remotePath,
 			&errorMessage,
+			&errorCode,
+			&durationMs,
+			&mode,
 		)
 		if err != nil {
 			return nil, err
@@ -352,6 +685,15 @@ func (d *Database) getBackendUploads(executionID string) ([]models.BackendResult
 		if errorMessage.Valid {
 			result.ErrorMessage = errorMessage.String
 		}
+		if errorCode.Valid {
+			result.ErrorCode = errorCode.String
+		}
+		if durationMs.Valid {
+			result.DurationMs = durationMs.Int64
+		}
+		if mode.Valid {
+			result.Mode = mode.String
+		}
 
 		results = append(results, result)
 	}
@@ -359,6 +701,332 @@ func (d *Database) getBackendUploads(executionID string) ([]models.BackendResult
 	return results, rows.Err()
 }
 
+// AddArchiveChunks records the chunk index of a chunked archive, in order
+func (d *Database) AddArchiveChunks(executionID string, chunks []models.ArchiveChunk) error {
+	d.writeMu.RLock()
+	defer d.writeMu.RUnlock()
+
+	tx, err := d.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		// Rollback is a no-op if Commit already succeeded; sql.ErrTxDone is expected in that case.
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			log.Printf("Error rolling back transaction: %v", err)
+		}
+	}()
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO archive_chunks (execution_id, seq, hash, chunk_offset, size)
+		VALUES (?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := stmt.Close(); err != nil {
+			log.Printf("Error closing statement: %v", err)
+		}
+	}()
+
+	for i, chunk := range chunks {
+		if _, err := stmt.Exec(executionID, i, chunk.Hash, chunk.Offset, chunk.Size); err != nil {
+			return fmt.Errorf("failed to record chunk %d: %w", i, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetArchiveChunks returns the chunk index of a chunked archive, in order
+func (d *Database) GetArchiveChunks(executionID string) ([]models.ArchiveChunk, error) {
+	query := `
+		SELECT hash, chunk_offset, size
+		FROM archive_chunks WHERE execution_id = ?
+		ORDER BY seq
+	`
+
+	rows, err := d.db.Query(query, executionID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			log.Printf("Error closing rows: %v", err)
+		}
+	}()
+
+	var chunks []models.ArchiveChunk
+	for rows.Next() {
+		var chunk models.ArchiveChunk
+		if err := rows.Scan(&chunk.Hash, &chunk.Offset, &chunk.Size); err != nil {
+			return nil, err
+		}
+		chunks = append(chunks, chunk)
+	}
+
+	return chunks, rows.Err()
+}
+
+// AddRetentionDeletion records a backup removed by the retention policy
+func (d *Database) AddRetentionDeletion(executionID string, deletion *models.RetentionDeletion) error {
+	d.writeMu.RLock()
+	defer d.writeMu.RUnlock()
+
+	query := `
+		INSERT INTO retention_deletions (
+			execution_id, backend_id, backend_name, remote_path, deleted_at
+		) VALUES (?, ?, ?, ?, ?)
+	`
+
+	_, err := d.db.Exec(query,
+		executionID,
+		deletion.BackendID,
+		deletion.BackendName,
+		deletion.RemotePath,
+		deletion.DeletedAt,
+	)
+
+	return err
+}
+
+// getRetentionDeletions retrieves backups removed by retention during an execution
+func (d *Database) getRetentionDeletions(executionID string) ([]models.RetentionDeletion, error) {
+	query := `
+		SELECT backend_id, backend_name, remote_path, deleted_at
+		FROM retention_deletions WHERE execution_id = ?
+		ORDER BY id
+	`
+
+	rows, err := d.db.Query(query, executionID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			log.Printf("Error closing rows: %v", err)
+		}
+	}()
+
+	var deletions []models.RetentionDeletion
+	for rows.Next() {
+		var deletion models.RetentionDeletion
+		if err := rows.Scan(
+			&deletion.BackendID,
+			&deletion.BackendName,
+			&deletion.RemotePath,
+			&deletion.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		deletions = append(deletions, deletion)
+	}
+
+	return deletions, rows.Err()
+}
+
+// AddBackupDeletion records a backup deleted by hand
+func (d *Database) AddBackupDeletion(deletion *models.BackupDeletion) error {
+	d.writeMu.RLock()
+	defer d.writeMu.RUnlock()
+
+	query := `
+		INSERT INTO backup_deletions (
+			task_id, backend_id, backend_name, remote_path, deleted_at
+		) VALUES (?, ?, ?, ?, ?)
+	`
+
+	_, err := d.db.Exec(query,
+		deletion.TaskID,
+		deletion.BackendID,
+		deletion.BackendName,
+		deletion.RemotePath,
+		deletion.DeletedAt,
+	)
+
+	return err
+}
+
+// ListBackupDeletions retrieves a task's hand-deleted backups, newest first
+func (d *Database) ListBackupDeletions(taskID string, limit int) ([]models.BackupDeletion, error) {
+	query := `
+		SELECT task_id, backend_id, backend_name, remote_path, deleted_at
+		FROM backup_deletions WHERE task_id = ?
+		ORDER BY id DESC LIMIT ?
+	`
+
+	rows, err := d.db.Query(query, taskID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			log.Printf("Error closing rows: %v", err)
+		}
+	}()
+
+	deletions := make([]models.BackupDeletion, 0)
+	for rows.Next() {
+		var deletion models.BackupDeletion
+		if err := rows.Scan(
+			&deletion.TaskID,
+			&deletion.BackendID,
+			&deletion.BackendName,
+			&deletion.RemotePath,
+			&deletion.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		deletions = append(deletions, deletion)
+	}
+
+	return deletions, rows.Err()
+}
+
+// SaveBackupVerification creates or updates a backup verification report
+func (d *Database) SaveBackupVerification(verification *models.BackupVerification) error {
+	d.writeMu.RLock()
+	defer d.writeMu.RUnlock()
+
+	results, err := json.Marshal(verification.Results)
+	if err != nil {
+		return fmt.Errorf("failed to encode verification results: %w", err)
+	}
+
+	query := `
+		INSERT OR REPLACE INTO backup_verifications (
+			id, task_id, status, started_at, completed_at,
+			intact, corrupt, unverified, error_message, results
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	_, err = d.db.Exec(query,
+		verification.ID,
+		verification.TaskID,
+		verification.Status,
+		verification.StartedAt,
+		verification.CompletedAt,
+		verification.Intact,
+		verification.Corrupt,
+		verification.Unverified,
+		verification.ErrorMessage,
+		string(results),
+	)
+
+	return err
+}
+
+// ListBackupVerifications retrieves a task's backup verification reports, newest first
+func (d *Database) ListBackupVerifications(taskID string, limit int) ([]models.BackupVerification, error) {
+	query := `
+		SELECT id, task_id, status, started_at, completed_at,
+			intact, corrupt, unverified, error_message, results
+		FROM backup_verifications WHERE task_id = ?
+		ORDER BY started_at DESC LIMIT ?
+	`
+
+	rows, err := d.db.Query(query, taskID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			log.Printf("Error closing rows: %v", err)
+		}
+	}()
+
+	verifications := make([]models.BackupVerification, 0)
+	for rows.Next() {
+		var verification models.BackupVerification
+		var completedAt sql.NullTime
+		var errorMessage sql.NullString
+		var results string
+		if err := rows.Scan(
+			&verification.ID,
+			&verification.TaskID,
+			&verification.Status,
+			&verification.StartedAt,
+			&completedAt,
+			&verification.Intact,
+			&verification.Corrupt,
+			&verification.Unverified,
+			&errorMessage,
+			&results,
+		); err != nil {
+			return nil, err
+		}
+		if completedAt.Valid {
+			verification.CompletedAt = &completedAt.Time
+		}
+		verification.ErrorMessage = errorMessage.String
+		if err := json.Unmarshal([]byte(results), &verification.Results); err != nil {
+			return nil, fmt.Errorf("failed to decode verification results: %w", err)
+		}
+		verifications = append(verifications, verification)
+	}
+
+	return verifications, rows.Err()
+}
+
+// RecordedArchiveHashes maps the remote paths of a task's successful uploads
+// to one backend to the hashes recorded for their archives
+func (d *Database) RecordedArchiveHashes(taskID, backendID string) (map[string]string, error) {
+	query := `
+		SELECT bu.remote_path, e.archive_hash
+		FROM backend_uploads bu
+		JOIN executions e ON e.id = bu.execution_id
+		WHERE e.task_id = ? AND bu.backend_id = ? AND bu.status = 'success'
+			AND (bu.mode IS NULL OR bu.mode != 'sync')
+			AND bu.remote_path IS NOT NULL AND e.archive_hash IS NOT NULL AND e.archive_hash != ''
+		ORDER BY e.started_at
+	`
+
+	rows, err := d.db.Query(query, taskID, backendID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			log.Printf("Error closing rows: %v", err)
+		}
+	}()
+
+	// Later uploads to the same path replace earlier ones
+	hashes := make(map[string]string)
+	for rows.Next() {
+		var remotePath, archiveHash string
+		if err := rows.Scan(&remotePath, &archiveHash); err != nil {
+			return nil, err
+		}
+		hashes[remotePath] = archiveHash
+	}
+
+	return hashes, rows.Err()
+}
+
+// RecordedArchiveHash returns the archive hash recorded for the newest
+// successful upload to remotePath on a backend, by any task, or "" if none
+func (d *Database) RecordedArchiveHash(backendID, remotePath string) (string, error) {
+	query := `
+		SELECT e.archive_hash
+		FROM backend_uploads bu
+		JOIN executions e ON e.id = bu.execution_id
+		WHERE bu.backend_id = ? AND bu.remote_path = ? AND bu.status = 'success'
+			AND (bu.mode IS NULL OR bu.mode != 'sync')
+			AND e.archive_hash IS NOT NULL AND e.archive_hash != ''
+		ORDER BY e.started_at DESC
+		LIMIT 1
+	`
+
+	var archiveHash string
+	err := d.db.QueryRow(query, backendID, remotePath).Scan(&archiveHash)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return archiveHash, err
+}
+
 // GetTaskStats returns statistics for a task
 func (d *Database) GetTaskStats(taskID string) (*models.TaskStats, error) {
 	query := `
@@ -382,10 +1050,11 @@ func (d *Database) GetTaskStats(taskID string) (*models.TaskStats, error) {
 		return nil, err
 	}
 	stats.AverageDurationMs = int64(avgDuration)
+	stats.SuccessRate = successRate(stats.SuccessCount, stats.FailureCount)
 
 	// Get last execution status and archive size
 	lastQuery := `
-		SELECT status, archive_size
+		SELECT status, archive_size, started_at
 		FROM executions
 		WHERE task_id = ?
 		ORDER BY started_at DESC
@@ -393,18 +1062,53 @@ func (d *Database) GetTaskStats(taskID string) (*models.TaskStats, error) {
 	`
 
 	var archiveSize sql.NullInt64
-	err = d.db.QueryRow(lastQuery, taskID).Scan(&stats.LastExecutionStatus, &archiveSize)
+	var lastRun time.Time
+	err = d.db.QueryRow(lastQuery, taskID).Scan(&stats.LastExecutionStatus, &archiveSize, &lastRun)
 	if err != nil && err != sql.ErrNoRows {
 		return nil, err
 	}
+	if err == nil {
+		stats.LastRun = &lastRun
+	}
 
 	if archiveSize.Valid {
 		stats.LastArchiveSize = archiveSize.Int64
 	}
 
+	// Get last successful run
+	stats.LastSuccessfulRun, err = d.GetLastSuccessfulRun(taskID)
+	if err != nil {
+		return nil, err
+	}
+
 	return &stats, nil
 }
 
+// GetLastSuccessfulRun returns the completion time of the newest successful execution for a task
+func (d *Database) GetLastSuccessfulRun(taskID string) (*time.Time, error) {
+	query := `
+		SELECT completed_at
+		FROM executions
+		WHERE task_id = ? AND status = 'success' AND completed_at IS NOT NULL
+		ORDER BY started_at DESC
+		LIMIT 1
+	`
+
+	var completedAt sql.NullTime
+	err := d.db.QueryRow(query, taskID).Scan(&completedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if !completedAt.Valid {
+		return nil, nil
+	}
+	return &completedAt.Time, nil
+}
+
 // GetExecutionCount returns the count of executions matching criteria
 func (d *Database) GetExecutionCount(since *time.Time, status string) (int, error) {
 	query := "SELECT COUNT(*) FROM executions WHERE 1=1"
@@ -457,8 +1161,56 @@ func (d *Database) GetExecutionStats() (*models.ExecutionsStats, error) {
 	return &stats, nil
 }
 
+// PruneExecutions deletes the oldest finished executions beyond the newest keep,
+// for one task or, with an empty taskID, across all tasks. Execution details
+// cascade. It returns the number of executions deleted.
+func (d *Database) PruneExecutions(taskID string, keep int) (int64, error) {
+	d.writeMu.RLock()
+	defer d.writeMu.RUnlock()
+
+	tx, err := d.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		// Rollback is a no-op if Commit already succeeded; sql.ErrTxDone is expected in that case.
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			log.Printf("Error rolling back transaction: %v", err)
+		}
+	}()
+
+	// Running and queued executions count toward the limit but are never deleted
+	result, err := tx.Exec(`
+		DELETE FROM executions
+		WHERE status NOT IN ('running', 'queued')
+			AND (? = '' OR task_id = ?)
+			AND id NOT IN (
+				SELECT id FROM executions
+				WHERE (? = '' OR task_id = ?)
+				ORDER BY started_at DESC
+				LIMIT ?
+			)
+	`, taskID, taskID, taskID, taskID, keep)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune executions: %w", err)
+	}
+
+	pruned, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return pruned, nil
+}
+
 // ClearHistory deletes all execution records
 func (d *Database) ClearHistory() error {
+	d.writeMu.RLock()
+	defer d.writeMu.RUnlock()
+
 	tx, err := d.db.Begin()
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
@@ -470,15 +1222,16 @@ func (d *Database) ClearHistory() error {
 		}
 	}()
 
-	// Delete backend uploads first (foreign key constraint)
-	if _, err := tx.Exec("DELETE FROM backend_uploads"); err != nil {
-		return fmt.Errorf("failed to delete backend uploads: %w", err)
-	}
-
-	// Delete executions
+	// Backend uploads, retention deletions and chunk indexes cascade
 	if _, err := tx.Exec("DELETE FROM executions"); err != nil {
 		return fmt.Errorf("failed to delete executions: %w", err)
 	}
+	if _, err := tx.Exec("DELETE FROM backup_deletions"); err != nil {
+		return fmt.Errorf("failed to delete backup deletions: %w", err)
+	}
+	if _, err := tx.Exec("DELETE FROM backup_verifications"); err != nil {
+		return fmt.Errorf("failed to delete backup verifications: %w", err)
+	}
 
 	if err := tx.Commit(); err != nil {
 		return fmt.Errorf("failed to commit transaction: %w", err)
@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/nsilverman/archivist/internal/models"
+)
+
+// Vacuum rebuilds the database file to return space freed by deleted rows.
+// Writers are held off until it finishes.
+func (d *Database) Vacuum(ctx context.Context) (*models.VacuumResult, error) {
+	d.writeMu.Lock()
+	defer d.writeMu.Unlock()
+
+	// VACUUM can't run inside a transaction, so use a dedicated connection
+	conn, err := d.db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer func() {
+		if err := conn.Close(); err != nil {
+			log.Printf("Error closing connection: %v", err)
+		}
+	}()
+
+	startTime := time.Now()
+
+	sizeBefore, err := databaseSize(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := conn.ExecContext(ctx, "VACUUM"); err != nil {
+		return nil, fmt.Errorf("failed to vacuum database: %w", err)
+	}
+
+	sizeAfter, err := databaseSize(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.VacuumResult{
+		SizeBefore:     sizeBefore,
+		SizeAfter:      sizeAfter,
+		ReclaimedBytes: sizeBefore - sizeAfter,
+		DurationMs:     time.Since(startTime).Milliseconds(),
+	}, nil
+}
+
+// Snapshot writes a consistent copy of the database to path, which must not
+// exist yet. VACUUM INTO reads from a single transaction, so writers carry on
+// and the copy comes out compacted.
+func (d *Database) Snapshot(ctx context.Context, path string) error {
+	if _, err := d.db.ExecContext(ctx, "VACUUM INTO ?", path); err != nil {
+		return fmt.Errorf("failed to snapshot database: %w", err)
+	}
+	return nil
+}
+
+// databaseSize returns the size of the main database in bytes
+func databaseSize(ctx context.Context, conn *sql.Conn) (int64, error) {
+	var pageCount, pageSize int64
+	if err := conn.QueryRowContext(ctx, "PRAGMA page_count").Scan(&pageCount); err != nil {
+		return 0, fmt.Errorf("failed to read page count: %w", err)
+	}
+	if err := conn.QueryRowContext(ctx, "PRAGMA page_size").Scan(&pageSize); err != nil {
+		return 0, fmt.Errorf("failed to read page size: %w", err)
+	}
+	return pageCount * pageSize, nil
+}
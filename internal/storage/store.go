@@ -0,0 +1,55 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/nsilverman/archivist/internal/models"
+)
+
+// Store is the execution history and metadata store. Database, backed by
+// SQLite, is the only implementation; another database needs only these methods.
+type Store interface {
+	// Connection
+	Close() error
+	Ping(ctx context.Context) error
+
+	// Executions
+	CreateExecution(exec *models.Execution) error
+	UpdateExecution(exec *models.Execution) error
+	GetExecution(id string) (*models.Execution, error)
+	DeleteExecution(id string) error
+	ResolveUnfinishedExecutions(now time.Time) (int64, error)
+	ListExecutions(taskID string, status string, limit, offset int) ([]models.Execution, error)
+	LastSourceFileCount(taskID string) (count int64, ok bool, err error)
+	AddBackendUpload(executionID string, result *models.BackendResult) error
+	AddArchiveChunks(executionID string, chunks []models.ArchiveChunk) error
+	GetArchiveChunks(executionID string) ([]models.ArchiveChunk, error)
+	AddRetentionDeletion(executionID string, deletion *models.RetentionDeletion) error
+	SaveArchiveContents(contents *models.ArchiveContents) error
+	GetArchiveContents(executionID string) (*models.ArchiveContents, error)
+
+	// Backups
+	AddBackupDeletion(deletion *models.BackupDeletion) error
+	ListBackupDeletions(taskID string, limit int) ([]models.BackupDeletion, error)
+	SaveBackupVerification(verification *models.BackupVerification) error
+	ListBackupVerifications(taskID string, limit int) ([]models.BackupVerification, error)
+	RecordedArchiveHashes(taskID, backendID string) (map[string]string, error)
+	RecordedArchiveHash(backendID, remotePath string) (string, error)
+
+	// Statistics
+	GetTaskStats(taskID string) (*models.TaskStats, error)
+	GetAllTaskStats() (map[string]*models.TaskStats, error)
+	GetTaskTrends(taskID string, start, end time.Time, bucket time.Duration) (*models.TaskTrends, error)
+	GetLastSuccessfulRun(taskID string) (*time.Time, error)
+	GetExecutionCount(since *time.Time, status string) (int, error)
+	GetExecutionStats() (*models.ExecutionsStats, error)
+
+	// Maintenance
+	PruneExecutions(taskID string, keep int) (int64, error)
+	ClearHistory() error
+	Vacuum(ctx context.Context) (*models.VacuumResult, error)
+	Snapshot(ctx context.Context, path string) error
+}
+
+var _ Store = (*Database)(nil)
@@ -0,0 +1,481 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nsilverman/archivist/internal/models"
+)
+
+// newTestDatabase opens a fresh SQLite database in a temporary directory
+func newTestDatabase(t *testing.T) Store {
+	t.Helper()
+	db, err := NewDatabase(filepath.Join(t.TempDir(), "archivist.db"))
+	if err != nil {
+		t.Fatalf("NewDatabase: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := db.Close(); err != nil {
+			t.Errorf("closing database: %v", err)
+		}
+	})
+	return db
+}
+
+// createExecution records an execution of task-1 started at offset from base
+func createExecution(t *testing.T, store Store, id, status string, offset time.Duration) *models.Execution {
+	t.Helper()
+	exec := &models.Execution{
+		ID:        id,
+		TaskID:    "task-1",
+		TaskName:  "documents",
+		StartedAt: time.Date(2025, 1, 27, 12, 0, 0, 0, time.UTC).Add(offset),
+		Status:    status,
+	}
+	if err := store.CreateExecution(exec); err != nil {
+		t.Fatalf("CreateExecution(%s): %v", id, err)
+	}
+	return exec
+}
+
+// storeContract is what every Store implementation must do. Each case gets
+// a new, empty store.
+var storeContract = []struct {
+	name string
+	run  func(t *testing.T, store Store)
+}{
+	{"execution round trip", func(t *testing.T, store Store) {
+		exec := createExecution(t, store, "exec-1", "running", 0)
+		exec.Status = "success"
+		exec.ArchiveHash = "abc123"
+		exec.ArchiveSize = 42
+		completed := exec.StartedAt.Add(time.Minute)
+		exec.CompletedAt = &completed
+		if err := store.UpdateExecution(exec); err != nil {
+			t.Fatalf("UpdateExecution: %v", err)
+		}
+
+		got, err := store.GetExecution("exec-1")
+		if err != nil {
+			t.Fatalf("GetExecution: %v", err)
+		}
+		if got.Status != "success" || got.ArchiveHash != "abc123" || got.ArchiveSize != 42 || got.CompletedAt == nil {
+			t.Errorf("GetExecution = %+v, want the updated record", got)
+		}
+	}},
+	{"missing execution", func(t *testing.T, store Store) {
+		if _, err := store.GetExecution("missing"); err == nil {
+			t.Error("GetExecution of a missing execution succeeded")
+		}
+	}},
+	{"details of a missing execution are rejected", func(t *testing.T, store Store) {
+		if err := store.AddBackendUpload("missing", &models.BackendResult{BackendID: "b", BackendName: "b", Status: "success"}); err == nil {
+			t.Error("AddBackendUpload for a missing execution succeeded")
+		}
+		if err := store.AddRetentionDeletion("missing", &models.RetentionDeletion{BackendID: "b", BackendName: "b", RemotePath: "old.tar.gz", DeletedAt: time.Now()}); err == nil {
+			t.Error("AddRetentionDeletion for a missing execution succeeded")
+		}
+		if err := store.AddArchiveChunks("missing", []models.ArchiveChunk{{Hash: "h", Size: 1}}); err == nil {
+			t.Error("AddArchiveChunks for a missing execution succeeded")
+		}
+	}},
+	{"deleting an execution deletes its details", func(t *testing.T, store Store) {
+		createExecution(t, store, "exec-1", "success", 0)
+		if err := store.AddBackendUpload("exec-1", &models.BackendResult{BackendID: "b", BackendName: "b", Status: "success", RemotePath: "a.tar.gz"}); err != nil {
+			t.Fatalf("AddBackendUpload: %v", err)
+		}
+		if err := store.AddArchiveChunks("exec-1", []models.ArchiveChunk{{Hash: "h1", Size: 1}, {Hash: "h2", Offset: 1, Size: 1}}); err != nil {
+			t.Fatalf("AddArchiveChunks: %v", err)
+		}
+
+		if err := store.DeleteExecution("exec-1"); err != nil {
+			t.Fatalf("DeleteExecution: %v", err)
+		}
+		chunks, err := store.GetArchiveChunks("exec-1")
+		if err != nil {
+			t.Fatalf("GetArchiveChunks: %v", err)
+		}
+		if len(chunks) != 0 {
+			t.Errorf("%d chunks left after deleting their execution", len(chunks))
+		}
+
+		// The ID is free again, and the new execution has none of the old details
+		createExecution(t, store, "exec-1", "success", 0)
+		got, err := store.GetExecution("exec-1")
+		if err != nil {
+			t.Fatalf("GetExecution: %v", err)
+		}
+		if len(got.BackendResults) != 0 {
+			t.Errorf("%d backend results left after deleting their execution", len(got.BackendResults))
+		}
+	}},
+	{"clearing history deletes every detail", func(t *testing.T, store Store) {
+		createExecution(t, store, "exec-1", "success", 0)
+		if err := store.AddRetentionDeletion("exec-1", &models.RetentionDeletion{BackendID: "b", BackendName: "b", RemotePath: "old.tar.gz", DeletedAt: time.Now()}); err != nil {
+			t.Fatalf("AddRetentionDeletion: %v", err)
+		}
+		if err := store.ClearHistory(); err != nil {
+			t.Fatalf("ClearHistory: %v", err)
+		}
+		executions, err := store.ListExecutions("", "", 10, 0)
+		if err != nil {
+			t.Fatalf("ListExecutions: %v", err)
+		}
+		if len(executions) != 0 {
+			t.Errorf("%d executions left after clearing history", len(executions))
+		}
+	}},
+	{"chunk index keeps its order", func(t *testing.T, store Store) {
+		createExecution(t, store, "exec-1", "success", 0)
+		want := []models.ArchiveChunk{{Hash: "c", Size: 3}, {Hash: "a", Offset: 3, Size: 1}, {Hash: "b", Offset: 4, Size: 2}}
+		if err := store.AddArchiveChunks("exec-1", want); err != nil {
+			t.Fatalf("AddArchiveChunks: %v", err)
+		}
+		got, err := store.GetArchiveChunks("exec-1")
+		if err != nil {
+			t.Fatalf("GetArchiveChunks: %v", err)
+		}
+		if len(got) != len(want) {
+			t.Fatalf("GetArchiveChunks returned %d chunks, want %d", len(got), len(want))
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("chunk %d = %+v, want %+v", i, got[i], want[i])
+			}
+		}
+	}},
+	{"unfinished executions are resolved", func(t *testing.T, store Store) {
+		createExecution(t, store, "queued", "queued", 0)
+		createExecution(t, store, "running", "running", time.Second)
+		createExecution(t, store, "done", "success", 2*time.Second)
+
+		resolved, err := store.ResolveUnfinishedExecutions(time.Now())
+		if err != nil {
+			t.Fatalf("ResolveUnfinishedExecutions: %v", err)
+		}
+		if resolved != 2 {
+			t.Errorf("resolved %d executions, want 2", resolved)
+		}
+		for id, want := range map[string]string{"queued": "cancelled", "running": "failed", "done": "success"} {
+			got, err := store.GetExecution(id)
+			if err != nil {
+				t.Fatalf("GetExecution(%s): %v", id, err)
+			}
+			if got.Status != want {
+				t.Errorf("%s execution is %s, want %s", id, got.Status, want)
+			}
+		}
+	}},
+	{"pruning keeps the newest and unfinished executions", func(t *testing.T, store Store) {
+		createExecution(t, store, "oldest", "success", 0)
+		createExecution(t, store, "old-running", "running", time.Second)
+		createExecution(t, store, "newer", "failed", 2*time.Second)
+		createExecution(t, store, "newest", "success", 3*time.Second)
+
+		pruned, err := store.PruneExecutions("task-1", 2)
+		if err != nil {
+			t.Fatalf("PruneExecutions: %v", err)
+		}
+		if pruned != 1 {
+			t.Errorf("pruned %d executions, want 1", pruned)
+		}
+		for id, wantKept := range map[string]bool{"oldest": false, "old-running": true, "newer": true, "newest": true} {
+			_, err := store.GetExecution(id)
+			if kept := err == nil; kept != wantKept {
+				t.Errorf("%s kept = %v, want %v", id, kept, wantKept)
+			}
+		}
+	}},
+	{"recorded archive hash is the newest upload's", func(t *testing.T, store Store) {
+		for i, hash := range []string{"first", "second"} {
+			exec := createExecution(t, store, hash, "success", time.Duration(i)*time.Hour)
+			exec.ArchiveHash = hash
+			if err := store.UpdateExecution(exec); err != nil {
+				t.Fatalf("UpdateExecution: %v", err)
+			}
+			if err := store.AddBackendUpload(exec.ID, &models.BackendResult{BackendID: "b", BackendName: "b", Status: "success", RemotePath: "documents_latest.tar.gz"}); err != nil {
+				t.Fatalf("AddBackendUpload: %v", err)
+			}
+		}
+
+		tests := []struct {
+			backendID, remotePath, want string
+		}{
+			{"b", "documents_latest.tar.gz", "second"},
+			{"b", "other.tar.gz", ""},
+			{"other", "documents_latest.tar.gz", ""},
+		}
+		for _, tt := range tests {
+			got, err := store.RecordedArchiveHash(tt.backendID, tt.remotePath)
+			if err != nil {
+				t.Fatalf("RecordedArchiveHash: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("RecordedArchiveHash(%s, %s) = %q, want %q", tt.backendID, tt.remotePath, got, tt.want)
+			}
+		}
+	}},
+	{"snapshot opens as a store", func(t *testing.T, store Store) {
+		createExecution(t, store, "exec-1", "success", 0)
+		path := filepath.Join(t.TempDir(), "snapshot.db")
+		if err := store.Snapshot(context.Background(), path); err != nil {
+			t.Fatalf("Snapshot: %v", err)
+		}
+		snapshot, err := NewDatabase(path)
+		if err != nil {
+			t.Fatalf("opening snapshot: %v", err)
+		}
+		defer func() { _ = snapshot.Close() }()
+		if _, err := snapshot.GetExecution("exec-1"); err != nil {
+			t.Errorf("snapshot is missing the execution: %v", err)
+		}
+	}},
+}
+
+func TestDatabaseStoreContract(t *testing.T) {
+	for _, tt := range storeContract {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.run(t, newTestDatabase(t))
+		})
+	}
+}
+
+func TestNewDatabaseReopens(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "archivist.db")
+	db, err := NewDatabase(path)
+	if err != nil {
+		t.Fatalf("NewDatabase: %v", err)
+	}
+	createExecution(t, db, "exec-1", "success", 0)
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Migrations already applied must not run again
+	db, err = NewDatabase(path)
+	if err != nil {
+		t.Fatalf("reopening: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+	if _, err := db.GetExecution("exec-1"); err != nil {
+		t.Errorf("execution lost on reopen: %v", err)
+	}
+}
+
+func TestVacuumShrinksFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "archivist.db")
+	fileSize := func() int64 {
+		t.Helper()
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return info.Size()
+	}
+	open := func() Store {
+		t.Helper()
+		db, err := NewDatabase(path)
+		if err != nil {
+			t.Fatalf("NewDatabase: %v", err)
+		}
+		return db
+	}
+
+	db := open()
+	padding := strings.Repeat("x", 4096)
+	for i := 0; i < 1000; i++ {
+		exec := createExecution(t, db, fmt.Sprintf("exec-%04d", i), "running", time.Duration(i)*time.Minute)
+		exec.Status = "failed"
+		exec.ErrorMessage = padding
+		if err := db.UpdateExecution(exec); err != nil {
+			t.Fatalf("UpdateExecution: %v", err)
+		}
+	}
+	if deleted, err := db.PruneExecutions("task-1", 50); err != nil || deleted != 950 {
+		t.Fatalf("PruneExecutions = %d, %v; want 950 deleted", deleted, err)
+	}
+	// Closing checkpoints the WAL, so the main file holds every page
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+	before := fileSize()
+
+	db = open()
+	result, err := db.Vacuum(context.Background())
+	if err != nil {
+		t.Fatalf("Vacuum: %v", err)
+	}
+	if result.ReclaimedBytes <= 0 || result.SizeAfter != result.SizeBefore-result.ReclaimedBytes {
+		t.Errorf("Vacuum = %+v, want space reclaimed", result)
+	}
+	executions, err := db.ListExecutions("task-1", "", 100, 0)
+	if err != nil {
+		t.Fatalf("ListExecutions: %v", err)
+	}
+	if len(executions) != 50 {
+		t.Errorf("%d executions after vacuum, want 50", len(executions))
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if after := fileSize(); after >= before/2 {
+		t.Errorf("database file is %d bytes after vacuum, was %d; want it at least halved", after, before)
+	}
+}
+
+func TestConcurrentReadsAndWrites(t *testing.T) {
+	db := newTestDatabase(t)
+	const workers, runs = 8, 40
+
+	var wg sync.WaitGroup
+	errs := make(chan error, workers*runs*4)
+	for w := 0; w < workers; w++ {
+		// Writers record executions as the executor does, step by step
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < runs; i++ {
+				exec := &models.Execution{
+					ID:        fmt.Sprintf("exec-%d-%d", w, i),
+					TaskID:    fmt.Sprintf("task-%d", w%3),
+					TaskName:  "documents",
+					StartedAt: time.Now(),
+					Status:    "running",
+				}
+				if err := db.CreateExecution(exec); err != nil {
+					errs <- fmt.Errorf("CreateExecution: %w", err)
+					continue
+				}
+				if err := db.AddBackendUpload(exec.ID, &models.BackendResult{BackendID: "local", BackendName: "local", Status: "success"}); err != nil {
+					errs <- fmt.Errorf("AddBackendUpload: %w", err)
+				}
+				completed := time.Now()
+				exec.Status, exec.CompletedAt = "success", &completed
+				if err := db.UpdateExecution(exec); err != nil {
+					errs <- fmt.Errorf("UpdateExecution: %w", err)
+				}
+			}
+		}(w)
+
+		// Readers poll history and statistics like the dashboard
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < runs; i++ {
+				if _, err := db.ListExecutions(fmt.Sprintf("task-%d", w%3), "", 20, 0); err != nil {
+					errs <- fmt.Errorf("ListExecutions: %w", err)
+				}
+				if _, err := db.GetAllTaskStats(); err != nil {
+					errs <- fmt.Errorf("GetAllTaskStats: %w", err)
+				}
+			}
+		}(w)
+	}
+
+	// Foreign keys hold on every pooled connection, not just the first
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if err := db.AddBackendUpload(fmt.Sprintf("missing-%d", i), &models.BackendResult{BackendID: "local", Status: "success"}); err == nil {
+				errs <- fmt.Errorf("upload recorded for missing execution missing-%d", i)
+			}
+		}(i)
+	}
+
+	// and a vacuum in the middle holds writers off rather than failing them
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if _, err := db.Vacuum(context.Background()); err != nil {
+			errs <- fmt.Errorf("Vacuum: %w", err)
+		}
+	}()
+
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	count, err := db.GetExecutionCount(nil, "success")
+	if err != nil {
+		t.Fatalf("GetExecutionCount: %v", err)
+	}
+	if count != workers*runs {
+		t.Errorf("%d successful executions recorded, want %d", count, workers*runs)
+	}
+	exec, err := db.GetExecution("exec-0-0")
+	if err != nil {
+		t.Fatalf("GetExecution: %v", err)
+	}
+	if len(exec.BackendResults) != 1 {
+		t.Errorf("%d backend results recorded, want 1", len(exec.BackendResults))
+	}
+}
+
+func TestGetTaskTrends(t *testing.T) {
+	db := newTestDatabase(t)
+	base := time.Date(2025, 1, 27, 12, 0, 0, 0, time.UTC)
+	finish := func(id, status string, offset time.Duration, size, durationMs int64) {
+		t.Helper()
+		exec := createExecution(t, db, id, "running", offset)
+		completed := exec.StartedAt.Add(time.Minute)
+		exec.Status, exec.CompletedAt = status, &completed
+		exec.ArchiveSize, exec.DurationMs = size, durationMs
+		if err := db.UpdateExecution(exec); err != nil {
+			t.Fatalf("UpdateExecution: %v", err)
+		}
+	}
+	finish("a", "success", 10*time.Minute, 100, 1000)
+	finish("b", "failed", 40*time.Minute, 0, 3000)
+	finish("c", "success", 50*time.Minute, 300, 2000)
+	finish("d", "success", 150*time.Minute, 50, 500)
+	finish("before", "success", -30*time.Minute, 999, 999)
+	createExecution(t, db, "unfinished", "running", 160*time.Minute)
+	other := &models.Execution{ID: "other", TaskID: "task-2", TaskName: "photos", StartedAt: base.Add(20 * time.Minute), Status: "success", CompletedAt: &base}
+	if err := db.CreateExecution(other); err != nil {
+		t.Fatalf("CreateExecution: %v", err)
+	}
+
+	// Starts are truncated to the bucket width
+	trends, err := db.GetTaskTrends("task-1", base.Add(5*time.Minute), base.Add(179*time.Minute), time.Hour)
+	if err != nil {
+		t.Fatalf("GetTaskTrends: %v", err)
+	}
+	want := []models.TrendBucket{
+		{Start: base, Executions: 3, SuccessCount: 2, FailureCount: 1, AvgArchiveSize: 200, MaxArchiveSize: 300, AvgDurationMs: 2000, MaxDurationMs: 3000},
+		{Start: base.Add(time.Hour)},
+		{Start: base.Add(2 * time.Hour), Executions: 1, SuccessCount: 1, AvgArchiveSize: 50, MaxArchiveSize: 50, AvgDurationMs: 500, MaxDurationMs: 500},
+	}
+	if len(trends.Buckets) != len(want) {
+		t.Fatalf("%d buckets, want %d: %+v", len(trends.Buckets), len(want), trends.Buckets)
+	}
+	for i := range want {
+		got := trends.Buckets[i]
+		if !got.Start.Equal(want[i].Start) {
+			t.Errorf("bucket %d starts at %v, want %v", i, got.Start, want[i].Start)
+		}
+		got.Start = want[i].Start
+		if got != want[i] {
+			t.Errorf("bucket %d = %+v, want %+v", i, got, want[i])
+		}
+	}
+	if trends.BucketSeconds != 3600 {
+		t.Errorf("bucket_seconds = %d, want 3600", trends.BucketSeconds)
+	}
+
+	if _, err := db.GetTaskTrends("task-1", base, base.Add(time.Hour), 0); err == nil {
+		t.Error("zero bucket width accepted")
+	}
+	if _, err := db.GetTaskTrends("task-1", base, base.Add(2000*time.Hour), time.Hour); err == nil {
+		t.Error("window over the bucket limit accepted")
+	}
+}
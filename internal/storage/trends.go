@@ -0,0 +1,109 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/nsilverman/archivist/internal/models"
+)
+
+// maxTrendBuckets bounds the size of a trends response
+const maxTrendBuckets = 1000
+
+// GetTaskTrends returns a task's completed executions between start and end,
+// grouped into buckets of the given width. Archive sizes are averaged over
+// executions that produced an archive; durations over those that recorded one.
+func (d *Database) GetTaskTrends(taskID string, start, end time.Time, bucket time.Duration) (*models.TaskTrends, error) {
+	if bucket <= 0 {
+		return nil, fmt.Errorf("bucket width must be positive")
+	}
+
+	start = start.UTC().Truncate(bucket)
+	end = end.UTC()
+	count := int(end.Sub(start)/bucket) + 1
+	if count > maxTrendBuckets {
+		return nil, fmt.Errorf("window would produce %d buckets, the maximum is %d", count, maxTrendBuckets)
+	}
+
+	// Timestamps are stored as text in the writer's time zone, so the window
+	// is applied after parsing rather than by comparing strings in SQL
+	query := `
+		SELECT started_at, status, archive_size, duration_ms
+		FROM executions
+		WHERE task_id = ? AND completed_at IS NOT NULL
+	`
+
+	rows, err := d.db.Query(query, taskID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			log.Printf("Error closing rows: %v", err)
+		}
+	}()
+
+	buckets := make([]models.TrendBucket, count)
+	for i := range buckets {
+		buckets[i].Start = start.Add(time.Duration(i) * bucket)
+	}
+	sizeTotals := make([]int64, count)
+	sizeCounts := make([]int64, count)
+	durationTotals := make([]int64, count)
+	durationCounts := make([]int64, count)
+
+	for rows.Next() {
+		var startedAt time.Time
+		var status string
+		var archiveSize, durationMs sql.NullInt64
+		if err := rows.Scan(&startedAt, &status, &archiveSize, &durationMs); err != nil {
+			return nil, err
+		}
+
+		if startedAt.Before(start) || startedAt.After(end) {
+			continue
+		}
+		i := int(startedAt.Sub(start) / bucket)
+
+		b := &buckets[i]
+		b.Executions++
+		switch status {
+		case "success":
+			b.SuccessCount++
+		case "failed":
+			b.FailureCount++
+		}
+		if archiveSize.Valid && archiveSize.Int64 > 0 {
+			sizeTotals[i] += archiveSize.Int64
+			sizeCounts[i]++
+			b.MaxArchiveSize = max(b.MaxArchiveSize, archiveSize.Int64)
+		}
+		if durationMs.Valid {
+			durationTotals[i] += durationMs.Int64
+			durationCounts[i]++
+			b.MaxDurationMs = max(b.MaxDurationMs, durationMs.Int64)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range buckets {
+		if sizeCounts[i] > 0 {
+			buckets[i].AvgArchiveSize = sizeTotals[i] / sizeCounts[i]
+		}
+		if durationCounts[i] > 0 {
+			buckets[i].AvgDurationMs = durationTotals[i] / durationCounts[i]
+		}
+	}
+
+	return &models.TaskTrends{
+		TaskID:        taskID,
+		Start:         start,
+		End:           end,
+		BucketSeconds: int64(bucket / time.Second),
+		Buckets:       buckets,
+	}, nil
+}
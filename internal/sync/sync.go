@@ -1,16 +1,36 @@
 package sync
 
 import (
+	"compress/gzip"
 	"context"
 	"fmt"
+	"io"
+	"log"
 	"os"
+	"path"
 	"path/filepath"
+	"sort"
+	"strings"
 	"time"
 
+	"github.com/nsilverman/archivist/internal/archive"
 	"github.com/nsilverman/archivist/internal/backend"
+	"github.com/nsilverman/archivist/internal/ignore"
 	"github.com/nsilverman/archivist/internal/models"
 )
 
+// CompressedSuffix is appended to remote names of files compressed on upload
+const CompressedSuffix = ".gz"
+
+// Layouts for the remote copy of the source tree
+const (
+	LayoutPreserve = "preserve"
+	LayoutFlatten  = "flatten"
+)
+
+// fileRetryDelay is the wait before the first retry of a failed file; it grows linearly per attempt
+const fileRetryDelay = time.Second
+
 // ProgressCallback is called during sync to report progress
 type ProgressCallback func(phase string, current, total int, currentFile string)
 
@@ -32,6 +52,15 @@ type SyncResult struct {
 	BytesTotal    int64
 	BytesUploaded int64
 	Errors        []error
+	FailedFiles   []string // Relative paths that failed after all retries
+	Aborted       bool     // Stopped early because failures exceeded MaxFailures
+	DeletesHeld   int      // Remote files kept because a deletion guard stopped their removal
+	DeleteLimited bool     // Deletions exceeded MaxDeletePercent, so none were made
+}
+
+// Failed reports whether the sync had more failures than the options tolerate
+func (r *SyncResult) Failed(options models.SyncOptions) bool {
+	return r.Aborted || r.DeleteLimited || len(r.Errors) > options.MaxFailures
 }
 
 // Syncer handles file-by-file synchronization
@@ -41,6 +70,9 @@ type Syncer struct {
 	RemotePath string
 	Options    models.SyncOptions
 	Progress   ProgressCallback
+	TempDir    string // Directory for compressed staging files (empty = OS default)
+
+	flatNames map[string]string // local relative path -> remote name, when flattening
 }
 
 // NewSyncer creates a new syncer
@@ -60,11 +92,18 @@ func (s *Syncer) Sync(ctx context.Context) (*SyncResult, error) {
 
 	// Step 1: Scan local files
 	s.reportProgress("scanning_local", 0, 0, "")
-	localFiles, err := s.scanLocalFiles()
+	localFiles, oversized, err := s.scanLocalFiles()
 	if err != nil {
 		return nil, fmt.Errorf("failed to scan local files: %w", err)
 	}
-	result.FilesScanned = len(localFiles)
+	if err := s.assignRemoteNames(localFiles, oversized); err != nil {
+		return nil, err
+	}
+	result.FilesScanned = len(localFiles) + len(oversized)
+	result.FilesSkipped += len(oversized)
+	for _, file := range oversized {
+		log.Printf("Skipping %s: %d bytes exceeds max file size of %d bytes", file.RelativePath, file.Size, s.Options.MaxFileBytes)
+	}
 
 	// Calculate total bytes
 	for _, file := range localFiles {
@@ -79,22 +118,26 @@ func (s *Syncer) Sync(ctx context.Context) (*SyncResult, error) {
 	}
 
 	// Create a map of remote files for easy lookup
-	remoteFileMap := make(map[string]backend.BackupInfo)
-	for _, rf := range remoteFiles {
-		// Remove remote path prefix to get relative path
-		relPath := rf.Path
-		if s.RemotePath != "" && len(relPath) > len(s.RemotePath)+1 {
-			relPath = relPath[len(s.RemotePath)+1:]
-		}
-		remoteFileMap[relPath] = rf
+	remoteFileMap := s.remoteFileMap(remoteFiles)
+
+	// Oversized files are left alone remotely rather than treated as removed
+	for _, file := range oversized {
+		delete(remoteFileMap, s.remoteRelativePath(file.RelativePath))
 	}
+	s.keepIgnoredRemotes(remoteFileMap)
 
 	// Step 3: Compare and upload changed/new files
 	s.reportProgress("syncing", 0, len(localFiles), "")
 	for i, localFile := range localFiles {
+		if s.tooManyFailures(result) {
+			result.Aborted = true
+			return result, nil
+		}
+
 		s.reportProgress("syncing", i, len(localFiles), localFile.RelativePath)
 
-		remoteFile, exists := remoteFileMap[localFile.RelativePath]
+		remoteRelPath := s.remoteRelativePath(localFile.RelativePath)
+		remoteFile, exists := remoteFileMap[remoteRelPath]
 		needsUpload := false
 
 		if !exists {
@@ -107,39 +150,48 @@ func (s *Syncer) Sync(ctx context.Context) (*SyncResult, error) {
 
 		if needsUpload {
 			// Upload file
-			remotePath := filepath.Join(s.RemotePath, localFile.RelativePath)
+			remotePath := filepath.Join(s.remoteRoot(), remoteRelPath)
 			// Convert to forward slashes for remote paths
 			remotePath = filepath.ToSlash(remotePath)
 
-			// Create progress callback for this file
-			uploadProgress := func(uploaded, total int64) {
-				// Could report per-file progress here if needed
-			}
-
-			err := s.Backend.Upload(ctx, localFile.Path, remotePath, uploadProgress)
+			var uploaded int64
+			err := s.withRetries(ctx, func() error {
+				var err error
+				uploaded, err = s.uploadFile(ctx, localFile, remotePath)
+				return err
+			})
 			if err != nil {
 				result.Errors = append(result.Errors, fmt.Errorf("failed to upload %s: %w", localFile.RelativePath, err))
+				result.FailedFiles = append(result.FailedFiles, localFile.RelativePath)
 			} else {
 				result.FilesUploaded++
-				result.BytesUploaded += localFile.Size
+				result.BytesUploaded += uploaded
 			}
 		} else {
 			result.FilesSkipped++
 		}
 
 		// Remove from remote map (we'll use the remaining entries for deletion)
-		delete(remoteFileMap, localFile.RelativePath)
+		delete(remoteFileMap, remoteRelPath)
 	}
 
 	// Step 4: Delete remote files that don't exist locally (if enabled)
-	if s.Options.DeleteRemote && len(remoteFileMap) > 0 {
+	if s.Options.DeleteRemote && len(remoteFileMap) > 0 && s.deletionsAllowed(ctx, result, len(remoteFileMap), len(remoteFiles)) {
 		s.reportProgress("deleting", 0, len(remoteFileMap), "")
 		i := 0
 		for _, remoteFile := range remoteFileMap {
+			if s.tooManyFailures(result) {
+				result.Aborted = true
+				return result, nil
+			}
+
 			s.reportProgress("deleting", i, len(remoteFileMap), remoteFile.Path)
-			err := s.Backend.Delete(ctx, remoteFile.Path)
+			err := s.withRetries(ctx, func() error {
+				return s.Backend.Delete(ctx, remoteFile.Path)
+			})
 			if err != nil {
 				result.Errors = append(result.Errors, fmt.Errorf("failed to delete %s: %w", remoteFile.Path, err))
+				result.FailedFiles = append(result.FailedFiles, remoteFile.Path)
 			} else {
 				result.FilesDeleted++
 			}
@@ -152,6 +204,49 @@ func (s *Syncer) Sync(ctx context.Context) (*SyncResult, error) {
 	return result, nil
 }
 
+// deletionsAllowed decides whether remote files missing from the source may be
+// deleted once uploads are done. Deletions are held back when the upload phase
+// was cancelled, when DeleteAfterSuccessfulUpload is set and an upload failed,
+// and when they would remove more than MaxDeletePercent of the remote files.
+func (s *Syncer) deletionsAllowed(ctx context.Context, result *SyncResult, deletions, remoteTotal int) bool {
+	switch {
+	case ctx.Err() != nil:
+		log.Printf("Sync cancelled; keeping %d remote files that are missing from the source", deletions)
+	case s.Options.DeleteAfterSuccessfulUpload && len(result.Errors) > 0:
+		log.Printf("%d uploads failed; keeping %d remote files that are missing from the source", len(result.Errors), deletions)
+	case s.Options.MaxDeletePercent > 0 && deletions*100 > s.Options.MaxDeletePercent*remoteTotal:
+		result.DeleteLimited = true
+		result.Errors = append(result.Errors, fmt.Errorf("refusing to delete %d of %d remote files, more than max_delete_percent (%d%%); check the source or raise the limit",
+			deletions, remoteTotal, s.Options.MaxDeletePercent))
+	default:
+		return true
+	}
+
+	result.DeletesHeld = deletions
+	return false
+}
+
+// tooManyFailures reports whether failures have exceeded MaxFailures, in which
+// case the sync stops instead of working through the rest of the files.
+// With no tolerance configured every file is still attempted.
+func (s *Syncer) tooManyFailures(result *SyncResult) bool {
+	return s.Options.MaxFailures > 0 && len(result.Errors) > s.Options.MaxFailures
+}
+
+// withRetries runs op, retrying up to FileRetries more times with a growing delay
+func (s *Syncer) withRetries(ctx context.Context, op func() error) error {
+	err := op()
+	for attempt := 1; err != nil && attempt <= s.Options.FileRetries; attempt++ {
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(time.Duration(attempt) * fileRetryDelay):
+		}
+		err = op()
+	}
+	return err
+}
+
 // DryRun performs sync analysis without making changes
 func (s *Syncer) DryRun(ctx context.Context) (*models.SyncDetails, error) {
 	details := &models.SyncDetails{
@@ -161,10 +256,13 @@ func (s *Syncer) DryRun(ctx context.Context) (*models.SyncDetails, error) {
 	}
 
 	// Scan local files
-	localFiles, err := s.scanLocalFiles()
+	localFiles, oversized, err := s.scanLocalFiles()
 	if err != nil {
 		return nil, fmt.Errorf("failed to scan local files: %w", err)
 	}
+	if err := s.assignRemoteNames(localFiles, oversized); err != nil {
+		return nil, err
+	}
 
 	// List remote files
 	remoteFiles, err := s.listRemoteFiles(ctx)
@@ -173,18 +271,25 @@ func (s *Syncer) DryRun(ctx context.Context) (*models.SyncDetails, error) {
 	}
 
 	// Create remote file map
-	remoteFileMap := make(map[string]backend.BackupInfo)
-	for _, rf := range remoteFiles {
-		relPath := rf.Path
-		if s.RemotePath != "" && len(relPath) > len(s.RemotePath)+1 {
-			relPath = relPath[len(s.RemotePath)+1:]
-		}
-		remoteFileMap[relPath] = rf
+	remoteFileMap := s.remoteFileMap(remoteFiles)
+
+	// Oversized files are skipped and their remote copies left alone
+	for _, file := range oversized {
+		details.FilesToSkip = append(details.FilesToSkip, models.FileDetail{
+			RelativePath: file.RelativePath,
+			Size:         file.Size,
+			ModTime:      file.ModTime,
+			Reason:       fmt.Sprintf("Exceeds max file size (%d bytes)", s.Options.MaxFileBytes),
+		})
+		details.SkipCount++
+		delete(remoteFileMap, s.remoteRelativePath(file.RelativePath))
 	}
+	s.keepIgnoredRemotes(remoteFileMap)
 
 	// Analyze what would happen
 	for _, localFile := range localFiles {
-		remoteFile, exists := remoteFileMap[localFile.RelativePath]
+		remoteRelPath := s.remoteRelativePath(localFile.RelativePath)
+		remoteFile, exists := remoteFileMap[remoteRelPath]
 
 		fileDetail := models.FileDetail{
 			RelativePath: localFile.RelativePath,
@@ -209,7 +314,7 @@ func (s *Syncer) DryRun(ctx context.Context) (*models.SyncDetails, error) {
 			details.SkipCount++
 		}
 
-		delete(remoteFileMap, localFile.RelativePath)
+		delete(remoteFileMap, remoteRelPath)
 	}
 
 	// Files remaining in remote map would be deleted
@@ -225,18 +330,29 @@ func (s *Syncer) DryRun(ctx context.Context) (*models.SyncDetails, error) {
 
 // getUploadReason explains why a file would be uploaded
 func (s *Syncer) getUploadReason(local FileInfo, remote backend.BackupInfo) string {
-	if local.Size != remote.Size {
+	if modTime, size, ok := remote.SourceFile(); ok {
+		if local.Size != size {
+			return "Size changed"
+		}
+		if local.ModTime.Before(modTime) {
+			return "Modified timestamp older"
+		}
+		return "Modified timestamp newer"
+	}
+
+	if !s.Options.CompressFiles && local.Size != remote.Size {
 		return "Size changed"
 	}
 
 	return "Modified timestamp newer"
 }
 
-// scanLocalFiles scans the source directory and returns a list of files
-func (s *Syncer) scanLocalFiles() ([]FileInfo, error) {
-	var files []FileInfo
-
-	err := filepath.Walk(s.SourcePath, func(path string, info os.FileInfo, err error) error {
+// scanLocalFiles scans the source directory and returns the files to sync,
+// plus the files skipped for exceeding MaxFileBytes. A single-file source
+// yields just that file.
+func (s *Syncer) scanLocalFiles() (files []FileInfo, oversized []FileInfo, err error) {
+	root := archive.SourceRoot(s.SourcePath)
+	err = ignore.Walk(s.SourcePath, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
@@ -247,7 +363,7 @@ func (s *Syncer) scanLocalFiles() ([]FileInfo, error) {
 		}
 
 		// Get relative path
-		relPath, err := filepath.Rel(s.SourcePath, path)
+		relPath, err := filepath.Rel(root, path)
 		if err != nil {
 			return err
 		}
@@ -259,22 +375,128 @@ func (s *Syncer) scanLocalFiles() ([]FileInfo, error) {
 			ModTime:      info.ModTime(),
 		}
 
+		if s.Options.MaxFileBytes > 0 && info.Size() > s.Options.MaxFileBytes {
+			oversized = append(oversized, fileInfo)
+			return nil
+		}
+
 		files = append(files, fileInfo)
 		return nil
 	})
 
-	return files, err
+	return files, oversized, err
 }
 
 // listRemoteFiles lists all files in the remote directory
 func (s *Syncer) listRemoteFiles(ctx context.Context) ([]backend.BackupInfo, error) {
-	return s.Backend.List(ctx, s.RemotePath)
+	return s.Backend.List(ctx, s.remoteRoot())
+}
+
+// remoteRoot is the remote directory files are synced into: RemotePath plus the optional sub-prefix
+func (s *Syncer) remoteRoot() string {
+	if s.Options.RemotePrefix == "" {
+		return s.RemotePath
+	}
+	return filepath.ToSlash(filepath.Join(s.RemotePath, s.Options.RemotePrefix))
+}
+
+// remoteFileMap indexes remote files by their path relative to the remote root
+func (s *Syncer) remoteFileMap(remoteFiles []backend.BackupInfo) map[string]backend.BackupInfo {
+	root := s.remoteRoot()
+	remoteFileMap := make(map[string]backend.BackupInfo, len(remoteFiles))
+	for _, rf := range remoteFiles {
+		// Remove remote path prefix to get relative path
+		relPath := rf.Path
+		if root != "" && len(relPath) > len(root)+1 {
+			relPath = relPath[len(root)+1:]
+		}
+		remoteFileMap[relPath] = rf
+	}
+	return remoteFileMap
+}
+
+// keepIgnoredRemotes drops remote files whose source files are excluded by an
+// ignore file, so they are kept rather than deleted as removed. Flattened names
+// can't be traced back to a source path, so with that layout they are deleted.
+func (s *Syncer) keepIgnoredRemotes(remoteFileMap map[string]backend.BackupInfo) {
+	if s.flatNames != nil {
+		return
+	}
+	if info, err := os.Stat(s.SourcePath); err != nil || !info.IsDir() {
+		return
+	}
+
+	matcher := ignore.NewMatcher(s.SourcePath)
+	for relPath := range remoteFileMap {
+		if s.Options.CompressFiles {
+			relPath = strings.TrimSuffix(relPath, CompressedSuffix)
+		}
+		if matcher.Excludes(relPath) {
+			delete(remoteFileMap, s.remoteRelativePath(relPath))
+		}
+	}
+}
+
+// assignRemoteNames works out the remote name of every file when flattening.
+// A file keeps its base name unless another file shares it; then all files with
+// that name get their directory appended, so "a/b/notes.txt" becomes
+// "notes~a_b.txt". Names depend only on the set of source paths, so they are
+// the same on every run. Any collision left after that is an error.
+func (s *Syncer) assignRemoteNames(fileSets ...[]FileInfo) error {
+	switch s.Options.Layout {
+	case "", LayoutPreserve:
+		s.flatNames = nil
+		return nil
+	case LayoutFlatten:
+	default:
+		return fmt.Errorf("unknown sync layout: %s", s.Options.Layout)
+	}
+
+	byName := make(map[string][]string)
+	for _, files := range fileSets {
+		for _, file := range files {
+			relPath := filepath.ToSlash(file.RelativePath)
+			name := path.Base(relPath)
+			byName[name] = append(byName[name], relPath)
+		}
+	}
+
+	s.flatNames = make(map[string]string)
+	owners := make(map[string][]string)
+	for name, relPaths := range byName {
+		for _, relPath := range relPaths {
+			flatName := name
+			if len(relPaths) > 1 {
+				if dir := path.Dir(relPath); dir != "." {
+					ext := path.Ext(name)
+					flatName = strings.TrimSuffix(name, ext) + "~" + strings.ReplaceAll(dir, "/", "_") + ext
+				}
+			}
+			s.flatNames[relPath] = flatName
+			owners[flatName] = append(owners[flatName], relPath)
+		}
+	}
+
+	for flatName, relPaths := range owners {
+		if len(relPaths) > 1 {
+			sort.Strings(relPaths)
+			return fmt.Errorf("cannot flatten: %s all map to %s", strings.Join(relPaths, ", "), flatName)
+		}
+	}
+	return nil
 }
 
 // needsUpload determines if a file needs to be uploaded based on size and modification time
 func (s *Syncer) needsUpload(local FileInfo, remote backend.BackupInfo) bool {
-	// Compare size first (fast check)
-	if local.Size != remote.Size {
+	// Metadata recorded on upload describes the source file exactly, so any
+	// difference, including an older mtime after a restore, means it changed
+	if modTime, size, ok := remote.SourceFile(); ok {
+		return local.Size != size || !local.ModTime.Equal(modTime)
+	}
+
+	// Compare size first (fast check). Compressed remotes have a different size
+	// than the local file, so only the timestamp can be compared.
+	if !s.Options.CompressFiles && local.Size != remote.Size {
 		return true
 	}
 
@@ -289,6 +511,164 @@ func (s *Syncer) needsUpload(local FileInfo, remote backend.BackupInfo) bool {
 	return local.ModTime.After(remoteModTime.Add(time.Second))
 }
 
+// remoteRelativePath maps a local relative path to its name under the remote root
+func (s *Syncer) remoteRelativePath(relPath string) string {
+	if s.flatNames != nil {
+		relPath = s.flatNames[filepath.ToSlash(relPath)]
+	}
+	if s.Options.CompressFiles {
+		return relPath + CompressedSuffix
+	}
+	return relPath
+}
+
+// uploadFile uploads a single file, compressing it first if enabled.
+// Returns the number of bytes sent to the backend.
+func (s *Syncer) uploadFile(ctx context.Context, localFile FileInfo, remotePath string) (int64, error) {
+	// Create progress callback for this file
+	uploadProgress := func(uploaded, total int64) {
+		// Could report per-file progress here if needed
+	}
+
+	// Record the source file's mtime and size, which needsUpload compares on the next run
+	ctx = backend.WithMetadata(ctx, backend.FileMetadata(localFile.ModTime, localFile.Size))
+
+	if !s.Options.CompressFiles {
+		if err := s.Backend.Upload(ctx, localFile.Path, remotePath, uploadProgress); err != nil {
+			return 0, err
+		}
+		return localFile.Size, nil
+	}
+
+	compressedPath, compressedSize, err := s.compressFile(localFile)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err := os.Remove(compressedPath); err != nil {
+			log.Printf("Error removing compressed staging file: %v", err)
+		}
+	}()
+
+	if err := s.Backend.Upload(ctx, compressedPath, remotePath, uploadProgress); err != nil {
+		return 0, err
+	}
+	return compressedSize, nil
+}
+
+// compressFile gzips a local file into a staging file and returns its path and size
+func (s *Syncer) compressFile(localFile FileInfo) (string, int64, error) {
+	src, err := os.Open(localFile.Path)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer func() {
+		if err := src.Close(); err != nil {
+			log.Printf("Error closing file: %v", err)
+		}
+	}()
+
+	dst, err := os.CreateTemp(s.TempDir, "archivist-sync-*"+CompressedSuffix)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to create staging file: %w", err)
+	}
+
+	gzipWriter := gzip.NewWriter(dst)
+	gzipWriter.Name = filepath.Base(localFile.Path)
+	gzipWriter.ModTime = localFile.ModTime
+
+	_, copyErr := io.Copy(gzipWriter, src)
+	if closeErr := gzipWriter.Close(); copyErr == nil {
+		copyErr = closeErr
+	}
+	if closeErr := dst.Close(); copyErr == nil {
+		copyErr = closeErr
+	}
+	if copyErr != nil {
+		if err := os.Remove(dst.Name()); err != nil {
+			log.Printf("Error removing staging file: %v", err)
+		}
+		return "", 0, fmt.Errorf("failed to compress file: %w", copyErr)
+	}
+
+	info, err := os.Stat(dst.Name())
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to stat staging file: %w", err)
+	}
+	return dst.Name(), info.Size(), nil
+}
+
+// IsCompressedFile reports whether a downloaded file was compressed on upload
+// by a sync task: it has CompressedSuffix and its gzip header names the file
+// without it, as compressFile records.
+func IsCompressedFile(path string) bool {
+	if !strings.HasSuffix(path, CompressedSuffix) {
+		return false
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer func() {
+		if err := f.Close(); err != nil {
+			log.Printf("Error closing file: %v", err)
+		}
+	}()
+
+	gzipReader, err := gzip.NewReader(f)
+	if err != nil {
+		return false
+	}
+	return gzipReader.Name != "" && gzipReader.Name == strings.TrimSuffix(filepath.Base(path), CompressedSuffix)
+}
+
+// DecompressFile restores a file that was compressed on upload.
+// The caller is responsible for stripping CompressedSuffix from the destination name.
+func DecompressFile(compressedPath, destPath string) error {
+	src, err := os.Open(compressedPath)
+	if err != nil {
+		return fmt.Errorf("failed to open compressed file: %w", err)
+	}
+	defer func() {
+		if err := src.Close(); err != nil {
+			log.Printf("Error closing file: %v", err)
+		}
+	}()
+
+	gzipReader, err := gzip.NewReader(src)
+	if err != nil {
+		return fmt.Errorf("failed to read gzip header: %w", err)
+	}
+	defer func() {
+		if err := gzipReader.Close(); err != nil {
+			log.Printf("Error closing gzip reader: %v", err)
+		}
+	}()
+
+	dst, err := os.Create(destPath)
+	if err != nil {
+		return fmt.Errorf("failed to create destination file: %w", err)
+	}
+	if _, err := io.Copy(dst, gzipReader); err != nil {
+		if closeErr := dst.Close(); closeErr != nil {
+			log.Printf("Error closing destination file: %v", closeErr)
+		}
+		return fmt.Errorf("failed to decompress file: %w", err)
+	}
+	if err := dst.Close(); err != nil {
+		return fmt.Errorf("failed to finalize destination file: %w", err)
+	}
+
+	// Restore the original modification time recorded in the gzip header
+	if !gzipReader.ModTime.IsZero() {
+		if err := os.Chtimes(destPath, gzipReader.ModTime, gzipReader.ModTime); err != nil {
+			log.Printf("Warning: failed to restore modification time: %v", err)
+		}
+	}
+
+	return nil
+}
+
 // reportProgress reports sync progress
 func (s *Syncer) reportProgress(phase string, current, total int, file string) {
 	if s.Progress != nil {
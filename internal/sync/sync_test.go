@@ -0,0 +1,135 @@
+package sync
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/nsilverman/archivist/internal/backend"
+	"github.com/nsilverman/archivist/internal/models"
+)
+
+// rootResolver resolves paths as given
+type rootResolver struct{}
+
+func (rootResolver) ResolvePath(path string) string { return path }
+
+// writeGzip writes data gzipped to path, naming name in the header
+func writeGzip(t *testing.T, path string, data []byte, name string) {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	gz.Name = name
+	if _, err := gz.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestIsCompressedFile(t *testing.T) {
+	dir := t.TempDir()
+	writeGzip(t, filepath.Join(dir, "notes.txt.gz"), []byte("notes"), "notes.txt")
+	writeGzip(t, filepath.Join(dir, "unnamed.txt.gz"), []byte("notes"), "")
+	writeGzip(t, filepath.Join(dir, "renamed.txt.gz"), []byte("notes"), "original.txt")
+	writeGzip(t, filepath.Join(dir, "named.txt"), []byte("notes"), "named")
+	if err := os.WriteFile(filepath.Join(dir, "plain.gz"), []byte("not gzip"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"notes.txt.gz", true},
+		{"unnamed.txt.gz", false},
+		{"renamed.txt.gz", false},
+		{"named.txt", false},
+		{"plain.gz", false},
+		{"missing.gz", false},
+	}
+	for _, tt := range tests {
+		if got := IsCompressedFile(filepath.Join(dir, tt.name)); got != tt.want {
+			t.Errorf("IsCompressedFile(%s) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCompressedSyncRoundTrip(t *testing.T) {
+	source := t.TempDir()
+	modTime := time.Date(2024, 6, 1, 8, 30, 0, 0, time.UTC)
+	files := map[string][]byte{
+		"report.csv":      []byte("a,b\n1,2\n"),
+		"nested/data.bin": bytes.Repeat([]byte{0, 1, 2, 3}, 1024),
+	}
+	for name, data := range files {
+		path := filepath.Join(source, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	storeDir := t.TempDir()
+	b, err := backend.Factory(&models.Backend{
+		ID:     "local",
+		Name:   "local",
+		Type:   "local",
+		Config: map[string]interface{}{"path": storeDir},
+	}, rootResolver{})
+	if err != nil {
+		t.Fatalf("Factory: %v", err)
+	}
+	defer func() { _ = b.Close() }()
+
+	syncer := NewSyncer(source, b, "docs", models.SyncOptions{CompressFiles: true}, nil)
+	syncer.TempDir = t.TempDir()
+	result, err := syncer.Sync(context.Background())
+	if err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	if len(result.Errors) != 0 {
+		t.Fatalf("sync errors: %v", result.Errors)
+	}
+
+	restoreDir := t.TempDir()
+	for name, data := range files {
+		stored := filepath.Join(storeDir, "docs", filepath.FromSlash(name)+CompressedSuffix)
+		if !IsCompressedFile(stored) {
+			t.Errorf("%s was not stored compressed", name)
+			continue
+		}
+
+		restored := filepath.Join(restoreDir, filepath.Base(name))
+		if err := DecompressFile(stored, restored); err != nil {
+			t.Fatalf("DecompressFile(%s): %v", name, err)
+		}
+		got, err := os.ReadFile(restored)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, data) {
+			t.Errorf("%s restored with different contents", name)
+		}
+		info, err := os.Stat(restored)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !info.ModTime().Equal(modTime) {
+			t.Errorf("%s restored with modification time %v, want %v", name, info.ModTime(), modTime)
+		}
+	}
+}